@@ -0,0 +1,174 @@
+// Command backtest replays historical transactions through a rule
+// configuration file and prints how its decisions compare with the stored
+// outcomes and, if given, fraud labels. It only reads from the database.
+//
+//	go run ./cmd/backtest -config rules.json [-from 2024-01-01T00:00:00Z] [-to ...] [-labels labels.csv] [-geoip ranges.csv] [-model model.json] [-velocity-windows 1m,5m,1h,24h] [-json]
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"risk-detection/internal/db"
+	"risk-detection/internal/geo"
+	"risk-detection/internal/risk"
+	"risk-detection/internal/risk/backtest"
+	"risk-detection/internal/risk/velocity"
+)
+
+func main() {
+	configPath := flag.String("config", "", "rule configuration file (JSON with rules and policies)")
+	fromFlag := flag.String("from", "", "report transactions from this RFC3339 time")
+	toFlag := flag.String("to", "", "replay transactions before this RFC3339 time")
+	labelsPath := flag.String("labels", "", "optional CSV of transaction_id,label (FRAUD or LEGIT)")
+	geoipPath := flag.String("geoip", "", "optional IP range CSV enabling GEO_LOCATION_RISK")
+	modelPath := flag.String("model", "", "optional model file enabling MODEL_RISK")
+	windowsFlag := flag.String("velocity-windows", os.Getenv("VELOCITY_WINDOWS"), "comma-separated velocity windows, e.g. 1m,5m,1h,24h (default from VELOCITY_WINDOWS)")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	verbose := flag.Bool("v", false, "keep scorer logging")
+	flag.Parse()
+
+	if *configPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	from, err := parseTime(*fromFlag)
+	if err != nil {
+		log.Fatalf("invalid -from: %v", err)
+	}
+	to, err := parseTime(*toFlag)
+	if err != nil {
+		log.Fatalf("invalid -to: %v", err)
+	}
+
+	cfg, err := backtest.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("unable to load config: %v", err)
+	}
+
+	in := backtest.Input{From: from, To: to}
+
+	if *labelsPath != "" {
+		f, err := os.Open(*labelsPath)
+		if err != nil {
+			log.Fatalf("unable to open labels: %v", err)
+		}
+		in.Labels, err = backtest.LoadLabels(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("unable to read labels: %v", err)
+		}
+	}
+
+	if *windowsFlag != "" {
+		in.VelocityWindows, err = velocity.ParseWindows(*windowsFlag)
+		if err != nil {
+			log.Fatalf("invalid -velocity-windows: %v", err)
+		}
+	}
+
+	if *geoipPath != "" {
+		locator, err := geo.LoadCSV(*geoipPath)
+		if err != nil {
+			log.Fatalf("unable to load geoip ranges: %v", err)
+		}
+		in.Locator = locator
+	}
+
+	if *modelPath != "" {
+		in.Model, err = risk.NewModelLoader(*modelPath, nil)
+		if err != nil {
+			log.Fatalf("unable to load model: %v", err)
+		}
+	}
+
+	DB, err := db.Connect()
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	// Everything is read inside a read-only transaction that is rolled back,
+	// so the backtest cannot write to production tables.
+	ctx := context.Background()
+	tx := DB.WithContext(ctx).Begin(&sql.TxOptions{ReadOnly: true})
+	if tx.Error != nil {
+		log.Fatalf("unable to start read-only transaction: %v", tx.Error)
+	}
+	defer tx.Rollback()
+
+	in.Records, err = backtest.LoadRecords(ctx, tx, to)
+	if err != nil {
+		log.Fatalf("unable to load transactions: %v", err)
+	}
+	in.Devices, err = backtest.LoadDevices(ctx, tx)
+	if err != nil {
+		log.Fatalf("unable to load devices: %v", err)
+	}
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+	report, err := backtest.Run(ctx, cfg, in)
+	log.SetOutput(os.Stderr)
+	if err != nil {
+		log.Fatalf("backtest failed: %v", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	printReport(os.Stdout, report)
+}
+
+func parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func printReport(out io.Writer, r *backtest.Report) {
+	fmt.Fprintf(out, "replayed %d transactions, scored %d, failed %d\n", r.Replayed, r.Scored, r.ScoreFailure)
+	fmt.Fprintf(out, "decision changed for %d, no original outcome for %d\n\n", r.Changed, r.NoOriginal)
+
+	fmt.Fprintln(out, "original vs backtest")
+	printMatrix(out, r.VsOriginal, backtest.Decisions)
+
+	if r.VsLabels != nil {
+		fmt.Fprintf(out, "\nlabels vs backtest (%d labeled)\n", r.VsLabels.Labeled)
+		printMatrix(out, r.VsLabels.Matrix, []string{backtest.LabelFraud, backtest.LabelLegit})
+		fmt.Fprintf(out, "\nprecision %.3f  recall %.3f\n", r.VsLabels.Precision, r.VsLabels.Recall)
+	}
+}
+
+func printMatrix(out io.Writer, m backtest.ConfusionMatrix, rows []string) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(w, "\t")
+	for _, d := range backtest.Decisions {
+		fmt.Fprintf(w, "%s\t", d)
+	}
+	fmt.Fprintln(w)
+
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s\t", row)
+		for _, d := range backtest.Decisions {
+			fmt.Fprintf(w, "%d\t", m[row][d])
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
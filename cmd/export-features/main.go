@@ -0,0 +1,93 @@
+// Command export-features writes the feature snapshots of risk evaluations
+// in a date range, joined with their outcomes and fraud labels, as training
+// data. It only reads from the database.
+//
+//	go run ./cmd/export-features -from 2024-01-01T00:00:00Z -to 2024-02-01T00:00:00Z [-format csv|ndjson] [-pseudonymize] [-labels labels.csv] [-out features.csv]
+//
+// Pseudonyms are keyed by FEATURE_EXPORT_KEY so exports can be joined; without
+// it every export draws its own.
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"risk-detection/internal/db"
+	"risk-detection/internal/risk"
+	"risk-detection/internal/risk/backtest"
+)
+
+func main() {
+	fromFlag := flag.String("from", "", "export snapshots evaluated from this RFC3339 time")
+	toFlag := flag.String("to", "", "export snapshots evaluated before this RFC3339 time")
+	format := flag.String("format", risk.ExportCSV, "output format, csv or ndjson")
+	pseudonymize := flag.Bool("pseudonymize", false, "replace user IDs with keyed pseudonyms")
+	labelsPath := flag.String("labels", "", "optional CSV of transaction_id,label (FRAUD or LEGIT) overriding stored labels")
+	outPath := flag.String("out", "", "output file (default stdout)")
+	flag.Parse()
+
+	if *fromFlag == "" || *toFlag == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	from, err := time.Parse(time.RFC3339, *fromFlag)
+	if err != nil {
+		log.Fatalf("invalid -from: %v", err)
+	}
+	to, err := time.Parse(time.RFC3339, *toFlag)
+	if err != nil {
+		log.Fatalf("invalid -to: %v", err)
+	}
+
+	req := risk.FeatureExportRequest{From: from, To: to, Format: *format, Pseudonymize: *pseudonymize}
+
+	if *labelsPath != "" {
+		f, err := os.Open(*labelsPath)
+		if err != nil {
+			log.Fatalf("unable to open labels: %v", err)
+		}
+		req.Labels, err = backtest.LoadLabels(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("unable to read labels: %v", err)
+		}
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatalf("unable to create output: %v", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	buf := bufio.NewWriter(out)
+
+	DB, err := db.Connect()
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	ctx := context.Background()
+	tx := DB.WithContext(ctx).Begin(&sql.TxOptions{ReadOnly: true})
+	if tx.Error != nil {
+		log.Fatalf("unable to start read-only transaction: %v", tx.Error)
+	}
+	defer tx.Rollback()
+
+	svc := risk.NewFeatureExportService(risk.NewFeatureSnapshotRepository(tx), nil, []byte(os.Getenv("FEATURE_EXPORT_KEY")))
+	if err := svc.Export(ctx, "", req, buf); err != nil {
+		log.Fatalf("export failed: %v", err)
+	}
+	if err := buf.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
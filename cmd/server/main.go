@@ -2,18 +2,30 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	"risk-detection/internal/audit"
 	"risk-detection/internal/auth"
+	"risk-detection/internal/callback"
+	"risk-detection/internal/challenge"
 	"risk-detection/internal/db"
+	"risk-detection/internal/geo"
+	"risk-detection/internal/idempotency"
+	"risk-detection/internal/review"
 	"risk-detection/internal/risk"
 	"risk-detection/internal/risk/cronjob"
+	"risk-detection/internal/risk/velocity"
 	customrouter "risk-detection/internal/router"
 	"risk-detection/internal/transaction"
+	"risk-detection/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 )
@@ -48,19 +60,224 @@ func main() {
 	transactionRepo := transaction.NewRepository(DB)
 
 	riskRepo := risk.NewRepository(DB)
-	riskService, err := risk.NewService(riskRepo, transactionRepo, auditLogger)
+	policyRepo := risk.NewPolicyRepository(DB)
+	deviceRepo := risk.NewDeviceRepository(DB)
+	counterpartyRepo := risk.NewCounterpartyRepository(DB)
+	listRepo := risk.NewListRepository(DB)
+	snapshotRepo := risk.NewFeatureSnapshotRepository(DB)
+
+	listIndex := risk.NewListIndex(listRepo)
+	if err := listIndex.Reload(ctx); err != nil {
+		log.Fatalf("unable to load block and allow lists: %v", err)
+	}
+
+	// Velocity windows default to 1m,5m,1h,24h
+	velocityWindows := velocity.DefaultWindows
+	if spec := os.Getenv("VELOCITY_WINDOWS"); spec != "" {
+		velocityWindows, err = velocity.ParseWindows(spec)
+		if err != nil {
+			log.Fatalf("invalid VELOCITY_WINDOWS: %v", err)
+		}
+	}
+	velocityStore := velocity.NewStore(velocityWindows)
+	warmed, err := risk.WarmVelocity(ctx, risk.NewVelocityRepository(DB), velocityStore, time.Now())
+	if err != nil {
+		log.Fatalf("unable to warm velocity store: %v", err)
+	}
+	log.Printf("velocity store warmed with %d transactions", warmed)
+
+	riskOpts := []risk.Option{
+		risk.WithVelocity(velocityStore),
+		risk.WithPolicyRepository(policyRepo),
+		risk.WithDeviceRepository(deviceRepo),
+		risk.WithCounterpartyRepository(counterpartyRepo),
+		risk.WithLists(listIndex),
+		risk.WithFeatureSnapshots(snapshotRepo),
+	}
+
+	// Scoring that overruns the deadline falls back to a FLAG decision
+	evaluationTimeout := 2 * time.Second
+	if spec := os.Getenv("EVALUATION_TIMEOUT"); spec != "" {
+		evaluationTimeout, err = time.ParseDuration(spec)
+		if err != nil {
+			log.Fatalf("invalid EVALUATION_TIMEOUT: %v", err)
+		}
+	}
+	riskOpts = append(riskOpts, risk.WithEvaluationTimeout(evaluationTimeout))
+
+	// IP geolocation is optional; without a range file GEO_LOCATION_RISK never fires
+	if path := os.Getenv("GEOIP_CSV"); path != "" {
+		locator, err := geo.LoadCSV(path)
+		if err != nil {
+			log.Fatalf("unable to load geoip ranges: %v", err)
+		}
+		riskOpts = append(riskOpts, risk.WithGeoLocation(locator, risk.NewSightingRepository(DB)))
+	}
+
+	// The model is optional; without a model file MODEL_RISK never fires
+	var modelLoader *risk.ModelLoader
+	if path := os.Getenv("MODEL_PATH"); path != "" {
+		modelLoader, err = risk.NewModelLoader(path, auditLogger)
+		if err != nil {
+			log.Fatalf("unable to load model: %v", err)
+		}
+		riskOpts = append(riskOpts, risk.WithModel(modelLoader))
+	}
+
+	riskService, err := risk.NewService(riskRepo, transactionRepo, auditLogger, riskOpts...)
     if err !=  nil {
         log.Fatal("unble to load rules in risks")
     }
 
+	ruleRepo := risk.NewRuleRepository(DB)
+	ruleService := risk.NewRuleService(ruleRepo, riskService, auditLogger)
+	ruleHandler := risk.NewRuleHandler(ruleService)
+
+	policyService := risk.NewPolicyService(policyRepo, riskService, auditLogger)
+	policyHandler := risk.NewPolicyHandler(policyService)
+
+	deviceService := risk.NewDeviceService(deviceRepo, auditLogger)
+	deviceHandler := risk.NewDeviceHandler(deviceService)
+
+	counterpartyService := risk.NewCounterpartyService(counterpartyRepo)
+	counterpartyHandler := risk.NewCounterpartyHandler(counterpartyService)
+
+	listService := risk.NewListService(listRepo, listIndex, riskService, auditLogger)
+	listHandler := risk.NewListHandler(listService)
+
+	modelHandler := risk.NewModelHandler(modelLoader)
+
+	// Without a key pseudonyms differ between exports and cannot be joined
+	featureExportService := risk.NewFeatureExportService(snapshotRepo, auditLogger, []byte(os.Getenv("FEATURE_EXPORT_KEY")))
+	featureSnapshotHandler := risk.NewFeatureSnapshotHandler(featureExportService)
+
+	labelService := risk.NewLabelService(risk.NewLabelRepository(DB), riskRepo, auditLogger)
+	rulePerformanceService := risk.NewRulePerformanceService(risk.NewRulePerformanceRepository(DB))
+	labelHandler := risk.NewLabelHandler(labelService, rulePerformanceService)
+
 	updater := cronjob.NewParameterUpdater(riskRepo ,auditLogger)
 	cronjob.StartBehaviorCron(ctx, updater)
+	cronjob.StartReceiverProfileCron(ctx, cronjob.NewReceiverProfileUpdater(counterpartyRepo, auditLogger))
+	cronjob.StartVelocitySweepCron(velocityStore)
+	cronjob.StartListRefreshCron(ctx, listIndex)
+	cronjob.StartRulePerformanceCron(ctx, rulePerformanceService)
+	if modelLoader != nil {
+		cronjob.StartModelReloadCron(ctx, modelLoader)
+	}
 
-	transactionService := transaction.NewService(transactionRepo, riskService, auditLogger)
+	reviewService := review.NewService(review.NewRepository(DB), transactionRepo, db.NewUnitOfWork(DB), auditLogger)
+	reviewHandler := review.NewHandler(reviewService)
+
+	// Flagged transactions go to the review queue unless STEP_UP_NOTIFIER
+	// names a notifier explicitly; then they get a step-up code instead
+	var challengeService challenge.Service
+	var challengeIssuer transaction.ChallengeIssuer
+	var notifier challenge.Notifier
+	switch os.Getenv("STEP_UP_NOTIFIER") {
+	case "console":
+		notifier = challenge.NewConsoleNotifier()
+	case "file":
+		path := os.Getenv("STEP_UP_NOTIFIER_FILE")
+		if path == "" {
+			path = "step_up_codes.log"
+		}
+		fileNotifier, err := challenge.NewFileNotifier(path)
+		if err != nil {
+			log.Fatalf("unable to open step-up notifier file: %v", err)
+		}
+		defer fileNotifier.Close()
+		notifier = fileNotifier
+	case "", "off":
+	default:
+		log.Fatalf("invalid STEP_UP_NOTIFIER: %s", os.Getenv("STEP_UP_NOTIFIER"))
+	}
+	if notifier != nil {
+		challengeService = challenge.NewService(challenge.NewRepository(DB), transactionRepo, db.NewUnitOfWork(DB), riskService, notifier, auditLogger, 5*time.Minute)
+		challengeIssuer = challengeService
+		cronjob.StartChallengeExpiryCron(ctx, challengeService)
+	}
+	challengeHandler := challenge.NewHandler(challengeService)
+
+	// Retries with the same Idempotency-Key replay the response for a day
+	idempotencyService := idempotency.NewService(idempotency.NewRepository(DB), 24*time.Hour)
+	cronjob.StartIdempotencyPurgeCron(ctx, idempotencyService)
+
+	callbackService := callback.NewService(callback.NewRepository(DB), callback.NewClient(5*time.Second), auditLogger)
+	callbackHandler := callback.NewHandler(callbackService)
+
+	// Decisions are queued for webhook subscribers with the evaluation and
+	// delivered, signed, by the cron with retries
+	webhookService := webhook.NewService(webhook.NewRepository(DB), callback.NewClient(10*time.Second), auditLogger)
+	webhookHandler := webhook.NewHandler(webhookService)
+	cronjob.StartWebhookDeliveryCron(ctx, webhookService)
+
+	// mode=async submissions wait in a queue of ASYNC_QUEUE_DEPTH for one of
+	// ASYNC_WORKERS workers
+	asyncWorkers := envInt("ASYNC_WORKERS", 4)
+	asyncQueueDepth := envInt("ASYNC_QUEUE_DEPTH", 100)
+	workerPool := transaction.NewWorkerPool(asyncWorkers, asyncQueueDepth)
+
+	transactionService := transaction.NewService(transactionRepo, db.NewUnitOfWork(DB), riskService, reviewService, challengeIssuer, idempotencyService, workerPool, callbackService, webhookService, auditLogger)
 	transactionHandler := transaction.NewHandler(transactionService)
 
-	customrouter.RegisterRoutes(router, authHandler, transactionHandler, jwtSecret)
+	workerPool.Start(transactionService.EvaluateQueued)
+	resumed, err := transactionService.ResumePending(ctx)
+	if err != nil {
+		log.Fatalf("unable to resume pending transactions: %v", err)
+	}
+	log.Printf("queued %d pending transactions", resumed)
+	cronjob.StartPendingResumeCron(ctx, transactionService)
+
+	customrouter.RegisterRoutes(router, authHandler, transactionHandler, ruleHandler, policyHandler, deviceHandler, counterpartyHandler, listHandler, modelHandler, featureSnapshotHandler, labelHandler, reviewHandler, challengeHandler, callbackHandler, webhookHandler, jwtSecret)
+
+	shutdownTimeout := 30 * time.Second
+	if spec := os.Getenv("SHUTDOWN_TIMEOUT"); spec != "" {
+		shutdownTimeout, err = time.ParseDuration(spec)
+		if err != nil {
+			log.Fatalf("invalid SHUTDOWN_TIMEOUT: %v", err)
+		}
+	}
+
+	addr := ":8080"
+	if port := os.Getenv("PORT"); port != "" {
+		addr = ":" + port
+	}
+	srv := &http.Server{Addr: addr, Handler: router}
 
 	fmt.Println("Connected to database")
-	router.Run()
+
+	stop, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("server failed: %v", err)
+		}
+	}()
+	<-stop.Done()
+
+	// Stop taking requests first, then let the workers finish what is queued.
+	// Whatever is left stays PENDING and is resumed on the next start.
+	log.Printf("shutting down, waiting up to %s for in-flight work", shutdownTimeout)
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("http shutdown: %v", err)
+	}
+	if err := workerPool.Shutdown(shutdownCtx); err != nil {
+		log.Printf("evaluation queue not drained: %v", err)
+	}
+}
+
+// envInt reads a positive integer from the environment, or returns def when
+// the variable is unset.
+func envInt(name string, def int) int {
+	spec := os.Getenv(name)
+	if spec == "" {
+		return def
+	}
+	n, err := strconv.Atoi(spec)
+	if err != nil || n < 1 {
+		log.Fatalf("invalid %s: %q", name, spec)
+	}
+	return n
 }
@@ -14,6 +14,32 @@ const (
 	EventUserBehaviorCreated   EventType = "USER_BEHAVIOR_CREATED"
 	EventUserBehaviorUpdated   EventType = "USER_BEHAVIOR_UPDATED"
 	EventSecurityUpdated       EventType = "SECURITY_UPDATED"
+	EventRuleCreated           EventType = "RULE_CREATED"
+	EventRuleUpdated           EventType = "RULE_UPDATED"
+	EventPolicyUpdated         EventType = "POLICY_UPDATED"
+	EventDeviceTrustUpdated    EventType = "DEVICE_TRUST_UPDATED"
+	EventReceiverProfilesUpdated EventType = "RECEIVER_PROFILES_UPDATED"
+	EventListEntryCreated      EventType = "LIST_ENTRY_CREATED"
+	EventListEntryUpdated      EventType = "LIST_ENTRY_UPDATED"
+	EventListEntryDeleted      EventType = "LIST_ENTRY_DELETED"
+	EventModelLoaded           EventType = "MODEL_LOADED"
+	EventFeatureSnapshotsExported EventType = "FEATURE_SNAPSHOTS_EXPORTED"
+	EventCaseOpened            EventType = "CASE_OPENED"
+	EventCaseClaimed           EventType = "CASE_CLAIMED"
+	EventCaseCommented         EventType = "CASE_COMMENTED"
+	EventCaseApproved          EventType = "CASE_APPROVED"
+	EventCaseRejected          EventType = "CASE_REJECTED"
+	EventChallengeIssued       EventType = "CHALLENGE_ISSUED"
+	EventChallengeVerified     EventType = "CHALLENGE_VERIFIED"
+	EventChallengeFailed       EventType = "CHALLENGE_FAILED"
+	EventChallengeExpired      EventType = "CHALLENGE_EXPIRED"
+	EventFraudLabeled          EventType = "FRAUD_LABELED"
+	EventCallbackRegistered    EventType = "CALLBACK_REGISTERED"
+	EventWebhookSubscriptionCreated EventType = "WEBHOOK_SUBSCRIPTION_CREATED"
+	EventWebhookSubscriptionUpdated EventType = "WEBHOOK_SUBSCRIPTION_UPDATED"
+	EventWebhookSubscriptionDeleted EventType = "WEBHOOK_SUBSCRIPTION_DELETED"
+	EventWebhookDeliveryDead   EventType = "WEBHOOK_DELIVERY_DEAD"
+	EventWebhookRedelivered    EventType = "WEBHOOK_REDELIVERED"
 )
 
 type AuditLog struct {
@@ -55,6 +55,7 @@ type Repository interface {
 	FindUserByEmail(email string) (*User, error)
 	CreateUser(user *User) error
 	UpdateUserSecurity(uuid.UUID, string, string) error
+	RecordDevice(userID uuid.UUID, deviceID string, ipAddress string) error
 }
 
 type Service interface {
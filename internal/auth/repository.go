@@ -70,4 +70,18 @@ func (r *repository) UpdateUserSecurity( userID uuid.UUID, deviceID string, ipAd
 
 }
 
-
+// RecordDevice adds the login device to the user's device history or bumps
+// its last-seen time and usage count. Unlike user_security it keeps every
+// device the user has used.
+func (r *repository) RecordDevice(userID uuid.UUID, deviceID string, ipAddress string) error {
+	now := time.Now()
+	return r.db.Exec(`
+		INSERT INTO user_devices
+			(user_id, device_id, last_ip_address, first_seen_at, last_seen_at, use_count)
+		VALUES (?, ?, ?, ?, ?, 1)
+		ON CONFLICT (user_id, device_id) DO UPDATE SET
+			last_ip_address = EXCLUDED.last_ip_address,
+			last_seen_at    = GREATEST(user_devices.last_seen_at, EXCLUDED.last_seen_at),
+			use_count       = user_devices.use_count + 1
+	`, userID, deviceID, ipAddress, now, now).Error
+}
@@ -74,6 +74,9 @@ func (s *service) Signup(req SignupRequest, ipAddress string) (SignupResponse, e
 	if err := s.repo.UpdateUserSecurity(user.ID, req.DeviceID, ipAddress); err != nil {
 		return SignupResponse{}, fmt.Errorf("update security: %w", err)
 	}
+	if err := s.repo.RecordDevice(user.ID, req.DeviceID, ipAddress); err != nil {
+		return SignupResponse{}, fmt.Errorf("record device: %w", err)
+	}
 	s.auditLog.Log(audit.AuditLog{
 		EventType:  audit.EventSecurityUpdated,
 		Action:     "CREATE",
@@ -120,6 +123,9 @@ func (s *service) Login(req LoginRequest, ipAddress string) (LoginResponse, erro
 	if err := s.repo.UpdateUserSecurity(user.ID, req.DeviceID, ipAddress); err != nil {
 		return LoginResponse{}, fmt.Errorf("update security: %w", err)
 	}
+	if err := s.repo.RecordDevice(user.ID, req.DeviceID, ipAddress); err != nil {
+		return LoginResponse{}, fmt.Errorf("record device: %w", err)
+	}
 	s.auditLog.Log(audit.AuditLog{
 		EventType:  audit.EventSecurityUpdated,
 		Action:     "UPDATE",
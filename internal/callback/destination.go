@@ -0,0 +1,73 @@
+package callback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenDestination = errors.New("callback url must not point to a loopback, link-local or private address")
+
+// allowedIP reports whether callbacks may be sent to ip. Internal addresses
+// are refused so a user cannot make the server call its own network.
+func allowedIP(ip net.IP) bool {
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified())
+}
+
+// CheckHost resolves host and refuses it if any of its addresses is not
+// allowed. Webhook subscriptions are held to the same rule as callbacks.
+func CheckHost(ctx context.Context, lookup func(ctx context.Context, host string) ([]net.IPAddr, error), host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !allowedIP(ip) {
+			return ErrForbiddenDestination
+		}
+		return nil
+	}
+
+	addrs, err := lookup(ctx, host)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	for _, addr := range addrs {
+		if !allowedIP(addr.IP) {
+			return ErrForbiddenDestination
+		}
+	}
+	return nil
+}
+
+// dialControl runs after a callback host is resolved, right before each
+// connection, so a host that resolves differently after registration or a
+// redirect to an internal address is refused too.
+func dialControl(network string, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !allowedIP(ip) {
+		return ErrForbiddenDestination
+	}
+	return nil
+}
+
+// NewClient returns the client callbacks and webhooks should be sent with. It refuses to
+// connect to addresses callbacks may not reach and ignores proxy settings,
+// which would hide the real destination from the check.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialControl}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
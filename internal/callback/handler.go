@@ -0,0 +1,90 @@
+package callback
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterEndpoint handles PUT /api/v1/callback.
+func (h *Handler) RegisterEndpoint(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req EndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	e, err := h.service.RegisterEndpoint(c.Request.Context(), userID, req.URL)
+	if err != nil {
+		writeCallbackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, e)
+}
+
+// GetEndpoint handles GET /api/v1/callback.
+func (h *Handler) GetEndpoint(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	e, err := h.service.GetEndpoint(c.Request.Context(), userID)
+	if err != nil {
+		writeCallbackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, e)
+}
+
+// DeleteEndpoint handles DELETE /api/v1/callback.
+func (h *Handler) DeleteEndpoint(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteEndpoint(c.Request.Context(), userID); err != nil {
+		writeCallbackError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func currentUser(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func writeCallbackError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrEndpointNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidURL), errors.Is(err, ErrForbiddenDestination):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,48 @@
+package callback
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Endpoint is the URL a user wants to be called back on, e.g. when an
+// asynchronous evaluation finished.
+type Endpoint struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"-"`
+	URL       string    `gorm:"type:text;not null" json:"url"`
+	CreatedAt time.Time `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
+	UpdatedAt time.Time `gorm:"type:timestamptz;not null;default:now()" json:"updated_at"`
+}
+
+type EndpointRequest struct {
+	URL string `json:"url" binding:"required"`
+}
+
+// Event is the body POSTed to an endpoint.
+type Event struct {
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+type Repository interface {
+	UpsertEndpoint(ctx context.Context, e *Endpoint) error
+	// GetEndpoint returns nil if the user has not registered one.
+	GetEndpoint(ctx context.Context, userID uuid.UUID) (*Endpoint, error)
+	DeleteEndpoint(ctx context.Context, userID uuid.UUID) error
+}
+
+type Service interface {
+	RegisterEndpoint(ctx context.Context, userID uuid.UUID, rawURL string) (*Endpoint, error)
+	GetEndpoint(ctx context.Context, userID uuid.UUID) (*Endpoint, error)
+	DeleteEndpoint(ctx context.Context, userID uuid.UUID) error
+	// Notify POSTs an event to the user's endpoint. Users without one are
+	// skipped.
+	Notify(ctx context.Context, userID uuid.UUID, eventType string, data interface{}) error
+}
+
+func (Endpoint) TableName() string {
+	return "callback_endpoints"
+}
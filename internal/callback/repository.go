@@ -0,0 +1,42 @@
+package callback
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) UpsertEndpoint(ctx context.Context, e *Endpoint) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"url", "updated_at"}),
+		}).
+		Create(e).Error
+}
+
+func (r *repository) GetEndpoint(ctx context.Context, userID uuid.UUID) (*Endpoint, error) {
+	var e Endpoint
+	if err := r.db.WithContext(ctx).First(&e, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *repository) DeleteEndpoint(ctx context.Context, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&Endpoint{}, "user_id = ?", userID).Error
+}
@@ -0,0 +1,121 @@
+package callback
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"risk-detection/internal/audit"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEndpointNotFound = errors.New("callback endpoint not found")
+	ErrInvalidURL       = errors.New("callback url must be an absolute http or https url")
+)
+
+type service struct {
+	repo     Repository
+	client   *http.Client
+	auditLog *audit.Logger
+	now      func() time.Time
+	lookup   func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// NewService delivers events with client, which should come from NewClient
+// so internal addresses stay out of reach after registration.
+func NewService(repo Repository, client *http.Client, auditLog *audit.Logger) Service {
+	return &service{
+		repo:     repo,
+		client:   client,
+		auditLog: auditLog,
+		now:      time.Now,
+		lookup:   net.DefaultResolver.LookupIPAddr,
+	}
+}
+
+func (s *service) RegisterEndpoint(ctx context.Context, userID uuid.UUID, rawURL string) (*Endpoint, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidURL
+	}
+	if err := CheckHost(ctx, s.lookup, u.Hostname()); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	e := &Endpoint{UserID: userID, URL: u.String(), CreatedAt: now, UpdatedAt: now}
+	if err := s.repo.UpsertEndpoint(ctx, e); err != nil {
+		return nil, err
+	}
+
+	if s.auditLog != nil {
+		s.auditLog.Log(audit.AuditLog{
+			EventType:  audit.EventCallbackRegistered,
+			Action:     "UPSERT",
+			EntityType: "callback_endpoints",
+			EntityID:   userID.String(),
+			ActorType:  "USER",
+			ActorID:    userID.String(),
+			NewValues:  map[string]interface{}{"url": e.URL},
+			Status:     "SUCCESS",
+		})
+	}
+	return e, nil
+}
+
+func (s *service) GetEndpoint(ctx context.Context, userID uuid.UUID) (*Endpoint, error) {
+	e, err := s.repo.GetEndpoint(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, ErrEndpointNotFound
+	}
+	return e, nil
+}
+
+func (s *service) DeleteEndpoint(ctx context.Context, userID uuid.UUID) error {
+	return s.repo.DeleteEndpoint(ctx, userID)
+}
+
+func (s *service) Notify(ctx context.Context, userID uuid.UUID, eventType string, data interface{}) error {
+	e, err := s.repo.GetEndpoint(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if e == nil {
+		return nil
+	}
+
+	body, err := json.Marshal(Event{Type: eventType, CreatedAt: s.now(), Data: data})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("callback to %s failed: %w", e.URL, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("callback to %s returned %d", e.URL, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package callback
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRepository is a mock implementation of the Repository interface
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) UpsertEndpoint(ctx context.Context, e *Endpoint) error {
+	args := m.Called(ctx, e)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetEndpoint(ctx context.Context, userID uuid.UUID) (*Endpoint, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Endpoint), args.Error(1)
+}
+
+func (m *MockRepository) DeleteEndpoint(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func TestRegisterEndpoint_InvalidURL(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, http.DefaultClient, nil)
+
+	for _, raw := range []string{"", "not a url", "ftp://example.com/hook", "https:///path"} {
+		_, err := svc.RegisterEndpoint(context.Background(), uuid.New(), raw)
+		assert.ErrorIs(t, err, ErrInvalidURL, raw)
+	}
+	mockRepo.AssertNotCalled(t, "UpsertEndpoint", mock.Anything, mock.Anything)
+}
+
+// staticLookup resolves every host to addrs.
+func staticLookup(addrs ...string) func(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		var out []net.IPAddr
+		for _, a := range addrs {
+			out = append(out, net.IPAddr{IP: net.ParseIP(a)})
+		}
+		return out, nil
+	}
+}
+
+func TestRegisterEndpoint_ForbiddenDestination(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, http.DefaultClient, nil).(*service)
+	svc.lookup = staticLookup("203.0.113.10", "10.1.2.3")
+
+	for _, raw := range []string{
+		"http://127.0.0.1/hook",
+		"http://[::1]:8080/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"https://10.0.0.5/hook",
+		"https://192.168.1.1/hook",
+		"http://0.0.0.0/hook",
+		"https://hooks.internal.example/hook",
+	} {
+		_, err := svc.RegisterEndpoint(context.Background(), uuid.New(), raw)
+		assert.ErrorIs(t, err, ErrForbiddenDestination, raw)
+	}
+	mockRepo.AssertNotCalled(t, "UpsertEndpoint", mock.Anything, mock.Anything)
+}
+
+func TestRegisterEndpoint_PublicHost(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("UpsertEndpoint", mock.Anything, mock.Anything).Return(nil)
+	svc := NewService(mockRepo, http.DefaultClient, nil).(*service)
+	svc.lookup = staticLookup("203.0.113.10")
+
+	e, err := svc.RegisterEndpoint(context.Background(), uuid.New(), "https://hooks.example.com/risk")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://hooks.example.com/risk", e.URL)
+}
+
+func TestNotify_ClientRefusesInternalAddress(t *testing.T) {
+	userID := uuid.New()
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	// An endpoint that resolved publicly at registration but points
+	// inside by the time it is called
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetEndpoint", mock.Anything, userID).Return(&Endpoint{UserID: userID, URL: server.URL}, nil)
+
+	err := NewService(mockRepo, NewClient(time.Second), nil).Notify(context.Background(), userID, "transaction.decision", nil)
+	assert.ErrorIs(t, err, ErrForbiddenDestination)
+	assert.False(t, called)
+}
+
+func TestNotify(t *testing.T) {
+	userID := uuid.New()
+
+	var received Event
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetEndpoint", mock.Anything, userID).Return(&Endpoint{UserID: userID, URL: server.URL}, nil)
+
+	svc := NewService(mockRepo, &http.Client{Timeout: time.Second}, nil)
+	err := svc.Notify(context.Background(), userID, "transaction.decision", map[string]string{"decision": "ALLOW"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", contentType)
+	assert.Equal(t, "transaction.decision", received.Type)
+	assert.Equal(t, map[string]interface{}{"decision": "ALLOW"}, received.Data)
+}
+
+func TestNotify_ErrorStatus(t *testing.T) {
+	userID := uuid.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetEndpoint", mock.Anything, userID).Return(&Endpoint{UserID: userID, URL: server.URL}, nil)
+
+	err := NewService(mockRepo, &http.Client{Timeout: time.Second}, nil).Notify(context.Background(), userID, "transaction.decision", nil)
+	assert.Error(t, err)
+}
+
+func TestNotify_NoEndpoint(t *testing.T) {
+	userID := uuid.New()
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetEndpoint", mock.Anything, userID).Return(nil, nil)
+
+	err := NewService(mockRepo, &http.Client{Timeout: time.Second}, nil).Notify(context.Background(), userID, "transaction.decision", nil)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,71 @@
+package challenge
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service Service
+}
+
+// NewHandler serves challenge endpoints. service may be nil when step-up
+// challenges are disabled.
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Verify handles POST /api/v1/transactions/:id/challenge.
+func (h *Handler) Verify(c *gin.Context) {
+	if h.service == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrChallengeNotFound.Error()})
+		return
+	}
+
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	transactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transaction id format"})
+		return
+	}
+
+	var req VerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	ch, err := h.service.Verify(c.Request.Context(), userID, transactionID, req.Code)
+	if err != nil {
+		writeChallengeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"challenge":          ch,
+		"transaction_status": statusCompleted,
+	})
+}
+
+func writeChallengeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrChallengeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidCode):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrChallengeExpired), errors.Is(err, ErrChallengeFailed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "transaction_status": statusBlocked})
+	case errors.Is(err, ErrChallengeClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
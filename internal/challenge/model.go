@@ -0,0 +1,95 @@
+package challenge
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Challenge states. Only a PENDING challenge accepts codes.
+const (
+	StatusPending  = "PENDING"
+	StatusVerified = "VERIFIED"
+	StatusFailed   = "FAILED"
+	StatusExpired  = "EXPIRED"
+)
+
+// Challenge is a one-time code a user must confirm before a flagged
+// transaction completes. The transaction stays PENDING until then.
+type Challenge struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	TransactionID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex" json:"transaction_id"`
+	UserID        uuid.UUID  `gorm:"type:uuid;not null" json:"-"`
+	Channel       string     `gorm:"type:varchar(20);not null" json:"channel"`
+	CodeHash      string     `gorm:"type:varchar(64);not null" json:"-"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts   int        `gorm:"not null" json:"max_attempts"`
+	Status        string     `gorm:"type:varchar(20);not null" json:"status"`
+	ExpiresAt     time.Time  `gorm:"type:timestamptz;not null" json:"expires_at"`
+	CreatedAt     time.Time  `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
+	ResolvedAt    *time.Time `gorm:"type:timestamptz" json:"resolved_at,omitempty"`
+}
+
+// IssueRequest describes the flagged transaction a challenge is issued for.
+type IssueRequest struct {
+	TransactionID uuid.UUID
+	UserID        uuid.UUID
+}
+
+type VerifyRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// Message is what a Notifier delivers to the user.
+type Message struct {
+	UserID        uuid.UUID
+	TransactionID uuid.UUID
+	Code          string
+	ExpiresAt     time.Time
+}
+
+// Notifier delivers challenge codes, e.g. by SMS or email.
+type Notifier interface {
+	// Channel names the delivery channel, e.g. SMS.
+	Channel() string
+	Send(ctx context.Context, msg Message) error
+}
+
+type Repository interface {
+	Create(ctx context.Context, c *Challenge) error
+	GetByTransactionID(ctx context.Context, transactionID uuid.UUID) (*Challenge, error)
+	// RecordAttempt counts a code attempt on a PENDING challenge that has
+	// attempts left and has not expired. It returns nil otherwise.
+	RecordAttempt(ctx context.Context, id uuid.UUID, now time.Time) (*Challenge, error)
+	// Resolve moves a PENDING challenge to status. It returns nil if the
+	// challenge is no longer PENDING.
+	Resolve(ctx context.Context, id uuid.UUID, status string, now time.Time) (*Challenge, error)
+	// ListExpired returns the IDs of PENDING challenges past their expiry.
+	ListExpired(ctx context.Context, now time.Time) ([]uuid.UUID, error)
+}
+
+// TransactionRepository updates the transaction a challenge guards. It is
+// satisfied by transaction.Repository.
+type TransactionRepository interface {
+	UpdateStatusByID(ctx context.Context, id uuid.UUID, status string) error
+}
+
+// BehaviorRecorder learns from passed challenges. It is satisfied by
+// risk.Service.
+type BehaviorRecorder interface {
+	RecordVerifiedChallenge(ctx context.Context, userID uuid.UUID, at time.Time) error
+}
+
+type Service interface {
+	Issue(ctx context.Context, req IssueRequest) (*Challenge, error)
+	Verify(ctx context.Context, userID uuid.UUID, transactionID uuid.UUID, code string) (*Challenge, error)
+	// ExpireChallenges blocks the transactions of expired challenges and
+	// returns how many it blocked. A challenge that fails to expire stays
+	// PENDING for the next run and does not stop the others.
+	ExpireChallenges(ctx context.Context) (int, error)
+}
+
+func (Challenge) TableName() string {
+	return "step_up_challenges"
+}
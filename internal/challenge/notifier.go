@@ -0,0 +1,51 @@
+package challenge
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// WriterNotifier writes codes to a file or the console. It stands in for an
+// SMS or email provider in local setups.
+type WriterNotifier struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewConsoleNotifier() *WriterNotifier {
+	return &WriterNotifier{w: os.Stdout}
+}
+
+// NewFileNotifier appends codes to the file at path.
+func NewFileNotifier(path string) (*WriterNotifier, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &WriterNotifier{w: file}, nil
+}
+
+func (n *WriterNotifier) Channel() string {
+	return "LOCAL"
+}
+
+func (n *WriterNotifier) Send(ctx context.Context, msg Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	_, err := fmt.Fprintf(n.w, "[STEP-UP] user=%s transaction=%s code=%s expires_at=%s\n",
+		msg.UserID, msg.TransactionID, msg.Code, msg.ExpiresAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+// Close closes the underlying file, if any.
+func (n *WriterNotifier) Close() error {
+	if f, ok := n.w.(*os.File); ok && f != os.Stdout {
+		return f.Close()
+	}
+	return nil
+}
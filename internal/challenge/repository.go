@@ -0,0 +1,78 @@
+package challenge
+
+import (
+	"context"
+	"time"
+
+	"risk-detection/internal/db"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(ctx context.Context, c *Challenge) error {
+	return db.Conn(ctx, r.db).Create(c).Error
+}
+
+func (r *repository) GetByTransactionID(ctx context.Context, transactionID uuid.UUID) (*Challenge, error) {
+	var c Challenge
+	if err := db.Conn(ctx, r.db).First(&c, "transaction_id = ?", transactionID).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// RecordAttempt counts the attempt in the same statement that checks the
+// challenge is still open, so parallel guesses cannot exceed MaxAttempts.
+func (r *repository) RecordAttempt(ctx context.Context, id uuid.UUID, now time.Time) (*Challenge, error) {
+	var c Challenge
+	res := db.Conn(ctx, r.db).
+		Model(&c).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND status = ? AND attempts < max_attempts AND expires_at > ?", id, StatusPending, now).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (r *repository) Resolve(ctx context.Context, id uuid.UUID, status string, now time.Time) (*Challenge, error) {
+	var c Challenge
+	res := db.Conn(ctx, r.db).
+		Model(&c).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND status = ?", id, StatusPending).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"resolved_at": now,
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (r *repository) ListExpired(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := db.Conn(ctx, r.db).
+		Model(&Challenge{}).
+		Where("status = ? AND expires_at <= ?", StatusPending, now).
+		Pluck("id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,323 @@
+package challenge
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"risk-detection/internal/audit"
+	"risk-detection/internal/db"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrChallengeNotFound = errors.New("challenge not found")
+	ErrChallengeClosed   = errors.New("challenge is no longer pending")
+	ErrChallengeExpired  = errors.New("challenge expired")
+	ErrChallengeFailed   = errors.New("too many invalid codes")
+	ErrInvalidCode       = errors.New("invalid code")
+)
+
+const (
+	codeDigits  = 6
+	maxAttempts = 3
+)
+
+// Transaction statuses a resolved challenge moves its transaction to.
+const (
+	statusCompleted = "COMPLETED"
+	statusBlocked   = "BLOCKED"
+)
+
+type service struct {
+	repo     Repository
+	txRepo   TransactionRepository
+	uow      db.UnitOfWork
+	behavior BehaviorRecorder
+	notifier Notifier
+	auditLog *audit.Logger
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+// NewService issues challenges through notifier. Codes are valid for ttl.
+// A challenge is resolved in uow together with its transaction, so neither
+// closes without the other. A nil uow runs every write on its own.
+func NewService(repo Repository, txRepo TransactionRepository, uow db.UnitOfWork, behavior BehaviorRecorder, notifier Notifier, auditLog *audit.Logger, ttl time.Duration) Service {
+	return &service{
+		repo:     repo,
+		txRepo:   txRepo,
+		uow:      uow,
+		behavior: behavior,
+		notifier: notifier,
+		auditLog: auditLog,
+		ttl:      ttl,
+		now:      time.Now,
+	}
+}
+
+// Issue stores a new code for the transaction and sends it to the user once
+// the caller's unit of work commits. Only a hash of the code is stored.
+func (s *service) Issue(ctx context.Context, req IssueRequest) (*Challenge, error) {
+	code, err := generateCode()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	c := &Challenge{
+		ID:            uuid.New(),
+		TransactionID: req.TransactionID,
+		UserID:        req.UserID,
+		Channel:       s.notifier.Channel(),
+		MaxAttempts:   maxAttempts,
+		Status:        StatusPending,
+		ExpiresAt:     now.Add(s.ttl),
+		CreatedAt:     now,
+	}
+	c.CodeHash = hashCode(c.ID, code)
+
+	if err := s.repo.Create(ctx, c); err != nil {
+		return nil, err
+	}
+
+	// The code goes out only once the challenge commits, so a rolled back
+	// evaluation never reaches the user. By then the challenge is stored, so a
+	// failed send is only logged and the expiry job blocks the transaction.
+	msg := Message{
+		UserID:        c.UserID,
+		TransactionID: c.TransactionID,
+		Code:          code,
+		ExpiresAt:     c.ExpiresAt,
+	}
+	db.AfterCommit(ctx, func() {
+		if err := s.notifier.Send(ctx, msg); err != nil {
+			log.Printf("unable to send challenge for transaction %s: %v", c.TransactionID, err)
+			s.logChallenge(audit.EventChallengeIssued, "SEND", "FAILURE", c, map[string]interface{}{
+				"channel": c.Channel,
+				"error":   err.Error(),
+			})
+		}
+	})
+
+	s.logChallenge(audit.EventChallengeIssued, "CREATE", "SUCCESS", c, map[string]interface{}{
+		"channel":    c.Channel,
+		"expires_at": c.ExpiresAt,
+	})
+	return c, nil
+}
+
+// Verify checks a code for the user's transaction. A correct code completes
+// the transaction; running out of attempts or time blocks it.
+func (s *service) Verify(ctx context.Context, userID uuid.UUID, transactionID uuid.UUID, code string) (*Challenge, error) {
+	c, err := s.repo.GetByTransactionID(ctx, transactionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChallengeNotFound
+		}
+		return nil, err
+	}
+
+	// Hide other users' challenges behind not found
+	if c.UserID != userID {
+		return nil, ErrChallengeNotFound
+	}
+	if c.Status != StatusPending {
+		return nil, ErrChallengeClosed
+	}
+
+	now := s.now()
+	if !now.Before(c.ExpiresAt) {
+		if _, err := s.block(ctx, c.ID, StatusExpired, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrChallengeExpired
+	}
+
+	attempt, err := s.repo.RecordAttempt(ctx, c.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if attempt == nil {
+		return nil, ErrChallengeClosed
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashCode(attempt.ID, code)), []byte(attempt.CodeHash)) != 1 {
+		if attempt.Attempts >= attempt.MaxAttempts {
+			if _, err := s.block(ctx, attempt.ID, StatusFailed, now); err != nil {
+				return nil, err
+			}
+			return nil, ErrChallengeFailed
+		}
+		return nil, fmt.Errorf("%w: %d attempts left", ErrInvalidCode, attempt.MaxAttempts-attempt.Attempts)
+	}
+
+	var verified *Challenge
+	err = s.atomic(ctx, func(ctx context.Context) error {
+		resolved, err := s.repo.Resolve(ctx, attempt.ID, StatusVerified, now)
+		if err != nil {
+			return err
+		}
+		if resolved == nil {
+			return ErrChallengeClosed
+		}
+		if err := s.txRepo.UpdateStatusByID(ctx, resolved.TransactionID, statusCompleted); err != nil {
+			return fmt.Errorf("failed to update transaction status: %w", err)
+		}
+
+		// The transaction completes either way, so a missed signal is only
+		// logged and rolled back on its own
+		if s.behavior != nil {
+			if err := db.Savepoint(ctx, func(ctx context.Context) error {
+				return s.behavior.RecordVerifiedChallenge(ctx, resolved.UserID, now)
+			}); err != nil {
+				log.Printf("unable to record verified challenge: %v", err)
+			}
+		}
+		verified = resolved
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logTransaction(verified, statusCompleted)
+
+	s.logChallenge(audit.EventChallengeVerified, "VERIFY", "SUCCESS", verified, map[string]interface{}{
+		"status":   verified.Status,
+		"attempts": verified.Attempts,
+	})
+	return verified, nil
+}
+
+func (s *service) ExpireChallenges(ctx context.Context) (int, error) {
+	now := s.now()
+	ids, err := s.repo.ListExpired(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	// Each challenge expires with its transaction in its own unit of work
+	blocked := 0
+	var errs []error
+	for _, id := range ids {
+		c, err := s.block(ctx, id, StatusExpired, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("challenge %s: %w", id, err))
+			continue
+		}
+		if c != nil {
+			blocked++
+		}
+	}
+	return blocked, errors.Join(errs...)
+}
+
+// block closes a pending challenge as failed or expired and blocks its
+// transaction in one unit of work. A challenge closed concurrently is left to
+// whoever closed it, and nil is returned for it.
+func (s *service) block(ctx context.Context, id uuid.UUID, status string, now time.Time) (*Challenge, error) {
+	var blocked *Challenge
+	err := s.atomic(ctx, func(ctx context.Context) error {
+		resolved, err := s.repo.Resolve(ctx, id, status, now)
+		if err != nil {
+			return err
+		}
+		if resolved == nil {
+			return nil
+		}
+		if err := s.txRepo.UpdateStatusByID(ctx, resolved.TransactionID, statusBlocked); err != nil {
+			return fmt.Errorf("failed to update transaction status: %w", err)
+		}
+		blocked = resolved
+		return nil
+	})
+	if err != nil || blocked == nil {
+		return nil, err
+	}
+	s.logBlocked(blocked)
+	return blocked, nil
+}
+
+func (s *service) logBlocked(c *Challenge) {
+	s.logTransaction(c, statusBlocked)
+
+	event := audit.EventChallengeFailed
+	if c.Status == StatusExpired {
+		event = audit.EventChallengeExpired
+	}
+	s.logChallenge(event, "VERIFY", "FAILURE", c, map[string]interface{}{
+		"status":   c.Status,
+		"attempts": c.Attempts,
+	})
+}
+
+func (s *service) atomic(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.uow == nil {
+		return fn(ctx)
+	}
+	return s.uow.Do(ctx, fn)
+}
+
+func (s *service) logTransaction(c *Challenge, status string) {
+	if s.auditLog == nil {
+		return
+	}
+
+	s.auditLog.Log(audit.AuditLog{
+		EventType:  audit.EventTransactionUpdated,
+		Action:     "UPDATE",
+		EntityType: "transactions",
+		EntityID:   c.TransactionID.String(),
+		ActorType:  "SYSTEM",
+		OldValues:  map[string]interface{}{"Status": "PENDING"},
+		NewValues:  map[string]interface{}{"Status": status},
+		Status:     "SUCCESS",
+	})
+}
+
+func (s *service) logChallenge(event audit.EventType, action string, status string, c *Challenge, values map[string]interface{}) {
+	if s.auditLog == nil {
+		return
+	}
+
+	s.auditLog.Log(audit.AuditLog{
+		EventType:     event,
+		Action:        action,
+		EntityType:    "step_up_challenges",
+		EntityID:      c.ID.String(),
+		ActorType:     "USER",
+		ActorID:       c.UserID.String(),
+		TransactionID: c.TransactionID.String(),
+		NewValues:     values,
+		Status:        status,
+	})
+}
+
+// generateCode draws a zero-padded numeric code.
+func generateCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < codeDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", codeDigits, n), nil
+}
+
+// hashCode binds the code to its challenge, so equal codes hash differently.
+func hashCode(id uuid.UUID, code string) string {
+	sum := sha256.Sum256([]byte(id.String() + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,343 @@
+package challenge
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"risk-detection/internal/audit"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockRepository is a mock implementation of the Repository interface
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Create(ctx context.Context, c *Challenge) error {
+	args := m.Called(ctx, c)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetByTransactionID(ctx context.Context, transactionID uuid.UUID) (*Challenge, error) {
+	args := m.Called(ctx, transactionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Challenge), args.Error(1)
+}
+
+func (m *MockRepository) RecordAttempt(ctx context.Context, id uuid.UUID, now time.Time) (*Challenge, error) {
+	args := m.Called(ctx, id, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Challenge), args.Error(1)
+}
+
+func (m *MockRepository) Resolve(ctx context.Context, id uuid.UUID, status string, now time.Time) (*Challenge, error) {
+	args := m.Called(ctx, id, status, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Challenge), args.Error(1)
+}
+
+func (m *MockRepository) ListExpired(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	args := m.Called(ctx, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+// MockTransactionRepository is a mock implementation of the TransactionRepository interface
+type MockTransactionRepository struct {
+	mock.Mock
+}
+
+func (m *MockTransactionRepository) UpdateStatusByID(ctx context.Context, id uuid.UUID, status string) error {
+	args := m.Called(ctx, id, status)
+	return args.Error(0)
+}
+
+// MockBehaviorRecorder is a mock implementation of the BehaviorRecorder interface
+type MockBehaviorRecorder struct {
+	mock.Mock
+}
+
+func (m *MockBehaviorRecorder) RecordVerifiedChallenge(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	args := m.Called(ctx, userID, at)
+	return args.Error(0)
+}
+
+// recordingNotifier keeps the messages it was asked to send.
+type recordingNotifier struct {
+	sent []Message
+	err  error
+}
+
+func (n *recordingNotifier) Channel() string {
+	return "TEST"
+}
+
+func (n *recordingNotifier) Send(ctx context.Context, msg Message) error {
+	n.sent = append(n.sent, msg)
+	return n.err
+}
+
+var testNow = time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+
+func newTestService(repo Repository, txRepo TransactionRepository, behavior BehaviorRecorder, notifier Notifier) *service {
+	s := NewService(repo, txRepo, nil, behavior, notifier, &audit.Logger{}, 5*time.Minute).(*service)
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+// pendingChallenge returns a challenge for code that has attempts attempts.
+func pendingChallenge(userID uuid.UUID, code string, attempts int) *Challenge {
+	c := &Challenge{
+		ID:            uuid.New(),
+		TransactionID: uuid.New(),
+		UserID:        userID,
+		Attempts:      attempts,
+		MaxAttempts:   maxAttempts,
+		Status:        StatusPending,
+		ExpiresAt:     testNow.Add(time.Minute),
+	}
+	c.CodeHash = hashCode(c.ID, code)
+	return c
+}
+
+// fakeUnitOfWork records whether the work it ran committed or rolled back,
+// and undoes the writes registered with it on rollback
+type fakeUnitOfWork struct {
+	committed  bool
+	rolledBack bool
+	undo       []func()
+}
+
+func (u *fakeUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := fn(ctx); err != nil {
+		u.rolledBack = true
+		for _, undo := range u.undo {
+			undo()
+		}
+		return err
+	}
+	u.committed = true
+	return nil
+}
+
+func TestIssue(t *testing.T) {
+	mockRepo := new(MockRepository)
+	var stored *Challenge
+	mockRepo.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*Challenge)
+	}).Return(nil)
+	notifier := &recordingNotifier{}
+
+	svc := newTestService(mockRepo, new(MockTransactionRepository), nil, notifier)
+	req := IssueRequest{TransactionID: uuid.New(), UserID: uuid.New()}
+
+	c, err := svc.Issue(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPending, c.Status)
+	assert.Equal(t, "TEST", c.Channel)
+	assert.Equal(t, testNow.Add(5*time.Minute), c.ExpiresAt)
+
+	// The code is sent, never stored
+	if assert.Len(t, notifier.sent, 1) {
+		msg := notifier.sent[0]
+		assert.Len(t, msg.Code, codeDigits)
+		assert.Equal(t, req.TransactionID, msg.TransactionID)
+		assert.Equal(t, hashCode(stored.ID, msg.Code), stored.CodeHash)
+		assert.NotContains(t, stored.CodeHash, msg.Code)
+	}
+}
+
+func TestIssue_NotifierFails(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+	notifier := &recordingNotifier{err: errors.New("smtp down")}
+	svc := newTestService(mockRepo, new(MockTransactionRepository), nil, notifier)
+
+	// The challenge is already stored, so the send failure is only logged
+	c, err := svc.Issue(context.Background(), IssueRequest{TransactionID: uuid.New(), UserID: uuid.New()})
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPending, c.Status)
+	assert.Len(t, notifier.sent, 1)
+}
+
+func TestVerify(t *testing.T) {
+	userID := uuid.New()
+
+	t.Run("correct_code_completes_transaction", func(t *testing.T) {
+		c := pendingChallenge(userID, "123456", 0)
+		attempted := *c
+		attempted.Attempts = 1
+		verified := attempted
+		verified.Status = StatusVerified
+
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetByTransactionID", mock.Anything, c.TransactionID).Return(c, nil)
+		mockRepo.On("RecordAttempt", mock.Anything, c.ID, testNow).Return(&attempted, nil)
+		mockRepo.On("Resolve", mock.Anything, c.ID, StatusVerified, testNow).Return(&verified, nil)
+		txRepo := new(MockTransactionRepository)
+		txRepo.On("UpdateStatusByID", mock.Anything, c.TransactionID, "COMPLETED").Return(nil)
+		behavior := new(MockBehaviorRecorder)
+		behavior.On("RecordVerifiedChallenge", mock.Anything, userID, testNow).Return(nil)
+
+		svc := newTestService(mockRepo, txRepo, behavior, &recordingNotifier{})
+		result, err := svc.Verify(context.Background(), userID, c.TransactionID, "123456")
+		assert.NoError(t, err)
+		assert.Equal(t, StatusVerified, result.Status)
+		txRepo.AssertExpectations(t)
+		behavior.AssertExpectations(t)
+	})
+
+	t.Run("failed_status_update_keeps_challenge_pending", func(t *testing.T) {
+		stored := pendingChallenge(userID, "123456", 0)
+		uow := &fakeUnitOfWork{}
+
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetByTransactionID", mock.Anything, stored.TransactionID).Return(stored, nil)
+		mockRepo.On("RecordAttempt", mock.Anything, stored.ID, testNow).Return(stored, nil)
+		mockRepo.On("Resolve", mock.Anything, stored.ID, StatusVerified, testNow).Run(func(args mock.Arguments) {
+			stored.Status = StatusVerified
+			uow.undo = append(uow.undo, func() { stored.Status = StatusPending })
+		}).Return(stored, nil)
+		txRepo := new(MockTransactionRepository)
+		txRepo.On("UpdateStatusByID", mock.Anything, stored.TransactionID, "COMPLETED").Return(errors.New("db down"))
+		behavior := new(MockBehaviorRecorder)
+
+		svc := NewService(mockRepo, txRepo, uow, behavior, &recordingNotifier{}, &audit.Logger{}, 5*time.Minute).(*service)
+		svc.now = func() time.Time { return testNow }
+		_, err := svc.Verify(context.Background(), userID, stored.TransactionID, "123456")
+		assert.Error(t, err)
+		assert.True(t, uow.rolledBack)
+		assert.False(t, uow.committed)
+		assert.Equal(t, StatusPending, stored.Status)
+		behavior.AssertNotCalled(t, "RecordVerifiedChallenge", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("wrong_code_with_attempts_left", func(t *testing.T) {
+		c := pendingChallenge(userID, "123456", 0)
+		attempted := *c
+		attempted.Attempts = 1
+
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetByTransactionID", mock.Anything, c.TransactionID).Return(c, nil)
+		mockRepo.On("RecordAttempt", mock.Anything, c.ID, testNow).Return(&attempted, nil)
+		txRepo := new(MockTransactionRepository)
+
+		svc := newTestService(mockRepo, txRepo, new(MockBehaviorRecorder), &recordingNotifier{})
+		_, err := svc.Verify(context.Background(), userID, c.TransactionID, "000000")
+		assert.ErrorIs(t, err, ErrInvalidCode)
+		assert.Contains(t, err.Error(), "2 attempts left")
+		txRepo.AssertNotCalled(t, "UpdateStatusByID", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "Resolve", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("last_wrong_code_blocks_transaction", func(t *testing.T) {
+		c := pendingChallenge(userID, "123456", maxAttempts-1)
+		attempted := *c
+		attempted.Attempts = maxAttempts
+		failed := attempted
+		failed.Status = StatusFailed
+
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetByTransactionID", mock.Anything, c.TransactionID).Return(c, nil)
+		mockRepo.On("RecordAttempt", mock.Anything, c.ID, testNow).Return(&attempted, nil)
+		mockRepo.On("Resolve", mock.Anything, c.ID, StatusFailed, testNow).Return(&failed, nil)
+		txRepo := new(MockTransactionRepository)
+		txRepo.On("UpdateStatusByID", mock.Anything, c.TransactionID, "BLOCKED").Return(nil)
+		behavior := new(MockBehaviorRecorder)
+
+		svc := newTestService(mockRepo, txRepo, behavior, &recordingNotifier{})
+		_, err := svc.Verify(context.Background(), userID, c.TransactionID, "000000")
+		assert.ErrorIs(t, err, ErrChallengeFailed)
+		txRepo.AssertExpectations(t)
+		behavior.AssertNotCalled(t, "RecordVerifiedChallenge", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("expired_blocks_transaction", func(t *testing.T) {
+		c := pendingChallenge(userID, "123456", 0)
+		c.ExpiresAt = testNow.Add(-time.Second)
+		expired := *c
+		expired.Status = StatusExpired
+
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetByTransactionID", mock.Anything, c.TransactionID).Return(c, nil)
+		mockRepo.On("Resolve", mock.Anything, c.ID, StatusExpired, testNow).Return(&expired, nil)
+		txRepo := new(MockTransactionRepository)
+		txRepo.On("UpdateStatusByID", mock.Anything, c.TransactionID, "BLOCKED").Return(nil)
+
+		svc := newTestService(mockRepo, txRepo, nil, &recordingNotifier{})
+		_, err := svc.Verify(context.Background(), userID, c.TransactionID, "123456")
+		assert.ErrorIs(t, err, ErrChallengeExpired)
+		txRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "RecordAttempt", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("other_users_challenge", func(t *testing.T) {
+		c := pendingChallenge(uuid.New(), "123456", 0)
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetByTransactionID", mock.Anything, c.TransactionID).Return(c, nil)
+
+		svc := newTestService(mockRepo, new(MockTransactionRepository), nil, &recordingNotifier{})
+		_, err := svc.Verify(context.Background(), userID, c.TransactionID, "123456")
+		assert.ErrorIs(t, err, ErrChallengeNotFound)
+	})
+
+	t.Run("no_challenge", func(t *testing.T) {
+		txID := uuid.New()
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetByTransactionID", mock.Anything, txID).Return(nil, gorm.ErrRecordNotFound)
+
+		svc := newTestService(mockRepo, new(MockTransactionRepository), nil, &recordingNotifier{})
+		_, err := svc.Verify(context.Background(), userID, txID, "123456")
+		assert.ErrorIs(t, err, ErrChallengeNotFound)
+	})
+
+	t.Run("already_resolved", func(t *testing.T) {
+		c := pendingChallenge(userID, "123456", 1)
+		c.Status = StatusVerified
+		mockRepo := new(MockRepository)
+		mockRepo.On("GetByTransactionID", mock.Anything, c.TransactionID).Return(c, nil)
+
+		svc := newTestService(mockRepo, new(MockTransactionRepository), nil, &recordingNotifier{})
+		_, err := svc.Verify(context.Background(), userID, c.TransactionID, "123456")
+		assert.ErrorIs(t, err, ErrChallengeClosed)
+	})
+}
+
+func TestExpireChallenges(t *testing.T) {
+	first := Challenge{ID: uuid.New(), TransactionID: uuid.New(), Status: StatusExpired}
+	second := Challenge{ID: uuid.New(), TransactionID: uuid.New(), Status: StatusExpired}
+	third := Challenge{ID: uuid.New(), TransactionID: uuid.New(), Status: StatusExpired}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("ListExpired", mock.Anything, testNow).Return([]uuid.UUID{first.ID, second.ID, third.ID}, nil)
+	mockRepo.On("Resolve", mock.Anything, first.ID, StatusExpired, testNow).Return(&first, nil)
+	mockRepo.On("Resolve", mock.Anything, second.ID, StatusExpired, testNow).Return(&second, nil)
+	mockRepo.On("Resolve", mock.Anything, third.ID, StatusExpired, testNow).Return(&third, nil)
+	txRepo := new(MockTransactionRepository)
+	txRepo.On("UpdateStatusByID", mock.Anything, first.TransactionID, "BLOCKED").Return(nil)
+	txRepo.On("UpdateStatusByID", mock.Anything, second.TransactionID, "BLOCKED").Return(errors.New("db down"))
+	txRepo.On("UpdateStatusByID", mock.Anything, third.TransactionID, "BLOCKED").Return(nil)
+
+	// A failed challenge does not stop the ones after it
+	svc := newTestService(mockRepo, txRepo, nil, &recordingNotifier{})
+	n, err := svc.ExpireChallenges(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), second.ID.String())
+	assert.Equal(t, 2, n)
+	txRepo.AssertExpectations(t)
+}
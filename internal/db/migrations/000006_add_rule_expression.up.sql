@@ -0,0 +1,2 @@
+ALTER TABLE rules
+    ADD COLUMN expression TEXT NOT NULL DEFAULT '';
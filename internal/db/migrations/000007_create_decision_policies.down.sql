@@ -0,0 +1,5 @@
+ALTER TABLE transaction_risks
+    DROP COLUMN IF EXISTS policy_version,
+    DROP COLUMN IF EXISTS policy_type;
+
+DROP TABLE IF EXISTS decision_policies;
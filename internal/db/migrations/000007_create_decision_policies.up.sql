@@ -0,0 +1,19 @@
+CREATE TABLE decision_policies (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_type VARCHAR(20) NOT NULL UNIQUE,
+    version INT NOT NULL DEFAULT 1,
+    low_max INT NOT NULL,
+    medium_max INT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_decision_policies_thresholds
+        CHECK (low_max >= 0 AND low_max < medium_max AND medium_max <= 100)
+);
+
+-- Fallback policy matching the original hard-coded cut-offs
+INSERT INTO decision_policies (transaction_type, low_max, medium_max)
+VALUES ('DEFAULT', 30, 70);
+
+ALTER TABLE transaction_risks
+    ADD COLUMN policy_type VARCHAR(20) NOT NULL DEFAULT 'DEFAULT',
+    ADD COLUMN policy_version INT NOT NULL DEFAULT 0;
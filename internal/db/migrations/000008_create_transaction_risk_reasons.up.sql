@@ -0,0 +1,23 @@
+CREATE TABLE transaction_risk_reasons (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id UUID NOT NULL,
+
+    rule_name VARCHAR(255) NOT NULL,
+    reason_code VARCHAR(64) NOT NULL,
+    raw_score INT NOT NULL,
+    weight INT NOT NULL,
+    weighted_score INT NOT NULL,
+
+    -- Input values the sub-check was computed from
+    inputs JSONB,
+
+    CONSTRAINT fk_transaction_risk_reasons_risk
+        FOREIGN KEY (transaction_id)
+        REFERENCES transaction_risks(transaction_id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_transaction_risk_reasons_transaction_id
+    ON transaction_risk_reasons(transaction_id);
+CREATE INDEX idx_transaction_risk_reasons_reason_code
+    ON transaction_risk_reasons(reason_code);
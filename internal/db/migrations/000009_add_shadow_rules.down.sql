@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_transaction_risks_shadow;
+
+ALTER TABLE transaction_risks
+    DROP COLUMN IF EXISTS shadow_decision,
+    DROP COLUMN IF EXISTS shadow_risk_score;
+
+DELETE FROM rules WHERE rule_set = 'SHADOW';
+
+ALTER TABLE rules
+    DROP CONSTRAINT IF EXISTS uq_rules_rule_set_name,
+    ADD CONSTRAINT rules_name_key UNIQUE (name);
+
+ALTER TABLE rules
+    DROP COLUMN IF EXISTS rule_set;
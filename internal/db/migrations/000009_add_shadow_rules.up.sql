@@ -0,0 +1,14 @@
+ALTER TABLE rules
+    ADD COLUMN rule_set VARCHAR(10) NOT NULL DEFAULT 'LIVE' CHECK (rule_set IN ('LIVE','SHADOW'));
+
+-- A shadow rule may reuse the name of the live rule it is meant to replace
+ALTER TABLE rules
+    DROP CONSTRAINT IF EXISTS rules_name_key,
+    ADD CONSTRAINT uq_rules_rule_set_name UNIQUE (rule_set, name);
+
+ALTER TABLE transaction_risks
+    ADD COLUMN shadow_risk_score INT,
+    ADD COLUMN shadow_decision VARCHAR(10) CHECK (shadow_decision IN ('ALLOW','FLAG','BLOCK'));
+
+CREATE INDEX idx_transaction_risks_shadow ON transaction_risks(evaluated_at)
+    WHERE shadow_decision IS NOT NULL;
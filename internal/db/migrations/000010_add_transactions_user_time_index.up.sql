@@ -0,0 +1,2 @@
+-- Last transaction of a user before a given time, for the geolocation signal
+CREATE INDEX idx_transactions_user_time ON transactions(user_id, transaction_time DESC);
@@ -0,0 +1,39 @@
+CREATE TABLE user_devices (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    device_id VARCHAR(255) NOT NULL,
+    last_ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    use_count BIGINT NOT NULL DEFAULT 1,
+    trust_state VARCHAR(20) NOT NULL DEFAULT 'UNVERIFIED'
+        CHECK (trust_state IN ('UNVERIFIED', 'TRUSTED', 'BLOCKED')),
+
+    CONSTRAINT uq_user_devices_user_device UNIQUE (user_id, device_id),
+
+    CONSTRAINT fk_user_devices_user
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+-- Seed the history from past transactions and the last login of each user
+INSERT INTO user_devices (user_id, device_id, last_ip_address, first_seen_at, last_seen_at, use_count)
+SELECT DISTINCT ON (user_id, device_id)
+    user_id,
+    device_id,
+    ip_address,
+    MIN(transaction_time) OVER w,
+    MAX(transaction_time) OVER w,
+    COUNT(*) OVER w
+FROM transactions
+WINDOW w AS (PARTITION BY user_id, device_id)
+ORDER BY user_id, device_id, transaction_time DESC;
+
+INSERT INTO user_devices (user_id, device_id, last_ip_address, first_seen_at, last_seen_at)
+SELECT user_id, device_id, ip_address, updated_at, updated_at
+FROM user_security
+ON CONFLICT (user_id, device_id) DO UPDATE SET
+    last_ip_address = EXCLUDED.last_ip_address,
+    last_seen_at    = GREATEST(user_devices.last_seen_at, EXCLUDED.last_seen_at),
+    use_count       = user_devices.use_count + 1;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_transactions_user_receiver;
+DROP INDEX IF EXISTS idx_transactions_receiver_time;
+
+DROP TABLE IF EXISTS receiver_profiles;
@@ -0,0 +1,16 @@
+CREATE TABLE receiver_profiles (
+    receiver_id UUID PRIMARY KEY,
+    distinct_senders_24h BIGINT NOT NULL DEFAULT 0,
+    distinct_senders_7d BIGINT NOT NULL DEFAULT 0,
+    inbound_count_7d BIGINT NOT NULL DEFAULT 0,
+    inbound_amount_7d NUMERIC(18,2) NOT NULL DEFAULT 0,
+    blocked_outbound_7d BIGINT NOT NULL DEFAULT 0,
+    risk_score INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Payments between a sender and receiver, and inbound activity per receiver
+CREATE INDEX idx_transactions_receiver_time ON transactions(receiver_id, transaction_time)
+    WHERE receiver_id IS NOT NULL;
+CREATE INDEX idx_transactions_user_receiver ON transactions(user_id, receiver_id)
+    WHERE receiver_id IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE user_behavior DROP COLUMN IF EXISTS hour_of_week;
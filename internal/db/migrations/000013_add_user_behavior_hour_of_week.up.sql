@@ -0,0 +1,31 @@
+-- Transactions per UTC hour of week (index = day of week * 24 + hour, Sunday = 0),
+-- decayed daily by the behavior cron.
+ALTER TABLE user_behavior
+    ADD COLUMN hour_of_week JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+-- Seed the histogram from the last 90 days of transactions
+UPDATE user_behavior b
+SET hour_of_week = h.histogram
+FROM (
+    SELECT
+        u.user_id,
+        jsonb_agg(COALESCE(c.cnt, 0) ORDER BY s.i) AS histogram
+    FROM (
+        SELECT DISTINCT user_id
+        FROM transactions
+        WHERE transaction_time >= NOW() - INTERVAL '90 days'
+    ) u
+    CROSS JOIN generate_series(0, 167) AS s(i)
+    LEFT JOIN (
+        SELECT
+            user_id,
+            EXTRACT(DOW FROM transaction_time AT TIME ZONE 'UTC')::int * 24
+                + EXTRACT(HOUR FROM transaction_time AT TIME ZONE 'UTC')::int AS i,
+            COUNT(*) AS cnt
+        FROM transactions
+        WHERE transaction_time >= NOW() - INTERVAL '90 days'
+        GROUP BY 1, 2
+    ) c ON c.user_id = u.user_id AND c.i = s.i
+    GROUP BY u.user_id
+) h
+WHERE h.user_id = b.user_id;
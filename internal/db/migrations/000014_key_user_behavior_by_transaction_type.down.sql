@@ -0,0 +1,6 @@
+DELETE FROM user_behavior WHERE transaction_type <> 'ALL';
+
+ALTER TABLE user_behavior DROP CONSTRAINT user_behavior_pkey;
+ALTER TABLE user_behavior ADD PRIMARY KEY (user_id);
+
+ALTER TABLE user_behavior DROP COLUMN IF EXISTS transaction_type;
@@ -0,0 +1,42 @@
+-- Baselines are kept per (user, transaction type). The existing rows become
+-- the user-wide baseline, stored under transaction type 'ALL'.
+ALTER TABLE user_behavior
+    ADD COLUMN transaction_type VARCHAR(20) NOT NULL DEFAULT 'ALL';
+
+ALTER TABLE user_behavior DROP CONSTRAINT user_behavior_pkey;
+ALTER TABLE user_behavior ADD PRIMARY KEY (user_id, transaction_type);
+
+-- Seed per-type baselines from transaction history
+INSERT INTO user_behavior (
+    user_id,
+    transaction_type,
+    total_transactions,
+    avg_transaction_amount,
+    amount_variance,
+    amount_std_dev,
+    amount_variance_acc,
+    recent_avg_amount,
+    ema_smoothing_factor,
+    last_transaction_amount,
+    last_transaction_time,
+    high_value_threshold,
+    updated_at
+)
+SELECT
+    t.user_id,
+    t.transaction_type,
+    COUNT(*),
+    AVG(t.amount),
+    COALESCE(VAR_SAMP(t.amount), 0),
+    COALESCE(STDDEV_SAMP(t.amount), 0),
+    COALESCE(VAR_SAMP(t.amount) * (COUNT(*) - 1), 0),
+    AVG(t.amount),
+    0.1,
+    (ARRAY_AGG(t.amount ORDER BY t.transaction_time DESC))[1],
+    MAX(t.transaction_time),
+    PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY t.amount),
+    NOW()
+FROM transactions t
+JOIN user_behavior b ON b.user_id = t.user_id AND b.transaction_type = 'ALL'
+WHERE t.transaction_type <> 'ALL'
+GROUP BY t.user_id, t.transaction_type;
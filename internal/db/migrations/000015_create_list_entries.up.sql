@@ -0,0 +1,23 @@
+CREATE TABLE list_entries (
+    id BIGSERIAL PRIMARY KEY,
+
+    list_type VARCHAR(10) NOT NULL
+        CHECK (list_type IN ('BLOCK', 'ALLOW')),
+    entry_type VARCHAR(20) NOT NULL
+        CHECK (entry_type IN ('IP', 'DEVICE', 'RECEIVER', 'USER')),
+
+    -- IP address or CIDR range, device id, or receiver / user UUID
+    value VARCHAR(255) NOT NULL,
+    reason TEXT NOT NULL,
+
+    -- Scorers and expression rules skipped by an ALLOW entry; empty skips all
+    skip_scorers JSONB,
+
+    expires_at TIMESTAMPTZ,
+    created_by VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT uq_list_entries_list_entry_value UNIQUE (list_type, entry_type, value)
+);
+
+CREATE INDEX idx_list_entries_expires_at ON list_entries(expires_at);
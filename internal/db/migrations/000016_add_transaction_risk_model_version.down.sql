@@ -0,0 +1 @@
+ALTER TABLE transaction_risks DROP COLUMN IF EXISTS model_version;
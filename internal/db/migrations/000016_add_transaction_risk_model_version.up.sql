@@ -0,0 +1,3 @@
+-- Version of the model file behind MODEL_RISK for each evaluation
+ALTER TABLE transaction_risks
+    ADD COLUMN model_version VARCHAR(64);
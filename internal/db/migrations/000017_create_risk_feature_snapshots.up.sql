@@ -0,0 +1,16 @@
+-- Feature values each risk evaluation saw at decision time, kept as
+-- training data. Missing features are left out of the object.
+CREATE TABLE risk_feature_snapshots (
+    transaction_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    transaction_type VARCHAR(20) NOT NULL,
+    evaluated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    features JSONB NOT NULL,
+
+    CONSTRAINT fk_risk_feature_snapshot_transaction
+        FOREIGN KEY (transaction_id)
+        REFERENCES transactions(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_risk_feature_snapshots_evaluated_at ON risk_feature_snapshots(evaluated_at);
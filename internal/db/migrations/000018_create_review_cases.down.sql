@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS review_case_comments;
+DROP TABLE IF EXISTS review_cases;
@@ -0,0 +1,42 @@
+-- Manual review of FLAGGED transactions. An analyst claims a case, then
+-- approves (transaction COMPLETED) or rejects it (transaction BLOCKED).
+CREATE TABLE review_cases (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    amount NUMERIC(12,2) NOT NULL,
+    risk_score INT NOT NULL,
+    priority INT NOT NULL,
+
+    status VARCHAR(20) NOT NULL DEFAULT 'OPEN'
+        CHECK (status IN ('OPEN', 'IN_REVIEW', 'APPROVED', 'REJECTED')),
+    assigned_to VARCHAR(255),
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    claimed_at TIMESTAMPTZ,
+    resolved_at TIMESTAMPTZ,
+
+    CONSTRAINT uq_review_cases_transaction UNIQUE (transaction_id),
+    CONSTRAINT fk_review_case_transaction
+        FOREIGN KEY (transaction_id)
+        REFERENCES transactions(id)
+        ON DELETE CASCADE
+);
+
+-- The queue is read by status, highest priority first
+CREATE INDEX idx_review_cases_queue ON review_cases(status, priority DESC, created_at);
+
+CREATE TABLE review_case_comments (
+    id BIGSERIAL PRIMARY KEY,
+    case_id BIGINT NOT NULL,
+    author_id VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_review_case_comment_case
+        FOREIGN KEY (case_id)
+        REFERENCES review_cases(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_review_case_comments_case_id ON review_case_comments(case_id);
@@ -0,0 +1 @@
+ALTER TABLE user_behavior DROP COLUMN IF EXISTS verified_challenges, DROP COLUMN IF EXISTS last_verified_at;
@@ -0,0 +1,4 @@
+-- Step-up challenges the user passed, counted on the user-wide baseline
+ALTER TABLE user_behavior
+    ADD COLUMN verified_challenges BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN last_verified_at TIMESTAMPTZ;
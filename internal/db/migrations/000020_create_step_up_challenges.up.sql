@@ -0,0 +1,28 @@
+-- One-time codes for FLAGGED transactions. The transaction stays PENDING
+-- until the code is confirmed (COMPLETED) or attempts or time run out (BLOCKED).
+CREATE TABLE step_up_challenges (
+    id UUID PRIMARY KEY,
+    transaction_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL,
+
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING'
+        CHECK (status IN ('PENDING', 'VERIFIED', 'FAILED', 'EXPIRED')),
+
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMPTZ,
+
+    CONSTRAINT uq_step_up_challenges_transaction UNIQUE (transaction_id),
+    CONSTRAINT fk_step_up_challenge_transaction
+        FOREIGN KEY (transaction_id)
+        REFERENCES transactions(id)
+        ON DELETE CASCADE
+);
+
+-- The expiry sweep looks for pending challenges past their expiry
+CREATE INDEX idx_step_up_challenges_pending ON step_up_challenges(expires_at)
+    WHERE status = 'PENDING';
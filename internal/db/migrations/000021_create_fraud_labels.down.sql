@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS rule_performance;
+DROP TABLE IF EXISTS fraud_labels;
+DROP INDEX IF EXISTS idx_transaction_risks_evaluated_at;
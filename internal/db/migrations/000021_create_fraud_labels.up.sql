@@ -0,0 +1,40 @@
+-- Feedback on what a transaction turned out to be, e.g. from a chargeback.
+-- A false positive is labeled LEGIT.
+CREATE TABLE fraud_labels (
+    transaction_id UUID PRIMARY KEY,
+    label VARCHAR(10) NOT NULL CHECK (label IN ('FRAUD', 'LEGIT')),
+    source VARCHAR(32) NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    labeled_by VARCHAR(255) NOT NULL,
+    labeled_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_fraud_label_transaction
+        FOREIGN KEY (transaction_id)
+        REFERENCES transactions(id)
+        ON DELETE CASCADE
+);
+
+-- Daily precision, recall and hit rate per rule and per decision band,
+-- recomputed by the rule performance cron
+CREATE TABLE rule_performance (
+    day DATE NOT NULL,
+    dimension VARCHAR(10) NOT NULL CHECK (dimension IN ('RULE', 'DECISION')),
+    name VARCHAR(255) NOT NULL,
+
+    evaluated BIGINT NOT NULL,
+    labeled BIGINT NOT NULL,
+    frauds BIGINT NOT NULL,
+    hits BIGINT NOT NULL,
+    true_positives BIGINT NOT NULL,
+    false_positives BIGINT NOT NULL,
+    precision DOUBLE PRECISION NOT NULL,
+    recall DOUBLE PRECISION NOT NULL,
+    hit_rate DOUBLE PRECISION NOT NULL,
+
+    computed_at TIMESTAMPTZ NOT NULL,
+
+    PRIMARY KEY (day, dimension, name)
+);
+
+-- Reports group reasons by rule over an evaluation window
+CREATE INDEX idx_transaction_risks_evaluated_at ON transaction_risks(evaluated_at);
@@ -0,0 +1,19 @@
+-- Idempotency-Key of each POST /api/v1/transaction with the response it got.
+-- A key is PENDING while its first request is being evaluated.
+CREATE TABLE idempotency_keys (
+    user_id UUID NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    fingerprint VARCHAR(64) NOT NULL,
+
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING'
+        CHECK (status IN ('PENDING', 'COMPLETED')),
+    response JSONB,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+
+    PRIMARY KEY (user_id, key)
+);
+
+-- The purge cron deletes keys past their expiry
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1 @@
+ALTER TABLE user_behavior DROP COLUMN IF EXISTS version;
@@ -0,0 +1,4 @@
+-- Per-transaction behavior updates only write a row whose version they read,
+-- so concurrent transactions of one user cannot lose each other's update
+ALTER TABLE user_behavior
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 0;
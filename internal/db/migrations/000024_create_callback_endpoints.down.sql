@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_transactions_pending;
+DROP TABLE IF EXISTS callback_endpoints;
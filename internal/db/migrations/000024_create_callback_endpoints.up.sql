@@ -0,0 +1,12 @@
+-- Where each user wants asynchronous evaluation results POSTed
+CREATE TABLE callback_endpoints (
+    user_id UUID PRIMARY KEY,
+    url TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Async submissions are PENDING until a worker evaluates them; the resume
+-- sweep looks for those still without an evaluation
+CREATE INDEX idx_transactions_pending ON transactions(created_at)
+    WHERE transaction_status = 'PENDING';
@@ -0,0 +1,43 @@
+-- Downstream systems subscribed to risk decisions. Empty event_types or
+-- decisions match every value.
+CREATE TABLE webhook_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    event_types JSONB NOT NULL DEFAULT '[]',
+    decisions JSONB NOT NULL DEFAULT '[]',
+    min_score INT NOT NULL DEFAULT 0
+        CHECK (min_score BETWEEN 0 AND 100),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Outbox of events per subscription. PENDING rows are retried with backoff
+-- until they are DELIVERED or run out of attempts and become DEAD.
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY,
+    subscription_id BIGINT NOT NULL
+        REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING'
+        CHECK (status IN ('PENDING', 'DELIVERED', 'DEAD')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_status_code INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ
+);
+
+-- The dispatcher claims due PENDING rows
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at)
+    WHERE status = 'PENDING';
+
+-- Dead-letter view and per-subscription listing
+CREATE INDEX idx_webhook_deliveries_status ON webhook_deliveries(status, created_at DESC);
+CREATE INDEX idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, created_at DESC);
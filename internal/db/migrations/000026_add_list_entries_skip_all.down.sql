@@ -0,0 +1 @@
+ALTER TABLE list_entries DROP COLUMN IF EXISTS skip_all;
@@ -0,0 +1,10 @@
+-- An ALLOW entry skips every scorer only when skip_all is set; an empty
+-- skip_scorers list no longer means everything.
+ALTER TABLE list_entries
+    ADD COLUMN skip_all BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Keep existing entries that relied on the old meaning
+UPDATE list_entries
+SET skip_all = TRUE
+WHERE list_type = 'ALLOW'
+  AND (skip_scorers IS NULL OR skip_scorers = '[]'::jsonb OR skip_scorers = 'null'::jsonb);
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// unit is the unit of work a context carries: its transaction and the hooks
+// to run once the outermost transaction commits.
+type unit struct {
+	tx          *gorm.DB
+	afterCommit []func()
+}
+
+func current(ctx context.Context) *unit {
+	u, _ := ctx.Value(txKey{}).(*unit)
+	return u
+}
+
+// UnitOfWork groups repository calls into one database transaction.
+// Repositories join it by building their queries with Conn.
+type UnitOfWork interface {
+	// Do runs fn with a context that carries the transaction. It commits
+	// when fn returns nil and rolls back otherwise. A Do inside another
+	// joins the outer transaction.
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type unitOfWork struct {
+	db *gorm.DB
+}
+
+func NewUnitOfWork(db *gorm.DB) UnitOfWork {
+	return &unitOfWork{db: db}
+}
+
+func (u *unitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if current(ctx) != nil {
+		return fn(ctx)
+	}
+	work := &unit{}
+	err := u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		work.tx = tx
+		return fn(context.WithValue(ctx, txKey{}, work))
+	})
+	if err != nil {
+		return err
+	}
+	for _, hook := range work.afterCommit {
+		hook()
+	}
+	return nil
+}
+
+// Conn returns the transaction carried by ctx, or db when ctx is not in a
+// unit of work.
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if u := current(ctx); u != nil {
+		return u.tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// Savepoint runs fn in a savepoint of the unit of work carried by ctx. In
+// Postgres a failed statement aborts the whole transaction, so writes whose
+// failure is only logged must run here; a failure then rolls back fn's
+// statements alone. Outside a unit of work fn just runs.
+func Savepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	u := current(ctx)
+	if u == nil {
+		return fn(ctx)
+	}
+	sp := &unit{}
+	err := u.tx.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		sp.tx = tx
+		return fn(context.WithValue(ctx, txKey{}, sp))
+	})
+	if err != nil {
+		return err
+	}
+	// Hooks of a rolled back savepoint are dropped with its statements
+	u.afterCommit = append(u.afterCommit, sp.afterCommit...)
+	return nil
+}
+
+// AfterCommit runs fn once the unit of work carried by ctx commits, and
+// never if it rolls back. Side effects outside the database, such as
+// messages to users, belong here. Outside a unit of work fn runs at once.
+func AfterCommit(ctx context.Context, fn func()) {
+	u := current(ctx)
+	if u == nil {
+		fn()
+		return
+	}
+	u.afterCommit = append(u.afterCommit, fn)
+}
+
+// Detach returns ctx without its transaction, keeping its deadline and
+// values. Queries on it run on their own connection, so canceling one
+// cannot abort the unit of work.
+func Detach(ctx context.Context) context.Context {
+	return context.WithValue(ctx, txKey{}, (*unit)(nil))
+}
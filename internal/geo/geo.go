@@ -0,0 +1,160 @@
+// Package geo resolves IP addresses to approximate locations from a local
+// range file, so no request ever leaves the process for a lookup.
+package geo
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const earthRadiusKm = 6371.0
+
+// Location is the approximate position of an IP address.
+type Location struct {
+	Country   string  `json:"country"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Locator looks up the location of an IP address. ok is false when the
+// address is invalid or not covered by the database.
+type Locator interface {
+	Lookup(ip string) (loc Location, ok bool)
+}
+
+type ipRange struct {
+	start netip.Addr
+	end   netip.Addr
+	loc   Location
+}
+
+// CSVLocator is an in-memory table of IP ranges loaded from a CSV file with
+// rows of start_ip,end_ip,country,latitude,longitude. Both IPv4 and IPv6
+// ranges are supported.
+type CSVLocator struct {
+	ranges []ipRange
+}
+
+// LoadCSV reads a range file from disk.
+func LoadCSV(path string) (*CSVLocator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadCSV(f)
+}
+
+// ReadCSV parses range rows. Blank lines, lines starting with # and a
+// header row are skipped.
+func ReadCSV(r io.Reader) (*CSVLocator, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+
+	l := &CSVLocator{}
+	line := 0
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line++
+
+		if len(row) < 5 {
+			return nil, fmt.Errorf("line %d: expected start_ip,end_ip,country,latitude,longitude", line)
+		}
+
+		start, err := netip.ParseAddr(strings.TrimSpace(row[0]))
+		if err != nil {
+			if line == 1 {
+				continue // header
+			}
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		end, err := netip.ParseAddr(strings.TrimSpace(row[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		start, end = start.Unmap(), end.Unmap()
+		if start.Is4() != end.Is4() || end.Less(start) {
+			return nil, fmt.Errorf("line %d: invalid range %s-%s", line, start, end)
+		}
+
+		lat, err := strconv.ParseFloat(strings.TrimSpace(row[3]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: latitude: %w", line, err)
+		}
+		lon, err := strconv.ParseFloat(strings.TrimSpace(row[4]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: longitude: %w", line, err)
+		}
+
+		l.ranges = append(l.ranges, ipRange{
+			start: start,
+			end:   end,
+			loc: Location{
+				Country:   strings.ToUpper(strings.TrimSpace(row[2])),
+				Latitude:  lat,
+				Longitude: lon,
+			},
+		})
+	}
+
+	sort.Slice(l.ranges, func(i, j int) bool {
+		return l.ranges[i].start.Less(l.ranges[j].start)
+	})
+	return l, nil
+}
+
+// Lookup finds the range containing ip.
+func (l *CSVLocator) Lookup(ip string) (Location, bool) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return Location{}, false
+	}
+	addr = addr.Unmap()
+
+	// First range starting after addr; the candidate is the one before it
+	i := sort.Search(len(l.ranges), func(i int) bool {
+		return addr.Less(l.ranges[i].start)
+	})
+	if i == 0 {
+		return Location{}, false
+	}
+
+	r := l.ranges[i-1]
+	if r.end.Less(addr) {
+		return Location{}, false
+	}
+	return r.loc, true
+}
+
+// Len returns the number of loaded ranges.
+func (l *CSVLocator) Len() int {
+	return len(l.ranges)
+}
+
+// DistanceKm returns the great-circle distance between two locations.
+func DistanceKm(a Location, b Location) float64 {
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
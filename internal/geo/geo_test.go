@@ -0,0 +1,73 @@
+package geo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testRanges = `start_ip,end_ip,country,latitude,longitude
+# documentation ranges
+192.0.2.0,192.0.2.255,US,40.7128,-74.0060
+198.51.100.0,198.51.100.255,GB,51.5074,-0.1278
+2001:db8::,2001:db8::ffff,JP,35.6762,139.6503
+`
+
+func TestCSVLocator_Lookup(t *testing.T) {
+	l, err := ReadCSV(strings.NewReader(testRanges))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, l.Len())
+
+	tests := []struct {
+		name            string
+		ip              string
+		expectedOK      bool
+		expectedCountry string
+	}{
+		{name: "inside_first_range", ip: "192.0.2.17", expectedOK: true, expectedCountry: "US"},
+		{name: "range_end_inclusive", ip: "198.51.100.255", expectedOK: true, expectedCountry: "GB"},
+		{name: "ipv4_mapped_ipv6", ip: "::ffff:198.51.100.7", expectedOK: true, expectedCountry: "GB"},
+		{name: "ipv6_range", ip: "2001:db8::1", expectedOK: true, expectedCountry: "JP"},
+		{name: "gap_between_ranges", ip: "193.0.0.1", expectedOK: false},
+		{name: "before_first_range", ip: "10.0.0.1", expectedOK: false},
+		{name: "invalid_ip", ip: "not-an-ip", expectedOK: false},
+		{name: "empty_ip", ip: "", expectedOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, ok := l.Lookup(tt.ip)
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expectedCountry, loc.Country)
+		})
+	}
+}
+
+func TestReadCSV_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "missing_columns", input: "192.0.2.0,192.0.2.255,US\n"},
+		{name: "reversed_range", input: "192.0.2.255,192.0.2.0,US,0,0\n"},
+		{name: "mixed_families", input: "192.0.2.0,2001:db8::1,US,0,0\n"},
+		{name: "bad_latitude", input: "192.0.2.0,192.0.2.255,US,north,0\n"},
+		{name: "bad_ip_after_header", input: "start,end,c,lat,lon\nfoo,192.0.2.255,US,0,0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadCSV(strings.NewReader(tt.input))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDistanceKm(t *testing.T) {
+	newYork := Location{Latitude: 40.7128, Longitude: -74.0060}
+	london := Location{Latitude: 51.5074, Longitude: -0.1278}
+
+	assert.InDelta(t, 5570, DistanceKm(newYork, london), 10)
+	assert.Equal(t, 0.0, DistanceKm(london, london))
+}
@@ -0,0 +1,54 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Key states. A key is PENDING while its first request is being processed.
+const (
+	StatusPending   = "PENDING"
+	StatusCompleted = "COMPLETED"
+)
+
+// Key is a client-chosen Idempotency-Key of one user with the fingerprint of
+// the request that first used it and, once done, that request's response.
+type Key struct {
+	UserID      uuid.UUID       `gorm:"type:uuid;primaryKey"`
+	Key         string          `gorm:"type:varchar(255);primaryKey"`
+	Fingerprint string          `gorm:"type:varchar(64);not null"`
+	Status      string          `gorm:"type:varchar(20);not null"`
+	Response    json.RawMessage `gorm:"type:jsonb"`
+	CreatedAt   time.Time       `gorm:"type:timestamptz;not null;default:now()"`
+	ExpiresAt   time.Time       `gorm:"type:timestamptz;not null"`
+}
+
+type Repository interface {
+	// Reserve stores k unless the user already holds a live key with the
+	// same name. An expired key, or a PENDING one created before
+	// staleBefore, is taken over. It returns the live key when there is
+	// one and nil when k was stored.
+	Reserve(ctx context.Context, k *Key, now time.Time, staleBefore time.Time) (*Key, error)
+	Complete(ctx context.Context, userID uuid.UUID, key string, response json.RawMessage) error
+	Delete(ctx context.Context, userID uuid.UUID, key string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type Service interface {
+	// Begin reserves key for a request with the given fingerprint. It
+	// returns the stored response when the key already completed a request
+	// with the same fingerprint, and nil when the caller should process the
+	// request and then Complete or Release the key.
+	Begin(ctx context.Context, userID uuid.UUID, key string, fingerprint string) (json.RawMessage, error)
+	Complete(ctx context.Context, userID uuid.UUID, key string, response interface{}) error
+	// Release forgets a reserved key so the request can be retried.
+	Release(ctx context.Context, userID uuid.UUID, key string) error
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+func (Key) TableName() string {
+	return "idempotency_keys"
+}
@@ -0,0 +1,70 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"risk-detection/internal/db"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// Reserve inserts and takes over in one statement, so two requests racing
+// on the same key cannot both reserve it.
+func (r *repository) Reserve(ctx context.Context, k *Key, now time.Time, staleBefore time.Time) (*Key, error) {
+	res := db.Conn(ctx, r.db).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"fingerprint", "status", "response", "created_at", "expires_at"}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				gorm.Expr("idempotency_keys.expires_at <= ? OR (idempotency_keys.status = ? AND idempotency_keys.created_at <= ?)", now, StatusPending, staleBefore),
+			}},
+		}).
+		Create(k)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return nil, nil
+	}
+
+	var existing Key
+	if err := db.Conn(ctx, r.db).First(&existing, "user_id = ? AND key = ?", k.UserID, k.Key).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func (r *repository) Complete(ctx context.Context, userID uuid.UUID, key string, response json.RawMessage) error {
+	return db.Conn(ctx, r.db).
+		Model(&Key{}).
+		Where("user_id = ? AND key = ?", userID, key).
+		Updates(map[string]interface{}{
+			"status":   StatusCompleted,
+			"response": string(response),
+		}).Error
+}
+
+func (r *repository) Delete(ctx context.Context, userID uuid.UUID, key string) error {
+	return db.Conn(ctx, r.db).
+		Where("user_id = ? AND key = ?", userID, key).
+		Delete(&Key{}).Error
+}
+
+func (r *repository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res := db.Conn(ctx, r.db).
+		Where("expires_at <= ?", now).
+		Delete(&Key{})
+	return res.RowsAffected, res.Error
+}
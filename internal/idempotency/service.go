@@ -0,0 +1,88 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidKey = errors.New("idempotency key must be 1 to 255 characters")
+	ErrKeyReused  = errors.New("idempotency key was already used with a different request")
+	ErrInProgress = errors.New("a request with this idempotency key is still being processed")
+)
+
+const (
+	maxKeyLength = 255
+
+	// pendingTimeout is how long a reservation may wait for Complete before
+	// a retry may take the key over, e.g. after the server restarted
+	// mid-request.
+	pendingTimeout = time.Minute
+)
+
+type service struct {
+	repo Repository
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// NewService keeps each key and its response for ttl.
+func NewService(repo Repository, ttl time.Duration) Service {
+	return &service{
+		repo: repo,
+		ttl:  ttl,
+		now:  time.Now,
+	}
+}
+
+func (s *service) Begin(ctx context.Context, userID uuid.UUID, key string, fingerprint string) (json.RawMessage, error) {
+	if key == "" || len(key) > maxKeyLength {
+		return nil, ErrInvalidKey
+	}
+
+	now := s.now()
+	existing, err := s.repo.Reserve(ctx, &Key{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		Status:      StatusPending,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+	}, now, now.Add(-pendingTimeout))
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, nil
+	}
+
+	switch {
+	case existing.Fingerprint != fingerprint:
+		return nil, ErrKeyReused
+	case existing.Status != StatusCompleted:
+		return nil, ErrInProgress
+	}
+	return existing.Response, nil
+}
+
+func (s *service) Complete(ctx context.Context, userID uuid.UUID, key string, response interface{}) error {
+	body, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return s.repo.Complete(ctx, userID, key, body)
+}
+
+func (s *service) Release(ctx context.Context, userID uuid.UUID, key string) error {
+	return s.repo.Delete(ctx, userID, key)
+}
+
+// PurgeExpired deletes keys past their ttl. A retry after that is processed
+// as a new request.
+func (s *service) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpired(ctx, s.now())
+}
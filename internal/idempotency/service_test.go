@@ -0,0 +1,127 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRepository is a mock implementation of the Repository interface
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Reserve(ctx context.Context, k *Key, now time.Time, staleBefore time.Time) (*Key, error) {
+	args := m.Called(ctx, k, now, staleBefore)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Key), args.Error(1)
+}
+
+func (m *MockRepository) Complete(ctx context.Context, userID uuid.UUID, key string, response json.RawMessage) error {
+	args := m.Called(ctx, userID, key, response)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Delete(ctx context.Context, userID uuid.UUID, key string) error {
+	args := m.Called(ctx, userID, key)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func newTestService(repo Repository, now time.Time) *service {
+	svc := NewService(repo, 24*time.Hour).(*service)
+	svc.now = func() time.Time { return now }
+	return svc
+}
+
+func TestBegin(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	tests := []struct {
+		name          string
+		existing      *Key
+		expectedBody  string
+		expectedError error
+	}{
+		{
+			name: "new_key_is_reserved",
+		},
+		{
+			name:         "completed_key_replays_response",
+			existing:     &Key{Fingerprint: "fp", Status: StatusCompleted, Response: json.RawMessage(`{"decision":"ALLOW"}`)},
+			expectedBody: `{"decision":"ALLOW"}`,
+		},
+		{
+			name:          "different_request_conflicts",
+			existing:      &Key{Fingerprint: "other", Status: StatusCompleted},
+			expectedError: ErrKeyReused,
+		},
+		{
+			name:          "pending_key_conflicts",
+			existing:      &Key{Fingerprint: "fp", Status: StatusPending},
+			expectedError: ErrInProgress,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockRepo.On("Reserve", mock.Anything, mock.MatchedBy(func(k *Key) bool {
+				return k.UserID == userID && k.Key == "key-1" && k.Fingerprint == "fp" &&
+					k.Status == StatusPending && k.ExpiresAt.Equal(now.Add(24*time.Hour))
+			}), now, now.Add(-pendingTimeout)).Return(tt.existing, nil)
+
+			body, err := newTestService(mockRepo, now).Begin(context.Background(), userID, "key-1", "fp")
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedBody, string(body))
+		})
+	}
+}
+
+func TestBegin_InvalidKey(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := newTestService(mockRepo, time.Now())
+
+	for _, key := range []string{"", strings.Repeat("k", maxKeyLength+1)} {
+		_, err := svc.Begin(context.Background(), uuid.New(), key, "fp")
+		assert.ErrorIs(t, err, ErrInvalidKey)
+	}
+	mockRepo.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestComplete(t *testing.T) {
+	mockRepo := new(MockRepository)
+	userID := uuid.New()
+	mockRepo.On("Complete", mock.Anything, userID, "key-1", json.RawMessage(`{"decision":"FLAG"}`)).Return(nil)
+
+	err := newTestService(mockRepo, time.Now()).Complete(context.Background(), userID, "key-1", map[string]string{"decision": "FLAG"})
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPurgeExpired(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	mockRepo := new(MockRepository)
+	mockRepo.On("DeleteExpired", mock.Anything, now).Return(int64(3), nil)
+
+	n, err := newTestService(mockRepo, now).PurgeExpired(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+}
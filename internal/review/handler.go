@@ -0,0 +1,142 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// ListCases handles GET /api/v1/admin/cases?status=OPEN&offset=0&limit=20.
+func (h *Handler) ListCases(c *gin.Context) {
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	cases, err := h.service.ListCases(c.Request.Context(), c.Query("status"), offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": cases})
+}
+
+// GetCase handles GET /api/v1/admin/cases/:id.
+func (h *Handler) GetCase(c *gin.Context) {
+	id, ok := caseID(c)
+	if !ok {
+		return
+	}
+
+	rc, err := h.service.GetCase(c.Request.Context(), id)
+	if err != nil {
+		writeCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rc)
+}
+
+// ClaimCase handles POST /api/v1/admin/cases/:id/claim.
+func (h *Handler) ClaimCase(c *gin.Context) {
+	id, ok := caseID(c)
+	if !ok {
+		return
+	}
+
+	rc, err := h.service.ClaimCase(c.Request.Context(), c.GetString("user_id"), id)
+	if err != nil {
+		writeCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rc)
+}
+
+// AddComment handles POST /api/v1/admin/cases/:id/comments.
+func (h *Handler) AddComment(c *gin.Context) {
+	id, ok := caseID(c)
+	if !ok {
+		return
+	}
+
+	var req CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	comment, err := h.service.AddComment(c.Request.Context(), c.GetString("user_id"), id, req.Body)
+	if err != nil {
+		writeCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// ApproveCase handles POST /api/v1/admin/cases/:id/approve.
+func (h *Handler) ApproveCase(c *gin.Context) {
+	h.resolve(c, h.service.ApproveCase)
+}
+
+// RejectCase handles POST /api/v1/admin/cases/:id/reject.
+func (h *Handler) RejectCase(c *gin.Context) {
+	h.resolve(c, h.service.RejectCase)
+}
+
+func (h *Handler) resolve(c *gin.Context, fn func(ctx context.Context, analystID string, id int64, comment string) (*Case, error)) {
+	id, ok := caseID(c)
+	if !ok {
+		return
+	}
+
+	// The comment is optional, so an empty body is fine
+	var req ResolveRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+			return
+		}
+	}
+
+	rc, err := fn(c.Request.Context(), c.GetString("user_id"), id, req.Comment)
+	if err != nil {
+		writeCaseError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rc)
+}
+
+func caseID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid case id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func writeCaseError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrCaseNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrCaseNotOpen), errors.Is(err, ErrCaseNotAssigned), errors.Is(err, ErrCaseClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrEmptyComment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
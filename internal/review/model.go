@@ -0,0 +1,101 @@
+package review
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Case states. A case is claimed by one analyst before it can be resolved.
+const (
+	CaseOpen     = "OPEN"
+	CaseInReview = "IN_REVIEW"
+	CaseApproved = "APPROVED"
+	CaseRejected = "REJECTED"
+)
+
+// Case is the manual review of one FLAGGED transaction. Higher priority
+// cases are worked first.
+type Case struct {
+	ID            int64      `gorm:"primaryKey" json:"id"`
+	TransactionID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex" json:"transaction_id"`
+	UserID        uuid.UUID  `gorm:"type:uuid;not null" json:"user_id"`
+	Amount        float64    `gorm:"type:numeric(12,2);not null" json:"amount"`
+	RiskScore     int        `gorm:"not null" json:"risk_score"`
+	Priority      int        `gorm:"not null" json:"priority"`
+	Status        string     `gorm:"type:varchar(20);not null" json:"status"`
+	AssignedTo    *string    `gorm:"type:varchar(255)" json:"assigned_to,omitempty"`
+	CreatedAt     time.Time  `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
+	ClaimedAt     *time.Time `gorm:"type:timestamptz" json:"claimed_at,omitempty"`
+	ResolvedAt    *time.Time `gorm:"type:timestamptz" json:"resolved_at,omitempty"`
+
+	Comments []CaseComment `gorm:"foreignKey:CaseID" json:"comments,omitempty"`
+}
+
+// CaseComment is an analyst's note on a case.
+type CaseComment struct {
+	ID        int64     `gorm:"primaryKey" json:"id"`
+	CaseID    int64     `gorm:"not null" json:"case_id"`
+	AuthorID  string    `gorm:"type:varchar(255);not null" json:"author_id"`
+	Body      string    `gorm:"type:text;not null" json:"body"`
+	CreatedAt time.Time `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
+}
+
+// OpenCaseRequest describes the flagged transaction a case is opened for.
+type OpenCaseRequest struct {
+	TransactionID uuid.UUID
+	UserID        uuid.UUID
+	Amount        float64
+	RiskScore     int
+}
+
+type CommentRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// ResolveRequest optionally records why a case was approved or rejected.
+type ResolveRequest struct {
+	Comment string `json:"comment"`
+}
+
+type Repository interface {
+	// CreateCase opens a case unless the transaction already has one, in
+	// which case the existing case is loaded into c.
+	CreateCase(ctx context.Context, c *Case) error
+	// ListCases returns cases by priority, highest first, then oldest
+	// first, optionally filtered by status.
+	ListCases(ctx context.Context, status string, offset int, limit int) ([]Case, error)
+	GetCase(ctx context.Context, id int64) (*Case, error)
+	// ClaimCase assigns an OPEN case to the analyst. It returns nil if the
+	// case is not OPEN.
+	ClaimCase(ctx context.Context, id int64, analystID string, now time.Time) (*Case, error)
+	// ResolveCase closes an IN_REVIEW case claimed by the analyst. It
+	// returns nil if the case is not in that state.
+	ResolveCase(ctx context.Context, id int64, analystID string, status string, now time.Time) (*Case, error)
+	AddComment(ctx context.Context, comment *CaseComment) error
+}
+
+// TransactionRepository updates the transaction a case is about. It is
+// satisfied by transaction.Repository.
+type TransactionRepository interface {
+	UpdateStatusByID(ctx context.Context, id uuid.UUID, status string) error
+}
+
+type Service interface {
+	OpenCase(ctx context.Context, req OpenCaseRequest) (*Case, error)
+	ListCases(ctx context.Context, status string, offset int, limit int) ([]Case, error)
+	GetCase(ctx context.Context, id int64) (*Case, error)
+	ClaimCase(ctx context.Context, analystID string, id int64) (*Case, error)
+	AddComment(ctx context.Context, analystID string, id int64, body string) (*CaseComment, error)
+	ApproveCase(ctx context.Context, analystID string, id int64, comment string) (*Case, error)
+	RejectCase(ctx context.Context, analystID string, id int64, comment string) (*Case, error)
+}
+
+func (Case) TableName() string {
+	return "review_cases"
+}
+
+func (CaseComment) TableName() string {
+	return "review_case_comments"
+}
@@ -0,0 +1,105 @@
+package review
+
+import (
+	"context"
+	"time"
+
+	"risk-detection/internal/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CreateCase(ctx context.Context, c *Case) error {
+	res := db.Conn(ctx, r.db).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "transaction_id"}}, DoNothing: true}).
+		Create(c)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return db.Conn(ctx, r.db).First(c, "transaction_id = ?", c.TransactionID).Error
+	}
+	return nil
+}
+
+func (r *repository) ListCases(ctx context.Context, status string, offset int, limit int) ([]Case, error) {
+	var cases []Case
+
+	q := db.Conn(ctx, r.db)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+
+	err := q.Order("priority DESC, created_at ASC, id ASC").
+		Offset(offset).
+		Limit(limit).
+		Find(&cases).Error
+	return cases, err
+}
+
+func (r *repository) GetCase(ctx context.Context, id int64) (*Case, error) {
+	var c Case
+	err := db.Conn(ctx, r.db).
+		Preload("Comments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC, id ASC")
+		}).
+		First(&c, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ClaimCase only updates a case that is still OPEN, so two analysts
+// claiming at once cannot both win.
+func (r *repository) ClaimCase(ctx context.Context, id int64, analystID string, now time.Time) (*Case, error) {
+	var c Case
+	res := db.Conn(ctx, r.db).
+		Model(&c).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND status = ?", id, CaseOpen).
+		Updates(map[string]interface{}{
+			"status":      CaseInReview,
+			"assigned_to": analystID,
+			"claimed_at":  now,
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (r *repository) ResolveCase(ctx context.Context, id int64, analystID string, status string, now time.Time) (*Case, error) {
+	var c Case
+	res := db.Conn(ctx, r.db).
+		Model(&c).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND status = ? AND assigned_to = ?", id, CaseInReview, analystID).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"resolved_at": now,
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (r *repository) AddComment(ctx context.Context, comment *CaseComment) error {
+	return db.Conn(ctx, r.db).Create(comment).Error
+}
@@ -0,0 +1,252 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"risk-detection/internal/audit"
+	"risk-detection/internal/db"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCaseNotFound    = errors.New("review case not found")
+	ErrCaseNotOpen     = errors.New("review case is not open")
+	ErrCaseNotAssigned = errors.New("review case is not in review by this analyst")
+	ErrCaseClosed      = errors.New("review case is already resolved")
+	ErrEmptyComment    = errors.New("comment must not be empty")
+)
+
+// Transaction statuses a resolved case moves its transaction to.
+const (
+	statusCompleted = "COMPLETED"
+	statusBlocked   = "BLOCKED"
+)
+
+type service struct {
+	repo     Repository
+	txRepo   TransactionRepository
+	uow      db.UnitOfWork
+	auditLog *audit.Logger
+}
+
+// NewService resolves cases in uow, so a case never closes without its
+// transaction. A nil uow runs every write on its own.
+func NewService(repo Repository, txRepo TransactionRepository, uow db.UnitOfWork, auditLog *audit.Logger) Service {
+	return &service{
+		repo:     repo,
+		txRepo:   txRepo,
+		uow:      uow,
+		auditLog: auditLog,
+	}
+}
+
+func (s *service) atomic(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.uow == nil {
+		return fn(ctx)
+	}
+	return s.uow.Do(ctx, fn)
+}
+
+// casePriority ranks cases by risk score, with up to 30 extra points for
+// large amounts so a borderline score on a big payment is not left behind.
+func casePriority(riskScore int, amount float64) int {
+	priority := riskScore
+	switch {
+	case amount >= 10000:
+		priority += 30
+	case amount >= 1000:
+		priority += 20
+	case amount >= 100:
+		priority += 10
+	}
+	return priority
+}
+
+// OpenCase queues a flagged transaction for review. Opening a case twice for
+// the same transaction returns the existing one.
+func (s *service) OpenCase(ctx context.Context, req OpenCaseRequest) (*Case, error) {
+	c := &Case{
+		TransactionID: req.TransactionID,
+		UserID:        req.UserID,
+		Amount:        req.Amount,
+		RiskScore:     req.RiskScore,
+		Priority:      casePriority(req.RiskScore, req.Amount),
+		Status:        CaseOpen,
+		CreatedAt:     time.Now(),
+	}
+	if err := s.repo.CreateCase(ctx, c); err != nil {
+		return nil, err
+	}
+
+	s.logCase(audit.EventCaseOpened, "CREATE", "SYSTEM", "", c, map[string]interface{}{
+		"transaction_id": c.TransactionID,
+		"priority":       c.Priority,
+		"status":         c.Status,
+	})
+	return c, nil
+}
+
+func (s *service) ListCases(ctx context.Context, status string, offset int, limit int) ([]Case, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.repo.ListCases(ctx, strings.ToUpper(status), offset, limit)
+}
+
+func (s *service) GetCase(ctx context.Context, id int64) (*Case, error) {
+	c, err := s.repo.GetCase(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCaseNotFound
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *service) ClaimCase(ctx context.Context, analystID string, id int64) (*Case, error) {
+	c, err := s.repo.ClaimCase(ctx, id, analystID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		if _, err := s.GetCase(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrCaseNotOpen
+	}
+
+	s.logCase(audit.EventCaseClaimed, "CLAIM", "USER", analystID, c, map[string]interface{}{
+		"status":      c.Status,
+		"assigned_to": analystID,
+	})
+	return c, nil
+}
+
+func (s *service) AddComment(ctx context.Context, analystID string, id int64, body string) (*CaseComment, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, ErrEmptyComment
+	}
+
+	c, err := s.GetCase(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &CaseComment{CaseID: c.ID, AuthorID: analystID, Body: body, CreatedAt: time.Now()}
+	if err := s.repo.AddComment(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	s.logCase(audit.EventCaseCommented, "COMMENT", "USER", analystID, c, map[string]interface{}{
+		"comment_id": comment.ID,
+		"body":       comment.Body,
+	})
+	return comment, nil
+}
+
+// ApproveCase releases the transaction as COMPLETED.
+func (s *service) ApproveCase(ctx context.Context, analystID string, id int64, comment string) (*Case, error) {
+	return s.resolve(ctx, analystID, id, CaseApproved, statusCompleted, comment)
+}
+
+// RejectCase moves the transaction to BLOCKED.
+func (s *service) RejectCase(ctx context.Context, analystID string, id int64, comment string) (*Case, error) {
+	return s.resolve(ctx, analystID, id, CaseRejected, statusBlocked, comment)
+}
+
+// resolve closes a case claimed by the analyst and moves its transaction
+// out of FLAGGED.
+func (s *service) resolve(ctx context.Context, analystID string, id int64, caseStatus string, txStatus string, comment string) (*Case, error) {
+	comment = strings.TrimSpace(comment)
+
+	// The case, its comment and the transaction status commit together
+	var c *Case
+	err := s.atomic(ctx, func(ctx context.Context) error {
+		var err error
+		c, err = s.repo.ResolveCase(ctx, id, analystID, caseStatus, time.Now())
+		if err != nil {
+			return err
+		}
+		if c == nil {
+			existing, err := s.GetCase(ctx, id)
+			if err != nil {
+				return err
+			}
+			if existing.Status == CaseApproved || existing.Status == CaseRejected {
+				return ErrCaseClosed
+			}
+			return ErrCaseNotAssigned
+		}
+
+		if comment != "" {
+			if err := s.repo.AddComment(ctx, &CaseComment{CaseID: c.ID, AuthorID: analystID, Body: comment, CreatedAt: time.Now()}); err != nil {
+				return err
+			}
+		}
+
+		if err := s.txRepo.UpdateStatusByID(ctx, c.TransactionID, txStatus); err != nil {
+			return fmt.Errorf("failed to update transaction status: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	event, action := audit.EventCaseApproved, "APPROVE"
+	if caseStatus == CaseRejected {
+		event, action = audit.EventCaseRejected, "REJECT"
+	}
+	s.logCase(event, action, "USER", analystID, c, map[string]interface{}{
+		"status":  c.Status,
+		"comment": comment,
+	})
+
+	if s.auditLog != nil {
+		s.auditLog.Log(audit.AuditLog{
+			EventType:  audit.EventTransactionUpdated,
+			Action:     "UPDATE",
+			EntityType: "transactions",
+			EntityID:   c.TransactionID.String(),
+			ActorType:  "USER",
+			ActorID:    analystID,
+			ActorRole:  "ADMIN",
+			OldValues:  map[string]interface{}{"Status": "FLAGGED"},
+			NewValues:  map[string]interface{}{"Status": txStatus},
+			Status:     "SUCCESS",
+		})
+	}
+	return c, nil
+}
+
+func (s *service) logCase(event audit.EventType, action string, actorType string, actorID string, c *Case, values map[string]interface{}) {
+	if s.auditLog == nil {
+		return
+	}
+
+	entry := audit.AuditLog{
+		EventType:  event,
+		Action:     action,
+		EntityType: "review_cases",
+		EntityID:   strconv.FormatInt(c.ID, 10),
+		ActorType:  actorType,
+		ActorID:    actorID,
+		NewValues:  values,
+		Status:     "SUCCESS",
+	}
+	if actorType == "USER" {
+		entry.ActorRole = "ADMIN"
+	}
+	s.auditLog.Log(entry)
+}
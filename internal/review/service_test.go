@@ -0,0 +1,250 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"risk-detection/internal/audit"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockRepository is a mock implementation of the Repository interface
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) CreateCase(ctx context.Context, c *Case) error {
+	args := m.Called(ctx, c)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListCases(ctx context.Context, status string, offset int, limit int) ([]Case, error) {
+	args := m.Called(ctx, status, offset, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Case), args.Error(1)
+}
+
+func (m *MockRepository) GetCase(ctx context.Context, id int64) (*Case, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Case), args.Error(1)
+}
+
+func (m *MockRepository) ClaimCase(ctx context.Context, id int64, analystID string, now time.Time) (*Case, error) {
+	args := m.Called(ctx, id, analystID, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Case), args.Error(1)
+}
+
+func (m *MockRepository) ResolveCase(ctx context.Context, id int64, analystID string, status string, now time.Time) (*Case, error) {
+	args := m.Called(ctx, id, analystID, status, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Case), args.Error(1)
+}
+
+func (m *MockRepository) AddComment(ctx context.Context, comment *CaseComment) error {
+	args := m.Called(ctx, comment)
+	return args.Error(0)
+}
+
+// MockTransactionRepository is a mock implementation of the TransactionRepository interface
+type MockTransactionRepository struct {
+	mock.Mock
+}
+
+func (m *MockTransactionRepository) UpdateStatusByID(ctx context.Context, id uuid.UUID, status string) error {
+	args := m.Called(ctx, id, status)
+	return args.Error(0)
+}
+
+func TestCasePriority(t *testing.T) {
+	tests := []struct {
+		name      string
+		riskScore int
+		amount    float64
+		expected  int
+	}{
+		{name: "small_amount", riskScore: 40, amount: 50, expected: 40},
+		{name: "medium_amount", riskScore: 40, amount: 500, expected: 50},
+		{name: "large_amount", riskScore: 40, amount: 5000, expected: 60},
+		{name: "very_large_amount", riskScore: 35, amount: 25000, expected: 65},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, casePriority(tt.riskScore, tt.amount))
+		})
+	}
+}
+
+func TestOpenCase(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("CreateCase", mock.Anything, mock.Anything).Return(nil)
+
+	svc := NewService(mockRepo, new(MockTransactionRepository), nil, &audit.Logger{})
+
+	txID := uuid.New()
+	c, err := svc.OpenCase(context.Background(), OpenCaseRequest{TransactionID: txID, UserID: uuid.New(), Amount: 2500, RiskScore: 55})
+	assert.NoError(t, err)
+	assert.Equal(t, txID, c.TransactionID)
+	assert.Equal(t, CaseOpen, c.Status)
+	assert.Equal(t, 75, c.Priority)
+}
+
+func TestClaimCase(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		analyst := "analyst-1"
+		mockRepo.On("ClaimCase", mock.Anything, int64(1), analyst, mock.Anything).Return(&Case{ID: 1, Status: CaseInReview, AssignedTo: &analyst}, nil)
+
+		svc := NewService(mockRepo, new(MockTransactionRepository), nil, &audit.Logger{})
+		c, err := svc.ClaimCase(context.Background(), analyst, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, CaseInReview, c.Status)
+	})
+
+	t.Run("already_claimed", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("ClaimCase", mock.Anything, int64(1), "analyst-2", mock.Anything).Return(nil, nil)
+		mockRepo.On("GetCase", mock.Anything, int64(1)).Return(&Case{ID: 1, Status: CaseInReview}, nil)
+
+		svc := NewService(mockRepo, new(MockTransactionRepository), nil, &audit.Logger{})
+		_, err := svc.ClaimCase(context.Background(), "analyst-2", 1)
+		assert.ErrorIs(t, err, ErrCaseNotOpen)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("ClaimCase", mock.Anything, int64(9), "analyst-1", mock.Anything).Return(nil, nil)
+		mockRepo.On("GetCase", mock.Anything, int64(9)).Return(nil, gorm.ErrRecordNotFound)
+
+		svc := NewService(mockRepo, new(MockTransactionRepository), nil, &audit.Logger{})
+		_, err := svc.ClaimCase(context.Background(), "analyst-1", 9)
+		assert.ErrorIs(t, err, ErrCaseNotFound)
+	})
+}
+
+func TestAddComment(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetCase", mock.Anything, int64(1)).Return(&Case{ID: 1, Status: CaseInReview}, nil)
+	mockRepo.On("AddComment", mock.Anything, mock.MatchedBy(func(c *CaseComment) bool {
+		return c.CaseID == 1 && c.AuthorID == "analyst-1" && c.Body == "called the customer"
+	})).Return(nil)
+
+	svc := NewService(mockRepo, new(MockTransactionRepository), nil, &audit.Logger{})
+
+	comment, err := svc.AddComment(context.Background(), "analyst-1", 1, "  called the customer ")
+	assert.NoError(t, err)
+	assert.Equal(t, "called the customer", comment.Body)
+
+	_, err = svc.AddComment(context.Background(), "analyst-1", 1, "   ")
+	assert.ErrorIs(t, err, ErrEmptyComment)
+	mockRepo.AssertNumberOfCalls(t, "AddComment", 1)
+}
+
+// fakeUnitOfWork records whether the work it ran committed or rolled back
+type fakeUnitOfWork struct {
+	committed  bool
+	rolledBack bool
+}
+
+func (u *fakeUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := fn(ctx); err != nil {
+		u.rolledBack = true
+		return err
+	}
+	u.committed = true
+	return nil
+}
+
+func TestResolveCase(t *testing.T) {
+	txID := uuid.New()
+	analyst := "analyst-1"
+
+	tests := []struct {
+		name             string
+		resolve          func(svc Service) (*Case, error)
+		caseStatus       string
+		expectedTxStatus string
+	}{
+		{
+			name: "approve",
+			resolve: func(svc Service) (*Case, error) {
+				return svc.ApproveCase(context.Background(), analyst, 1, "known customer")
+			},
+			caseStatus:       CaseApproved,
+			expectedTxStatus: "COMPLETED",
+		},
+		{
+			name: "reject",
+			resolve: func(svc Service) (*Case, error) {
+				return svc.RejectCase(context.Background(), analyst, 1, "")
+			},
+			caseStatus:       CaseRejected,
+			expectedTxStatus: "BLOCKED",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockRepo.On("ResolveCase", mock.Anything, int64(1), analyst, tt.caseStatus, mock.Anything).Return(&Case{ID: 1, TransactionID: txID, Status: tt.caseStatus}, nil)
+			mockRepo.On("AddComment", mock.Anything, mock.Anything).Return(nil)
+			txRepo := new(MockTransactionRepository)
+			txRepo.On("UpdateStatusByID", mock.Anything, txID, tt.expectedTxStatus).Return(nil)
+
+			c, err := tt.resolve(NewService(mockRepo, txRepo, nil, &audit.Logger{}))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.caseStatus, c.Status)
+			txRepo.AssertExpectations(t)
+		})
+	}
+
+	t.Run("not_claimed_by_analyst", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("ResolveCase", mock.Anything, int64(1), "analyst-2", CaseApproved, mock.Anything).Return(nil, nil)
+		mockRepo.On("GetCase", mock.Anything, int64(1)).Return(&Case{ID: 1, Status: CaseInReview, AssignedTo: &analyst}, nil)
+		txRepo := new(MockTransactionRepository)
+
+		_, err := NewService(mockRepo, txRepo, nil, &audit.Logger{}).ApproveCase(context.Background(), "analyst-2", 1, "")
+		assert.ErrorIs(t, err, ErrCaseNotAssigned)
+		txRepo.AssertNotCalled(t, "UpdateStatusByID", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("already_resolved", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("ResolveCase", mock.Anything, int64(1), analyst, CaseRejected, mock.Anything).Return(nil, nil)
+		mockRepo.On("GetCase", mock.Anything, int64(1)).Return(&Case{ID: 1, Status: CaseApproved}, nil)
+
+		_, err := NewService(mockRepo, new(MockTransactionRepository), nil, &audit.Logger{}).RejectCase(context.Background(), analyst, 1, "")
+		assert.ErrorIs(t, err, ErrCaseClosed)
+	})
+
+	t.Run("transaction_update_fails", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockRepo.On("ResolveCase", mock.Anything, int64(1), analyst, CaseApproved, mock.Anything).Return(&Case{ID: 1, TransactionID: txID, Status: CaseApproved}, nil)
+		txRepo := new(MockTransactionRepository)
+		txRepo.On("UpdateStatusByID", mock.Anything, txID, "COMPLETED").Return(errors.New("db down"))
+
+		// The case must not stay approved without its transaction
+		uow := &fakeUnitOfWork{}
+		_, err := NewService(mockRepo, txRepo, uow, &audit.Logger{}).ApproveCase(context.Background(), analyst, 1, "")
+		assert.Error(t, err)
+		assert.True(t, uow.rolledBack)
+		assert.False(t, uow.committed)
+	})
+}
@@ -0,0 +1,199 @@
+// Package backtest replays historical transactions through a candidate rule
+// configuration and compares its decisions with the stored outcomes.
+package backtest
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"risk-detection/internal/audit"
+	"risk-detection/internal/geo"
+	"risk-detection/internal/risk"
+	"risk-detection/internal/risk/velocity"
+
+	"github.com/google/uuid"
+)
+
+// Input is the history to replay.
+type Input struct {
+	Records []Record
+	Devices map[uuid.UUID]risk.UserSecurity
+	Labels  map[uuid.UUID]string
+
+	// Locator enables GEO_LOCATION_RISK when set.
+	Locator geo.Locator
+
+	// Model enables MODEL_RISK when set.
+	Model *risk.ModelLoader
+
+	// VelocityWindows are the windows rules can reference; nil uses the
+	// defaults. Velocity is rebuilt from the replayed transactions.
+	VelocityWindows []velocity.Window
+
+	// Only transactions in [From, To) are reported. Earlier ones are still
+	// replayed to build up user behavior. A zero bound is open.
+	From time.Time
+	To   time.Time
+}
+
+// LoadConfig reads a rule configuration file.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	if len(cfg.Rules) == 0 {
+		return cfg, errors.New("config has no rules")
+	}
+	for _, p := range cfg.Policies {
+		if p.TransactionType == "" {
+			return cfg, errors.New("policy transaction_type is required")
+		}
+		if p.LowMax < 0 || p.LowMax >= p.MediumMax || p.MediumMax > 100 {
+			return cfg, fmt.Errorf("policy %s: thresholds must satisfy 0 <= low_max < medium_max <= 100", p.TransactionType)
+		}
+	}
+	return cfg, nil
+}
+
+// LoadLabels reads a CSV file of transaction_id,label rows. Labels are FRAUD
+// or LEGIT; a header row is skipped.
+func LoadLabels(r io.Reader) (map[uuid.UUID]string, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make(map[uuid.UUID]string)
+	for i, row := range rows {
+		if len(row) < 2 {
+			return nil, fmt.Errorf("line %d: expected transaction_id,label", i+1)
+		}
+		id, err := uuid.Parse(strings.TrimSpace(row[0]))
+		if err != nil {
+			if i == 0 {
+				continue // header
+			}
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		label := strings.ToUpper(strings.TrimSpace(row[1]))
+		if label != LabelFraud && label != LabelLegit {
+			return nil, fmt.Errorf("line %d: label must be FRAUD or LEGIT", i+1)
+		}
+		labels[id] = label
+	}
+	return labels, nil
+}
+
+// Run replays the records in time order through a risk service configured
+// with cfg. User behavior is rebuilt in memory exactly as the live service
+// updates it after each transaction.
+func Run(ctx context.Context, cfg Config, in Input) (*Report, error) {
+	st := newStore(cfg, in.Devices)
+
+	opts := []risk.Option{
+		risk.WithVelocity(velocity.NewStore(in.VelocityWindows)),
+		risk.WithPolicyRepository(st),
+		risk.WithDeviceRepository(st),
+	}
+	if in.Locator != nil {
+		opts = append(opts, risk.WithGeoLocation(in.Locator, st))
+	}
+	if in.Model != nil {
+		opts = append(opts, risk.WithModel(in.Model))
+	}
+
+	// A zero-value audit logger drops entries instead of writing them
+	svc, err := risk.NewService(st, st, &audit.Logger{}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+
+	records := make([]Record, len(in.Records))
+	copy(records, in.Records)
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].TransactionTime.Before(records[j].TransactionTime)
+	})
+
+	report := &Report{
+		From:       in.From,
+		To:         in.To,
+		VsOriginal: newConfusionMatrix(Decisions...),
+	}
+	if len(in.Labels) > 0 {
+		report.VsLabels = &LabelReport{Matrix: newConfusionMatrix(LabelFraud, LabelLegit)}
+	}
+
+	for _, rec := range records {
+		if !in.To.IsZero() && !rec.TransactionTime.Before(in.To) {
+			break
+		}
+
+		st.advance(rec)
+		result, err := svc.CalculateRisk(ctx, rec.dto())
+		report.Replayed++
+
+		if !in.From.IsZero() && rec.TransactionTime.Before(in.From) {
+			continue
+		}
+		if err != nil {
+			report.ScoreFailure++
+			continue
+		}
+		report.Scored++
+
+		if rec.OriginalDecision == nil {
+			report.NoOriginal++
+		} else {
+			report.VsOriginal.add(*rec.OriginalDecision, result.Decision)
+			if *rec.OriginalDecision != result.Decision {
+				report.Changed++
+			}
+		}
+
+		if report.VsLabels != nil {
+			if label, ok := in.Labels[rec.ID]; ok {
+				report.VsLabels.Labeled++
+				report.VsLabels.Matrix.add(label, result.Decision)
+			}
+		}
+	}
+
+	if report.VsLabels != nil {
+		report.VsLabels.Precision, report.VsLabels.Recall = precisionRecall(report.VsLabels.Matrix)
+	}
+
+	return report, nil
+}
+
+func precisionRecall(m ConfusionMatrix) (precision float64, recall float64) {
+	fraud := m[LabelFraud]
+	legit := m[LabelLegit]
+
+	tp := fraud["FLAG"] + fraud["BLOCK"]
+	fn := fraud["ALLOW"]
+	fp := legit["FLAG"] + legit["BLOCK"]
+
+	if tp+fp > 0 {
+		precision = float64(tp) / float64(tp+fp)
+	}
+	if tp+fn > 0 {
+		recall = float64(tp) / float64(tp+fn)
+	}
+	return precision, recall
+}
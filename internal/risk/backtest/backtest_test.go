@@ -0,0 +1,160 @@
+package backtest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"risk-detection/internal/risk"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func strPtr(v string) *string { return &v }
+
+// history is five ordinary payments followed by a 50x outlier, shuffled so
+// Run has to order them.
+func history(userID uuid.UUID, start time.Time) []Record {
+	var records []Record
+	for i := 0; i < 5; i++ {
+		records = append(records, Record{
+			ID:               uuid.New(),
+			UserID:           userID,
+			TransactionType:  "PAYMENT",
+			Amount:           100,
+			TransactionTime:  start.Add(time.Duration(i) * time.Hour),
+			OriginalDecision: strPtr("ALLOW"),
+		})
+	}
+	outlier := Record{
+		ID:               uuid.New(),
+		UserID:           userID,
+		TransactionType:  "PAYMENT",
+		Amount:           5000,
+		TransactionTime:  start.Add(5 * time.Hour),
+		OriginalDecision: strPtr("ALLOW"),
+	}
+	return append([]Record{outlier}, records...)
+}
+
+func TestRun(t *testing.T) {
+	userID := uuid.New()
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	records := history(userID, start)
+	outlierID := records[0].ID
+	firstID := records[1].ID
+
+	cfg := Config{Rules: []risk.RiskRule{{Name: risk.RuleTransactionAmount, Enabled: true, Weight: 100}}}
+
+	tests := []struct {
+		name           string
+		in             Input
+		expectedScored int64
+		expectedMatrix map[string]int64
+		expectLabels   bool
+		description    string
+	}{
+		{
+			name:           "outlier_blocked_after_behavior_rebuilt",
+			in:             Input{Records: records},
+			expectedScored: 6,
+			expectedMatrix: map[string]int64{"ALLOW": 5, "FLAG": 0, "BLOCK": 1},
+			description:    "Behavior from earlier payments should make the outlier score high",
+		},
+		{
+			name:           "from_limits_report_not_replay",
+			in:             Input{Records: records, From: start.Add(5 * time.Hour)},
+			expectedScored: 1,
+			expectedMatrix: map[string]int64{"ALLOW": 0, "FLAG": 0, "BLOCK": 1},
+			description:    "Transactions before from should build behavior without being reported",
+		},
+		{
+			name:           "labels_compared",
+			in:             Input{Records: records, Labels: map[uuid.UUID]string{outlierID: LabelFraud, firstID: LabelLegit}},
+			expectedScored: 6,
+			expectedMatrix: map[string]int64{"ALLOW": 5, "FLAG": 0, "BLOCK": 1},
+			expectLabels:   true,
+			description:    "Labeled transactions should produce precision and recall",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Run(context.Background(), cfg, tt.in)
+			assert.NoError(t, err)
+
+			assert.Equal(t, int64(6), report.Replayed, tt.description)
+			assert.Equal(t, tt.expectedScored, report.Scored, tt.description)
+			assert.Equal(t, tt.expectedMatrix, report.VsOriginal["ALLOW"], tt.description)
+			assert.Equal(t, tt.expectedMatrix["BLOCK"], report.Changed, tt.description)
+
+			if !tt.expectLabels {
+				assert.Nil(t, report.VsLabels)
+				return
+			}
+			assert.Equal(t, int64(2), report.VsLabels.Labeled)
+			assert.Equal(t, int64(1), report.VsLabels.Matrix[LabelFraud]["BLOCK"])
+			assert.Equal(t, int64(1), report.VsLabels.Matrix[LabelLegit]["ALLOW"])
+			assert.Equal(t, 1.0, report.VsLabels.Precision)
+			assert.Equal(t, 1.0, report.VsLabels.Recall)
+		})
+	}
+}
+
+func TestRun_UsesConfigPolicies(t *testing.T) {
+	userID := uuid.New()
+	records := history(userID, time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC))
+
+	cfg := Config{
+		Rules:    []risk.RiskRule{{Name: risk.RuleTransactionAmount, Enabled: true, Weight: 100}},
+		Policies: []risk.DecisionPolicy{{TransactionType: "PAYMENT", LowMax: 10, MediumMax: 99}},
+	}
+
+	report, err := Run(context.Background(), cfg, Input{Records: records})
+	assert.NoError(t, err)
+	// The history-free first payment (20) and the outlier (95) fall between
+	// the PAYMENT policy's 10 and 99 cut-offs
+	assert.Equal(t, int64(4), report.VsOriginal["ALLOW"]["ALLOW"])
+	assert.Equal(t, int64(2), report.VsOriginal["ALLOW"]["FLAG"])
+}
+
+func TestLoadLabels(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+		expected    map[uuid.UUID]string
+	}{
+		{
+			name:     "header_skipped",
+			input:    "transaction_id,label\n" + id.String() + ",fraud\n",
+			expected: map[uuid.UUID]string{id: LabelFraud},
+		},
+		{
+			name:        "unknown_label",
+			input:       id.String() + ",MAYBE\n",
+			expectError: true,
+		},
+		{
+			name:        "missing_column",
+			input:       id.String() + "\n",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels, err := LoadLabels(strings.NewReader(tt.input))
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, labels)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package backtest
+
+import (
+	"context"
+	"time"
+
+	"risk-detection/internal/risk"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LoadRecords reads every transaction before to, oldest first, with the
+// decision stored for it. A zero to loads the whole table.
+func LoadRecords(ctx context.Context, db *gorm.DB, to time.Time) ([]Record, error) {
+	var records []Record
+
+	q := db.WithContext(ctx).
+		Table("transactions AS t").
+		Select(`
+			t.id,
+			t.user_id,
+			t.transaction_type,
+			t.amount,
+			t.device_id,
+			t.ip_address,
+			t.transaction_time,
+			r.decision AS original_decision
+		`).
+		Joins("LEFT JOIN transaction_risks r ON r.transaction_id = t.id")
+	if !to.IsZero() {
+		q = q.Where("t.transaction_time < ?", to)
+	}
+
+	err := q.Order("t.transaction_time ASC, t.id ASC").
+		Scan(&records).Error
+	return records, err
+}
+
+// LoadDevices reads the known device of every user.
+func LoadDevices(ctx context.Context, db *gorm.DB) (map[uuid.UUID]risk.UserSecurity, error) {
+	var rows []risk.UserSecurity
+	if err := db.WithContext(ctx).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	devices := make(map[uuid.UUID]risk.UserSecurity, len(rows))
+	for _, row := range rows {
+		devices[row.UserID] = row
+	}
+	return devices, nil
+}
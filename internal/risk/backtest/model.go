@@ -0,0 +1,94 @@
+package backtest
+
+import (
+	"time"
+
+	"risk-detection/internal/risk"
+
+	"github.com/google/uuid"
+)
+
+// Decisions in the order they are reported.
+var Decisions = []string{"ALLOW", "FLAG", "BLOCK"}
+
+// Fraud labels accepted in a labels file.
+const (
+	LabelFraud = risk.LabelFraud
+	LabelLegit = risk.LabelLegit
+)
+
+// Config is the rule configuration under test, read from a JSON file with the
+// same fields the admin API uses for rules and policies.
+type Config struct {
+	Rules    []risk.RiskRule       `json:"rules"`
+	Policies []risk.DecisionPolicy `json:"policies"`
+}
+
+// Record is one historical transaction with the decision it originally got.
+type Record struct {
+	ID               uuid.UUID
+	UserID           uuid.UUID
+	TransactionType  string
+	Amount           float64
+	DeviceID         string
+	IPAddress        string
+	TransactionTime  time.Time
+	OriginalDecision *string
+}
+
+func (r Record) dto() risk.TransactionDTO {
+	return risk.TransactionDTO{
+		TxID:      r.ID,
+		UserID:    r.UserID,
+		TxType:    r.TransactionType,
+		Amount:    r.Amount,
+		TxTime:    r.TransactionTime,
+		DeviceID:  r.DeviceID,
+		IPAddress: r.IPAddress,
+	}
+}
+
+// ConfusionMatrix counts transactions by actual outcome (rows) and backtest
+// decision (columns).
+type ConfusionMatrix map[string]map[string]int64
+
+func newConfusionMatrix(rows ...string) ConfusionMatrix {
+	m := make(ConfusionMatrix)
+	for _, row := range rows {
+		m[row] = make(map[string]int64)
+		for _, d := range Decisions {
+			m[row][d] = 0
+		}
+	}
+	return m
+}
+
+func (m ConfusionMatrix) add(actual string, decision string) {
+	if _, ok := m[actual]; !ok {
+		m[actual] = make(map[string]int64)
+	}
+	m[actual][decision]++
+}
+
+// LabelReport compares backtest decisions with fraud labels. FLAG and BLOCK
+// count as a positive prediction.
+type LabelReport struct {
+	Labeled   int64           `json:"labeled"`
+	Matrix    ConfusionMatrix `json:"matrix"`
+	Precision float64         `json:"precision"`
+	Recall    float64         `json:"recall"`
+}
+
+// Report is the result of one backtest run.
+type Report struct {
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	Replayed     int64     `json:"replayed"`
+	Scored       int64     `json:"scored"`
+	NoOriginal   int64     `json:"no_original"`
+	Changed      int64     `json:"changed"`
+	ScoreFailure int64     `json:"score_failures"`
+
+	VsOriginal ConfusionMatrix `json:"vs_original"`
+	VsLabels   *LabelReport    `json:"vs_labels,omitempty"`
+}
@@ -0,0 +1,225 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"risk-detection/internal/risk"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var errReadOnly = errors.New("backtest store is read-only")
+
+// store is an in-memory stand-in for the risk, transaction and policy
+// repositories. The risk service reads and writes behavior here instead of
+// the database, so replaying history never touches production tables.
+type store struct {
+	rules    []risk.RiskRule
+	policies []risk.DecisionPolicy
+	devices  map[uuid.UUID]risk.UserSecurity
+
+	behavior map[behaviorKey]risk.UserBehavior
+	history  map[uuid.UUID][]Record
+	used     map[uuid.UUID][]risk.UserDevice
+	results  map[uuid.UUID]*risk.TransactionRisk
+
+	// now is the time of the transaction being replayed
+	now time.Time
+}
+
+func newStore(cfg Config, devices map[uuid.UUID]risk.UserSecurity) *store {
+	return &store{
+		rules:    cfg.Rules,
+		policies: cfg.Policies,
+		devices:  devices,
+		behavior: make(map[behaviorKey]risk.UserBehavior),
+		history:  make(map[uuid.UUID][]Record),
+		used:     make(map[uuid.UUID][]risk.UserDevice),
+		results:  make(map[uuid.UUID]*risk.TransactionRisk),
+	}
+}
+
+// advance records a transaction as stored before it is scored, as the
+// transaction service does in production.
+func (s *store) advance(rec Record) {
+	s.now = rec.TransactionTime
+	s.history[rec.UserID] = append(s.history[rec.UserID], rec)
+}
+
+// ---- risk.TransactionRiskRepository ----
+
+func (s *store) Create(ctx context.Context, r *risk.TransactionRisk) error {
+	s.results[r.TransactionID] = r
+	return nil
+}
+
+func (s *store) GetRiskByTransactionID(ctx context.Context, id uuid.UUID) (*risk.TransactionRisk, error) {
+	r, ok := s.results[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r, nil
+}
+
+// behaviorKey identifies a baseline: a user and a transaction type.
+type behaviorKey struct {
+	userID uuid.UUID
+	txType string
+}
+
+func (s *store) GetBehaviorByUserID(ctx context.Context, userID uuid.UUID) (*risk.UserBehavior, error) {
+	return s.GetTypeBehavior(ctx, userID, risk.BehaviorAllTypes)
+}
+
+func (s *store) GetTypeBehavior(ctx context.Context, userID uuid.UUID, txType string) (*risk.UserBehavior, error) {
+	b, ok := s.behavior[behaviorKey{userID, txType}]
+	if !ok {
+		return nil, nil // new user
+	}
+	return &b, nil
+}
+
+func (s *store) GetDailyTransactionAggregate(ctx context.Context, from time.Time, to time.Time) ([]risk.DailyAggregate, error) {
+	return nil, nil
+}
+
+func (s *store) UpdateBehaviorParams(ctx context.Context, userID uuid.UUID, txType string, stdDev float64, p95 float64) error {
+	return errReadOnly
+}
+
+func (s *store) UpdateBehaviorPerTransaction(ctx context.Context, behavior *risk.UserBehavior) error {
+	key := behaviorKey{behavior.UserID, behavior.TransactionType}
+	if stored, ok := s.behavior[key]; !ok || stored.Version != behavior.Version {
+		return risk.ErrBehaviorConflict
+	}
+	behavior.Version++
+	s.behavior[key] = *behavior
+	return nil
+}
+
+func (s *store) CreateFirstBehavior(ctx context.Context, behavior *risk.UserBehavior) error {
+	s.behavior[behaviorKey{behavior.UserID, behavior.TransactionType}] = *behavior
+	return nil
+}
+
+func (s *store) GetDeviceInfo(ctx context.Context, userID uuid.UUID) (*risk.UserSecurity, error) {
+	d, ok := s.devices[userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &d, nil
+}
+
+func (s *store) GetEnabledRules(ctx context.Context) ([]risk.RiskRule, error) {
+	var rules []risk.RiskRule
+	for _, r := range s.rules {
+		if r.Enabled {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+// DecayHourOfWeek is a no-op: the daily cron does not run during a replay,
+// so histograms keep their undecayed counts.
+func (s *store) DecayHourOfWeek(ctx context.Context, factor float64) (int64, error) {
+	return 0, nil
+}
+
+// RecordVerifiedChallenge is a no-op: replays never issue challenges.
+func (s *store) RecordVerifiedChallenge(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	return nil
+}
+
+// ---- risk.TransactionRepository ----
+
+// CountTransactionFrequency counts the user's other replayed transactions in
+// the window ending at the transaction being scored.
+func (s *store) CountTransactionFrequency(ctx context.Context, userID uuid.UUID, duration int32, excludeTxID uuid.UUID) (float64, error) {
+	from := s.now.Add(-time.Duration(duration) * time.Minute)
+
+	var count float64
+	for _, rec := range s.history[userID] {
+		if rec.ID == excludeTxID {
+			continue
+		}
+		if t := rec.TransactionTime; !t.Before(from) && !t.After(s.now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ---- risk.SightingRepository ----
+
+// GetLastSighting returns the latest replayed transaction or, if more recent,
+// the user's stored login.
+func (s *store) GetLastSighting(ctx context.Context, userID uuid.UUID, before time.Time, excludeTxID uuid.UUID) (*risk.Sighting, error) {
+	var last *risk.Sighting
+
+	history := s.history[userID]
+	for i := len(history) - 1; i >= 0; i-- {
+		rec := history[i]
+		if rec.ID == excludeTxID || rec.TransactionTime.After(before) {
+			continue
+		}
+		last = &risk.Sighting{IPAddress: rec.IPAddress, SeenAt: rec.TransactionTime, Source: risk.SightingTransaction}
+		break
+	}
+
+	if login, ok := s.devices[userID]; ok && !login.UpdatedAt.After(before) {
+		if last == nil || login.UpdatedAt.After(last.SeenAt) {
+			last = &risk.Sighting{IPAddress: login.IPAddress, SeenAt: login.UpdatedAt, Source: risk.SightingLogin}
+		}
+	}
+	return last, nil
+}
+
+// ---- risk.DeviceRepository ----
+
+// Device history is rebuilt from replayed transactions only; logins and
+// trust decisions are not part of the replayed history.
+
+func (s *store) ListDevices(ctx context.Context, userID uuid.UUID) ([]risk.UserDevice, error) {
+	return s.used[userID], nil
+}
+
+func (s *store) RecordDeviceUse(ctx context.Context, userID uuid.UUID, deviceID string, ipAddress string, seenAt time.Time) error {
+	devices := s.used[userID]
+	for i := range devices {
+		if devices[i].DeviceID == deviceID {
+			devices[i].LastIPAddress = ipAddress
+			devices[i].LastSeenAt = seenAt
+			devices[i].UseCount++
+			return nil
+		}
+	}
+
+	s.used[userID] = append(devices, risk.UserDevice{
+		UserID:        userID,
+		DeviceID:      deviceID,
+		LastIPAddress: ipAddress,
+		FirstSeenAt:   seenAt,
+		LastSeenAt:    seenAt,
+		UseCount:      1,
+		TrustState:    risk.DeviceUnverified,
+	})
+	return nil
+}
+
+func (s *store) SetTrustState(ctx context.Context, userID uuid.UUID, deviceID string, state string) (*risk.UserDevice, error) {
+	return nil, errReadOnly
+}
+
+// ---- risk.PolicyRepository ----
+
+func (s *store) ListPolicies(ctx context.Context) ([]risk.DecisionPolicy, error) {
+	return s.policies, nil
+}
+
+func (s *store) UpsertPolicy(ctx context.Context, policy *risk.DecisionPolicy) error {
+	return errReadOnly
+}
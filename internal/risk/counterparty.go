@@ -0,0 +1,110 @@
+package risk
+
+import (
+	"context"
+	"log"
+)
+
+const (
+	// A payee needs at least this many distinct senders in a day before
+	// fan-in is considered.
+	minFanInSenders = 5
+
+	// Fan-in is sudden when the last day's distinct senders are at least
+	// this multiple of the 7-day daily average.
+	fanInSurgeFactor = 3
+)
+
+// WithCounterpartyRepository registers the COUNTERPARTY_RISK scorer, which
+// looks at who the transaction pays.
+func WithCounterpartyRepository(repo CounterpartyRepository) Option {
+	return func(s *service) {
+		s.RegisterScorer(detailedScorer{
+			name: RuleCounterparty,
+			fn: func(ctx context.Context, tx TransactionDTO) (ScoreResult, error) {
+				return counterpartyRisk(ctx, repo, tx)
+			},
+		})
+	}
+}
+
+// counterpartyRisk flags first-time payees and payees whose profile shows
+// mule-like fan-in or recently blocked payments of their own.
+func counterpartyRisk(ctx context.Context, repo CounterpartyRepository, tx TransactionDTO) (ScoreResult, error) {
+	if tx.ReceiverID == nil {
+		return ScoreResult{}, nil
+	}
+	receiverID := *tx.ReceiverID
+
+	var result ScoreResult
+
+	previous, err := repo.CountPayments(ctx, tx.UserID, receiverID, tx.TxID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ScoreResult{}, ctx.Err()
+		}
+		log.Printf("unable to count payments to receiver: %v", err)
+	} else if previous == 0 {
+		result.Score += 30
+		result.Reasons = append(result.Reasons, Reason{
+			Code:   "COUNTERPARTY_FIRST_PAYMENT",
+			Score:  30,
+			Inputs: map[string]interface{}{"receiver_id": receiverID.String()},
+		})
+	}
+
+	profile, err := repo.GetReceiverProfile(ctx, receiverID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ScoreResult{}, ctx.Err()
+		}
+		log.Printf("unable to get receiver profile: %v", err)
+	}
+	if profile != nil {
+		profileResult := profile.Score()
+		result.Score += profileResult.Score
+		result.Reasons = append(result.Reasons, profileResult.Reasons...)
+	}
+
+	if result.Score > 100 {
+		result.Score = 100
+	}
+	return result, nil
+}
+
+// Score grades the profile on its own: sudden fan-in from many senders and
+// blocked payments made by the receiver. The cron stores it as RiskScore.
+func (p ReceiverProfile) Score() ScoreResult {
+	var result ScoreResult
+
+	if p.DistinctSenders24h >= minFanInSenders &&
+		p.DistinctSenders24h*7 >= fanInSurgeFactor*p.DistinctSenders7d {
+		result.Score += 40
+		result.Reasons = append(result.Reasons, Reason{
+			Code:  "COUNTERPARTY_FAN_IN",
+			Score: 40,
+			Inputs: map[string]interface{}{
+				"receiver_id":          p.ReceiverID.String(),
+				"distinct_senders_24h": p.DistinctSenders24h,
+				"distinct_senders_7d":  p.DistinctSenders7d,
+			},
+		})
+	}
+
+	if p.BlockedOutbound7d > 0 {
+		result.Score += 50
+		result.Reasons = append(result.Reasons, Reason{
+			Code:  "COUNTERPARTY_RECENTLY_BLOCKED",
+			Score: 50,
+			Inputs: map[string]interface{}{
+				"receiver_id":         p.ReceiverID.String(),
+				"blocked_outbound_7d": p.BlockedOutbound7d,
+			},
+		})
+	}
+
+	if result.Score > 100 {
+		result.Score = 100
+	}
+	return result
+}
@@ -0,0 +1,38 @@
+package risk
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CounterpartyHandler struct {
+	service CounterpartyService
+}
+
+func NewCounterpartyHandler(service CounterpartyService) *CounterpartyHandler {
+	return &CounterpartyHandler{service: service}
+}
+
+// GetReceiverProfile handles GET /api/v1/admin/receivers/:id/profile.
+func (h *CounterpartyHandler) GetReceiverProfile(c *gin.Context) {
+	receiverID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid receiver id"})
+		return
+	}
+
+	profile, err := h.service.GetReceiverProfile(c.Request.Context(), receiverID)
+	if err != nil {
+		if errors.Is(err, ErrReceiverProfileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "receiver profile not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
@@ -0,0 +1,127 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type counterpartyRepository struct {
+	db *gorm.DB
+}
+
+func NewCounterpartyRepository(db *gorm.DB) CounterpartyRepository {
+	return &counterpartyRepository{db: db}
+}
+
+func (r *counterpartyRepository) CountPayments(
+	ctx context.Context,
+	senderID uuid.UUID,
+	receiverID uuid.UUID,
+	excludeTxID uuid.UUID,
+) (int64, error) {
+
+	var count int64
+
+	err := r.db.WithContext(ctx).
+		Table("transactions").
+		Where("user_id = ? AND receiver_id = ?", senderID, receiverID).
+		Where("id <> ?", excludeTxID).
+		Count(&count).Error
+
+	return count, err
+}
+
+func (r *counterpartyRepository) GetReceiverProfile(ctx context.Context, receiverID uuid.UUID) (*ReceiverProfile, error) {
+	var profile ReceiverProfile
+
+	err := r.db.WithContext(ctx).
+		Where("receiver_id = ?", receiverID).
+		First(&profile).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil // never profiled
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// AggregateReceiverProfiles computes profiles for every receiver paid in the
+// last 7 days and every user with a blocked payment in that window.
+func (r *counterpartyRepository) AggregateReceiverProfiles(ctx context.Context, now time.Time) ([]ReceiverProfile, error) {
+	var result []ReceiverProfile
+
+	dayAgo := now.Add(-24 * time.Hour)
+	weekAgo := now.AddDate(0, 0, -7)
+
+	err := r.db.WithContext(ctx).
+		Raw(`
+			WITH inbound AS (
+				SELECT
+					receiver_id,
+					COUNT(DISTINCT user_id) FILTER (WHERE transaction_time >= ?) AS distinct_senders_24h,
+					COUNT(DISTINCT user_id) AS distinct_senders_7d,
+					COUNT(*) AS inbound_count_7d,
+					COALESCE(SUM(amount), 0) AS inbound_amount_7d
+				FROM transactions
+				WHERE receiver_id IS NOT NULL
+				  AND transaction_time >= ?
+				  AND transaction_time < ?
+				GROUP BY receiver_id
+			),
+			blocked AS (
+				SELECT
+					t.user_id AS receiver_id,
+					COUNT(*) AS blocked_outbound_7d
+				FROM transactions t
+				JOIN transaction_risks r ON r.transaction_id = t.id
+				WHERE r.decision = 'BLOCK'
+				  AND t.transaction_time >= ?
+				  AND t.transaction_time < ?
+				GROUP BY t.user_id
+			)
+			SELECT
+				COALESCE(i.receiver_id, b.receiver_id) AS receiver_id,
+				COALESCE(i.distinct_senders_24h, 0) AS distinct_senders_24h,
+				COALESCE(i.distinct_senders_7d, 0) AS distinct_senders_7d,
+				COALESCE(i.inbound_count_7d, 0) AS inbound_count_7d,
+				COALESCE(i.inbound_amount_7d, 0) AS inbound_amount_7d,
+				COALESCE(b.blocked_outbound_7d, 0) AS blocked_outbound_7d
+			FROM inbound i
+			FULL OUTER JOIN blocked b ON b.receiver_id = i.receiver_id
+		`, dayAgo, weekAgo, now, weekAgo, now).
+		Scan(&result).Error
+
+	return result, err
+}
+
+func (r *counterpartyRepository) UpsertReceiverProfile(ctx context.Context, profile *ReceiverProfile) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "receiver_id"}},
+			UpdateAll: true,
+		}).
+		Create(profile).Error
+}
+
+// ResetStaleReceiverProfiles zeroes profiles not refreshed since before, i.e.
+// receivers with no activity left in the window.
+func (r *counterpartyRepository) ResetStaleReceiverProfiles(ctx context.Context, before time.Time) error {
+	return r.db.WithContext(ctx).
+		Table("receiver_profiles").
+		Where("updated_at < ?", before).
+		Updates(map[string]interface{}{
+			"distinct_senders_24h": 0,
+			"distinct_senders_7d":  0,
+			"inbound_count_7d":     0,
+			"inbound_amount_7d":    0,
+			"blocked_outbound_7d":  0,
+			"risk_score":           0,
+			"updated_at":           time.Now(),
+		}).Error
+}
@@ -0,0 +1,30 @@
+package risk
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var ErrReceiverProfileNotFound = errors.New("receiver profile not found")
+
+type counterpartyService struct {
+	repo CounterpartyRepository
+}
+
+// NewCounterpartyService exposes the receiver profiles kept by the cron.
+func NewCounterpartyService(repo CounterpartyRepository) CounterpartyService {
+	return &counterpartyService{repo: repo}
+}
+
+func (s *counterpartyService) GetReceiverProfile(ctx context.Context, receiverID uuid.UUID) (*ReceiverProfile, error) {
+	profile, err := s.repo.GetReceiverProfile(ctx, receiverID)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, ErrReceiverProfileNotFound
+	}
+	return profile, nil
+}
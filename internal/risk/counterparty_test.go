@@ -0,0 +1,203 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"risk-detection/internal/audit"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCounterpartyRepository struct {
+	mock.Mock
+}
+
+func (m *MockCounterpartyRepository) CountPayments(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID, excludeTxID uuid.UUID) (int64, error) {
+	args := m.Called(ctx, senderID, receiverID, excludeTxID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCounterpartyRepository) GetReceiverProfile(ctx context.Context, receiverID uuid.UUID) (*ReceiverProfile, error) {
+	args := m.Called(ctx, receiverID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ReceiverProfile), args.Error(1)
+}
+
+func (m *MockCounterpartyRepository) AggregateReceiverProfiles(ctx context.Context, now time.Time) ([]ReceiverProfile, error) {
+	args := m.Called(ctx, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]ReceiverProfile), args.Error(1)
+}
+
+func (m *MockCounterpartyRepository) UpsertReceiverProfile(ctx context.Context, profile *ReceiverProfile) error {
+	args := m.Called(ctx, profile)
+	return args.Error(0)
+}
+
+func (m *MockCounterpartyRepository) ResetStaleReceiverProfiles(ctx context.Context, before time.Time) error {
+	args := m.Called(ctx, before)
+	return args.Error(0)
+}
+
+func TestReceiverProfile_Score(t *testing.T) {
+	tests := []struct {
+		name          string
+		profile       ReceiverProfile
+		expectedScore int
+		description   string
+	}{
+		{
+			name:          "quiet_receiver",
+			profile:       ReceiverProfile{DistinctSenders24h: 1, DistinctSenders7d: 3},
+			expectedScore: 0,
+			description:   "Few senders should not score",
+		},
+		{
+			name:          "sudden_fan_in",
+			profile:       ReceiverProfile{DistinctSenders24h: 12, DistinctSenders7d: 14},
+			expectedScore: 40,
+			description:   "Most of the week's senders arriving in one day is mule-like fan-in",
+		},
+		{
+			name:          "steady_merchant",
+			profile:       ReceiverProfile{DistinctSenders24h: 50, DistinctSenders7d: 300},
+			expectedScore: 0,
+			description:   "Many senders at a steady daily rate should not count as sudden",
+		},
+		{
+			name:          "recently_blocked",
+			profile:       ReceiverProfile{BlockedOutbound7d: 2},
+			expectedScore: 50,
+			description:   "A receiver with blocked payments of its own should score",
+		},
+		{
+			name:          "fan_in_and_blocked",
+			profile:       ReceiverProfile{DistinctSenders24h: 8, DistinctSenders7d: 8, BlockedOutbound7d: 1},
+			expectedScore: 90,
+			description:   "Both signals should add up",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedScore, tt.profile.Score().Score, tt.description)
+		})
+	}
+}
+
+func TestCounterpartyRisk(t *testing.T) {
+	receiverID := uuid.New()
+
+	tests := []struct {
+		name          string
+		receiverID    *uuid.UUID
+		previous      int64
+		countErr      error
+		profile       *ReceiverProfile
+		expectedScore int
+		expectedCodes []string
+		description   string
+	}{
+		{
+			name:          "no_receiver",
+			receiverID:    nil,
+			expectedScore: 0,
+			description:   "Transactions without a receiver should not score",
+		},
+		{
+			name:          "known_payee_without_profile",
+			receiverID:    &receiverID,
+			previous:      4,
+			expectedScore: 0,
+			description:   "Paying a known, unprofiled payee should not score",
+		},
+		{
+			name:          "first_time_payee",
+			receiverID:    &receiverID,
+			previous:      0,
+			expectedScore: 30,
+			expectedCodes: []string{"COUNTERPARTY_FIRST_PAYMENT"},
+			description:   "The first payment to a receiver should score",
+		},
+		{
+			name:          "first_time_payee_to_mule",
+			receiverID:    &receiverID,
+			previous:      0,
+			profile:       &ReceiverProfile{ReceiverID: receiverID, DistinctSenders24h: 20, DistinctSenders7d: 20, BlockedOutbound7d: 3},
+			expectedScore: 100,
+			expectedCodes: []string{"COUNTERPARTY_FIRST_PAYMENT", "COUNTERPARTY_FAN_IN", "COUNTERPARTY_RECENTLY_BLOCKED"},
+			description:   "All signals together should clamp to 100",
+		},
+		{
+			name:          "count_error_still_uses_profile",
+			receiverID:    &receiverID,
+			countErr:      errors.New("db down"),
+			profile:       &ReceiverProfile{ReceiverID: receiverID, BlockedOutbound7d: 1},
+			expectedScore: 50,
+			expectedCodes: []string{"COUNTERPARTY_RECENTLY_BLOCKED"},
+			description:   "A failed payment count should not hide the profile",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockCounterpartyRepository)
+			repo.On("CountPayments", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(tt.previous, tt.countErr)
+			if tt.profile != nil {
+				repo.On("GetReceiverProfile", mock.Anything, receiverID).Return(tt.profile, nil)
+			} else {
+				repo.On("GetReceiverProfile", mock.Anything, receiverID).Return(nil, nil)
+			}
+
+			res, err := counterpartyRisk(context.Background(), repo, TransactionDTO{TxID: uuid.New(), UserID: uuid.New(), ReceiverID: tt.receiverID})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedScore, res.Score, tt.description)
+
+			var codes []string
+			for _, r := range res.Reasons {
+				codes = append(codes, r.Code)
+			}
+			assert.Equal(t, tt.expectedCodes, codes, tt.description)
+		})
+	}
+}
+
+func TestCalculateRisk_CounterpartyRule(t *testing.T) {
+	receiverID := uuid.New()
+	txID := uuid.New()
+	userID := uuid.New()
+
+	mockRepo := new(MockTransactionRiskRepository)
+	mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{{Name: RuleCounterparty, Enabled: true, Weight: 50}}, nil)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("GetBehaviorByUserID", mock.Anything, mock.Anything).Return(&UserBehavior{UserID: userID}, nil)
+	mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
+
+	repo := new(MockCounterpartyRepository)
+	repo.On("CountPayments", mock.Anything, userID, receiverID, txID).Return(int64(0), nil)
+	repo.On("GetReceiverProfile", mock.Anything, receiverID).Return(nil, nil)
+
+	svc, err := NewService(mockRepo, nil, &audit.Logger{}, WithCounterpartyRepository(repo))
+	assert.NoError(t, err)
+
+	// ReceiverID is read from the transaction struct as the transaction service passes it
+	tx := struct {
+		ID         uuid.UUID
+		UserID     uuid.UUID
+		ReceiverID *uuid.UUID
+	}{ID: txID, UserID: userID, ReceiverID: &receiverID}
+
+	result, err := svc.CalculateRisk(context.Background(), &tx)
+	assert.NoError(t, err)
+	assert.Equal(t, 15, result.RiskScore)
+	repo.AssertExpectations(t)
+}
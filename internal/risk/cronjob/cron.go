@@ -34,3 +34,252 @@ func StartBehaviorCron(
 
 	c.Start()
 }
+
+type ReceiverProfileRefresher interface {
+	UpdateReceiverProfiles(ctx context.Context, now time.Time) error
+}
+
+func StartReceiverProfileCron(
+	ctx context.Context,
+	updater ReceiverProfileRefresher,
+) {
+
+	c := cron.New(cron.WithLocation(time.UTC))
+
+	// Hourly so fan-in to a mule account shows up the same day
+	_, err := c.AddFunc("0 * * * *", func() {
+		if err := updater.UpdateReceiverProfiles(ctx, time.Now().UTC()); err != nil {
+			log.Printf("[RISK][CRON] receiver profile update failed: %v", err)
+		}
+	})
+
+	if err != nil {
+		log.Fatalf("failed to start receiver profile cron: %v", err)
+	}
+
+	c.Start()
+}
+
+type VelocitySweeper interface {
+	Sweep(now time.Time) int
+}
+
+func StartVelocitySweepCron(sweeper VelocitySweeper) {
+
+	c := cron.New(cron.WithLocation(time.UTC))
+
+	// Idle users, devices and IPs are only pruned when they transact again
+	_, err := c.AddFunc("*/10 * * * *", func() {
+		if n := sweeper.Sweep(time.Now()); n > 0 {
+			log.Printf("[RISK][CRON] velocity sweep removed %d idle keys", n)
+		}
+	})
+
+	if err != nil {
+		log.Fatalf("failed to start velocity sweep cron: %v", err)
+	}
+
+	c.Start()
+}
+
+type ListReloader interface {
+	Reload(ctx context.Context) error
+}
+
+func StartListRefreshCron(
+	ctx context.Context,
+	reloader ListReloader,
+) {
+
+	c := cron.New(cron.WithLocation(time.UTC))
+
+	// Picks up changes made through other instances and drops expired entries
+	_, err := c.AddFunc("* * * * *", func() {
+		if err := reloader.Reload(ctx); err != nil {
+			log.Printf("[RISK][CRON] list reload failed: %v", err)
+		}
+	})
+
+	if err != nil {
+		log.Fatalf("failed to start list refresh cron: %v", err)
+	}
+
+	c.Start()
+}
+
+// performanceLookback is how far back rule performance is recomputed.
+// Chargebacks arrive weeks after the payment, so old days keep changing.
+const performanceLookback = 90 * 24 * time.Hour
+
+type PerformanceComputer interface {
+	ComputePerformance(ctx context.Context, from time.Time, to time.Time) (int, error)
+}
+
+func StartRulePerformanceCron(
+	ctx context.Context,
+	computer PerformanceComputer,
+) {
+
+	c := cron.New(cron.WithLocation(time.UTC))
+
+	_, err := c.AddFunc("30 2 * * *", func() {
+		now := time.Now().UTC()
+		n, err := computer.ComputePerformance(ctx, now.Add(-performanceLookback), now)
+		if err != nil {
+			log.Printf("[RISK][CRON] rule performance failed: %v", err)
+			return
+		}
+		log.Printf("[RISK][CRON] computed %d rule performance rows", n)
+	})
+
+	if err != nil {
+		log.Fatalf("failed to start rule performance cron: %v", err)
+	}
+
+	c.Start()
+}
+
+type ChallengeExpirer interface {
+	ExpireChallenges(ctx context.Context) (int, error)
+}
+
+func StartChallengeExpiryCron(
+	ctx context.Context,
+	expirer ChallengeExpirer,
+) {
+
+	c := cron.New(cron.WithLocation(time.UTC))
+
+	// Unanswered challenges block their transaction once they expire
+	_, err := c.AddFunc("* * * * *", func() {
+		n, err := expirer.ExpireChallenges(ctx)
+		if err != nil {
+			log.Printf("[RISK][CRON] challenge expiry failed: %v", err)
+		}
+		if n > 0 {
+			log.Printf("[RISK][CRON] blocked %d transactions with expired challenges", n)
+		}
+	})
+
+	if err != nil {
+		log.Fatalf("failed to start challenge expiry cron: %v", err)
+	}
+
+	c.Start()
+}
+
+type IdempotencyPurger interface {
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+func StartIdempotencyPurgeCron(
+	ctx context.Context,
+	purger IdempotencyPurger,
+) {
+
+	c := cron.New(cron.WithLocation(time.UTC))
+
+	// Expired idempotency keys no longer replay, so they can go
+	_, err := c.AddFunc("0 * * * *", func() {
+		n, err := purger.PurgeExpired(ctx)
+		if err != nil {
+			log.Printf("[RISK][CRON] idempotency key purge failed: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("[RISK][CRON] purged %d expired idempotency keys", n)
+		}
+	})
+
+	if err != nil {
+		log.Fatalf("failed to start idempotency purge cron: %v", err)
+	}
+
+	c.Start()
+}
+
+type ModelReloader interface {
+	Reload(ctx context.Context) error
+}
+
+func StartModelReloadCron(
+	ctx context.Context,
+	reloader ModelReloader,
+) {
+
+	c := cron.New(cron.WithLocation(time.UTC))
+
+	// A new model file is picked up once its version changes
+	_, err := c.AddFunc("* * * * *", func() {
+		if err := reloader.Reload(ctx); err != nil {
+			log.Printf("[RISK][CRON] model reload failed: %v", err)
+		}
+	})
+
+	if err != nil {
+		log.Fatalf("failed to start model reload cron: %v", err)
+	}
+
+	c.Start()
+}
+
+type PendingResumer interface {
+	ResumePending(ctx context.Context) (int, error)
+}
+
+func StartPendingResumeCron(
+	ctx context.Context,
+	resumer PendingResumer,
+) {
+
+	c := cron.New(cron.WithLocation(time.UTC))
+
+	// Asynchronous submissions that never reached a worker are queued again
+	_, err := c.AddFunc("* * * * *", func() {
+		n, err := resumer.ResumePending(ctx)
+		if err != nil {
+			log.Printf("[RISK][CRON] pending transaction resume failed: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("[RISK][CRON] queued %d pending transactions", n)
+		}
+	})
+
+	if err != nil {
+		log.Fatalf("failed to start pending transaction resume cron: %v", err)
+	}
+
+	c.Start()
+}
+
+type WebhookDispatcher interface {
+	DeliverDue(ctx context.Context) (int, error)
+}
+
+func StartWebhookDeliveryCron(
+	ctx context.Context,
+	dispatcher WebhookDispatcher,
+) {
+
+	c := cron.New(cron.WithLocation(time.UTC))
+
+	// Runs often enough for the shortest retry backoff; overlapping runs
+	// claim different deliveries
+	_, err := c.AddFunc("@every 10s", func() {
+		n, err := dispatcher.DeliverDue(ctx)
+		if err != nil {
+			log.Printf("[RISK][CRON] webhook delivery failed: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("[RISK][CRON] delivered %d webhooks", n)
+		}
+	})
+
+	if err != nil {
+		log.Fatalf("failed to start webhook delivery cron: %v", err)
+	}
+
+	c.Start()
+}
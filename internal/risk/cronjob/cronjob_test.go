@@ -18,19 +18,27 @@ type mockTransactionRiskRepository struct {
 	mock.Mock
 }
 
-func (m *mockTransactionRiskRepository) Create(risk *risk.TransactionRisk) error {
-	args := m.Called(risk)
+func (m *mockTransactionRiskRepository) Create(ctx context.Context, risk *risk.TransactionRisk) error {
+	args := m.Called(ctx, risk)
 	return args.Error(0)
 }
 
-func (m *mockTransactionRiskRepository) GetRiskByTransactionID(id uuid.UUID) (*risk.TransactionRisk, error) {
-	args := m.Called(id)
+func (m *mockTransactionRiskRepository) GetRiskByTransactionID(ctx context.Context, id uuid.UUID) (*risk.TransactionRisk, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*risk.TransactionRisk), args.Error(1)
 }
 
+func (m *mockTransactionRiskRepository) GetTypeBehavior(ctx context.Context, userID uuid.UUID, txType string) (*risk.UserBehavior, error) {
+	args := m.Called(ctx, userID, txType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*risk.UserBehavior), args.Error(1)
+}
+
 func (m *mockTransactionRiskRepository) GetDailyTransactionAggregate(ctx context.Context, from, to time.Time) ([]risk.DailyAggregate, error) {
 	args := m.Called(ctx, from, to)
 	if args.Get(0) == nil {
@@ -39,8 +47,8 @@ func (m *mockTransactionRiskRepository) GetDailyTransactionAggregate(ctx context
 	return args.Get(0).([]risk.DailyAggregate), args.Error(1)
 }
 
-func (m *mockTransactionRiskRepository) UpdateBehaviorParams(ctx context.Context, userID uuid.UUID, stdDev, p95 float64) error {
-	args := m.Called(ctx, userID, stdDev, p95)
+func (m *mockTransactionRiskRepository) UpdateBehaviorParams(ctx context.Context, userID uuid.UUID, txType string, stdDev, p95 float64) error {
+	args := m.Called(ctx, userID, txType, stdDev, p95)
 	return args.Error(0)
 }
 
@@ -78,6 +86,16 @@ func (m *mockTransactionRiskRepository) GetEnabledRules(ctx context.Context) ([]
 	return args.Get(0).([]risk.RiskRule), args.Error(1)
 }
 
+func (m *mockTransactionRiskRepository) DecayHourOfWeek(ctx context.Context, factor float64) (int64, error) {
+	args := m.Called(ctx, factor)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockTransactionRiskRepository) RecordVerifiedChallenge(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	args := m.Called(ctx, userID, at)
+	return args.Error(0)
+}
+
 // ============ Integration Tests ============
 // Tests that verify the daily behavior update job handles various user populations correctly.
 
@@ -150,7 +168,7 @@ func TestParameterUpdater_DailyBehaviorUpdate(t *testing.T) {
 			updateCallCount := 0
 			if tt.repositoryError == nil && aggregates != nil {
 				for _, agg := range aggregates {
-					repo.On("UpdateBehaviorParams", mock.Anything, agg.UserID, mock.AnythingOfType("float64"), agg.P95Amount).
+					repo.On("UpdateBehaviorParams", mock.Anything, agg.UserID, risk.BehaviorAllTypes, mock.AnythingOfType("float64"), agg.P95Amount).
 						Run(func(args mock.Arguments) {
 							updateCallCount++
 						}).
@@ -158,6 +176,8 @@ func TestParameterUpdater_DailyBehaviorUpdate(t *testing.T) {
 				}
 			}
 
+			repo.On("DecayHourOfWeek", mock.Anything, risk.HourOfWeekDecay).Return(int64(0), nil)
+
 			updater := NewParameterUpdater(repo, auditLog)
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
@@ -239,12 +259,14 @@ func TestParameterUpdater_VarianceCalculation(t *testing.T) {
 				Return(aggregates, nil)
 
 			capturedStdDev := 0.0
-			repo.On("UpdateBehaviorParams", mock.Anything, mock.Anything, mock.AnythingOfType("float64"), mock.Anything).
+			repo.On("UpdateBehaviorParams", mock.Anything, mock.Anything, mock.Anything, mock.AnythingOfType("float64"), mock.Anything).
 				Run(func(args mock.Arguments) {
-					capturedStdDev = args.Get(2).(float64)
+					capturedStdDev = args.Get(3).(float64)
 				}).
 				Return(nil)
 
+			repo.On("DecayHourOfWeek", mock.Anything, risk.HourOfWeekDecay).Return(int64(0), nil)
+
 			updater := NewParameterUpdater(repo, auditLog)
 			ctx := context.Background()
 			day := time.Now().UTC()
@@ -317,11 +339,13 @@ func TestParameterUpdater_ErrorHandling(t *testing.T) {
 					Return(aggregates, nil)
 
 				for _, agg := range aggregates {
-					repo.On("UpdateBehaviorParams", mock.Anything, agg.UserID, mock.AnythingOfType("float64"), agg.P95Amount).
+					repo.On("UpdateBehaviorParams", mock.Anything, agg.UserID, risk.BehaviorAllTypes, mock.AnythingOfType("float64"), agg.P95Amount).
 						Return(nil)
 				}
 			}
 
+			repo.On("DecayHourOfWeek", mock.Anything, risk.HourOfWeekDecay).Return(int64(0), nil)
+
 			updater := NewParameterUpdater(repo, auditLog)
 			ctx := context.Background()
 			day := time.Now().UTC()
@@ -389,12 +413,14 @@ func TestParameterUpdater_ContextHandling(t *testing.T) {
 			repo.On("GetDailyTransactionAggregate", mock.Anything, mock.Anything, mock.Anything).
 				Return(aggregates, nil)
 
-			repo.On("UpdateBehaviorParams", mock.Anything, mock.Anything, mock.AnythingOfType("float64"), mock.Anything).
+			repo.On("UpdateBehaviorParams", mock.Anything, mock.Anything, mock.Anything, mock.AnythingOfType("float64"), mock.Anything).
 				Return(nil)
 
 			ctx, cancel := context.WithTimeout(context.Background(), tt.contextTimeout)
 			defer cancel()
 
+			repo.On("DecayHourOfWeek", mock.Anything, risk.HourOfWeekDecay).Return(int64(0), nil)
+
 			updater := NewParameterUpdater(repo, auditLog)
 			day := time.Now().UTC()
 
@@ -410,3 +436,102 @@ func TestParameterUpdater_ContextHandling(t *testing.T) {
 		})
 	}
 }
+
+// ============ Receiver Profile Tests ============
+
+type mockCounterpartyRepository struct {
+	mock.Mock
+}
+
+func (m *mockCounterpartyRepository) CountPayments(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID, excludeTxID uuid.UUID) (int64, error) {
+	args := m.Called(ctx, senderID, receiverID, excludeTxID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockCounterpartyRepository) GetReceiverProfile(ctx context.Context, receiverID uuid.UUID) (*risk.ReceiverProfile, error) {
+	args := m.Called(ctx, receiverID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*risk.ReceiverProfile), args.Error(1)
+}
+
+func (m *mockCounterpartyRepository) AggregateReceiverProfiles(ctx context.Context, now time.Time) ([]risk.ReceiverProfile, error) {
+	args := m.Called(ctx, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]risk.ReceiverProfile), args.Error(1)
+}
+
+func (m *mockCounterpartyRepository) UpsertReceiverProfile(ctx context.Context, profile *risk.ReceiverProfile) error {
+	args := m.Called(ctx, profile)
+	return args.Error(0)
+}
+
+func (m *mockCounterpartyRepository) ResetStaleReceiverProfiles(ctx context.Context, before time.Time) error {
+	args := m.Called(ctx, before)
+	return args.Error(0)
+}
+
+func TestReceiverProfileUpdater_UpdateReceiverProfiles(t *testing.T) {
+	tests := []struct {
+		name           string
+		profiles       []risk.ReceiverProfile
+		aggregateErr   error
+		upsertErr      error
+		expectError    bool
+		expectedScores []int
+	}{
+		{
+			name: "stores profile risk score",
+			profiles: []risk.ReceiverProfile{
+				{ReceiverID: uuid.New(), DistinctSenders24h: 10, DistinctSenders7d: 10},
+				{ReceiverID: uuid.New(), BlockedOutbound7d: 1},
+				{ReceiverID: uuid.New(), DistinctSenders24h: 1, DistinctSenders7d: 2},
+			},
+			expectedScores: []int{40, 50, 0},
+		},
+		{
+			name:         "stops when aggregation fails",
+			aggregateErr: errors.New("database connection failed"),
+			expectError:  true,
+		},
+		{
+			name: "continues when one upsert fails",
+			profiles: []risk.ReceiverProfile{
+				{ReceiverID: uuid.New()},
+				{ReceiverID: uuid.New()},
+			},
+			upsertErr:      errors.New("write failed"),
+			expectedScores: []int{0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockCounterpartyRepository)
+			repo.On("AggregateReceiverProfiles", mock.Anything, mock.Anything).Return(tt.profiles, tt.aggregateErr)
+
+			var stored []int
+			repo.On("UpsertReceiverProfile", mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					stored = append(stored, args.Get(1).(*risk.ReceiverProfile).RiskScore)
+				}).
+				Return(tt.upsertErr)
+			repo.On("ResetStaleReceiverProfiles", mock.Anything, mock.Anything).Return(nil)
+
+			updater := NewReceiverProfileUpdater(repo, &audit.Logger{})
+			err := updater.UpdateReceiverProfiles(context.Background(), time.Now().UTC())
+
+			if tt.expectError {
+				assert.Error(t, err)
+				repo.AssertNotCalled(t, "ResetStaleReceiverProfiles", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedScores, stored)
+			repo.AssertCalled(t, "ResetStaleReceiverProfiles", mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package cronjob
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"risk-detection/internal/audit"
+	"risk-detection/internal/risk"
+)
+
+type ReceiverProfileUpdater struct {
+	repo     risk.CounterpartyRepository
+	auditLog *audit.Logger
+}
+
+func NewReceiverProfileUpdater(repo risk.CounterpartyRepository, auditLog *audit.Logger) *ReceiverProfileUpdater {
+	return &ReceiverProfileUpdater{
+		repo:     repo,
+		auditLog: auditLog,
+	}
+}
+
+// UpdateReceiverProfiles recomputes the profile of every receiver active in
+// the 7 days before now and zeroes the ones that dropped out of the window.
+func (p *ReceiverProfileUpdater) UpdateReceiverProfiles(ctx context.Context, now time.Time) error {
+	log.Println("receiver profile update trigger")
+
+	rows, err := p.repo.AggregateReceiverProfiles(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	started := time.Now()
+	failed := 0
+	for i := range rows {
+		profile := rows[i]
+		profile.RiskScore = profile.Score().Score
+		profile.UpdatedAt = time.Now()
+
+		if err := p.repo.UpsertReceiverProfile(ctx, &profile); err != nil {
+			log.Printf("[RISK][CRON] receiver profile failed for %s: %v", profile.ReceiverID, err)
+			failed++
+		}
+	}
+
+	if err := p.repo.ResetStaleReceiverProfiles(ctx, started); err != nil {
+		return err
+	}
+
+	if p.auditLog != nil {
+		status := "SUCCESS"
+		if failed > 0 {
+			status = "FAILURE"
+		}
+		p.auditLog.Log(audit.AuditLog{
+			EventType:  audit.EventReceiverProfilesUpdated,
+			Action:     "UPDATE",
+			EntityType: "receiver_profiles",
+			ActorType:  "SYSTEM",
+			NewValues: map[string]interface{}{
+				"profiles": len(rows),
+				"failed":   failed,
+			},
+			Status: status,
+		})
+	}
+
+	return nil
+}
@@ -48,9 +48,15 @@ func (p *ParameterUpdater) UpdateDailyBehavior(
 		variance := (r.AvgAmount * r.AvgAmount) * (1 - varianceDecay)
 		stdDev := math.Sqrt(variance)
 
+		txType := r.TransactionType
+		if txType == "" {
+			txType = risk.BehaviorAllTypes
+		}
+
 		err := p.repo.UpdateBehaviorParams(
 			ctx,
 			r.UserID,
+			txType,
 			stdDev,
 			r.P95Amount,
 		)
@@ -71,6 +77,7 @@ func (p *ParameterUpdater) UpdateDailyBehavior(
 			})
 		}
 		newValues := map[string]interface{}{
+			"transaction_type":     txType,
 			"amount_std_dev":       stdDev,
 			"high_value_treshould": r.P95Amount,
 		}
@@ -85,5 +92,13 @@ func (p *ParameterUpdater) UpdateDailyBehavior(
 		})
 	}
 
+	// Age every user's hour-of-week histogram so old habits fade out
+	decayed, err := p.repo.DecayHourOfWeek(ctx, risk.HourOfWeekDecay)
+	if err != nil {
+		log.Printf("[RISK][CRON] hour of week decay failed: %v", err)
+		return err
+	}
+	log.Printf("[RISK][CRON] decayed hour of week histogram of %d users", decayed)
+
 	return nil
 }
@@ -0,0 +1,65 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReasonEvaluationTimeout marks a result decided by the fallback because
+// scoring did not finish within the evaluation deadline.
+const ReasonEvaluationTimeout = "EVALUATION_TIMEOUT"
+
+// FallbackDecision is the decision of an evaluation that ran out of time.
+// The transaction is neither released nor blocked outright: it is FLAGGED
+// with the lowest MEDIUM score of its policy so it goes to step-up or
+// manual review like any other borderline payment.
+const FallbackDecision = "FLAG"
+
+// WithEvaluationTimeout bounds how long scoring may take. When the deadline
+// passes before the scorers finish, CalculateRisk returns the
+// FallbackDecision instead of an error. Zero or negative disables the
+// deadline, leaving only the caller's context.
+func WithEvaluationTimeout(d time.Duration) Option {
+	return func(s *service) {
+		s.evalTimeout = d
+	}
+}
+
+// evaluationContext derives the context scoring runs under.
+func (s *service) evaluationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.evalTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.evalTimeout)
+}
+
+// evaluationTimedOut reports whether scoring stopped because of the
+// evaluation deadline rather than the caller giving up.
+func evaluationTimedOut(parent context.Context, evalCtx context.Context, err error) bool {
+	if parent.Err() != nil {
+		return false
+	}
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(evalCtx.Err(), context.DeadlineExceeded)
+}
+
+// applyFallback fills result with the FallbackDecision.
+func (s *service) applyFallback(result *TransactionRisk, policy DecisionPolicy, txID uuid.UUID) {
+	score := policy.LowMax + 1
+	result.Reasons = []RiskReason{{
+		TransactionID: txID,
+		RuleName:      ReasonEvaluationTimeout,
+		ReasonCode:    ReasonEvaluationTimeout,
+		RawScore:      score,
+		Weight:        100,
+		WeightedScore: score,
+		Inputs: map[string]interface{}{
+			"timeout_ms": s.evalTimeout.Milliseconds(),
+		},
+	}}
+	result.RiskScore = score
+	result.RiskLevel = policy.RiskLevel(score)
+	result.Decision = FallbackDecision
+}
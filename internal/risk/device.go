@@ -0,0 +1,105 @@
+package risk
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"risk-detection/internal/db"
+)
+
+// WithDeviceRepository grades NEW_DEVICE_RISK by the user's device history
+// and records every transaction's device in it. Without it the device is
+// compared with the single device of the last login.
+func WithDeviceRepository(repo DeviceRepository) Option {
+	return func(s *service) {
+		s.deviceRepo = repo
+		s.RegisterScorer(detailedScorer{
+			name: RuleNewDevice,
+			fn: func(ctx context.Context, tx TransactionDTO) (ScoreResult, error) {
+				return deviceHistoryRisk(ctx, repo, tx)
+			},
+		})
+	}
+}
+
+// deviceHistoryRisk scores a device by its trust state, age and how often the
+// user has used it. Blocked devices score highest, then devices never seen
+// for a user who has others, then young or rarely used ones.
+func deviceHistoryRisk(ctx context.Context, repo DeviceRepository, tx TransactionDTO) (ScoreResult, error) {
+	if tx.DeviceID == "" {
+		return ScoreResult{}, nil
+	}
+
+	devices, err := repo.ListDevices(ctx, tx.UserID)
+	if err != nil {
+		// A canceled or timed out evaluation is not a missing history
+		if ctx.Err() != nil {
+			return ScoreResult{}, ctx.Err()
+		}
+		log.Printf("unable to get device history: %v", err)
+		return ScoreResult{Score: 20, Reasons: []Reason{{Code: "DEVICE_UNKNOWN_HISTORY", Score: 20, Inputs: map[string]interface{}{"device_id": tx.DeviceID}}}}, nil
+	}
+
+	if len(devices) == 0 {
+		return ScoreResult{Score: 20, Reasons: []Reason{{Code: "DEVICE_UNKNOWN_HISTORY", Score: 20, Inputs: map[string]interface{}{"device_id": tx.DeviceID}}}}, nil
+	}
+
+	var device *UserDevice
+	for i := range devices {
+		if devices[i].DeviceID == tx.DeviceID {
+			device = &devices[i]
+			break
+		}
+	}
+
+	if device == nil {
+		return ScoreResult{Score: 70, Reasons: []Reason{{Code: "DEVICE_NEW", Score: 70, Inputs: map[string]interface{}{
+			"device_id":     tx.DeviceID,
+			"known_devices": len(devices),
+		}}}}, nil
+	}
+
+	switch device.TrustState {
+	case DeviceBlocked:
+		return ScoreResult{Score: 100, Reasons: []Reason{{Code: "DEVICE_BLOCKED", Score: 100, Inputs: map[string]interface{}{"device_id": tx.DeviceID}}}}, nil
+	case DeviceTrusted:
+		return ScoreResult{}, nil
+	}
+
+	var result ScoreResult
+
+	age := tx.TxTime.Sub(device.FirstSeenAt)
+	ageInputs := map[string]interface{}{"device_id": tx.DeviceID, "age_hours": age.Hours()}
+	if age < 24*time.Hour {
+		result.Score += 40
+		result.Reasons = append(result.Reasons, Reason{Code: "DEVICE_AGE_LT_1D", Score: 40, Inputs: ageInputs})
+	} else if age < 7*24*time.Hour {
+		result.Score += 20
+		result.Reasons = append(result.Reasons, Reason{Code: "DEVICE_AGE_LT_7D", Score: 20, Inputs: ageInputs})
+	}
+
+	if device.UseCount < 3 {
+		result.Score += 10
+		result.Reasons = append(result.Reasons, Reason{Code: "DEVICE_FEW_USES", Score: 10, Inputs: map[string]interface{}{
+			"device_id": tx.DeviceID,
+			"use_count": device.UseCount,
+		}})
+	}
+
+	return result, nil
+}
+
+// recordDevice adds the transaction's device to the user's history once it
+// has been scored, so it never counts towards its own familiarity.
+func (s *service) recordDevice(ctx context.Context, tx TransactionDTO) {
+	if s.deviceRepo == nil || tx.DeviceID == "" {
+		return
+	}
+	err := db.Savepoint(ctx, func(ctx context.Context) error {
+		return s.deviceRepo.RecordDeviceUse(ctx, tx.UserID, tx.DeviceID, tx.IPAddress, tx.TxTime)
+	})
+	if err != nil {
+		log.Printf("unable to record device use: %v", err)
+	}
+}
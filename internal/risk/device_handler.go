@@ -0,0 +1,61 @@
+package risk
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type DeviceHandler struct {
+	service DeviceService
+}
+
+func NewDeviceHandler(service DeviceService) *DeviceHandler {
+	return &DeviceHandler{service: service}
+}
+
+// ListDevices handles GET /api/v1/admin/users/:id/devices.
+func (h *DeviceHandler) ListDevices(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	devices, err := h.service.ListDevices(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": devices})
+}
+
+// SetTrustState handles PUT /api/v1/admin/users/:id/devices/:device_id/trust.
+func (h *DeviceHandler) SetTrustState(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req DeviceTrustRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	device, err := h.service.SetTrustState(c.Request.Context(), c.GetString("user_id"), userID, c.Param("device_id"), req.TrustState)
+	if err != nil {
+		if errors.Is(err, ErrDeviceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "device not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, device)
+}
@@ -0,0 +1,82 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"risk-detection/internal/db"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrDeviceNotFound = errors.New("device not found")
+
+type deviceRepository struct {
+	db *gorm.DB
+}
+
+func NewDeviceRepository(db *gorm.DB) DeviceRepository {
+	return &deviceRepository{db: db}
+}
+
+func (r *deviceRepository) ListDevices(ctx context.Context, userID uuid.UUID) ([]UserDevice, error) {
+	var devices []UserDevice
+	err := db.Conn(ctx, r.db).
+		Where("user_id = ?", userID).
+		Order("last_seen_at DESC").
+		Find(&devices).Error
+	return devices, err
+}
+
+// RecordDeviceUse adds the device to the user's history or bumps its
+// last-seen time and usage count.
+func (r *deviceRepository) RecordDeviceUse(
+	ctx context.Context,
+	userID uuid.UUID,
+	deviceID string,
+	ipAddress string,
+	seenAt time.Time,
+) error {
+
+	return db.Conn(ctx, r.db).
+		Exec(`
+			INSERT INTO user_devices
+				(user_id, device_id, last_ip_address, first_seen_at, last_seen_at, use_count)
+			VALUES (?, ?, ?, ?, ?, 1)
+			ON CONFLICT (user_id, device_id) DO UPDATE SET
+				last_ip_address = EXCLUDED.last_ip_address,
+				first_seen_at   = LEAST(user_devices.first_seen_at, EXCLUDED.first_seen_at),
+				last_seen_at    = GREATEST(user_devices.last_seen_at, EXCLUDED.last_seen_at),
+				use_count       = user_devices.use_count + 1
+		`, userID, deviceID, ipAddress, seenAt, seenAt).Error
+}
+
+func (r *deviceRepository) SetTrustState(
+	ctx context.Context,
+	userID uuid.UUID,
+	deviceID string,
+	state string,
+) (*UserDevice, error) {
+
+	var device UserDevice
+
+	err := db.Conn(ctx, r.db).
+		Where("user_id = ? AND device_id = ?", userID, deviceID).
+		First(&device).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDeviceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Conn(ctx, r.db).
+		Model(&device).
+		Update("trust_state", state).Error; err != nil {
+		return nil, err
+	}
+	device.TrustState = state
+	return &device, nil
+}
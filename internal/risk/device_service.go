@@ -0,0 +1,57 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"risk-detection/internal/audit"
+
+	"github.com/google/uuid"
+)
+
+type deviceService struct {
+	repo     DeviceRepository
+	auditLog *audit.Logger
+}
+
+// NewDeviceService lets admins inspect a user's devices and change how much
+// they are trusted by the device scorer.
+func NewDeviceService(repo DeviceRepository, auditLog *audit.Logger) DeviceService {
+	return &deviceService{
+		repo:     repo,
+		auditLog: auditLog,
+	}
+}
+
+func (s *deviceService) ListDevices(ctx context.Context, userID uuid.UUID) ([]UserDevice, error) {
+	return s.repo.ListDevices(ctx, userID)
+}
+
+func (s *deviceService) SetTrustState(ctx context.Context, actorID string, userID uuid.UUID, deviceID string, state string) (*UserDevice, error) {
+	state = strings.ToUpper(strings.TrimSpace(state))
+
+	device, err := s.repo.SetTrustState(ctx, userID, deviceID, state)
+	if err != nil {
+		return nil, fmt.Errorf("set trust state: %w", err)
+	}
+
+	if s.auditLog != nil {
+		s.auditLog.Log(audit.AuditLog{
+			EventType:  audit.EventDeviceTrustUpdated,
+			Action:     "UPDATE",
+			EntityType: "user_devices",
+			EntityID:   userID.String(),
+			ActorType:  "USER",
+			ActorID:    actorID,
+			ActorRole:  "ADMIN",
+			DeviceID:   deviceID,
+			NewValues: map[string]interface{}{
+				"trust_state": state,
+			},
+			Status: "SUCCESS",
+		})
+	}
+
+	return device, nil
+}
@@ -0,0 +1,215 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"risk-detection/internal/audit"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDeviceRepository struct {
+	mock.Mock
+}
+
+func (m *MockDeviceRepository) ListDevices(ctx context.Context, userID uuid.UUID) ([]UserDevice, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]UserDevice), args.Error(1)
+}
+
+func (m *MockDeviceRepository) RecordDeviceUse(ctx context.Context, userID uuid.UUID, deviceID string, ipAddress string, seenAt time.Time) error {
+	args := m.Called(ctx, userID, deviceID, ipAddress, seenAt)
+	return args.Error(0)
+}
+
+func (m *MockDeviceRepository) SetTrustState(ctx context.Context, userID uuid.UUID, deviceID string, state string) (*UserDevice, error) {
+	args := m.Called(ctx, userID, deviceID, state)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*UserDevice), args.Error(1)
+}
+
+func TestDeviceHistoryRisk(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	phone := UserDevice{DeviceID: "phone", FirstSeenAt: now.AddDate(0, -3, 0), UseCount: 40, TrustState: DeviceUnverified}
+	tablet := UserDevice{DeviceID: "tablet", FirstSeenAt: now.AddDate(0, -1, 0), UseCount: 12, TrustState: DeviceUnverified}
+
+	tests := []struct {
+		name          string
+		deviceID      string
+		devices       []UserDevice
+		listErr       error
+		expectedScore int
+		expectedCodes []string
+		description   string
+	}{
+		{
+			name:          "no_device_id",
+			deviceID:      "",
+			expectedScore: 0,
+			description:   "Transactions without a device should not score",
+		},
+		{
+			name:          "second_familiar_device",
+			deviceID:      "tablet",
+			devices:       []UserDevice{phone, tablet},
+			expectedScore: 0,
+			description:   "A long-used second device should not be treated as a mismatch",
+		},
+		{
+			name:          "no_history",
+			deviceID:      "phone",
+			devices:       []UserDevice{},
+			expectedScore: 20,
+			expectedCodes: []string{"DEVICE_UNKNOWN_HISTORY"},
+			description:   "A user without device history gets a moderate score",
+		},
+		{
+			name:          "history_unavailable",
+			deviceID:      "phone",
+			listErr:       errors.New("db down"),
+			expectedScore: 20,
+			expectedCodes: []string{"DEVICE_UNKNOWN_HISTORY"},
+			description:   "Repository errors should fall back to a moderate score",
+		},
+		{
+			name:          "new_device",
+			deviceID:      "laptop",
+			devices:       []UserDevice{phone, tablet},
+			expectedScore: 70,
+			expectedCodes: []string{"DEVICE_NEW"},
+			description:   "A device never seen for a user with history should score high",
+		},
+		{
+			name:          "young_rarely_used_device",
+			deviceID:      "laptop",
+			devices:       []UserDevice{phone, {DeviceID: "laptop", FirstSeenAt: now.Add(-2 * time.Hour), UseCount: 1, TrustState: DeviceUnverified}},
+			expectedScore: 50,
+			expectedCodes: []string{"DEVICE_AGE_LT_1D", "DEVICE_FEW_USES"},
+			description:   "A device first seen hours ago and used once should add age and familiarity",
+		},
+		{
+			name:          "week_old_device",
+			deviceID:      "laptop",
+			devices:       []UserDevice{{DeviceID: "laptop", FirstSeenAt: now.AddDate(0, 0, -3), UseCount: 5, TrustState: DeviceUnverified}},
+			expectedScore: 20,
+			expectedCodes: []string{"DEVICE_AGE_LT_7D"},
+			description:   "A device younger than a week should score lower than a day-old one",
+		},
+		{
+			name:          "trusted_young_device",
+			deviceID:      "laptop",
+			devices:       []UserDevice{{DeviceID: "laptop", FirstSeenAt: now.Add(-time.Hour), UseCount: 1, TrustState: DeviceTrusted}},
+			expectedScore: 0,
+			description:   "Trusted devices should not score regardless of age",
+		},
+		{
+			name:          "blocked_device",
+			deviceID:      "phone",
+			devices:       []UserDevice{{DeviceID: "phone", FirstSeenAt: now.AddDate(-1, 0, 0), UseCount: 99, TrustState: DeviceBlocked}},
+			expectedScore: 100,
+			expectedCodes: []string{"DEVICE_BLOCKED"},
+			description:   "Blocked devices should score the maximum",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockDeviceRepository)
+			if tt.listErr != nil {
+				repo.On("ListDevices", mock.Anything, mock.Anything).Return(nil, tt.listErr)
+			} else {
+				repo.On("ListDevices", mock.Anything, mock.Anything).Return(tt.devices, nil)
+			}
+
+			res, err := deviceHistoryRisk(context.Background(), repo, TransactionDTO{UserID: uuid.New(), DeviceID: tt.deviceID, TxTime: now})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedScore, res.Score, tt.description)
+
+			var codes []string
+			for _, r := range res.Reasons {
+				codes = append(codes, r.Code)
+			}
+			assert.Equal(t, tt.expectedCodes, codes, tt.description)
+		})
+	}
+}
+
+func TestCalculateRisk_SlowDeviceHistoryTimesOut(t *testing.T) {
+	mockRepo := new(MockTransactionRiskRepository)
+	mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{{Name: RuleNewDevice, Enabled: true, Weight: 100}}, nil)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("GetBehaviorByUserID", mock.Anything, mock.Anything).Return(&UserBehavior{UserID: uuid.New()}, nil)
+	mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
+
+	// The history query only returns once the evaluation deadline has passed
+	deviceRepo := new(MockDeviceRepository)
+	deviceRepo.On("ListDevices", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	}).Return(nil, context.DeadlineExceeded)
+	deviceRepo.On("RecordDeviceUse", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	svc, err := NewService(mockRepo, nil, &audit.Logger{}, WithDeviceRepository(deviceRepo), WithEvaluationTimeout(20*time.Millisecond))
+	assert.NoError(t, err)
+
+	result, err := svc.CalculateRisk(context.Background(), &TransactionDTO{TxID: uuid.New(), UserID: uuid.New(), DeviceID: "phone", TxTime: time.Now()})
+	assert.NoError(t, err)
+	assert.Equal(t, FallbackDecision, result.Decision, "A timed out lookup must not pass for a missing device history")
+	if assert.Len(t, result.Reasons, 1) {
+		assert.Equal(t, ReasonEvaluationTimeout, result.Reasons[0].ReasonCode)
+	}
+}
+
+func TestCalculateRisk_RecordsDeviceUse(t *testing.T) {
+	mockRepo := new(MockTransactionRiskRepository)
+	mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{{Name: RuleNewDevice, Enabled: true, Weight: 100}}, nil)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("GetBehaviorByUserID", mock.Anything, mock.Anything).Return(&UserBehavior{UserID: uuid.New()}, nil)
+	mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
+
+	now := time.Now()
+	userID := uuid.New()
+	deviceRepo := new(MockDeviceRepository)
+	deviceRepo.On("ListDevices", mock.Anything, userID).Return([]UserDevice{
+		{DeviceID: "phone", FirstSeenAt: now.AddDate(0, -2, 0), UseCount: 30, TrustState: DeviceUnverified},
+		{DeviceID: "tablet", FirstSeenAt: now.AddDate(0, -1, 0), UseCount: 8, TrustState: DeviceUnverified},
+	}, nil)
+	deviceRepo.On("RecordDeviceUse", mock.Anything, userID, "tablet", "203.0.113.9", now).Return(nil)
+
+	svc, err := NewService(mockRepo, nil, &audit.Logger{}, WithDeviceRepository(deviceRepo))
+	assert.NoError(t, err)
+
+	result, err := svc.CalculateRisk(context.Background(), &TransactionDTO{TxID: uuid.New(), UserID: userID, DeviceID: "tablet", IPAddress: "203.0.113.9", TxTime: now})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.RiskScore, "Switching between two known devices should not score")
+	mockRepo.AssertNotCalled(t, "GetDeviceInfo", mock.Anything, mock.Anything)
+	deviceRepo.AssertExpectations(t)
+}
+
+func TestDeviceService_SetTrustState(t *testing.T) {
+	userID := uuid.New()
+
+	repo := new(MockDeviceRepository)
+	repo.On("SetTrustState", mock.Anything, userID, "phone", DeviceTrusted).
+		Return(&UserDevice{UserID: userID, DeviceID: "phone", TrustState: DeviceTrusted}, nil)
+	repo.On("SetTrustState", mock.Anything, userID, "missing", DeviceBlocked).
+		Return(nil, ErrDeviceNotFound)
+
+	svc := NewDeviceService(repo, &audit.Logger{})
+
+	device, err := svc.SetTrustState(context.Background(), "admin-1", userID, "phone", "trusted")
+	assert.NoError(t, err)
+	assert.Equal(t, DeviceTrusted, device.TrustState)
+
+	_, err = svc.SetTrustState(context.Background(), "admin-1", userID, "missing", DeviceBlocked)
+	assert.ErrorIs(t, err, ErrDeviceNotFound)
+}
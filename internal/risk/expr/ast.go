@@ -0,0 +1,198 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrDivisionByZero = errors.New("division by zero")
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) check(schema Schema) (Kind, error) {
+	return kindOf(n.value)
+}
+
+func (n *literalNode) eval(env Env) (interface{}, error) {
+	return n.value, nil
+}
+
+type identNode struct {
+	name string
+}
+
+func (n *identNode) check(schema Schema) (Kind, error) {
+	k, ok := schema[n.name]
+	if !ok {
+		return 0, fmt.Errorf("unknown variable %q", n.name)
+	}
+	return k, nil
+}
+
+func (n *identNode) eval(env Env) (interface{}, error) {
+	v, ok := env[n.name]
+	if !ok {
+		return nil, fmt.Errorf("variable %q not set", n.name)
+	}
+	return v, nil
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) check(schema Schema) (Kind, error) {
+	k, err := n.operand.check(schema)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case "!":
+		if k != KindBool {
+			return 0, fmt.Errorf("operator ! needs bool, got %s", k)
+		}
+		return KindBool, nil
+	default:
+		if k != KindNumber {
+			return 0, fmt.Errorf("operator - needs number, got %s", k)
+		}
+		return KindNumber, nil
+	}
+}
+
+func (n *unaryNode) eval(env Env) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "!":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator ! needs bool")
+		}
+		return !b, nil
+	default:
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("operator - needs number")
+		}
+		return -f, nil
+	}
+}
+
+type binaryNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n *binaryNode) check(schema Schema) (Kind, error) {
+	lk, err := n.left.check(schema)
+	if err != nil {
+		return 0, err
+	}
+	rk, err := n.right.check(schema)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case "&&", "||":
+		if lk != KindBool || rk != KindBool {
+			return 0, fmt.Errorf("operator %s needs bool operands, got %s and %s", n.op, lk, rk)
+		}
+		return KindBool, nil
+	case "==", "!=":
+		if lk != rk {
+			return 0, fmt.Errorf("operator %s compares %s with %s", n.op, lk, rk)
+		}
+		return KindBool, nil
+	case "<", "<=", ">", ">=":
+		if lk != KindNumber || rk != KindNumber {
+			return 0, fmt.Errorf("operator %s needs number operands, got %s and %s", n.op, lk, rk)
+		}
+		return KindBool, nil
+	default:
+		if lk != KindNumber || rk != KindNumber {
+			return 0, fmt.Errorf("operator %s needs number operands, got %s and %s", n.op, lk, rk)
+		}
+		return KindNumber, nil
+	}
+}
+
+func (n *binaryNode) eval(env Env) (interface{}, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit logical operators
+	if n.op == "&&" || n.op == "||" {
+		lb, ok := l.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s needs bool operands", n.op)
+		}
+		if n.op == "&&" && !lb {
+			return false, nil
+		}
+		if n.op == "||" && lb {
+			return true, nil
+		}
+		r, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		rb, ok := r.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s needs bool operands", n.op)
+		}
+		return rb, nil
+	}
+
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return l == r, nil
+	case "!=":
+		return l != r, nil
+	}
+
+	lf, lok := l.(float64)
+	rf, rok := r.(float64)
+	if !lok || !rok {
+		return nil, fmt.Errorf("operator %s needs number operands", n.op)
+	}
+
+	switch n.op {
+	case "<":
+		return lf < rf, nil
+	case "<=":
+		return lf <= rf, nil
+	case ">":
+		return lf > rf, nil
+	case ">=":
+		return lf >= rf, nil
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return lf / rf, nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", n.op)
+}
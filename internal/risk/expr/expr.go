@@ -0,0 +1,125 @@
+// Package expr implements the small, side-effect free expression language
+// used by rules stored in the rules table, for example:
+//
+//	tx.amount > 5 * user.avg_amount && tx.type == "TRANSFER"
+//
+// Expressions support number, string and bool values, arithmetic (+ - * /),
+// comparisons, && || ! and parentheses. Variables are checked against a
+// Schema at compile time, so an invalid rule is rejected before it is used.
+package expr
+
+import (
+	"fmt"
+)
+
+type Kind int
+
+const (
+	KindNumber Kind = iota + 1
+	KindString
+	KindBool
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNumber:
+		return "number"
+	case KindString:
+		return "string"
+	case KindBool:
+		return "bool"
+	}
+	return "unknown"
+}
+
+// Schema declares the variables an expression may reference and their kinds.
+type Schema map[string]Kind
+
+// Env holds variable values for a single evaluation. Numbers are float64.
+type Env map[string]interface{}
+
+// Program is a compiled, type-checked boolean expression.
+type Program struct {
+	source    string
+	root      node
+	variables []string
+}
+
+// Compile parses src and verifies it is a boolean expression over schema.
+func Compile(src string, schema Schema) (*Program, error) {
+	root, err := parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("parse expression: %w", err)
+	}
+
+	k, err := root.check(schema)
+	if err != nil {
+		return nil, fmt.Errorf("check expression: %w", err)
+	}
+	if k != KindBool {
+		return nil, fmt.Errorf("check expression: result must be bool, got %s", k)
+	}
+
+	return &Program{source: src, root: root, variables: collectVariables(root)}, nil
+}
+
+// Source returns the original expression text.
+func (p *Program) Source() string {
+	return p.source
+}
+
+// Variables returns the distinct variables referenced by the expression,
+// in order of first appearance.
+func (p *Program) Variables() []string {
+	return p.variables
+}
+
+// Eval evaluates the program against env.
+func (p *Program) Eval(env Env) (bool, error) {
+	v, err := p.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression did not produce a bool")
+	}
+	return b, nil
+}
+
+func kindOf(v interface{}) (Kind, error) {
+	switch v.(type) {
+	case float64:
+		return KindNumber, nil
+	case string:
+		return KindString, nil
+	case bool:
+		return KindBool, nil
+	}
+	return 0, fmt.Errorf("unsupported value %T", v)
+}
+
+func collectVariables(root node) []string {
+	var names []string
+	seen := make(map[string]bool)
+
+	var walk func(n node)
+	walk = func(n node) {
+		switch v := n.(type) {
+		case *identNode:
+			if !seen[v.name] {
+				seen[v.name] = true
+				names = append(names, v.name)
+			}
+		case *unaryNode:
+			walk(v.operand)
+		case *binaryNode:
+			walk(v.left)
+			walk(v.right)
+		}
+	}
+	walk(root)
+
+	return names
+}
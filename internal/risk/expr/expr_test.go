@@ -0,0 +1,157 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testSchema = Schema{
+	"tx.amount":       KindNumber,
+	"tx.type":         KindString,
+	"user.avg_amount": KindNumber,
+	"user.is_new":     KindBool,
+}
+
+func TestCompile_Validation(t *testing.T) {
+	tests := []struct {
+		name        string
+		src         string
+		expectError bool
+		description string
+	}{
+		{
+			name:        "valid_compound_rule",
+			src:         `tx.amount > 5 * user.avg_amount && tx.type == "TRANSFER"`,
+			expectError: false,
+			description: "Arithmetic, comparison and logical operators should compile",
+		},
+		{
+			name:        "valid_single_quotes",
+			src:         `tx.type != 'DEPOSIT' || !user.is_new`,
+			expectError: false,
+			description: "Single quoted strings and negation should compile",
+		},
+		{
+			name:        "unknown_variable",
+			src:         `tx.ammount > 10`,
+			expectError: true,
+			description: "Misspelled variables should be rejected",
+		},
+		{
+			name:        "non_bool_result",
+			src:         `tx.amount * 2`,
+			expectError: true,
+			description: "Expression must produce a bool",
+		},
+		{
+			name:        "type_mismatch",
+			src:         `tx.type > 10`,
+			expectError: true,
+			description: "Ordering strings against numbers should be rejected",
+		},
+		{
+			name:        "unbalanced_parens",
+			src:         `(tx.amount > 10`,
+			expectError: true,
+			description: "Missing closing paren should be rejected",
+		},
+		{
+			name:        "unterminated_string",
+			src:         `tx.type == "TRANSFER`,
+			expectError: true,
+			description: "Unterminated string should be rejected",
+		},
+		{
+			name:        "unexpected_character",
+			src:         `tx.amount > 10; drop`,
+			expectError: true,
+			description: "Characters outside the grammar should be rejected",
+		},
+		{
+			name:        "empty_expression",
+			src:         ``,
+			expectError: true,
+			description: "Empty expression should be rejected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.src, testSchema)
+			if tt.expectError {
+				assert.Error(t, err, tt.description)
+			} else {
+				assert.NoError(t, err, tt.description)
+			}
+		})
+	}
+}
+
+func TestProgram_Eval(t *testing.T) {
+	tests := []struct {
+		name        string
+		src         string
+		env         Env
+		expected    bool
+		expectError bool
+	}{
+		{
+			name:     "large_transfer_matches",
+			src:      `tx.amount > 5 * user.avg_amount && tx.type == "TRANSFER"`,
+			env:      Env{"tx.amount": 600.0, "tx.type": "TRANSFER", "user.avg_amount": 100.0, "user.is_new": false},
+			expected: true,
+		},
+		{
+			name:     "large_deposit_does_not_match",
+			src:      `tx.amount > 5 * user.avg_amount && tx.type == "TRANSFER"`,
+			env:      Env{"tx.amount": 600.0, "tx.type": "DEPOSIT", "user.avg_amount": 100.0, "user.is_new": false},
+			expected: false,
+		},
+		{
+			name:     "precedence_and_parens",
+			src:      `(tx.amount - 10) / 2 >= 45`,
+			env:      Env{"tx.amount": 100.0},
+			expected: true,
+		},
+		{
+			name:     "short_circuit_skips_division",
+			src:      `user.avg_amount > 0 && tx.amount / user.avg_amount > 3`,
+			env:      Env{"tx.amount": 100.0, "user.avg_amount": 0.0},
+			expected: false,
+		},
+		{
+			name:        "division_by_zero",
+			src:         `tx.amount / user.avg_amount > 3`,
+			env:         Env{"tx.amount": 100.0, "user.avg_amount": 0.0},
+			expectError: true,
+		},
+		{
+			name:        "missing_variable",
+			src:         `tx.amount > 3`,
+			env:         Env{},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := Compile(tt.src, testSchema)
+			assert.NoError(t, err)
+
+			got, err := prog.Eval(tt.env)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestProgram_Variables(t *testing.T) {
+	prog, err := Compile(`tx.amount > 5 * user.avg_amount && tx.amount < 1000000`, testSchema)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tx.amount", "user.avg_amount"}, prog.Variables())
+}
@@ -0,0 +1,117 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokTrue
+	tokFalse
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+// twoCharOps must be checked before single character operators.
+var twoCharOps = []string{"&&", "||", "==", "!=", "<=", ">="}
+
+const singleCharOps = "<>+-*/!"
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+
+	for i < len(src) {
+		c := rune(src[i])
+
+		if unicode.IsSpace(c) {
+			i++
+			continue
+		}
+
+		switch {
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+			continue
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+			continue
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(src[i+1:], byte(c))
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokString, text: src[i+1 : i+1+end], pos: i})
+			i += end + 2
+			continue
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1]))):
+			start := i
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || src[i] == '.') {
+				i++
+			}
+			n, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", src[start:i], start)
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], num: n, pos: start})
+			continue
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(src) && (unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i])) || src[i] == '_' || src[i] == '.') {
+				i++
+			}
+			word := src[start:i]
+			switch word {
+			case "true":
+				tokens = append(tokens, token{kind: tokTrue, text: word, pos: start})
+			case "false":
+				tokens = append(tokens, token{kind: tokFalse, text: word, pos: start})
+			default:
+				tokens = append(tokens, token{kind: tokIdent, text: word, pos: start})
+			}
+			continue
+		}
+
+		matched := false
+		for _, op := range twoCharOps {
+			if strings.HasPrefix(src[i:], op) {
+				tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+				i += 2
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		if strings.ContainsRune(singleCharOps, c) {
+			tokens = append(tokens, token{kind: tokOp, text: string(c), pos: i})
+			i++
+			continue
+		}
+
+		return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, pos: len(src)})
+	return tokens, nil
+}
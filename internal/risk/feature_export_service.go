@@ -0,0 +1,238 @@
+package risk
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"risk-detection/internal/audit"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidFeatureExport = errors.New("invalid feature export")
+
+// featureExportColumns precede the feature columns of a CSV export.
+var featureExportColumns = []string{
+	"transaction_id",
+	"user_id",
+	"transaction_type",
+	"transaction_time",
+	"evaluated_at",
+	"transaction_status",
+	"risk_score",
+	"decision",
+	"model_version",
+	"label",
+}
+
+type featureExportService struct {
+	repo         FeatureSnapshotRepository
+	auditLog     *audit.Logger
+	pseudonymKey []byte
+}
+
+// NewFeatureExportService exports snapshots with their outcomes. User IDs
+// are pseudonymized with pseudonymKey, so the same user maps to the same
+// pseudonym across exports; without a key every export draws its own.
+func NewFeatureExportService(repo FeatureSnapshotRepository, auditLog *audit.Logger, pseudonymKey []byte) FeatureExportService {
+	return &featureExportService{repo: repo, auditLog: auditLog, pseudonymKey: pseudonymKey}
+}
+
+// Export writes the snapshots selected by req to w. Invalid requests fail
+// before anything is written.
+func (s *featureExportService) Export(ctx context.Context, actorID string, req FeatureExportRequest, w io.Writer) error {
+	if err := validateFeatureExport(req); err != nil {
+		return err
+	}
+
+	pseudonym, err := s.pseudonymizer(req.Pseudonymize)
+	if err != nil {
+		return err
+	}
+
+	var count int
+	switch req.Format {
+	case ExportNDJSON:
+		count, err = s.exportNDJSON(ctx, req, pseudonym, w)
+	default:
+		count, err = s.exportCSV(ctx, req, pseudonym, w)
+	}
+	if err != nil {
+		return err
+	}
+
+	if s.auditLog != nil {
+		s.auditLog.Log(audit.AuditLog{
+			EventType:  audit.EventFeatureSnapshotsExported,
+			Action:     "EXPORT",
+			EntityType: "risk_feature_snapshots",
+			ActorType:  "USER",
+			ActorID:    actorID,
+			ActorRole:  "ADMIN",
+			NewValues: map[string]interface{}{
+				"from":         req.From,
+				"to":           req.To,
+				"format":       req.Format,
+				"pseudonymize": req.Pseudonymize,
+				"rows":         count,
+			},
+			Status: "SUCCESS",
+		})
+	}
+	return nil
+}
+
+func validateFeatureExport(req FeatureExportRequest) error {
+	if req.Format != ExportCSV && req.Format != ExportNDJSON {
+		return fmt.Errorf("%w: format must be %s or %s", ErrInvalidFeatureExport, ExportCSV, ExportNDJSON)
+	}
+	if req.From.IsZero() || req.To.IsZero() {
+		return fmt.Errorf("%w: from and to are required", ErrInvalidFeatureExport)
+	}
+	if !req.From.Before(req.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidFeatureExport)
+	}
+	return nil
+}
+
+// pseudonymizer maps user IDs to keyed hashes, or leaves them as is.
+func (s *featureExportService) pseudonymizer(enabled bool) (func(uuid.UUID) string, error) {
+	if !enabled {
+		return func(id uuid.UUID) string { return id.String() }, nil
+	}
+
+	key := s.pseudonymKey
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(id uuid.UUID) string {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(id[:])
+		return hex.EncodeToString(mac.Sum(nil)[:16])
+	}, nil
+}
+
+type featureExportRecord struct {
+	TransactionID     uuid.UUID          `json:"transaction_id"`
+	UserID            string             `json:"user_id"`
+	TransactionType   string             `json:"transaction_type"`
+	TransactionTime   time.Time          `json:"transaction_time"`
+	EvaluatedAt       time.Time          `json:"evaluated_at"`
+	TransactionStatus string             `json:"transaction_status"`
+	RiskScore         int                `json:"risk_score"`
+	Decision          string             `json:"decision"`
+	ModelVersion      *string            `json:"model_version"`
+	Label             *string            `json:"label"`
+	Features          map[string]float64 `json:"features"`
+}
+
+func newFeatureExportRecord(row FeatureSnapshotRow, labels map[uuid.UUID]string, pseudonym func(uuid.UUID) string) featureExportRecord {
+	rec := featureExportRecord{
+		TransactionID:     row.TransactionID,
+		UserID:            pseudonym(row.UserID),
+		TransactionType:   row.TransactionType,
+		TransactionTime:   row.TransactionTime,
+		EvaluatedAt:       row.EvaluatedAt,
+		TransactionStatus: row.TransactionStatus,
+		RiskScore:         row.RiskScore,
+		Decision:          row.Decision,
+		ModelVersion:      row.ModelVersion,
+		Features:          row.Features,
+		Label:             row.Label,
+	}
+	if label, ok := labels[row.TransactionID]; ok {
+		rec.Label = &label
+	}
+	return rec
+}
+
+// exportNDJSON streams one JSON object per line.
+func (s *featureExportService) exportNDJSON(ctx context.Context, req FeatureExportRequest, pseudonym func(uuid.UUID) string, w io.Writer) (int, error) {
+	enc := json.NewEncoder(w)
+	count := 0
+	err := s.repo.ExportSnapshots(ctx, req.From, req.To, func(row FeatureSnapshotRow) error {
+		count++
+		return enc.Encode(newFeatureExportRecord(row, req.Labels, pseudonym))
+	})
+	return count, err
+}
+
+// exportCSV writes one column per feature seen in the range, so rows are
+// collected before the header can be written. Missing features are empty.
+func (s *featureExportService) exportCSV(ctx context.Context, req FeatureExportRequest, pseudonym func(uuid.UUID) string, w io.Writer) (int, error) {
+	var records []featureExportRecord
+	seen := make(map[string]bool)
+
+	err := s.repo.ExportSnapshots(ctx, req.From, req.To, func(row FeatureSnapshotRow) error {
+		rec := newFeatureExportRecord(row, req.Labels, pseudonym)
+		for name := range rec.Features {
+			seen[name] = true
+		}
+		records = append(records, rec)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	features := make([]string, 0, len(seen))
+	for name := range seen {
+		features = append(features, name)
+	}
+	sort.Strings(features)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append(append([]string{}, featureExportColumns...), features...)); err != nil {
+		return 0, err
+	}
+
+	for _, rec := range records {
+		line := []string{
+			rec.TransactionID.String(),
+			rec.UserID,
+			rec.TransactionType,
+			rec.TransactionTime.UTC().Format(time.RFC3339),
+			rec.EvaluatedAt.UTC().Format(time.RFC3339),
+			rec.TransactionStatus,
+			strconv.Itoa(rec.RiskScore),
+			rec.Decision,
+			optionalString(rec.ModelVersion),
+			optionalString(rec.Label),
+		}
+		for _, name := range features {
+			if v, ok := rec.Features[name]; ok {
+				line = append(line, strconv.FormatFloat(v, 'g', -1, 64))
+			} else {
+				line = append(line, "")
+			}
+		}
+		if err := cw.Write(line); err != nil {
+			return 0, err
+		}
+	}
+
+	cw.Flush()
+	return len(records), cw.Error()
+}
+
+func optionalString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,51 @@
+package risk
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"risk-detection/internal/db"
+)
+
+// WithFeatureSnapshots stores the features of every evaluation as training
+// data for models.
+func WithFeatureSnapshots(repo FeatureSnapshotRepository) Option {
+	return func(s *service) {
+		s.snapshots = repo
+	}
+}
+
+// takeSnapshot records the features of tx as the scorers see them. It must
+// run before the transaction is added to the user's behavior and devices.
+func (s *service) takeSnapshot(ctx context.Context, tx TransactionDTO) *FeatureSnapshot {
+	if s.snapshots == nil {
+		return nil
+	}
+
+	behavior, err := s.repo.GetBehaviorByUserID(ctx, tx.UserID)
+	if err != nil {
+		log.Printf("unable to get behavior for feature snapshot: %v", err)
+		behavior = nil
+	}
+
+	return &FeatureSnapshot{
+		TransactionID:   tx.TxID,
+		UserID:          tx.UserID,
+		TransactionType: tx.TxType,
+		Features:        s.featureValues(ctx, tx, behavior),
+	}
+}
+
+func (s *service) saveSnapshot(ctx context.Context, snapshot *FeatureSnapshot, evaluatedAt time.Time) {
+	if snapshot == nil {
+		return
+	}
+	snapshot.EvaluatedAt = evaluatedAt
+	err := db.Savepoint(ctx, func(ctx context.Context) error {
+		return s.snapshots.SaveSnapshot(ctx, snapshot)
+	})
+	if err != nil {
+		log.Printf("unable to save feature snapshot: %v", err)
+	}
+}
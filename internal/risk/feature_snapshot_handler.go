@@ -0,0 +1,64 @@
+package risk
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FeatureSnapshotHandler struct {
+	service FeatureExportService
+}
+
+func NewFeatureSnapshotHandler(service FeatureExportService) *FeatureSnapshotHandler {
+	return &FeatureSnapshotHandler{service: service}
+}
+
+// Export handles GET /api/v1/admin/feature-snapshots/export?from=...&to=...&format=csv&pseudonymize=true.
+// from and to are RFC3339 times; format is csv (default) or ndjson.
+func (h *FeatureSnapshotHandler) Export(c *gin.Context) {
+	req := FeatureExportRequest{Format: c.DefaultQuery("format", ExportCSV)}
+
+	var err error
+	if req.From, err = time.Parse(time.RFC3339, c.Query("from")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from, expected RFC3339"})
+		return
+	}
+	if req.To, err = time.Parse(time.RFC3339, c.Query("to")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to, expected RFC3339"})
+		return
+	}
+	if v := c.Query("pseudonymize"); v != "" {
+		if req.Pseudonymize, err = strconv.ParseBool(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid pseudonymize"})
+			return
+		}
+	}
+
+	contentType := "text/csv"
+	if req.Format == ExportNDJSON {
+		contentType = "application/x-ndjson"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="feature-snapshots.`+req.Format+`"`)
+
+	err = h.service.Export(c.Request.Context(), c.GetString("user_id"), req, c.Writer)
+	if err == nil {
+		return
+	}
+
+	// Once rows are streamed the status is sent; the truncated body is all
+	// the client gets
+	if c.Writer.Written() {
+		c.Error(err)
+		return
+	}
+	if errors.Is(err, ErrInvalidFeatureExport) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,66 @@
+package risk
+
+import (
+	"context"
+	"time"
+
+	"risk-detection/internal/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type featureSnapshotRepository struct {
+	db *gorm.DB
+}
+
+func NewFeatureSnapshotRepository(db *gorm.DB) FeatureSnapshotRepository {
+	return &featureSnapshotRepository{db: db}
+}
+
+// SaveSnapshot keeps the first snapshot of a transaction; a re-evaluation
+// does not overwrite what the original decision saw.
+func (r *featureSnapshotRepository) SaveSnapshot(ctx context.Context, snapshot *FeatureSnapshot) error {
+	return db.Conn(ctx, r.db).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(snapshot).Error
+}
+
+func (r *featureSnapshotRepository) ExportSnapshots(ctx context.Context, from time.Time, to time.Time, fn func(FeatureSnapshotRow) error) error {
+	rows, err := db.Conn(ctx, r.db).
+		Table("risk_feature_snapshots AS s").
+		Select(`
+			s.transaction_id,
+			s.user_id,
+			s.transaction_type,
+			s.evaluated_at,
+			s.features,
+			t.transaction_time,
+			t.transaction_status,
+			COALESCE(r.risk_score, 0) AS risk_score,
+			COALESCE(r.decision, '') AS decision,
+			r.model_version,
+			l.label
+		`).
+		Joins("JOIN transactions t ON t.id = s.transaction_id").
+		Joins("LEFT JOIN transaction_risks r ON r.transaction_id = s.transaction_id").
+		Joins("LEFT JOIN fraud_labels l ON l.transaction_id = s.transaction_id").
+		Where("s.evaluated_at >= ? AND s.evaluated_at < ?", from, to).
+		Order("s.evaluated_at ASC, s.transaction_id ASC").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row FeatureSnapshotRow
+		if err := r.db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
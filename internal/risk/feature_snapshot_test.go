@@ -0,0 +1,191 @@
+package risk
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"risk-detection/internal/audit"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+type MockFeatureSnapshotRepository struct {
+	mock.Mock
+}
+
+func (m *MockFeatureSnapshotRepository) SaveSnapshot(ctx context.Context, snapshot *FeatureSnapshot) error {
+	args := m.Called(ctx, snapshot)
+	return args.Error(0)
+}
+
+func (m *MockFeatureSnapshotRepository) ExportSnapshots(ctx context.Context, from time.Time, to time.Time, fn func(FeatureSnapshotRow) error) error {
+	args := m.Called(ctx, from, to)
+	for _, row := range args.Get(0).([]FeatureSnapshotRow) {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func TestFeatureValues(t *testing.T) {
+	userID := uuid.New()
+	txTime := time.Date(2024, 6, 5, 10, 0, 0, 0, time.UTC) // Wednesday
+	tx := TransactionDTO{UserID: userID, TxType: "DEPOSIT", Amount: 300, TxTime: txTime, DeviceID: "phone"}
+
+	mockRepo := new(MockTransactionRiskRepository)
+	mockRepo.On("GetDeviceInfo", mock.Anything, userID).Return(nil, gorm.ErrRecordNotFound)
+
+	svc := &service{repo: mockRepo}
+	values := svc.featureValues(context.Background(), tx, nil)
+	assert.Equal(t, 300.0, values["tx.amount"])
+	assert.Equal(t, 3.0, values["tx.weekday"])
+	assert.Equal(t, 1.0, values["tx.type.DEPOSIT"])
+	assert.Equal(t, 1.0, values["user.is_new"])
+	assert.Equal(t, 0.0, values["tx.has_receiver"])
+	assert.Equal(t, 0.0, values["device.known"])
+	assert.NotContains(t, values, "user.amount_zscore", "Ratios are missing without a baseline")
+	assert.NotContains(t, values, "tx.device_id", "Strings are not features")
+
+	deviceRepo := new(MockDeviceRepository)
+	deviceRepo.On("ListDevices", mock.Anything, userID).Return([]UserDevice{
+		{DeviceID: "laptop", TrustState: DeviceTrusted},
+		{DeviceID: "phone", TrustState: DeviceUnverified, FirstSeenAt: txTime.Add(-48 * time.Hour), UseCount: 4},
+	}, nil)
+
+	svc = &service{repo: mockRepo, deviceRepo: deviceRepo}
+	values = svc.featureValues(context.Background(), tx, &UserBehavior{TotalTransactions: 10, AvgTransactionAmount: 100, AmountStdDev: 50, HighValueThreshold: 200})
+	assert.Equal(t, 4.0, values["user.amount_zscore"])
+	assert.Equal(t, 3.0, values["user.amount_to_avg"])
+	assert.Equal(t, 1.5, values["user.amount_to_p95"])
+	assert.Equal(t, 0.0, values["user.is_new"])
+	assert.Equal(t, 1.0, values["device.known"])
+	assert.Equal(t, 2.0, values["device.known_devices"])
+	assert.Equal(t, 0.0, values["device.trusted"])
+	assert.Equal(t, 48.0, values["device.age_hours"])
+	assert.Equal(t, 4.0, values["device.use_count"])
+}
+
+func TestCalculateRisk_FeatureSnapshot(t *testing.T) {
+	userID := uuid.New()
+
+	mockRepo := new(MockTransactionRiskRepository)
+	mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{}, nil)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("GetBehaviorByUserID", mock.Anything, userID).Return(&UserBehavior{UserID: userID, TotalTransactions: 5, AvgTransactionAmount: 100, AmountStdDev: 20, EMASmoothingFactor: 0.1}, nil)
+	mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
+
+	var saved *FeatureSnapshot
+	snapshots := new(MockFeatureSnapshotRepository)
+	snapshots.On("SaveSnapshot", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*FeatureSnapshot)
+	}).Return(nil)
+
+	svc, err := NewService(mockRepo, nil, &audit.Logger{}, WithFeatureSnapshots(snapshots))
+	assert.NoError(t, err)
+
+	txID := uuid.New()
+	result, err := svc.CalculateRisk(context.Background(), &TransactionDTO{TxID: txID, UserID: userID, Amount: 160, TxTime: time.Now()})
+	assert.NoError(t, err)
+
+	if assert.NotNil(t, saved) {
+		assert.Equal(t, txID, saved.TransactionID)
+		assert.Equal(t, result.EvaluatedAt, saved.EvaluatedAt)
+		assert.Equal(t, 5.0, saved.Features["user.total_transactions"], "The snapshot holds the behavior before this transaction")
+		assert.Equal(t, 3.0, saved.Features["user.amount_zscore"])
+	}
+}
+
+func TestFeatureExportService_Export(t *testing.T) {
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	userID := uuid.New()
+	fraudTx := uuid.New()
+	version := "gbt-7"
+
+	rows := []FeatureSnapshotRow{
+		{
+			FeatureSnapshot:   FeatureSnapshot{TransactionID: fraudTx, UserID: userID, TransactionType: "WITHDRAWAL", EvaluatedAt: from.Add(time.Hour), Features: map[string]float64{"tx.amount": 900, "user.amount_zscore": 4.5}},
+			TransactionTime:   from.Add(time.Hour),
+			TransactionStatus: "BLOCKED",
+			RiskScore:         85,
+			Decision:          "BLOCK",
+			ModelVersion:      &version,
+		},
+		{
+			FeatureSnapshot:   FeatureSnapshot{TransactionID: uuid.New(), UserID: userID, TransactionType: "DEPOSIT", EvaluatedAt: from.Add(2 * time.Hour), Features: map[string]float64{"tx.amount": 20}},
+			TransactionTime:   from.Add(2 * time.Hour),
+			TransactionStatus: "COMPLETED",
+			RiskScore:         5,
+			Decision:          "ALLOW",
+		},
+	}
+
+	repo := new(MockFeatureSnapshotRepository)
+	repo.On("ExportSnapshots", mock.Anything, from, to).Return(rows, nil)
+	labels := map[uuid.UUID]string{fraudTx: "FRAUD"}
+
+	t.Run("csv", func(t *testing.T) {
+		svc := NewFeatureExportService(repo, &audit.Logger{}, nil)
+
+		var buf bytes.Buffer
+		err := svc.Export(context.Background(), "admin-1", FeatureExportRequest{From: from, To: to, Format: ExportCSV, Labels: labels}, &buf)
+		assert.NoError(t, err)
+
+		records, err := csv.NewReader(&buf).ReadAll()
+		assert.NoError(t, err)
+		assert.Len(t, records, 3)
+		assert.Equal(t, append(append([]string{}, featureExportColumns...), "tx.amount", "user.amount_zscore"), records[0])
+		assert.Equal(t, []string{fraudTx.String(), userID.String(), "WITHDRAWAL", "2024-06-01T01:00:00Z", "2024-06-01T01:00:00Z", "BLOCKED", "85", "BLOCK", "gbt-7", "FRAUD", "900", "4.5"}, records[1])
+		assert.Equal(t, []string{"", "", "20", ""}, []string{records[2][8], records[2][9], records[2][10], records[2][11]}, "Missing values are empty")
+	})
+
+	t.Run("ndjson_pseudonymized", func(t *testing.T) {
+		export := func(key []byte) []featureExportRecord {
+			var buf bytes.Buffer
+			svc := NewFeatureExportService(repo, &audit.Logger{}, key)
+			err := svc.Export(context.Background(), "admin-1", FeatureExportRequest{From: from, To: to, Format: ExportNDJSON, Pseudonymize: true}, &buf)
+			assert.NoError(t, err)
+
+			var out []featureExportRecord
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				var rec featureExportRecord
+				assert.NoError(t, json.Unmarshal([]byte(line), &rec))
+				out = append(out, rec)
+			}
+			return out
+		}
+
+		first := export([]byte("secret"))
+		assert.Len(t, first, 2)
+		assert.NotEqual(t, userID.String(), first[0].UserID)
+		assert.Equal(t, first[0].UserID, first[1].UserID, "A user keeps one pseudonym within an export")
+		assert.Nil(t, first[0].Label)
+		assert.Equal(t, 4.5, first[0].Features["user.amount_zscore"])
+
+		assert.Equal(t, first[0].UserID, export([]byte("secret"))[0].UserID, "The same key gives the same pseudonym")
+		assert.NotEqual(t, first[0].UserID, export(nil)[0].UserID, "Without a key every export draws its own")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		svc := NewFeatureExportService(repo, &audit.Logger{}, nil)
+		for _, req := range []FeatureExportRequest{
+			{From: from, To: to, Format: "xlsx"},
+			{To: to, Format: ExportCSV},
+			{From: to, To: from, Format: ExportCSV},
+		} {
+			var buf bytes.Buffer
+			err := svc.Export(context.Background(), "admin-1", req, &buf)
+			assert.ErrorIs(t, err, ErrInvalidFeatureExport)
+			assert.Zero(t, buf.Len(), "Nothing is written for an invalid request")
+		}
+	})
+}
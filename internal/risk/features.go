@@ -0,0 +1,165 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"risk-detection/internal/risk/expr"
+	"risk-detection/internal/risk/velocity"
+
+	"gorm.io/gorm"
+)
+
+// txTypeFeaturePrefix one-hot encodes the transaction type, e.g.
+// tx.type.WITHDRAWAL is 1 for withdrawals and 0 otherwise.
+const txTypeFeaturePrefix = "tx.type."
+
+// derivedFeatures are features on top of the numeric and boolean rule
+// variables. Ratios are missing when the user has no baseline, and device
+// details when the device has not been seen for the user.
+var derivedFeatures = map[string]bool{
+	"tx.weekday":         true,
+	"tx.has_receiver":    true,
+	"user.amount_zscore": true,
+	"user.amount_to_avg": true,
+	"user.amount_to_p95": true,
+	"user.hour_share":    true,
+
+	"device.known":         true,
+	"device.known_devices": true,
+	"device.trusted":       true,
+	"device.blocked":       true,
+	"device.age_hours":     true,
+	"device.use_count":     true,
+}
+
+// knownFeature reports whether featureValues can produce name.
+func knownFeature(name string) bool {
+	if derivedFeatures[name] {
+		return true
+	}
+	if strings.HasPrefix(name, txTypeFeaturePrefix) && len(name) > len(txTypeFeaturePrefix) {
+		return true
+	}
+	if isVelocityVariable(name) {
+		return true
+	}
+
+	kind, ok := ruleSchema[name]
+	return ok && (kind == expr.KindNumber || kind == expr.KindBool)
+}
+
+// isVelocityVariable accepts velocity variables of any window, since a model
+// may be loaded before the store registers its windows. Windows the store
+// does not track are missing at scoring time.
+func isVelocityVariable(name string) bool {
+	parts := strings.SplitN(name, ".", 3)
+	if len(parts) != 3 || parts[0] != "velocity" {
+		return false
+	}
+
+	known := false
+	for _, dim := range velocity.Dimensions {
+		if parts[1] == string(dim) {
+			known = true
+		}
+	}
+	metric, window, ok := strings.Cut(parts[2], "_")
+	if !known || !ok || (metric != "count" && metric != "sum") {
+		return false
+	}
+	windows, err := velocity.ParseWindows(window)
+	return err == nil && len(windows) == 1 && windows[0].Name == window
+}
+
+// featureValues builds the named features of tx from the behavior before
+// the transaction is applied to it. Booleans are 0 or 1 and string
+// variables are left out. Models score and snapshots record these values.
+func (s *service) featureValues(ctx context.Context, tx TransactionDTO, behavior *UserBehavior) map[string]float64 {
+	env := buildExpressionEnv(tx, behavior)
+	for name, v := range s.velocityEnv(tx) {
+		env[name] = v
+	}
+
+	values := make(map[string]float64, len(env)+len(derivedFeatures)+1)
+	for name, v := range env {
+		switch v := v.(type) {
+		case float64:
+			values[name] = v
+		case bool:
+			values[name] = boolFeature(v)
+		}
+	}
+
+	values["tx.weekday"] = float64(tx.TxTime.UTC().Weekday())
+	values["tx.has_receiver"] = boolFeature(tx.ReceiverID != nil)
+	if tx.TxType != "" {
+		values[txTypeFeaturePrefix+tx.TxType] = 1
+	}
+
+	if behavior != nil {
+		if behavior.AmountStdDev > 0 {
+			values["user.amount_zscore"] = (tx.Amount - behavior.AvgTransactionAmount) / behavior.AmountStdDev
+		}
+		if behavior.AvgTransactionAmount > 0 {
+			values["user.amount_to_avg"] = tx.Amount / behavior.AvgTransactionAmount
+		}
+		if behavior.HighValueThreshold > 0 {
+			values["user.amount_to_p95"] = tx.Amount / behavior.HighValueThreshold
+		}
+		if behavior.HourOfWeek.Total() > 0 {
+			values["user.hour_share"] = behavior.HourOfWeek.hourOfDayShare(tx.TxTime)
+		}
+	}
+
+	s.deviceFeatures(ctx, tx, values)
+	return values
+}
+
+// deviceFeatures matches the device against the user's device history, or
+// against the device of the last login without one. Lookup failures leave
+// the features missing.
+func (s *service) deviceFeatures(ctx context.Context, tx TransactionDTO, values map[string]float64) {
+	if tx.DeviceID == "" {
+		return
+	}
+
+	if s.deviceRepo == nil {
+		security, err := s.repo.GetDeviceInfo(ctx, tx.UserID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("unable to get device info for features: %v", err)
+			return
+		}
+		values["device.known"] = boolFeature(security != nil && security.DeviceID == tx.DeviceID)
+		return
+	}
+
+	devices, err := s.deviceRepo.ListDevices(ctx, tx.UserID)
+	if err != nil {
+		log.Printf("unable to get device history for features: %v", err)
+		return
+	}
+
+	values["device.known_devices"] = float64(len(devices))
+	values["device.known"] = 0
+	for _, d := range devices {
+		if d.DeviceID != tx.DeviceID {
+			continue
+		}
+		values["device.known"] = 1
+		values["device.trusted"] = boolFeature(d.TrustState == DeviceTrusted)
+		values["device.blocked"] = boolFeature(d.TrustState == DeviceBlocked)
+		values["device.age_hours"] = tx.TxTime.Sub(d.FirstSeenAt).Hours()
+		values["device.use_count"] = float64(d.UseCount)
+		break
+	}
+}
+
+func boolFeature(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,107 @@
+package risk
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"risk-detection/internal/geo"
+)
+
+const (
+	// MaxTravelSpeedKmh is roughly airliner cruising speed. Moving faster
+	// than this between two sightings is treated as impossible travel.
+	MaxTravelSpeedKmh = 900.0
+
+	// Geolocation of an IP is only accurate to a city or region, so shorter
+	// hops never count as travel.
+	minTravelDistanceKm = 100.0
+
+	// Sightings closer together than this are compared as if a minute apart
+	// to keep the speed finite.
+	minTravelElapsed = time.Minute
+)
+
+// WithGeoLocation registers the GEO_LOCATION_RISK scorer, which compares the
+// location of the transaction IP with where the user was last seen.
+func WithGeoLocation(locator geo.Locator, sightings SightingRepository) Option {
+	return func(s *service) {
+		s.RegisterScorer(detailedScorer{
+			name: RuleGeoLocation,
+			fn: func(ctx context.Context, tx TransactionDTO) (ScoreResult, error) {
+				return geoLocationRisk(ctx, locator, sightings, tx)
+			},
+		})
+	}
+}
+
+// geoLocationRisk flags a country change and physically impossible travel
+// since the previous transaction or login of the same user.
+func geoLocationRisk(ctx context.Context, locator geo.Locator, sightings SightingRepository, tx TransactionDTO) (ScoreResult, error) {
+	if tx.IPAddress == "" {
+		return ScoreResult{}, nil
+	}
+
+	current, ok := locator.Lookup(tx.IPAddress)
+	if !ok {
+		return ScoreResult{}, nil
+	}
+
+	last, err := sightings.GetLastSighting(ctx, tx.UserID, tx.TxTime, tx.TxID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ScoreResult{}, ctx.Err()
+		}
+		log.Printf("unable to get last sighting: %v", err)
+		return ScoreResult{}, nil
+	}
+	if last == nil || last.IPAddress == tx.IPAddress {
+		return ScoreResult{}, nil
+	}
+
+	previous, ok := locator.Lookup(last.IPAddress)
+	if !ok {
+		return ScoreResult{}, nil
+	}
+
+	var result ScoreResult
+
+	if previous.Country != current.Country {
+		result.Score += 40
+		result.Reasons = append(result.Reasons, Reason{
+			Code:  "GEO_COUNTRY_CHANGE",
+			Score: 40,
+			Inputs: map[string]interface{}{
+				"country":          current.Country,
+				"previous_country": previous.Country,
+				"previous_source":  last.Source,
+			},
+		})
+	}
+
+	distance := geo.DistanceKm(previous, current)
+	elapsed := tx.TxTime.Sub(last.SeenAt)
+	if elapsed < minTravelElapsed {
+		elapsed = minTravelElapsed
+	}
+	speed := distance / elapsed.Hours()
+
+	if distance >= minTravelDistanceKm && speed > MaxTravelSpeedKmh {
+		result.Score += 80
+		result.Reasons = append(result.Reasons, Reason{
+			Code:  "GEO_IMPOSSIBLE_TRAVEL",
+			Score: 80,
+			Inputs: map[string]interface{}{
+				"distance_km":     distance,
+				"elapsed_hours":   elapsed.Hours(),
+				"speed_kmh":       speed,
+				"previous_source": last.Source,
+			},
+		})
+	}
+
+	if result.Score > 100 {
+		result.Score = 100
+	}
+	return result, nil
+}
@@ -0,0 +1,150 @@
+package risk
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"risk-detection/internal/audit"
+	"risk-detection/internal/geo"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSightingRepository struct {
+	mock.Mock
+}
+
+func (m *MockSightingRepository) GetLastSighting(ctx context.Context, userID uuid.UUID, before time.Time, excludeTxID uuid.UUID) (*Sighting, error) {
+	args := m.Called(ctx, userID, before, excludeTxID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Sighting), args.Error(1)
+}
+
+const testGeoRanges = `192.0.2.0,192.0.2.127,US,40.7128,-74.0060
+192.0.2.128,192.0.2.255,US,34.0522,-118.2437
+198.51.100.0,198.51.100.255,GB,51.5074,-0.1278
+203.0.113.0,203.0.113.255,US,40.7306,-73.9352
+`
+
+func TestGeoLocationRisk(t *testing.T) {
+	locator, err := geo.ReadCSV(strings.NewReader(testGeoRanges))
+	assert.NoError(t, err)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		ip            string
+		last          *Sighting
+		expectedScore int
+		expectedCodes []string
+		description   string
+	}{
+		{
+			name:          "no_ip",
+			ip:            "",
+			expectedScore: 0,
+			description:   "Transactions without an IP should not score",
+		},
+		{
+			name:          "unknown_ip",
+			ip:            "10.0.0.1",
+			last:          &Sighting{IPAddress: "198.51.100.1", SeenAt: now.Add(-time.Hour)},
+			expectedScore: 0,
+			description:   "IPs outside the range file should not score",
+		},
+		{
+			name:          "first_sighting",
+			ip:            "192.0.2.1",
+			expectedScore: 0,
+			description:   "A user never seen before has nothing to compare against",
+		},
+		{
+			name:          "country_change_feasible",
+			ip:            "198.51.100.1",
+			last:          &Sighting{IPAddress: "192.0.2.1", SeenAt: now.Add(-10 * time.Hour), Source: SightingLogin},
+			expectedScore: 40,
+			expectedCodes: []string{"GEO_COUNTRY_CHANGE"},
+			description:   "New York to London in 10 hours is a country change but possible",
+		},
+		{
+			name:          "country_change_impossible",
+			ip:            "198.51.100.1",
+			last:          &Sighting{IPAddress: "192.0.2.1", SeenAt: now.Add(-time.Hour), Source: SightingTransaction},
+			expectedScore: 100,
+			expectedCodes: []string{"GEO_COUNTRY_CHANGE", "GEO_IMPOSSIBLE_TRAVEL"},
+			description:   "New York to London in an hour should score both checks, clamped to 100",
+		},
+		{
+			name:          "domestic_impossible_travel",
+			ip:            "192.0.2.200",
+			last:          &Sighting{IPAddress: "192.0.2.1", SeenAt: now.Add(-time.Hour), Source: SightingTransaction},
+			expectedScore: 80,
+			expectedCodes: []string{"GEO_IMPOSSIBLE_TRAVEL"},
+			description:   "New York to Los Angeles in an hour is impossible within one country",
+		},
+		{
+			name:          "short_hop_ignored",
+			ip:            "203.0.113.5",
+			last:          &Sighting{IPAddress: "192.0.2.1", SeenAt: now.Add(-10 * time.Second), Source: SightingTransaction},
+			expectedScore: 0,
+			description:   "Moves under the geolocation accuracy should never count as travel",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sightings := new(MockSightingRepository)
+			if tt.last != nil {
+				sightings.On("GetLastSighting", mock.Anything, mock.Anything, now, mock.Anything).Return(tt.last, nil)
+			} else {
+				sightings.On("GetLastSighting", mock.Anything, mock.Anything, now, mock.Anything).Return(nil, nil)
+			}
+
+			res, err := geoLocationRisk(context.Background(), locator, sightings, TransactionDTO{
+				TxID:      uuid.New(),
+				UserID:    uuid.New(),
+				IPAddress: tt.ip,
+				TxTime:    now,
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedScore, res.Score, tt.description)
+
+			var codes []string
+			for _, r := range res.Reasons {
+				codes = append(codes, r.Code)
+			}
+			assert.Equal(t, tt.expectedCodes, codes, tt.description)
+		})
+	}
+}
+
+func TestCalculateRisk_GeoLocationRule(t *testing.T) {
+	locator, err := geo.ReadCSV(strings.NewReader(testGeoRanges))
+	assert.NoError(t, err)
+
+	mockRepo := new(MockTransactionRiskRepository)
+	mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{{Name: RuleGeoLocation, Enabled: true, Weight: 50}}, nil)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("GetBehaviorByUserID", mock.Anything, mock.Anything).Return(&UserBehavior{UserID: uuid.New()}, nil)
+	mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
+
+	now := time.Now()
+	sightings := new(MockSightingRepository)
+	sightings.On("GetLastSighting", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&Sighting{IPAddress: "192.0.2.1", SeenAt: now.Add(-time.Hour), Source: SightingLogin}, nil)
+
+	svc, err := NewService(mockRepo, nil, &audit.Logger{}, WithGeoLocation(locator, sightings))
+	assert.NoError(t, err)
+
+	result, err := svc.CalculateRisk(context.Background(), &TransactionDTO{TxID: uuid.New(), UserID: uuid.New(), IPAddress: "198.51.100.1", TxTime: now})
+	assert.NoError(t, err)
+	assert.Equal(t, 50, result.RiskScore, "Impossible travel should contribute the full rule weight")
+	assert.Len(t, result.Reasons, 2)
+}
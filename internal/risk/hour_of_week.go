@@ -0,0 +1,141 @@
+package risk
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// HoursPerWeek is the number of buckets in an hour-of-week histogram.
+const HoursPerWeek = 7 * 24
+
+const (
+	// HourOfWeekDecay is applied to every bucket by the daily cron, giving
+	// old habits a half-life of about a month.
+	HourOfWeekDecay = 0.98
+
+	// Below this many (decayed) transactions the histogram is too thin to
+	// tell an unusual hour from a gap in the data.
+	minHourHistory = 20
+
+	// An hour of day holding less than this share of the user's history is
+	// rare for them.
+	rareHourShare = 0.02
+)
+
+// HourHistogram counts a user's transactions per hour of the week in UTC,
+// indexed by weekday*24 + hour with Sunday as day 0. Counts decay daily.
+// Transaction times are stored without their offset, so UTC is the only
+// clock every evaluation path agrees on; a user who moves time zones shifts
+// their history and may score as unusual until it catches up.
+type HourHistogram []float64
+
+// hourOfWeek returns the histogram bucket of t.
+func hourOfWeek(t time.Time) int {
+	t = t.UTC()
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// Observe adds one transaction at t.
+func (h HourHistogram) Observe(t time.Time) HourHistogram {
+	if len(h) != HoursPerWeek {
+		grown := make(HourHistogram, HoursPerWeek)
+		copy(grown, h)
+		h = grown
+	}
+	h[hourOfWeek(t)]++
+	return h
+}
+
+// Total is the decayed number of transactions in the histogram.
+func (h HourHistogram) Total() float64 {
+	total := 0.0
+	for _, v := range h {
+		total += v
+	}
+	return total
+}
+
+// bucket returns the count at a possibly out-of-range index, wrapping
+// around the end of the week.
+func (h HourHistogram) bucket(i int) float64 {
+	if len(h) != HoursPerWeek {
+		return 0
+	}
+	return h[((i%HoursPerWeek)+HoursPerWeek)%HoursPerWeek]
+}
+
+// smoothed weighs neighbouring hours at half, so 7pm is not unusual for a
+// user who always transacts until 6pm.
+func (h HourHistogram) smoothed(i int) float64 {
+	return h.bucket(i) + 0.5*(h.bucket(i-1)+h.bucket(i+1))
+}
+
+// hourOfDayShare is the share of history at the hour of day of t, across
+// every day of the week.
+func (h HourHistogram) hourOfDayShare(t time.Time) float64 {
+	total := h.Total()
+	if total == 0 {
+		return 0
+	}
+
+	i := hourOfWeek(t)
+	mass := 0.0
+	for day := 0; day < 7; day++ {
+		mass += h.smoothed(i + day*24)
+	}
+	return mass / total
+}
+
+// timeOfDayRisk flags transactions at hours where the user has little or no
+// history, the pattern of an account takeover in the middle of the night.
+func (s *service) timeOfDayRisk(ctx context.Context, tx TransactionDTO) (ScoreResult, error) {
+	if tx.TxTime.IsZero() {
+		return ScoreResult{}, nil
+	}
+
+	behavior, err := s.repo.GetBehaviorByUserID(ctx, tx.UserID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ScoreResult{}, ctx.Err()
+		}
+		log.Printf("unable to get behavior for time of day risk: %v", err)
+		return ScoreResult{}, nil
+	}
+	if behavior == nil {
+		return ScoreResult{}, nil
+	}
+	return behavior.HourOfWeek.score(tx.TxTime), nil
+}
+
+func (h HourHistogram) score(t time.Time) ScoreResult {
+	total := h.Total()
+	if total < minHourHistory {
+		return ScoreResult{}
+	}
+
+	share := h.hourOfDayShare(t)
+	inputs := map[string]interface{}{
+		"hour_utc":      t.UTC().Hour(),
+		"weekday":       t.UTC().Weekday().String(),
+		"hour_share":    share,
+		"history_total": total,
+	}
+
+	var result ScoreResult
+	switch {
+	case share == 0:
+		result.Score = 60
+		result.Reasons = append(result.Reasons, Reason{Code: "TIME_NO_HISTORY_AT_HOUR", Score: 60, Inputs: inputs})
+	case share < rareHourShare:
+		result.Score = 30
+		result.Reasons = append(result.Reasons, Reason{Code: "TIME_RARE_HOUR", Score: 30, Inputs: inputs})
+	}
+
+	// A usual hour on an unusual day, e.g. weekday office hours on a Sunday
+	if result.Score == 0 && h.smoothed(hourOfWeek(t)) == 0 {
+		result.Score = 15
+		result.Reasons = append(result.Reasons, Reason{Code: "TIME_UNUSUAL_WEEKDAY", Score: 15, Inputs: inputs})
+	}
+	return result
+}
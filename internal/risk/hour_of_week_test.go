@@ -0,0 +1,129 @@
+package risk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"risk-detection/internal/audit"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// officeHours is a user who transacts on weekdays between 09:00 and 17:00 UTC.
+func officeHours() HourHistogram {
+	var h HourHistogram
+	monday := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+	for week := 0; week < 4; week++ {
+		for day := 0; day < 5; day++ {
+			for hour := 9; hour <= 17; hour += 2 {
+				h = h.Observe(monday.AddDate(0, 0, week*7+day).Add(time.Duration(hour) * time.Hour))
+			}
+		}
+	}
+	return h
+}
+
+func TestHourHistogram_Observe(t *testing.T) {
+	sunday := time.Date(2024, 6, 2, 23, 30, 0, 0, time.UTC)
+
+	var h HourHistogram
+	h = h.Observe(sunday)
+	h = h.Observe(sunday.In(time.FixedZone("IST", 5*3600+1800)))
+
+	assert.Len(t, h, HoursPerWeek)
+	assert.Equal(t, 2.0, h[23], "Buckets are in UTC regardless of the time's zone")
+	assert.Equal(t, 2.0, h.Total())
+}
+
+func TestHourHistogram_Score(t *testing.T) {
+	wednesday := time.Date(2024, 7, 3, 0, 0, 0, 0, time.UTC)
+	saturday := time.Date(2024, 7, 6, 0, 0, 0, 0, time.UTC)
+
+	rare := officeHours()
+	rare = rare.Observe(wednesday.Add(21 * time.Hour))
+
+	tests := []struct {
+		name          string
+		histogram     HourHistogram
+		at            time.Time
+		expectedScore int
+		expectedCodes []string
+		description   string
+	}{
+		{
+			name:          "thin_history",
+			histogram:     HourHistogram{}.Observe(wednesday.Add(10 * time.Hour)),
+			at:            wednesday.Add(3 * time.Hour),
+			expectedScore: 0,
+			description:   "Too little history should not score",
+		},
+		{
+			name:          "usual_hour",
+			histogram:     officeHours(),
+			at:            wednesday.Add(11 * time.Hour),
+			expectedScore: 0,
+			description:   "A usual hour on a usual day should not score",
+		},
+		{
+			name:          "next_to_usual_hour",
+			histogram:     officeHours(),
+			at:            wednesday.Add(18 * time.Hour),
+			expectedScore: 0,
+			description:   "The hour after the user's last usual hour is smoothed in",
+		},
+		{
+			name:          "three_am",
+			histogram:     officeHours(),
+			at:            wednesday.Add(3 * time.Hour),
+			expectedScore: 60,
+			expectedCodes: []string{"TIME_NO_HISTORY_AT_HOUR"},
+			description:   "An hour with no history at all should score high",
+		},
+		{
+			name:          "rare_hour",
+			histogram:     rare,
+			at:            wednesday.Add(21 * time.Hour),
+			expectedScore: 30,
+			expectedCodes: []string{"TIME_RARE_HOUR"},
+			description:   "An hour with a single past transaction is rare",
+		},
+		{
+			name:          "weekend_office_hour",
+			histogram:     officeHours(),
+			at:            saturday.Add(11 * time.Hour),
+			expectedScore: 15,
+			expectedCodes: []string{"TIME_UNUSUAL_WEEKDAY"},
+			description:   "A usual hour on a day the user never transacts adds a little",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := tt.histogram.score(tt.at)
+			assert.Equal(t, tt.expectedScore, res.Score, tt.description)
+
+			var codes []string
+			for _, r := range res.Reasons {
+				codes = append(codes, r.Code)
+			}
+			assert.Equal(t, tt.expectedCodes, codes, tt.description)
+		})
+	}
+}
+
+func TestUpdateUserBehaviorAfterTransaction_HourOfWeek(t *testing.T) {
+	mockRepo := new(MockTransactionRiskRepository)
+	mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
+
+	svc := &service{repo: mockRepo, auditLog: &audit.Logger{}}
+	behavior := &UserBehavior{UserID: uuid.New(), EMASmoothingFactor: 0.1}
+	txTime := time.Date(2024, 6, 5, 3, 15, 0, 0, time.UTC) // Wednesday
+
+	err := svc.UpdateUserBehaviorAfterTransaction(context.Background(), behavior, 100, uuid.New(), txTime)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, behavior.HourOfWeek[3*24+3])
+	assert.Equal(t, 1.0, behavior.HourOfWeek.Total())
+}
@@ -0,0 +1,104 @@
+package risk
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type LabelHandler struct {
+	service     LabelService
+	performance RulePerformanceService
+}
+
+func NewLabelHandler(service LabelService, performance RulePerformanceService) *LabelHandler {
+	return &LabelHandler{service: service, performance: performance}
+}
+
+// SetLabel handles PUT /api/v1/admin/transactions/:id/label.
+func (h *LabelHandler) SetLabel(c *gin.Context) {
+	transactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transaction id format"})
+		return
+	}
+
+	var req FraudLabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	label, err := h.service.SetLabel(c.Request.Context(), c.GetString("user_id"), transactionID, req)
+	if err != nil {
+		writeLabelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, label)
+}
+
+// GetLabel handles GET /api/v1/admin/transactions/:id/label.
+func (h *LabelHandler) GetLabel(c *gin.Context) {
+	transactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transaction id format"})
+		return
+	}
+
+	label, err := h.service.GetLabel(c.Request.Context(), transactionID)
+	if err != nil {
+		writeLabelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, label)
+}
+
+// PerformanceReport handles GET /api/v1/admin/rules/performance.
+// from and to are RFC3339 timestamps; the default window is the last 30 days.
+func (h *LabelHandler) PerformanceReport(c *gin.Context) {
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -30)
+
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from timestamp"})
+			return
+		}
+		from = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to timestamp"})
+			return
+		}
+		to = t
+	}
+
+	report, err := h.performance.Report(c.Request.Context(), from, to)
+	if err != nil {
+		writeLabelError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func writeLabelError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrLabelNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrLabelNoEvaluation):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidLabel), errors.Is(err, ErrInvalidReportWindow):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,34 @@
+package risk
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type labelRepository struct {
+	db *gorm.DB
+}
+
+func NewLabelRepository(db *gorm.DB) LabelRepository {
+	return &labelRepository{db: db}
+}
+
+func (r *labelRepository) UpsertLabel(ctx context.Context, label *FraudLabel) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "transaction_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"label", "source", "note", "labeled_by", "labeled_at"}),
+		}).
+		Create(label).Error
+}
+
+func (r *labelRepository) GetLabel(ctx context.Context, transactionID uuid.UUID) (*FraudLabel, error) {
+	var label FraudLabel
+	if err := r.db.WithContext(ctx).First(&label, "transaction_id = ?", transactionID).Error; err != nil {
+		return nil, err
+	}
+	return &label, nil
+}
@@ -0,0 +1,107 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"risk-detection/internal/audit"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrLabelNotFound     = errors.New("fraud label not found")
+	ErrInvalidLabel      = errors.New("invalid fraud label")
+	ErrLabelNoEvaluation = errors.New("transaction has no risk evaluation")
+)
+
+type labelService struct {
+	repo     LabelRepository
+	riskRepo TransactionRiskRepository
+	auditLog *audit.Logger
+}
+
+// NewLabelService labels evaluated transactions. riskRepo is used to check
+// the transaction was evaluated, since unevaluated ones cannot be reported on.
+func NewLabelService(repo LabelRepository, riskRepo TransactionRiskRepository, auditLog *audit.Logger) LabelService {
+	return &labelService{
+		repo:     repo,
+		riskRepo: riskRepo,
+		auditLog: auditLog,
+	}
+}
+
+func (s *labelService) SetLabel(ctx context.Context, actorID string, transactionID uuid.UUID, req FraudLabelRequest) (*FraudLabel, error) {
+	label := &FraudLabel{
+		TransactionID: transactionID,
+		Label:         strings.ToUpper(strings.TrimSpace(req.Label)),
+		Source:        strings.ToUpper(strings.TrimSpace(req.Source)),
+		Note:          strings.TrimSpace(req.Note),
+		LabeledBy:     actorID,
+		LabeledAt:     time.Now(),
+	}
+	if label.Label != LabelFraud && label.Label != LabelLegit {
+		return nil, ErrInvalidLabel
+	}
+	if label.Source == "" {
+		return nil, ErrInvalidLabel
+	}
+
+	if _, err := s.riskRepo.GetRiskByTransactionID(ctx, transactionID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLabelNoEvaluation
+		}
+		return nil, err
+	}
+
+	old, err := s.repo.GetLabel(ctx, transactionID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err := s.repo.UpsertLabel(ctx, label); err != nil {
+		return nil, err
+	}
+
+	if s.auditLog != nil {
+		entry := audit.AuditLog{
+			EventType:     audit.EventFraudLabeled,
+			Action:        "LABEL",
+			EntityType:    "fraud_labels",
+			EntityID:      transactionID.String(),
+			ActorType:     "USER",
+			ActorID:       actorID,
+			ActorRole:     "ADMIN",
+			TransactionID: transactionID.String(),
+			NewValues:     labelValues(*label),
+			Status:        "SUCCESS",
+		}
+		if old != nil {
+			entry.OldValues = labelValues(*old)
+		}
+		s.auditLog.Log(entry)
+	}
+	return label, nil
+}
+
+func (s *labelService) GetLabel(ctx context.Context, transactionID uuid.UUID) (*FraudLabel, error) {
+	label, err := s.repo.GetLabel(ctx, transactionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLabelNotFound
+		}
+		return nil, err
+	}
+	return label, nil
+}
+
+func labelValues(label FraudLabel) map[string]interface{} {
+	return map[string]interface{}{
+		"label":  label.Label,
+		"source": label.Source,
+		"note":   label.Note,
+	}
+}
@@ -0,0 +1,200 @@
+package risk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"risk-detection/internal/audit"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockLabelRepository is a mock implementation of the LabelRepository interface
+type MockLabelRepository struct {
+	mock.Mock
+}
+
+func (m *MockLabelRepository) UpsertLabel(ctx context.Context, label *FraudLabel) error {
+	args := m.Called(ctx, label)
+	return args.Error(0)
+}
+
+func (m *MockLabelRepository) GetLabel(ctx context.Context, transactionID uuid.UUID) (*FraudLabel, error) {
+	args := m.Called(ctx, transactionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*FraudLabel), args.Error(1)
+}
+
+// MockRulePerformanceRepository is a mock implementation of the RulePerformanceRepository interface
+type MockRulePerformanceRepository struct {
+	mock.Mock
+}
+
+func (m *MockRulePerformanceRepository) DailyTotals(ctx context.Context, from time.Time, to time.Time) ([]PerformanceTotals, error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).([]PerformanceTotals), args.Error(1)
+}
+
+func (m *MockRulePerformanceRepository) DailyRuleHits(ctx context.Context, from time.Time, to time.Time) ([]PerformanceHits, error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).([]PerformanceHits), args.Error(1)
+}
+
+func (m *MockRulePerformanceRepository) DailyDecisionHits(ctx context.Context, from time.Time, to time.Time) ([]PerformanceHits, error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).([]PerformanceHits), args.Error(1)
+}
+
+func (m *MockRulePerformanceRepository) UpsertPerformance(ctx context.Context, rows []RulePerformance) error {
+	args := m.Called(ctx, rows)
+	return args.Error(0)
+}
+
+func (m *MockRulePerformanceRepository) ListPerformance(ctx context.Context, from time.Time, to time.Time) ([]RulePerformance, error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).([]RulePerformance), args.Error(1)
+}
+
+func TestLabelService_SetLabel(t *testing.T) {
+	txID := uuid.New()
+
+	t.Run("labels_evaluated_transaction", func(t *testing.T) {
+		repo := new(MockLabelRepository)
+		riskRepo := new(MockTransactionRiskRepository)
+		riskRepo.On("GetRiskByTransactionID", mock.Anything, txID).Return(&TransactionRisk{TransactionID: txID}, nil)
+		repo.On("GetLabel", mock.Anything, txID).Return(nil, gorm.ErrRecordNotFound)
+		repo.On("UpsertLabel", mock.Anything, mock.MatchedBy(func(l *FraudLabel) bool {
+			return l.TransactionID == txID && l.Label == LabelFraud && l.Source == "CHARGEBACK" && l.LabeledBy == "admin-1"
+		})).Return(nil)
+
+		svc := NewLabelService(repo, riskRepo, &audit.Logger{})
+		label, err := svc.SetLabel(context.Background(), "admin-1", txID, FraudLabelRequest{Label: "fraud", Source: "chargeback"})
+		assert.NoError(t, err)
+		assert.Equal(t, LabelFraud, label.Label)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("relabel_replaces_label", func(t *testing.T) {
+		repo := new(MockLabelRepository)
+		riskRepo := new(MockTransactionRiskRepository)
+		riskRepo.On("GetRiskByTransactionID", mock.Anything, txID).Return(&TransactionRisk{TransactionID: txID}, nil)
+		repo.On("GetLabel", mock.Anything, txID).Return(&FraudLabel{TransactionID: txID, Label: LabelFraud, Source: "CUSTOMER_REPORT"}, nil)
+		repo.On("UpsertLabel", mock.Anything, mock.Anything).Return(nil)
+
+		svc := NewLabelService(repo, riskRepo, &audit.Logger{})
+		label, err := svc.SetLabel(context.Background(), "admin-1", txID, FraudLabelRequest{Label: LabelLegit, Source: "ANALYST"})
+		assert.NoError(t, err)
+		assert.Equal(t, LabelLegit, label.Label)
+	})
+
+	t.Run("not_evaluated", func(t *testing.T) {
+		repo := new(MockLabelRepository)
+		riskRepo := new(MockTransactionRiskRepository)
+		riskRepo.On("GetRiskByTransactionID", mock.Anything, txID).Return(nil, gorm.ErrRecordNotFound)
+
+		svc := NewLabelService(repo, riskRepo, &audit.Logger{})
+		_, err := svc.SetLabel(context.Background(), "admin-1", txID, FraudLabelRequest{Label: LabelFraud, Source: "CHARGEBACK"})
+		assert.ErrorIs(t, err, ErrLabelNoEvaluation)
+		repo.AssertNotCalled(t, "UpsertLabel", mock.Anything, mock.Anything)
+	})
+
+	t.Run("invalid_label", func(t *testing.T) {
+		svc := NewLabelService(new(MockLabelRepository), new(MockTransactionRiskRepository), &audit.Logger{})
+		_, err := svc.SetLabel(context.Background(), "admin-1", txID, FraudLabelRequest{Label: "MAYBE", Source: "ANALYST"})
+		assert.ErrorIs(t, err, ErrInvalidLabel)
+	})
+}
+
+func TestRulePerformanceService_ComputePerformance(t *testing.T) {
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	from := day.Add(6 * time.Hour)
+	to := day.Add(18 * time.Hour)
+	dayEnd := day.AddDate(0, 0, 1)
+
+	repo := new(MockRulePerformanceRepository)
+	repo.On("DailyTotals", mock.Anything, day, dayEnd).Return([]PerformanceTotals{
+		{Day: day, Evaluated: 100, Labeled: 20, Frauds: 8},
+	}, nil)
+	repo.On("DailyRuleHits", mock.Anything, day, dayEnd).Return([]PerformanceHits{
+		{Day: day, Name: "NEW_DEVICE_RISK", Hits: 25, TruePositives: 6, FalsePositives: 2},
+	}, nil)
+	repo.On("DailyDecisionHits", mock.Anything, day, dayEnd).Return([]PerformanceHits{
+		{Day: day, Name: "ALLOW", Hits: 90, TruePositives: 3, FalsePositives: 10},
+		{Day: day, Name: "FLAG", Hits: 10, TruePositives: 5, FalsePositives: 2},
+	}, nil)
+
+	var written []RulePerformance
+	repo.On("UpsertPerformance", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		written = args.Get(1).([]RulePerformance)
+	}).Return(nil)
+
+	svc := NewRulePerformanceService(repo)
+	n, err := svc.ComputePerformance(context.Background(), from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+
+	rows := make(map[string]RulePerformance)
+	for _, row := range written {
+		assert.Equal(t, day, row.Day)
+		rows[row.Dimension+"/"+row.Name] = row
+	}
+
+	rule := rows["RULE/NEW_DEVICE_RISK"]
+	assert.InDelta(t, 0.75, rule.Precision, 1e-9)
+	assert.InDelta(t, 0.75, rule.Recall, 1e-9)
+	assert.InDelta(t, 0.25, rule.HitRate, 1e-9)
+
+	flag := rows["DECISION/FLAG"]
+	assert.InDelta(t, 5.0/7.0, flag.Precision, 1e-9)
+	assert.InDelta(t, 5.0/8.0, flag.Recall, 1e-9)
+
+	// BLOCK never fired but still gets a row
+	block, ok := rows["DECISION/BLOCK"]
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), block.Hits)
+	assert.Equal(t, int64(8), block.Frauds)
+	assert.Equal(t, 0.0, block.Recall)
+}
+
+func TestRulePerformanceService_Report(t *testing.T) {
+	day1 := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	from, to := day1, day2.AddDate(0, 0, 1)
+
+	repo := new(MockRulePerformanceRepository)
+	repo.On("ListPerformance", mock.Anything, from, to).Return([]RulePerformance{
+		{Day: day1, Dimension: PerformanceRule, Name: "GEO_LOCATION_RISK", PerformanceMetrics: PerformanceMetrics{
+			Evaluated: 100, Labeled: 10, Frauds: 4, Hits: 10, TruePositives: 3, FalsePositives: 1, Precision: 0.75,
+		}},
+		{Day: day2, Dimension: PerformanceRule, Name: "GEO_LOCATION_RISK", PerformanceMetrics: PerformanceMetrics{
+			Evaluated: 300, Labeled: 10, Frauds: 6, Hits: 10, TruePositives: 1, FalsePositives: 3, Precision: 0.25,
+		}},
+		{Day: day1, Dimension: PerformanceDecision, Name: "BLOCK", PerformanceMetrics: PerformanceMetrics{
+			Evaluated: 100, Labeled: 10, Frauds: 4, Hits: 2, TruePositives: 2,
+		}},
+	}, nil)
+
+	report, err := NewRulePerformanceService(repo).Report(context.Background(), from, to)
+	assert.NoError(t, err)
+	assert.Len(t, report.Daily, 3)
+
+	// Rates come from summed counts, not the mean of daily rates
+	geo := report.Rules["GEO_LOCATION_RISK"]
+	assert.Equal(t, int64(400), geo.Evaluated)
+	assert.InDelta(t, 0.5, geo.Precision, 1e-9)
+	assert.InDelta(t, 0.4, geo.Recall, 1e-9)
+	assert.InDelta(t, 0.05, geo.HitRate, 1e-9)
+
+	block := report.Decisions["BLOCK"]
+	assert.InDelta(t, 1.0, block.Precision, 1e-9)
+	assert.InDelta(t, 0.5, block.Recall, 1e-9)
+
+	_, err = NewRulePerformanceService(repo).Report(context.Background(), to, from)
+	assert.ErrorIs(t, err, ErrInvalidReportWindow)
+}
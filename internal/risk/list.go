@@ -0,0 +1,253 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WithLists checks every transaction against the blocklist and allowlist
+// before scoring.
+func WithLists(index *ListIndex) Option {
+	return func(s *service) {
+		s.lists = index
+	}
+}
+
+func (s *service) matchLists(tx TransactionDTO) ListMatch {
+	if s.lists == nil {
+		return ListMatch{}
+	}
+	return s.lists.Match(tx, time.Now())
+}
+
+// ListMatch is the blocklist and allowlist entries a transaction hit. Block
+// wins over any allow entry.
+type ListMatch struct {
+	Block *ListEntry
+	Allow []ListEntry
+}
+
+// skips reports whether an allow entry skips the scorer or rule name.
+func (m ListMatch) skips(name string) bool {
+	for _, e := range m.Allow {
+		if e.SkipAll {
+			return true
+		}
+		for _, s := range e.SkipScorers {
+			if s == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *service) SkippableNames() []string {
+	var names []string
+	for _, sc := range s.getScorers() {
+		names = append(names, sc.Name())
+	}
+	live, shadow := s.ruleSets()
+	for _, rs := range []*ruleSet{live, shadow} {
+		if rs == nil {
+			continue
+		}
+		for name := range rs.rules {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// reasons records every hit so the evaluation shows why it was forced or
+// why scorers were skipped.
+func (m ListMatch) reasons(txID uuid.UUID) []RiskReason {
+	var out []RiskReason
+
+	add := func(e ListEntry, rawScore int) {
+		out = append(out, RiskReason{
+			TransactionID: txID,
+			RuleName:      e.ListType + "LIST",
+			ReasonCode:    e.ListType + "LIST_" + e.EntryType,
+			RawScore:      rawScore,
+			Weight:        100,
+			WeightedScore: rawScore,
+			Inputs: map[string]interface{}{
+				"entry_id":     e.ID,
+				"value":        e.Value,
+				"reason":       e.Reason,
+				"skip_scorers": e.SkipScorers,
+				"skip_all":     e.SkipAll,
+			},
+		})
+	}
+
+	if m.Block != nil {
+		add(*m.Block, 100)
+		return out
+	}
+	for _, e := range m.Allow {
+		add(e, 0)
+	}
+	return out
+}
+
+type listKey struct {
+	entryType string
+	value     string
+}
+
+type prefixEntry struct {
+	prefix netip.Prefix
+	entry  ListEntry
+}
+
+// listSet indexes the active entries of one list.
+type listSet struct {
+	values   map[listKey]ListEntry
+	prefixes []prefixEntry
+}
+
+func newListSet() *listSet {
+	return &listSet{values: make(map[listKey]ListEntry)}
+}
+
+func (ls *listSet) add(e ListEntry) error {
+	if e.EntryType == ListEntryIP {
+		prefix, err := parseIPEntry(e.Value)
+		if err != nil {
+			return fmt.Errorf("list entry %d: %w", e.ID, err)
+		}
+		if !prefix.IsSingleIP() {
+			ls.prefixes = append(ls.prefixes, prefixEntry{prefix: prefix, entry: e})
+			return nil
+		}
+		e.Value = prefix.Addr().String()
+	}
+	ls.values[listKey{e.EntryType, e.Value}] = e
+	return nil
+}
+
+// match returns the entries hit by tx that are still active at now.
+func (ls *listSet) match(tx TransactionDTO, now time.Time) []ListEntry {
+	var out []ListEntry
+
+	active := func(e ListEntry) bool {
+		return e.ExpiresAt == nil || now.Before(*e.ExpiresAt)
+	}
+	lookup := func(entryType, value string) {
+		if value == "" {
+			return
+		}
+		if e, ok := ls.values[listKey{entryType, value}]; ok && active(e) {
+			out = append(out, e)
+		}
+	}
+
+	lookup(ListEntryUser, tx.UserID.String())
+	lookup(ListEntryDevice, tx.DeviceID)
+	if tx.ReceiverID != nil {
+		lookup(ListEntryReceiver, tx.ReceiverID.String())
+	}
+
+	if addr, err := netip.ParseAddr(tx.IPAddress); err == nil {
+		addr = addr.Unmap()
+		lookup(ListEntryIP, addr.String())
+		// Ranges are sorted most specific first
+		for _, p := range ls.prefixes {
+			if p.prefix.Contains(addr) && active(p.entry) {
+				out = append(out, p.entry)
+			}
+		}
+	}
+	return out
+}
+
+// ListIndex holds the active list entries in memory so they can be checked
+// on every transaction without a query. It is rebuilt by Reload after every
+// change and periodically to pick up changes made by other instances.
+type ListIndex struct {
+	repo  ListRepository
+	mu    sync.RWMutex
+	block *listSet
+	allow *listSet
+}
+
+func NewListIndex(repo ListRepository) *ListIndex {
+	return &ListIndex{
+		repo:  repo,
+		block: newListSet(),
+		allow: newListSet(),
+	}
+}
+
+// Reload replaces the index with the entries active now.
+func (x *ListIndex) Reload(ctx context.Context) error {
+	entries, err := x.repo.ListActiveEntries(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	block, allow := newListSet(), newListSet()
+	for _, e := range entries {
+		set := allow
+		if e.ListType == ListBlock {
+			set = block
+		}
+		if err := set.add(e); err != nil {
+			return err
+		}
+	}
+	for _, set := range []*listSet{block, allow} {
+		sort.SliceStable(set.prefixes, func(i, j int) bool {
+			return set.prefixes[i].prefix.Bits() > set.prefixes[j].prefix.Bits()
+		})
+	}
+
+	x.mu.Lock()
+	x.block = block
+	x.allow = allow
+	x.mu.Unlock()
+
+	return nil
+}
+
+// Match checks tx against both lists.
+func (x *ListIndex) Match(tx TransactionDTO, now time.Time) ListMatch {
+	x.mu.RLock()
+	block, allow := x.block, x.allow
+	x.mu.RUnlock()
+
+	var m ListMatch
+	if hits := block.match(tx, now); len(hits) > 0 {
+		m.Block = &hits[0]
+	}
+	m.Allow = allow.match(tx, now)
+	return m
+}
+
+// parseIPEntry reads an address or CIDR range, masking host bits.
+func parseIPEntry(value string) (netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(value); err == nil {
+		addr, bits := prefix.Addr(), prefix.Bits()
+		if addr.Is4In6() {
+			if bits < 96 {
+				return netip.Prefix{}, fmt.Errorf("invalid IP or CIDR %q", value)
+			}
+			addr, bits = addr.Unmap(), bits-96
+		}
+		return netip.PrefixFrom(addr, bits).Masked(), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid IP or CIDR %q", value)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
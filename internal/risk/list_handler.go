@@ -0,0 +1,72 @@
+package risk
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ListHandler struct {
+	service ListService
+}
+
+func NewListHandler(service ListService) *ListHandler {
+	return &ListHandler{service: service}
+}
+
+// ListEntries handles GET /api/v1/admin/lists?list_type=BLOCK&entry_type=IP.
+func (h *ListHandler) ListEntries(c *gin.Context) {
+	entries, err := h.service.ListEntries(c.Request.Context(), c.Query("list_type"), c.Query("entry_type"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": entries})
+}
+
+// CreateEntry handles POST /api/v1/admin/lists.
+func (h *ListHandler) CreateEntry(c *gin.Context) {
+	var req ListEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	entry, err := h.service.CreateEntry(c.Request.Context(), c.GetString("user_id"), req)
+	if err != nil {
+		writeListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// DeleteEntry handles DELETE /api/v1/admin/lists/:id.
+func (h *ListHandler) DeleteEntry(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid list entry id"})
+		return
+	}
+
+	if err := h.service.DeleteEntry(c.Request.Context(), c.GetString("user_id"), id); err != nil {
+		writeListError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func writeListError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrListEntryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "list entry not found"})
+	case errors.Is(err, ErrInvalidListEntry):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,85 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrListEntryNotFound = errors.New("list entry not found")
+
+type listRepository struct {
+	db *gorm.DB
+}
+
+func NewListRepository(db *gorm.DB) ListRepository {
+	return &listRepository{db: db}
+}
+
+func (r *listRepository) ListEntries(ctx context.Context, listType string, entryType string) ([]ListEntry, error) {
+	var entries []ListEntry
+
+	q := r.db.WithContext(ctx)
+	if listType != "" {
+		q = q.Where("list_type = ?", listType)
+	}
+	if entryType != "" {
+		q = q.Where("entry_type = ?", entryType)
+	}
+
+	err := q.Order("id ASC").Find(&entries).Error
+	return entries, err
+}
+
+func (r *listRepository) ListActiveEntries(ctx context.Context, now time.Time) ([]ListEntry, error) {
+	var entries []ListEntry
+	err := r.db.WithContext(ctx).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *listRepository) UpsertEntry(ctx context.Context, entry *ListEntry) (*ListEntry, error) {
+	var previous *ListEntry
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []ListEntry
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("list_type = ? AND entry_type = ? AND value = ?", entry.ListType, entry.EntryType, entry.Value).
+			Limit(1).
+			Find(&existing).Error
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			previous = &existing[0]
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "list_type"}, {Name: "entry_type"}, {Name: "value"}},
+			DoUpdates: clause.AssignmentColumns([]string{"reason", "skip_scorers", "skip_all", "expires_at", "created_by", "created_at"}),
+		}).
+			Create(entry).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+func (r *listRepository) DeleteEntry(ctx context.Context, id int64) (*ListEntry, error) {
+	var entry ListEntry
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Where("id = ?", id).
+		Delete(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	if entry.ID == 0 {
+		return nil, ErrListEntryNotFound
+	}
+	return &entry, nil
+}
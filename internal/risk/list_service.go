@@ -0,0 +1,207 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"risk-detection/internal/audit"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidListEntry = errors.New("invalid list entry")
+
+type listService struct {
+	repo        ListRepository
+	index       *ListIndex
+	riskService Service
+	auditLog    *audit.Logger
+}
+
+// NewListService manages blocklist and allowlist entries. Every change is
+// audited and reloads the in-memory index the risk service matches against.
+// Skipped scorers must be known to riskService.
+func NewListService(repo ListRepository, index *ListIndex, riskService Service, auditLog *audit.Logger) ListService {
+	return &listService{
+		repo:        repo,
+		index:       index,
+		riskService: riskService,
+		auditLog:    auditLog,
+	}
+}
+
+func (s *listService) ListEntries(ctx context.Context, listType string, entryType string) ([]ListEntry, error) {
+	return s.repo.ListEntries(ctx, strings.ToUpper(listType), strings.ToUpper(entryType))
+}
+
+func (s *listService) CreateEntry(ctx context.Context, actorID string, req ListEntryRequest) (*ListEntry, error) {
+	entry := ListEntry{
+		ListType:    strings.ToUpper(strings.TrimSpace(req.ListType)),
+		EntryType:   strings.ToUpper(strings.TrimSpace(req.EntryType)),
+		Reason:      strings.TrimSpace(req.Reason),
+		SkipScorers: req.SkipScorers,
+		SkipAll:     req.SkipAll,
+		ExpiresAt:   req.ExpiresAt,
+		CreatedBy:   actorID,
+		CreatedAt:   time.Now(),
+	}
+
+	value, err := normalizeListValue(entry.EntryType, req.Value)
+	if err != nil {
+		return nil, err
+	}
+	entry.Value = value
+
+	if err := validateListEntry(entry); err != nil {
+		return nil, err
+	}
+	if err := s.validateSkipScorers(entry.SkipScorers); err != nil {
+		return nil, err
+	}
+
+	previous, err := s.repo.UpsertEntry(ctx, &entry)
+	if err != nil {
+		return nil, fmt.Errorf("create list entry: %w", err)
+	}
+
+	if previous != nil {
+		s.logChange(audit.EventListEntryUpdated, "UPDATE", actorID, previous, &entry)
+	} else {
+		s.logChange(audit.EventListEntryCreated, "CREATE", actorID, nil, &entry)
+	}
+
+	if err := s.index.Reload(ctx); err != nil {
+		return nil, fmt.Errorf("reload lists: %w", err)
+	}
+	return &entry, nil
+}
+
+func (s *listService) DeleteEntry(ctx context.Context, actorID string, id int64) error {
+	entry, err := s.repo.DeleteEntry(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	s.logChange(audit.EventListEntryDeleted, "DELETE", actorID, entry, nil)
+
+	if err := s.index.Reload(ctx); err != nil {
+		return fmt.Errorf("reload lists: %w", err)
+	}
+	return nil
+}
+
+// normalizeListValue stores values in the form transactions are matched in:
+// masked CIDR ranges, plain addresses and canonical UUIDs.
+func normalizeListValue(entryType string, value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	switch entryType {
+	case ListEntryIP:
+		prefix, err := parseIPEntry(value)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidListEntry, err)
+		}
+		if prefix.IsSingleIP() {
+			return prefix.Addr().String(), nil
+		}
+		return prefix.String(), nil
+	case ListEntryReceiver, ListEntryUser:
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return "", fmt.Errorf("%w: value must be a UUID", ErrInvalidListEntry)
+		}
+		return id.String(), nil
+	}
+	return value, nil
+}
+
+func validateListEntry(entry ListEntry) error {
+	if entry.ListType != ListBlock && entry.ListType != ListAllow {
+		return fmt.Errorf("%w: list_type must be BLOCK or ALLOW", ErrInvalidListEntry)
+	}
+	switch entry.EntryType {
+	case ListEntryIP, ListEntryDevice, ListEntryReceiver, ListEntryUser:
+	default:
+		return fmt.Errorf("%w: entry_type must be IP, DEVICE, RECEIVER or USER", ErrInvalidListEntry)
+	}
+	if entry.Value == "" {
+		return fmt.Errorf("%w: value is required", ErrInvalidListEntry)
+	}
+	if entry.Reason == "" {
+		return fmt.Errorf("%w: reason is required", ErrInvalidListEntry)
+	}
+	if entry.ListType == ListBlock && (len(entry.SkipScorers) > 0 || entry.SkipAll) {
+		return fmt.Errorf("%w: skip_scorers and skip_all only apply to ALLOW entries", ErrInvalidListEntry)
+	}
+	// Skipping everything has to be asked for, never implied by a missing list
+	if entry.ListType == ListAllow && (len(entry.SkipScorers) > 0) == entry.SkipAll {
+		return fmt.Errorf("%w: ALLOW entries need either skip_scorers or skip_all", ErrInvalidListEntry)
+	}
+	if entry.ExpiresAt != nil && !entry.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("%w: expires_at must be in the future", ErrInvalidListEntry)
+	}
+	return nil
+}
+
+// validateSkipScorers rejects names that are neither a scorer nor a rule.
+// A misspelled name would otherwise skip nothing without saying so.
+func (s *listService) validateSkipScorers(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	known := make(map[string]bool)
+	for _, name := range s.riskService.SkippableNames() {
+		known[name] = true
+	}
+	for _, name := range names {
+		if !known[name] {
+			return fmt.Errorf("%w: skip_scorers: %q is not a registered scorer or rule", ErrInvalidListEntry, name)
+		}
+	}
+	return nil
+}
+
+// logChange audits a change from before to after; before is nil for a new
+// entry and after is nil for a deleted one.
+func (s *listService) logChange(event audit.EventType, action string, actorID string, before *ListEntry, after *ListEntry) {
+	if s.auditLog == nil {
+		return
+	}
+
+	values := func(entry *ListEntry) map[string]interface{} {
+		if entry == nil {
+			return nil
+		}
+		return map[string]interface{}{
+			"list_type":    entry.ListType,
+			"entry_type":   entry.EntryType,
+			"value":        entry.Value,
+			"reason":       entry.Reason,
+			"skip_scorers": entry.SkipScorers,
+			"skip_all":     entry.SkipAll,
+			"expires_at":   entry.ExpiresAt,
+		}
+	}
+
+	entity := after
+	if entity == nil {
+		entity = before
+	}
+	s.auditLog.Log(audit.AuditLog{
+		EventType:  event,
+		Action:     action,
+		EntityType: "list_entries",
+		EntityID:   strconv.FormatInt(entity.ID, 10),
+		ActorType:  "USER",
+		ActorID:    actorID,
+		ActorRole:  "ADMIN",
+		OldValues:  values(before),
+		NewValues:  values(after),
+		Status:     "SUCCESS",
+	})
+}
@@ -0,0 +1,315 @@
+package risk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"risk-detection/internal/audit"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockListRepository struct {
+	mock.Mock
+}
+
+func (m *MockListRepository) ListEntries(ctx context.Context, listType string, entryType string) ([]ListEntry, error) {
+	args := m.Called(ctx, listType, entryType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]ListEntry), args.Error(1)
+}
+
+func (m *MockListRepository) ListActiveEntries(ctx context.Context, now time.Time) ([]ListEntry, error) {
+	args := m.Called(ctx, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]ListEntry), args.Error(1)
+}
+
+func (m *MockListRepository) UpsertEntry(ctx context.Context, entry *ListEntry) (*ListEntry, error) {
+	args := m.Called(ctx, entry)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ListEntry), args.Error(1)
+}
+
+func (m *MockListRepository) DeleteEntry(ctx context.Context, id int64) (*ListEntry, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ListEntry), args.Error(1)
+}
+
+func newTestListIndex(t *testing.T, entries ...ListEntry) *ListIndex {
+	repo := new(MockListRepository)
+	repo.On("ListActiveEntries", mock.Anything, mock.Anything).Return(entries, nil)
+
+	index := NewListIndex(repo)
+	assert.NoError(t, index.Reload(context.Background()))
+	return index
+}
+
+func TestListIndex_Match(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	receiverID := uuid.New()
+	userID := uuid.New()
+
+	index := newTestListIndex(t,
+		ListEntry{ID: 1, ListType: ListBlock, EntryType: ListEntryIP, Value: "203.0.113.9"},
+		ListEntry{ID: 2, ListType: ListBlock, EntryType: ListEntryIP, Value: "198.51.100.0/24"},
+		ListEntry{ID: 3, ListType: ListBlock, EntryType: ListEntryDevice, Value: "emulator-1"},
+		ListEntry{ID: 4, ListType: ListBlock, EntryType: ListEntryReceiver, Value: receiverID.String()},
+		ListEntry{ID: 5, ListType: ListAllow, EntryType: ListEntryUser, Value: userID.String()},
+		ListEntry{ID: 6, ListType: ListBlock, EntryType: ListEntryDevice, Value: "expired", ExpiresAt: &past},
+	)
+
+	tests := []struct {
+		name          string
+		tx            TransactionDTO
+		expectedBlock int64
+		expectedAllow int
+		description   string
+	}{
+		{
+			name:        "no_hit",
+			tx:          TransactionDTO{UserID: uuid.New(), DeviceID: "phone", IPAddress: "192.0.2.1"},
+			description: "Unlisted transactions should not match",
+		},
+		{
+			name:          "exact_ip",
+			tx:            TransactionDTO{UserID: uuid.New(), IPAddress: "203.0.113.9"},
+			expectedBlock: 1,
+			description:   "A listed address should match",
+		},
+		{
+			name:          "ipv4_mapped_ip",
+			tx:            TransactionDTO{UserID: uuid.New(), IPAddress: "::ffff:203.0.113.9"},
+			expectedBlock: 1,
+			description:   "IPv4-mapped IPv6 addresses should match their IPv4 entry",
+		},
+		{
+			name:          "cidr_range",
+			tx:            TransactionDTO{UserID: uuid.New(), IPAddress: "198.51.100.77"},
+			expectedBlock: 2,
+			description:   "An address inside a listed range should match",
+		},
+		{
+			name:          "device",
+			tx:            TransactionDTO{UserID: uuid.New(), DeviceID: "emulator-1"},
+			expectedBlock: 3,
+			description:   "A listed device should match",
+		},
+		{
+			name:          "receiver",
+			tx:            TransactionDTO{UserID: uuid.New(), ReceiverID: &receiverID},
+			expectedBlock: 4,
+			description:   "A listed receiver should match",
+		},
+		{
+			name:          "allowed_user_on_blocked_device",
+			tx:            TransactionDTO{UserID: userID, DeviceID: "emulator-1"},
+			expectedBlock: 3,
+			expectedAllow: 1,
+			description:   "Both lists are reported; the block entry decides",
+		},
+		{
+			name:        "expired_entry",
+			tx:          TransactionDTO{UserID: uuid.New(), DeviceID: "expired"},
+			description: "Expired entries should not match",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := index.Match(tt.tx, now)
+			if tt.expectedBlock == 0 {
+				assert.Nil(t, m.Block, tt.description)
+			} else if assert.NotNil(t, m.Block, tt.description) {
+				assert.Equal(t, tt.expectedBlock, m.Block.ID, tt.description)
+			}
+			assert.Len(t, m.Allow, tt.expectedAllow, tt.description)
+		})
+	}
+}
+
+func TestCalculateRisk_Lists(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name             string
+		entry            ListEntry
+		expectedScore    int
+		expectedDecision string
+		expectedCodes    []string
+		fixedCalled      bool
+		description      string
+	}{
+		{
+			name:             "blocklist_forces_block",
+			entry:            ListEntry{ID: 1, ListType: ListBlock, EntryType: ListEntryUser, Value: userID.String(), Reason: "confirmed takeover"},
+			expectedScore:    100,
+			expectedDecision: "BLOCK",
+			expectedCodes:    []string{"BLOCKLIST_USER"},
+			fixedCalled:      false,
+			description:      "A blocklist hit should block without running scorers",
+		},
+		{
+			name:             "allowlist_skips_chosen_scorer",
+			entry:            ListEntry{ID: 2, ListType: ListAllow, EntryType: ListEntryUser, Value: userID.String(), Reason: "payroll account", SkipScorers: []string{"FIXED"}},
+			expectedScore:    20,
+			expectedDecision: "ALLOW",
+			expectedCodes:    []string{"ALLOWLIST_USER", "BIG_AMOUNT"},
+			fixedCalled:      false,
+			description:      "Only the chosen scorer should be skipped",
+		},
+		{
+			name:             "allowlist_skips_everything",
+			entry:            ListEntry{ID: 3, ListType: ListAllow, EntryType: ListEntryUser, Value: userID.String(), Reason: "internal test user", SkipAll: true},
+			expectedScore:    0,
+			expectedDecision: "ALLOW",
+			expectedCodes:    []string{"ALLOWLIST_USER"},
+			fixedCalled:      false,
+			description:      "An entry with skip_all should skip every scorer and rule",
+		},
+		{
+			name:             "other_user",
+			entry:            ListEntry{ID: 4, ListType: ListBlock, EntryType: ListEntryUser, Value: uuid.NewString(), Reason: "someone else"},
+			expectedScore:    60,
+			expectedDecision: "FLAG",
+			expectedCodes:    []string{"FIXED", "BIG_AMOUNT"},
+			fixedCalled:      true,
+			description:      "Unlisted users are scored normally",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockTransactionRiskRepository)
+			mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{
+				{Name: "FIXED", Enabled: true, Weight: 100},
+				{Name: "BIG_AMOUNT", Enabled: true, Weight: 100, Threshold: 20, Expression: `tx.amount > 100`},
+			}, nil)
+			mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("GetBehaviorByUserID", mock.Anything, mock.Anything).Return(&UserBehavior{UserID: userID}, nil)
+			mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
+
+			called := false
+			fixed := ScorerFunc{
+				RuleName: "FIXED",
+				Fn: func(ctx context.Context, tx TransactionDTO) (int, error) {
+					called = true
+					return 40, nil
+				},
+			}
+
+			svc, err := NewService(mockRepo, nil, &audit.Logger{}, WithScorers(fixed), WithLists(newTestListIndex(t, tt.entry)))
+			assert.NoError(t, err)
+
+			result, err := svc.CalculateRisk(context.Background(), &TransactionDTO{TxID: uuid.New(), UserID: userID, Amount: 500, TxTime: time.Now()})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedScore, result.RiskScore, tt.description)
+			assert.Equal(t, tt.expectedDecision, result.Decision, tt.description)
+			assert.Equal(t, tt.fixedCalled, called, tt.description)
+
+			var codes []string
+			for _, r := range result.Reasons {
+				codes = append(codes, r.ReasonCode)
+			}
+			assert.Equal(t, tt.expectedCodes, codes, tt.description)
+		})
+	}
+}
+
+func TestSkippableNames(t *testing.T) {
+	svc := &service{
+		live:   &ruleSet{rules: map[string]RiskRule{"HIGH_VALUE_NIGHT": {Name: "HIGH_VALUE_NIGHT"}}},
+		shadow: &ruleSet{rules: map[string]RiskRule{"CANDIDATE_RULE": {Name: "CANDIDATE_RULE"}}},
+	}
+	svc.registerDefaultScorers()
+
+	names := svc.SkippableNames()
+	assert.Contains(t, names, RuleNewDevice)
+	assert.Contains(t, names, "HIGH_VALUE_NIGHT")
+	assert.Contains(t, names, "CANDIDATE_RULE")
+}
+
+func TestListService_CreateEntry(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+
+	repo := new(MockListRepository)
+	repo.On("UpsertEntry", mock.Anything, mock.Anything).Return(nil, nil)
+	repo.On("ListActiveEntries", mock.Anything, mock.Anything).Return([]ListEntry{}, nil)
+
+	riskSvc := new(MockRiskService)
+	riskSvc.On("SkippableNames").Return([]string{RuleNewDevice, "HIGH_VALUE_NIGHT"})
+	svc := NewListService(repo, NewListIndex(repo), riskSvc, &audit.Logger{})
+
+	entry, err := svc.CreateEntry(context.Background(), "admin-1", ListEntryRequest{
+		ListType: "block", EntryType: "ip", Value: " 198.51.100.17/24 ", Reason: "botnet",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, ListBlock, entry.ListType)
+	assert.Equal(t, "198.51.100.0/24", entry.Value, "Ranges should be stored masked")
+	assert.Equal(t, "admin-1", entry.CreatedBy)
+	repo.AssertCalled(t, "ListActiveEntries", mock.Anything, mock.Anything)
+
+	invalid := []ListEntryRequest{
+		{ListType: ListBlock, EntryType: ListEntryIP, Value: "not-an-ip", Reason: "x"},
+		{ListType: ListBlock, EntryType: ListEntryReceiver, Value: "not-a-uuid", Reason: "x"},
+		{ListType: ListBlock, EntryType: ListEntryDevice, Value: "d1", Reason: "x", SkipScorers: []string{RuleNewDevice}},
+		{ListType: ListBlock, EntryType: ListEntryDevice, Value: "d1", Reason: "x", SkipAll: true},
+		{ListType: ListAllow, EntryType: ListEntryDevice, Value: "d1", Reason: "x", SkipAll: true, ExpiresAt: &past},
+		{ListType: ListAllow, EntryType: ListEntryDevice, Value: "d1", SkipAll: true},
+		{ListType: ListAllow, EntryType: ListEntryDevice, Value: "d1", Reason: "x"},
+		{ListType: ListAllow, EntryType: ListEntryDevice, Value: "d1", Reason: "x", SkipAll: true, SkipScorers: []string{RuleNewDevice}},
+		{ListType: ListAllow, EntryType: ListEntryDevice, Value: "d1", Reason: "x", SkipScorers: []string{RuleNewDevice, "NEW_DEVICE"}},
+	}
+	for _, req := range invalid {
+		_, err := svc.CreateEntry(context.Background(), "admin-1", req)
+		assert.ErrorIs(t, err, ErrInvalidListEntry, "%+v", req)
+	}
+	repo.AssertNumberOfCalls(t, "UpsertEntry", 1)
+}
+
+func TestListService_CreateEntry_ReplacesExisting(t *testing.T) {
+	previous := &ListEntry{ID: 9, ListType: ListAllow, EntryType: ListEntryDevice, Value: "d1", Reason: "old", SkipAll: true}
+
+	repo := new(MockListRepository)
+	repo.On("UpsertEntry", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*ListEntry).ID = previous.ID
+	}).Return(previous, nil)
+	repo.On("ListActiveEntries", mock.Anything, mock.Anything).Return([]ListEntry{}, nil)
+
+	riskSvc := new(MockRiskService)
+	riskSvc.On("SkippableNames").Return([]string{RuleNewDevice})
+	svc := NewListService(repo, NewListIndex(repo), riskSvc, &audit.Logger{})
+
+	entry, err := svc.CreateEntry(context.Background(), "admin-1", ListEntryRequest{
+		ListType: ListAllow, EntryType: ListEntryDevice, Value: "d1", Reason: "narrowed", SkipScorers: []string{RuleNewDevice},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), entry.ID)
+	assert.False(t, entry.SkipAll, "The replacement should only skip the listed scorer")
+}
+
+func TestListService_DeleteEntry(t *testing.T) {
+	repo := new(MockListRepository)
+	repo.On("DeleteEntry", mock.Anything, int64(7)).Return(&ListEntry{ID: 7, ListType: ListBlock, EntryType: ListEntryDevice, Value: "d1"}, nil)
+	repo.On("DeleteEntry", mock.Anything, int64(8)).Return(nil, ErrListEntryNotFound)
+	repo.On("ListActiveEntries", mock.Anything, mock.Anything).Return([]ListEntry{}, nil)
+
+	svc := NewListService(repo, NewListIndex(repo), new(MockRiskService), &audit.Logger{})
+
+	assert.NoError(t, svc.DeleteEntry(context.Background(), "admin-1", 7))
+	assert.ErrorIs(t, svc.DeleteEntry(context.Background(), "admin-1", 8), ErrListEntryNotFound)
+	repo.AssertNumberOfCalls(t, "ListActiveEntries", 1)
+}
@@ -0,0 +1,249 @@
+// Package ml evaluates fraud models exported to JSON by an offline training
+// pipeline. Logistic regressions and gradient-boosted tree ensembles are
+// supported; evaluation is pure Go with no runtime dependencies.
+package ml
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Model types.
+const (
+	TypeLogistic = "logistic_regression"
+	TypeTrees    = "gradient_boosted_trees"
+)
+
+// Model is a trained model as exported to JSON. Features lists the model's
+// input names in column order; FeatureMap renames them to the names the
+// caller provides values under, and names absent from it are used as is.
+type Model struct {
+	Version    string            `json:"version"`
+	Type       string            `json:"type"`
+	Features   []string          `json:"features"`
+	FeatureMap map[string]string `json:"feature_map,omitempty"`
+
+	// Logistic regression
+	Intercept    float64   `json:"intercept,omitempty"`
+	Coefficients []float64 `json:"coefficients,omitempty"`
+
+	// Gradient-boosted trees. The margin is BaseScore plus the leaf of
+	// every tree.
+	BaseScore float64 `json:"base_score,omitempty"`
+	Trees     []Tree  `json:"trees,omitempty"`
+
+	// Calibration maps the raw margin to a probability. Without it the
+	// margin goes through a plain sigmoid.
+	Calibration *Calibration `json:"calibration,omitempty"`
+}
+
+// Tree is one tree of an ensemble. Nodes[0] is the root.
+type Tree struct {
+	Nodes []Node `json:"nodes"`
+}
+
+// Node is a split or, when Leaf is set, a leaf. A split sends values below
+// Threshold left; a missing value goes left when DefaultLeft is set.
+type Node struct {
+	Feature     int      `json:"feature"`
+	Threshold   float64  `json:"threshold"`
+	Left        int      `json:"left"`
+	Right       int      `json:"right"`
+	DefaultLeft bool     `json:"default_left"`
+	Leaf        *float64 `json:"leaf,omitempty"`
+}
+
+// Calibration is Platt scaling: p = sigmoid(Slope*margin + Intercept).
+type Calibration struct {
+	Slope     float64 `json:"slope"`
+	Intercept float64 `json:"intercept"`
+}
+
+// Load reads a model file from disk.
+func Load(path string) (*Model, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Read parses and validates a model.
+func Read(r io.Reader) (*Model, error) {
+	var m Model
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("parse model: %w", err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Validate checks that the model can be evaluated without going out of
+// bounds or looping.
+func (m *Model) Validate() error {
+	if m.Version == "" {
+		return errors.New("model version is required")
+	}
+	if len(m.Features) == 0 {
+		return errors.New("model has no features")
+	}
+	seen := make(map[string]bool, len(m.Features))
+	for _, f := range m.Features {
+		if seen[f] {
+			return fmt.Errorf("duplicate feature %q", f)
+		}
+		seen[f] = true
+	}
+	for f := range m.FeatureMap {
+		if !seen[f] {
+			return fmt.Errorf("feature_map renames unknown feature %q", f)
+		}
+	}
+
+	switch m.Type {
+	case TypeLogistic:
+		if len(m.Coefficients) != len(m.Features) {
+			return fmt.Errorf("%d coefficients for %d features", len(m.Coefficients), len(m.Features))
+		}
+	case TypeTrees:
+		if len(m.Trees) == 0 {
+			return errors.New("model has no trees")
+		}
+		for i, t := range m.Trees {
+			if err := t.validate(len(m.Features)); err != nil {
+				return fmt.Errorf("tree %d: %w", i, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown model type %q", m.Type)
+	}
+
+	if m.Calibration != nil && m.Calibration.Slope == 0 {
+		return errors.New("calibration slope must not be zero")
+	}
+	return nil
+}
+
+// validate requires children to come after their parent, which rules out
+// cycles.
+func (t Tree) validate(features int) error {
+	if len(t.Nodes) == 0 {
+		return errors.New("empty tree")
+	}
+	for i, n := range t.Nodes {
+		if n.Leaf != nil {
+			continue
+		}
+		if n.Feature < 0 || n.Feature >= features {
+			return fmt.Errorf("node %d: feature index %d out of range", i, n.Feature)
+		}
+		for _, child := range []int{n.Left, n.Right} {
+			if child <= i || child >= len(t.Nodes) {
+				return fmt.Errorf("node %d: child %d out of range", i, child)
+			}
+		}
+	}
+	return nil
+}
+
+// Inputs returns the caller-side name of each feature in column order.
+func (m *Model) Inputs() []string {
+	out := make([]string, len(m.Features))
+	for i, f := range m.Features {
+		if mapped, ok := m.FeatureMap[f]; ok {
+			out[i] = mapped
+		} else {
+			out[i] = f
+		}
+	}
+	return out
+}
+
+// Vector builds the input row from named values. Missing values are NaN.
+func (m *Model) Vector(values map[string]float64) []float64 {
+	x := make([]float64, len(m.Features))
+	for i, name := range m.Inputs() {
+		v, ok := values[name]
+		if !ok {
+			v = math.NaN()
+		}
+		x[i] = v
+	}
+	return x
+}
+
+// Margin is the uncalibrated log-odds of x. A logistic regression treats a
+// missing value as zero.
+func (m *Model) Margin(x []float64) float64 {
+	switch m.Type {
+	case TypeLogistic:
+		margin := m.Intercept
+		for i, c := range m.Coefficients {
+			if i < len(x) && !math.IsNaN(x[i]) {
+				margin += c * x[i]
+			}
+		}
+		return margin
+	case TypeTrees:
+		margin := m.BaseScore
+		for _, t := range m.Trees {
+			margin += t.leaf(x)
+		}
+		return margin
+	}
+	return 0
+}
+
+// Predict returns the calibrated probability of fraud for x.
+func (m *Model) Predict(x []float64) float64 {
+	margin := m.Margin(x)
+	if m.Calibration != nil {
+		margin = m.Calibration.Slope*margin + m.Calibration.Intercept
+	}
+	return sigmoid(margin)
+}
+
+func (t Tree) leaf(x []float64) float64 {
+	i := 0
+	for {
+		n := t.Nodes[i]
+		if n.Leaf != nil {
+			return *n.Leaf
+		}
+
+		v := math.NaN()
+		if n.Feature < len(x) {
+			v = x[n.Feature]
+		}
+		switch {
+		case math.IsNaN(v):
+			if n.DefaultLeft {
+				i = n.Left
+			} else {
+				i = n.Right
+			}
+		case v < n.Threshold:
+			i = n.Left
+		default:
+			i = n.Right
+		}
+	}
+}
+
+func sigmoid(z float64) float64 {
+	return 1 / (1 + math.Exp(-z))
+}
@@ -0,0 +1,106 @@
+package ml
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testLogistic = `{
+  "version": "lr-1",
+  "type": "logistic_regression",
+  "features": ["amount_z", "is_new"],
+  "feature_map": {"amount_z": "user.amount_zscore"},
+  "intercept": -2,
+  "coefficients": [1, 3]
+}`
+
+// testTrees splits on amount at 1000 and, above it, on is_new; a missing
+// amount takes the low branch.
+const testTrees = `{
+  "version": "gbt-7",
+  "type": "gradient_boosted_trees",
+  "features": ["amount", "is_new"],
+  "base_score": -1,
+  "trees": [
+    {"nodes": [
+      {"feature": 0, "threshold": 1000, "left": 1, "right": 2, "default_left": true},
+      {"leaf": -1},
+      {"feature": 1, "threshold": 0.5, "left": 3, "right": 4},
+      {"leaf": 0.5},
+      {"leaf": 2}
+    ]},
+    {"nodes": [{"leaf": 0.25}]}
+  ],
+  "calibration": {"slope": 2, "intercept": 0}
+}`
+
+func TestRead_Logistic(t *testing.T) {
+	m, err := Read(strings.NewReader(testLogistic))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user.amount_zscore", "is_new"}, m.Inputs())
+
+	x := m.Vector(map[string]float64{"user.amount_zscore": 2, "is_new": 1})
+	assert.Equal(t, []float64{2, 1}, x)
+	assert.InDelta(t, 3.0, m.Margin(x), 1e-9)
+	assert.InDelta(t, sigmoid(3), m.Predict(x), 1e-9)
+
+	// A missing value contributes nothing
+	x = m.Vector(map[string]float64{"is_new": 1})
+	assert.True(t, math.IsNaN(x[0]))
+	assert.InDelta(t, 1.0, m.Margin(x), 1e-9)
+}
+
+func TestRead_Trees(t *testing.T) {
+	m, err := Read(strings.NewReader(testTrees))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		values         map[string]float64
+		expectedMargin float64
+	}{
+		{name: "low_amount", values: map[string]float64{"amount": 10, "is_new": 1}, expectedMargin: -1.75},
+		{name: "high_amount_known_user", values: map[string]float64{"amount": 5000, "is_new": 0}, expectedMargin: -0.25},
+		{name: "high_amount_new_user", values: map[string]float64{"amount": 5000, "is_new": 1}, expectedMargin: 1.25},
+		{name: "missing_amount_goes_default", values: map[string]float64{"is_new": 1}, expectedMargin: -1.75},
+		{name: "missing_flag_goes_right", values: map[string]float64{"amount": 5000}, expectedMargin: 1.25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := m.Vector(tt.values)
+			assert.InDelta(t, tt.expectedMargin, m.Margin(x), 1e-9)
+			assert.InDelta(t, sigmoid(2*tt.expectedMargin), m.Predict(x), 1e-9, "Calibration scales the margin")
+		})
+	}
+}
+
+func TestRead_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		model string
+	}{
+		{name: "not_json", model: `{`},
+		{name: "unknown_field", model: `{"version":"1","type":"logistic_regression","features":["a"],"coefficients":[1],"weights":[1]}`},
+		{name: "no_version", model: `{"type":"logistic_regression","features":["a"],"coefficients":[1]}`},
+		{name: "no_features", model: `{"version":"1","type":"logistic_regression"}`},
+		{name: "duplicate_feature", model: `{"version":"1","type":"logistic_regression","features":["a","a"],"coefficients":[1,1]}`},
+		{name: "unknown_type", model: `{"version":"1","type":"svm","features":["a"]}`},
+		{name: "coefficient_count", model: `{"version":"1","type":"logistic_regression","features":["a","b"],"coefficients":[1]}`},
+		{name: "map_unknown_feature", model: `{"version":"1","type":"logistic_regression","features":["a"],"coefficients":[1],"feature_map":{"b":"x"}}`},
+		{name: "no_trees", model: `{"version":"1","type":"gradient_boosted_trees","features":["a"]}`},
+		{name: "feature_out_of_range", model: `{"version":"1","type":"gradient_boosted_trees","features":["a"],"trees":[{"nodes":[{"feature":1,"left":1,"right":2},{"leaf":0},{"leaf":1}]}]}`},
+		{name: "cycle", model: `{"version":"1","type":"gradient_boosted_trees","features":["a"],"trees":[{"nodes":[{"feature":0,"left":0,"right":1},{"leaf":1}]}]}`},
+		{name: "zero_slope", model: `{"version":"1","type":"logistic_regression","features":["a"],"coefficients":[1],"calibration":{"slope":0,"intercept":1}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(tt.model))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -2,23 +2,61 @@ package risk
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type TransactionRisk struct {
-	TransactionID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	TransactionID uuid.UUID `gorm:"type:uuid;primaryKey" json:"transaction_id"`
 
-	RiskScore int    `gorm:"not null"`
-	RiskLevel string `gorm:"type:varchar(20);not null"`
-	Decision  string `gorm:"type:varchar(10);not null;check:decision IN ('ALLOW','FLAG','BLOCK')"`
+	RiskScore int    `gorm:"not null" json:"risk_score"`
+	RiskLevel string `gorm:"type:varchar(20);not null" json:"risk_level"`
+	Decision  string `gorm:"type:varchar(10);not null;check:decision IN ('ALLOW','FLAG','BLOCK')" json:"decision"`
 
-	EvaluatedAt time.Time `gorm:"type:timestamptz;not null;default:now()"`
+	EvaluatedAt time.Time `gorm:"type:timestamptz;not null;default:now()" json:"evaluated_at"`
+
+	// Decision policy that mapped the score to a level and decision
+	PolicyType    string `gorm:"type:varchar(20);not null;default:'DEFAULT'" json:"policy_type"`
+	PolicyVersion int    `gorm:"not null;default:0" json:"policy_version"`
+
+	// Score and decision of the shadow rule set, when one is configured.
+	// They are recorded for comparison and never enforced.
+	ShadowRiskScore *int    `json:"shadow_risk_score,omitempty"`
+	ShadowDecision  *string `gorm:"type:varchar(10)" json:"shadow_decision,omitempty"`
+
+	// Version of the model behind MODEL_RISK, when it was scored
+	ModelVersion *string `gorm:"type:varchar(64)" json:"model_version,omitempty"`
+
+	Reasons []RiskReason `gorm:"foreignKey:TransactionID;references:TransactionID" json:"reasons"`
+}
+
+// RiskReason records one contributing sub-check of a risk evaluation.
+// WeightedScore is the reason's raw score scaled by the rule weight; a rule
+// whose raw total was clamped at 100 can have reasons summing above it.
+type RiskReason struct {
+	ID            int64     `gorm:"primaryKey" json:"-"`
+	TransactionID uuid.UUID `gorm:"type:uuid;not null;index" json:"-"`
+
+	RuleName      string `gorm:"type:varchar(255);not null" json:"rule_name"`
+	ReasonCode    string `gorm:"type:varchar(64);not null" json:"reason_code"`
+	RawScore      int    `gorm:"not null" json:"raw_score"`
+	Weight        int    `gorm:"not null" json:"weight"`
+	WeightedScore int    `gorm:"not null" json:"weighted_score"`
+
+	Inputs map[string]interface{} `gorm:"type:jsonb;serializer:json" json:"inputs,omitempty"`
 }
 
+// BehaviorAllTypes is the TransactionType of a user's baseline across every
+// transaction type.
+const BehaviorAllTypes = "ALL"
+
+// UserBehavior is a user's baseline for one transaction type, or across all
+// of them when TransactionType is BehaviorAllTypes.
 type UserBehavior struct {
 	UserID            uuid.UUID `gorm:"type:uuid;primaryKey;column:user_id"`
+	TransactionType   string    `gorm:"type:varchar(20);primaryKey;column:transaction_type;default:'ALL'"`
 	TotalTransactions int64     `gorm:"column:total_transactions;not null;default:0"`
 
 	AvgTransactionAmount float64 `gorm:"column:avg_transaction_amount;type:numeric(18,2);not null;default:0"`
@@ -34,8 +72,18 @@ type UserBehavior struct {
 
 	HighValueThreshold float64 `gorm:"column:high_value_threshold;type:numeric(18,2)"` //p95
 
+	// HourOfWeek is the decayed count of transactions per UTC hour of week
+	HourOfWeek HourHistogram `gorm:"column:hour_of_week;type:jsonb;serializer:json"`
+
+	// Step-up challenges the user passed. Only the user-wide baseline keeps them.
+	VerifiedChallenges int64      `gorm:"column:verified_challenges;not null;default:0"`
+	LastVerifiedAt     *time.Time `gorm:"column:last_verified_at"`
+
 	// ---- Metadata ----
 	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime"`
+	// Version advances on every write so concurrent read-modify-write
+	// updates can detect each other
+	Version int64 `gorm:"column:version;not null;default:0"`
 }
 
 type UserSecurity struct {
@@ -45,51 +93,505 @@ type UserSecurity struct {
 	UpdatedAt time.Time `gorm:"type:timestamptz;not null;default:now()"`
 }
 
+// DailyAggregate is one day of a user's transactions of one type, or of all
+// types when TransactionType is BehaviorAllTypes.
 type DailyAggregate struct {
-	UserID    uuid.UUID
+	UserID          uuid.UUID
+	TransactionType string
 	TxnCount  int64
 	AvgAmount float64
 	P95Amount float64
 }
 
 type TransactionRiskRepository interface {
-	Create(risk *TransactionRisk) error
-	GetRiskByTransactionID(id uuid.UUID) (*TransactionRisk, error)
+	Create(ctx context.Context, risk *TransactionRisk) error
+	GetRiskByTransactionID(ctx context.Context, id uuid.UUID) (*TransactionRisk, error)
+	// GetBehaviorByUserID returns the user's baseline across all types, or
+	// nil if the user has none yet.
 	GetBehaviorByUserID(ctx context.Context, userID uuid.UUID) (*UserBehavior, error)
+	// GetTypeBehavior returns the user's baseline for one transaction type,
+	// or nil if the user has none yet.
+	GetTypeBehavior(ctx context.Context, userID uuid.UUID, txType string) (*UserBehavior, error)
 	GetDailyTransactionAggregate(ctx context.Context, from time.Time, to time.Time) ([]DailyAggregate, error)
-	UpdateBehaviorParams(ctx context.Context, userID uuid.UUID, stdDev float64, p95 float64) error
+	UpdateBehaviorParams(ctx context.Context, userID uuid.UUID, txType string, stdDev float64, p95 float64) error
+	// UpdateBehaviorPerTransaction writes behavior if its Version is still
+	// the stored one and advances Version. Otherwise it returns
+	// ErrBehaviorConflict and the caller has to re-read.
 	UpdateBehaviorPerTransaction(ctx context.Context, behavior *UserBehavior) error
 	CreateFirstBehavior(ctx context.Context, behavior *UserBehavior) error
 	GetDeviceInfo(ctx context.Context,  userID uuid.UUID)(*UserSecurity, error)
 	GetEnabledRules(ctx context.Context) ([]RiskRule, error)
+	// DecayHourOfWeek multiplies every hour-of-week bucket by factor and
+	// returns the number of users updated.
+	DecayHourOfWeek(ctx context.Context, factor float64) (int64, error)
+	// RecordVerifiedChallenge counts a passed step-up challenge on the
+	// user's baseline across all types.
+	RecordVerifiedChallenge(ctx context.Context, userID uuid.UUID, at time.Time) error
 }
 type RiskRule struct {
-	ID        int64
-	Name      string
-	Threshold int
-	Weight    int
-	Enabled   bool
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Threshold int    `json:"threshold"`
+	Weight    int    `json:"weight"`
+	Enabled   bool   `json:"enabled"`
+
+	// Expression is an optional boolean rule in the expr language. When set,
+	// the rule scores Threshold (or 100) whenever the expression matches.
+	Expression string `json:"expression"`
+
+	// RuleSet is LIVE or SHADOW
+	RuleSet string `json:"rule_set"`
 }
 
-type TransactionDTO struct {
-	TxID      uuid.UUID
-	UserID    uuid.UUID
-	Amount    float64
-	TxTime    time.Time
-	DeviceID  string
+type RuleRequest struct {
+	Name       string `json:"name" binding:"required"`
+	Threshold  int    `json:"threshold" binding:"gte=0,lte=100"`
+	Weight     int    `json:"weight" binding:"gte=0,lte=100"`
+	Enabled    *bool  `json:"enabled"`
+	Expression string `json:"expression"`
+	RuleSet    string `json:"rule_set" binding:"omitempty,oneof=LIVE SHADOW"`
+}
+
+// ShadowComparison counts evaluations by live and shadow decision.
+type ShadowComparison struct {
+	Decision       string
+	ShadowDecision string
+	Count          int64
+}
+
+// ShadowDecisionReport summarizes shadow results for one live decision.
+type ShadowDecisionReport struct {
+	Total         int64            `json:"total"`
+	Disagreements int64            `json:"disagreements"`
+	Rate          float64          `json:"disagreement_rate"`
+	ShadowCounts  map[string]int64 `json:"shadow_decisions"`
+}
+
+type ShadowReport struct {
+	From          time.Time                       `json:"from"`
+	To            time.Time                       `json:"to"`
+	Total         int64                           `json:"total"`
+	Disagreements int64                           `json:"disagreements"`
+	ByDecision    map[string]ShadowDecisionReport `json:"by_decision"`
+}
+
+type RuleRepository interface {
+	ListRules(ctx context.Context) ([]RiskRule, error)
+	GetRuleByID(ctx context.Context, id int64) (*RiskRule, error)
+	CreateRule(ctx context.Context, rule *RiskRule) error
+	UpdateRule(ctx context.Context, rule *RiskRule) error
+	GetShadowComparison(ctx context.Context, from time.Time, to time.Time) ([]ShadowComparison, error)
+}
+
+type RuleService interface {
+	ListRules(ctx context.Context) ([]RiskRule, error)
+	CreateRule(ctx context.Context, actorID string, req RuleRequest) (*RiskRule, error)
+	UpdateRule(ctx context.Context, actorID string, id int64, req RuleRequest) (*RiskRule, error)
+	SetRuleEnabled(ctx context.Context, actorID string, id int64, enabled bool) (*RiskRule, error)
+	ShadowReport(ctx context.Context, from time.Time, to time.Time) (*ShadowReport, error)
+}
+
+// DecisionPolicy maps a risk score to a level and decision for one
+// transaction type. Scores <= LowMax are LOW/ALLOW, scores <= MediumMax are
+// MEDIUM/FLAG and anything above is HIGH/BLOCK.
+type DecisionPolicy struct {
+	ID              int64     `json:"id"`
+	TransactionType string    `json:"transaction_type"`
+	Version         int       `json:"version"`
+	LowMax          int       `json:"low_max"`
+	MediumMax       int       `json:"medium_max"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type DecisionPolicyRequest struct {
+	LowMax    int `json:"low_max" binding:"gte=0"`
+	MediumMax int `json:"medium_max" binding:"gte=0"`
+}
+
+type PolicyRepository interface {
+	ListPolicies(ctx context.Context) ([]DecisionPolicy, error)
+	UpsertPolicy(ctx context.Context, policy *DecisionPolicy) error
+}
+
+type PolicyService interface {
+	ListPolicies(ctx context.Context) ([]DecisionPolicy, error)
+	UpsertPolicy(ctx context.Context, actorID string, transactionType string, req DecisionPolicyRequest) (*DecisionPolicy, error)
+}
+
+// Trust states of a UserDevice.
+const (
+	DeviceUnverified = "UNVERIFIED"
+	DeviceTrusted    = "TRUSTED"
+	DeviceBlocked    = "BLOCKED"
+)
+
+// UserDevice is one device a user has logged in or transacted from.
+type UserDevice struct {
+	ID            int64     `json:"id"`
+	UserID        uuid.UUID `gorm:"type:uuid" json:"user_id"`
+	DeviceID      string    `json:"device_id"`
+	LastIPAddress string    `gorm:"column:last_ip_address" json:"last_ip_address"`
+	FirstSeenAt   time.Time `json:"first_seen_at"`
+	LastSeenAt    time.Time `json:"last_seen_at"`
+	UseCount      int64     `json:"use_count"`
+	TrustState    string    `json:"trust_state"`
+}
+
+type DeviceTrustRequest struct {
+	TrustState string `json:"trust_state" binding:"required,oneof=UNVERIFIED TRUSTED BLOCKED"`
+}
+
+type DeviceRepository interface {
+	ListDevices(ctx context.Context, userID uuid.UUID) ([]UserDevice, error)
+	RecordDeviceUse(ctx context.Context, userID uuid.UUID, deviceID string, ipAddress string, seenAt time.Time) error
+	SetTrustState(ctx context.Context, userID uuid.UUID, deviceID string, state string) (*UserDevice, error)
+}
+
+type DeviceService interface {
+	ListDevices(ctx context.Context, userID uuid.UUID) ([]UserDevice, error)
+	SetTrustState(ctx context.Context, actorID string, userID uuid.UUID, deviceID string, state string) (*UserDevice, error)
+}
+
+// ReceiverProfile summarises recent inbound activity of a payee and the
+// outcome of its own payments. It is refreshed by the receiver profile cron.
+type ReceiverProfile struct {
+	ReceiverID         uuid.UUID `gorm:"type:uuid;primaryKey" json:"receiver_id"`
+	DistinctSenders24h int64     `gorm:"column:distinct_senders_24h" json:"distinct_senders_24h"`
+	DistinctSenders7d  int64     `gorm:"column:distinct_senders_7d" json:"distinct_senders_7d"`
+	InboundCount7d     int64     `gorm:"column:inbound_count_7d" json:"inbound_count_7d"`
+	InboundAmount7d    float64   `gorm:"column:inbound_amount_7d;type:numeric(18,2)" json:"inbound_amount_7d"`
+	BlockedOutbound7d  int64     `gorm:"column:blocked_outbound_7d" json:"blocked_outbound_7d"`
+	RiskScore          int       `json:"risk_score"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+type CounterpartyRepository interface {
+	// CountPayments counts earlier transactions from sender to receiver,
+	// ignoring the transaction being scored.
+	CountPayments(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID, excludeTxID uuid.UUID) (int64, error)
+	GetReceiverProfile(ctx context.Context, receiverID uuid.UUID) (*ReceiverProfile, error)
+	AggregateReceiverProfiles(ctx context.Context, now time.Time) ([]ReceiverProfile, error)
+	UpsertReceiverProfile(ctx context.Context, profile *ReceiverProfile) error
+	ResetStaleReceiverProfiles(ctx context.Context, before time.Time) error
+}
+
+type CounterpartyService interface {
+	GetReceiverProfile(ctx context.Context, receiverID uuid.UUID) (*ReceiverProfile, error)
+}
+
+// List types: a BLOCK entry forces a BLOCK decision, an ALLOW entry skips
+// scorers for matching transactions.
+const (
+	ListBlock = "BLOCK"
+	ListAllow = "ALLOW"
+)
+
+// What a list entry matches against. IP entries hold an address or a CIDR
+// range.
+const (
+	ListEntryIP       = "IP"
+	ListEntryDevice   = "DEVICE"
+	ListEntryReceiver = "RECEIVER"
+	ListEntryUser     = "USER"
+)
+
+// ListEntry is one blocklist or allowlist entry. An ALLOW entry skips the
+// scorers and expression rules in SkipScorers, or all of them with SkipAll;
+// it must set exactly one of the two.
+type ListEntry struct {
+	ID          int64      `gorm:"primaryKey" json:"id"`
+	ListType    string     `gorm:"type:varchar(10);not null" json:"list_type"`
+	EntryType   string     `gorm:"type:varchar(20);not null" json:"entry_type"`
+	Value       string     `gorm:"type:varchar(255);not null" json:"value"`
+	Reason      string     `gorm:"type:text;not null" json:"reason"`
+	SkipScorers []string   `gorm:"type:jsonb;serializer:json" json:"skip_scorers,omitempty"`
+	SkipAll     bool       `gorm:"not null" json:"skip_all"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedBy   string     `gorm:"type:varchar(255)" json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type ListEntryRequest struct {
+	ListType    string     `json:"list_type" binding:"required,oneof=BLOCK ALLOW"`
+	EntryType   string     `json:"entry_type" binding:"required,oneof=IP DEVICE RECEIVER USER"`
+	Value       string     `json:"value" binding:"required"`
+	Reason      string     `json:"reason" binding:"required"`
+	SkipScorers []string   `json:"skip_scorers"`
+	SkipAll     bool       `json:"skip_all"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+type ListRepository interface {
+	// ListEntries returns entries, optionally filtered by list and entry
+	// type, including expired ones.
+	ListEntries(ctx context.Context, listType string, entryType string) ([]ListEntry, error)
+	// ListActiveEntries returns the entries not expired at now.
+	ListActiveEntries(ctx context.Context, now time.Time) ([]ListEntry, error)
+	// UpsertEntry adds an entry, or refreshes the reason, expiry and skipped
+	// scorers of an existing one with the same list, type and value. It
+	// returns the entry it replaced, or nil if it added one.
+	UpsertEntry(ctx context.Context, entry *ListEntry) (*ListEntry, error)
+	DeleteEntry(ctx context.Context, id int64) (*ListEntry, error)
+}
+
+type ListService interface {
+	ListEntries(ctx context.Context, listType string, entryType string) ([]ListEntry, error)
+	CreateEntry(ctx context.Context, actorID string, req ListEntryRequest) (*ListEntry, error)
+	DeleteEntry(ctx context.Context, actorID string, id int64) error
+}
+
+// FeatureSnapshot is the feature values an evaluation saw at decision time,
+// before the transaction was added to the user's behavior and devices.
+// Missing features are left out.
+type FeatureSnapshot struct {
+	TransactionID   uuid.UUID          `gorm:"type:uuid;primaryKey" json:"transaction_id"`
+	UserID          uuid.UUID          `gorm:"type:uuid;not null" json:"user_id"`
+	TransactionType string             `gorm:"type:varchar(20);not null" json:"transaction_type"`
+	EvaluatedAt     time.Time          `gorm:"type:timestamptz;not null" json:"evaluated_at"`
+	Features        map[string]float64 `gorm:"type:jsonb;serializer:json;not null" json:"features"`
+}
+
+// FeatureSnapshotRow is a snapshot joined with the outcome of its
+// transaction.
+type FeatureSnapshotRow struct {
+	FeatureSnapshot
+	TransactionTime   time.Time `json:"transaction_time"`
+	TransactionStatus string    `json:"transaction_status"`
+	RiskScore         int       `json:"risk_score"`
+	Decision          string    `json:"decision"`
+	ModelVersion      *string   `json:"model_version"`
+	Label             *string   `json:"label"`
+}
+
+// Feature export formats.
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+)
+
+// FeatureExportRequest selects the snapshots evaluated in [From, To).
+// Labels, keyed by transaction, take precedence over stored fraud labels.
+type FeatureExportRequest struct {
+	From         time.Time
+	To           time.Time
+	Format       string
+	Pseudonymize bool
+	Labels       map[uuid.UUID]string
+}
+
+type FeatureSnapshotRepository interface {
+	SaveSnapshot(ctx context.Context, snapshot *FeatureSnapshot) error
+	// ExportSnapshots calls fn for every snapshot evaluated in [from, to),
+	// oldest first, without loading them all at once.
+	ExportSnapshots(ctx context.Context, from time.Time, to time.Time, fn func(FeatureSnapshotRow) error) error
+}
+
+type FeatureExportService interface {
+	Export(ctx context.Context, actorID string, req FeatureExportRequest, w io.Writer) error
+}
+
+// Fraud labels: what a transaction turned out to be. A false positive is
+// labeled LEGIT.
+const (
+	LabelFraud = "FRAUD"
+	LabelLegit = "LEGIT"
+)
+
+// FraudLabel is feedback on a transaction, e.g. from a chargeback or a
+// customer dispute. A transaction has at most one label; relabeling
+// replaces it.
+type FraudLabel struct {
+	TransactionID uuid.UUID `gorm:"type:uuid;primaryKey" json:"transaction_id"`
+	Label         string    `gorm:"type:varchar(10);not null" json:"label"`
+	Source        string    `gorm:"type:varchar(32);not null" json:"source"`
+	Note          string    `gorm:"type:text;not null;default:''" json:"note,omitempty"`
+	LabeledBy     string    `gorm:"type:varchar(255);not null" json:"labeled_by"`
+	LabeledAt     time.Time `gorm:"type:timestamptz;not null;default:now()" json:"labeled_at"`
+}
+
+type FraudLabelRequest struct {
+	Label  string `json:"label" binding:"required,oneof=FRAUD LEGIT"`
+	Source string `json:"source" binding:"required,max=32"`
+	Note   string `json:"note"`
+}
+
+type LabelRepository interface {
+	// UpsertLabel stores the label, replacing any earlier one.
+	UpsertLabel(ctx context.Context, label *FraudLabel) error
+	GetLabel(ctx context.Context, transactionID uuid.UUID) (*FraudLabel, error)
+}
+
+type LabelService interface {
+	SetLabel(ctx context.Context, actorID string, transactionID uuid.UUID, req FraudLabelRequest) (*FraudLabel, error)
+	GetLabel(ctx context.Context, transactionID uuid.UUID) (*FraudLabel, error)
+}
+
+// Dimensions of rule performance: a rule that contributed to the score, or
+// the decision band an evaluation fell in.
+const (
+	PerformanceRule     = "RULE"
+	PerformanceDecision = "DECISION"
+)
+
+// PerformanceMetrics measures how well a rule or decision band separates
+// fraud. A hit is an evaluation the rule scored or that got the decision.
+// Precision and recall only count labeled evaluations; hit rate counts all.
+type PerformanceMetrics struct {
+	Evaluated      int64   `gorm:"not null" json:"evaluated"`
+	Labeled        int64   `gorm:"not null" json:"labeled"`
+	Frauds         int64   `gorm:"not null" json:"frauds"`
+	Hits           int64   `gorm:"not null" json:"hits"`
+	TruePositives  int64   `gorm:"not null" json:"true_positives"`
+	FalsePositives int64   `gorm:"not null" json:"false_positives"`
+	Precision      float64 `gorm:"not null" json:"precision"`
+	Recall         float64 `gorm:"not null" json:"recall"`
+	HitRate        float64 `gorm:"not null" json:"hit_rate"`
+}
+
+// RulePerformance is the performance of one rule or decision band over the
+// evaluations of one UTC day.
+type RulePerformance struct {
+	Day       time.Time `gorm:"type:date;primaryKey" json:"day"`
+	Dimension string    `gorm:"type:varchar(10);primaryKey" json:"dimension"`
+	Name      string    `gorm:"type:varchar(255);primaryKey" json:"name"`
+
+	PerformanceMetrics `gorm:"embedded"`
+
+	ComputedAt time.Time `gorm:"type:timestamptz;not null" json:"computed_at"`
+}
+
+// PerformanceTotals counts the evaluations of one day.
+type PerformanceTotals struct {
+	Day       time.Time
+	Evaluated int64
+	Labeled   int64
+	Frauds    int64
+}
+
+// PerformanceHits counts the evaluations of one day a rule or decision band
+// hit, by label.
+type PerformanceHits struct {
+	Day            time.Time
+	Name           string
+	Hits           int64
+	TruePositives  int64
+	FalsePositives int64
+}
+
+// RulePerformanceReport sums daily performance over [From, To). Daily rows
+// show how it changed over time.
+type RulePerformanceReport struct {
+	From      time.Time                     `json:"from"`
+	To        time.Time                     `json:"to"`
+	Rules     map[string]PerformanceMetrics `json:"rules"`
+	Decisions map[string]PerformanceMetrics `json:"decisions"`
+	Daily     []RulePerformance             `json:"daily"`
+}
+
+type RulePerformanceRepository interface {
+	DailyTotals(ctx context.Context, from time.Time, to time.Time) ([]PerformanceTotals, error)
+	// DailyRuleHits counts evaluations with a positive reason per rule.
+	DailyRuleHits(ctx context.Context, from time.Time, to time.Time) ([]PerformanceHits, error)
+	DailyDecisionHits(ctx context.Context, from time.Time, to time.Time) ([]PerformanceHits, error)
+	UpsertPerformance(ctx context.Context, rows []RulePerformance) error
+	ListPerformance(ctx context.Context, from time.Time, to time.Time) ([]RulePerformance, error)
+}
+
+type RulePerformanceService interface {
+	// ComputePerformance recomputes the daily performance of the days
+	// overlapping [from, to) and returns the number of rows written.
+	ComputePerformance(ctx context.Context, from time.Time, to time.Time) (int, error)
+	Report(ctx context.Context, from time.Time, to time.Time) (*RulePerformanceReport, error)
+}
+
+type VelocityRepository interface {
+	// ListTransactionsSince returns transactions at or after since, oldest
+	// first, to warm the velocity store after a restart.
+	ListTransactionsSince(ctx context.Context, since time.Time) ([]TransactionDTO, error)
+}
+
+// Sources of a Sighting.
+const (
+	SightingTransaction = "TRANSACTION"
+	SightingLogin       = "LOGIN"
+)
+
+// Sighting is the IP address a user was last seen from, by a transaction or a
+// login, and when.
+type Sighting struct {
 	IPAddress string
+	SeenAt    time.Time
+	Source    string
+}
+
+type SightingRepository interface {
+	// GetLastSighting returns the most recent sighting at or before the given
+	// time, ignoring the transaction being scored. It returns nil if the
+	// user has never been seen.
+	GetLastSighting(ctx context.Context, userID uuid.UUID, before time.Time, excludeTxID uuid.UUID) (*Sighting, error)
+}
+
+type TransactionDTO struct {
+	TxID       uuid.UUID
+	UserID     uuid.UUID
+	TxType     string
+	ReceiverID *uuid.UUID
+	Amount     float64
+	TxTime     time.Time
+	DeviceID   string
+	IPAddress  string
 }
 
 type Service interface {
-	CalculateRisk(tx interface{}) (*TransactionRisk, error)
+	// CalculateRisk scores tx within ctx. If the evaluation deadline passes
+	// first, the result carries FallbackDecision.
+	CalculateRisk(ctx context.Context, tx interface{}) (*TransactionRisk, error)
+	GetRisk(ctx context.Context, transactionID uuid.UUID) (*TransactionRisk, error)
+	ReloadRules(ctx context.Context) error
+	ReloadPolicies(ctx context.Context) error
+	// RecordVerifiedChallenge feeds a passed step-up challenge back into the
+	// user's behavior.
+	RecordVerifiedChallenge(ctx context.Context, userID uuid.UUID, at time.Time) error
+	// SkippableNames returns the names an allowlist entry may skip: the
+	// registered scorers and the rules loaded into the LIVE and SHADOW sets.
+	SkippableNames() []string
+	// ValidateRuleExpression reports whether src is a valid rule expression
+	// for the variables this service provides.
+	ValidateRuleExpression(src string) error
 }
 
+func (RiskReason) TableName() string {
+	return "transaction_risk_reasons"
+}
 func (UserBehavior) TableName() string {
 	return "user_behavior"
 }
+func (UserDevice) TableName() string {
+	return "user_devices"
+}
+
+func (ReceiverProfile) TableName() string {
+	return "receiver_profiles"
+}
+
+func (FraudLabel) TableName() string {
+	return "fraud_labels"
+}
+
+func (RulePerformance) TableName() string {
+	return "rule_performance"
+}
+
+func (FeatureSnapshot) TableName() string {
+	return "risk_feature_snapshots"
+}
+
 func (UserSecurity) TableName() string {
 	return "user_security"
 }
 func (RiskRule) TableName() string {
 	return "rules"
 }
+func (DecisionPolicy) TableName() string {
+	return "decision_policies"
+}
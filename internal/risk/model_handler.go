@@ -0,0 +1,43 @@
+package risk
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ModelHandler exposes the model behind MODEL_RISK. loader is nil when no
+// model is configured.
+type ModelHandler struct {
+	loader *ModelLoader
+}
+
+func NewModelHandler(loader *ModelLoader) *ModelHandler {
+	return &ModelHandler{loader: loader}
+}
+
+// GetModel handles GET /api/v1/admin/model.
+func (h *ModelHandler) GetModel(c *gin.Context) {
+	if h.loader == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no model configured"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": h.loader.Info()})
+}
+
+// ReloadModel handles POST /api/v1/admin/model/reload. The model file is
+// swapped in right away instead of on the next scheduled reload.
+func (h *ModelHandler) ReloadModel(c *gin.Context) {
+	if h.loader == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no model configured"})
+		return
+	}
+
+	if err := h.loader.Reload(c.Request.Context()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": h.loader.Info()})
+}
@@ -0,0 +1,172 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"risk-detection/internal/audit"
+	"risk-detection/internal/risk/ml"
+)
+
+// WithModel registers the MODEL_RISK scorer, which scores transactions with
+// the model currently held by loader.
+func WithModel(loader *ModelLoader) Option {
+	return func(s *service) {
+		s.RegisterScorer(detailedScorer{
+			name: RuleModel,
+			fn: func(ctx context.Context, tx TransactionDTO) (ScoreResult, error) {
+				return s.modelRisk(ctx, loader.Model(), tx)
+			},
+		})
+	}
+}
+
+// ModelInfo describes the model in use.
+type ModelInfo struct {
+	Version  string    `json:"version"`
+	Type     string    `json:"type"`
+	Features []string  `json:"features"`
+	Path     string    `json:"path,omitempty"`
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+// ModelLoader holds the model read from a file and swaps it when the file
+// changes to a new version. Evaluations in flight keep the model they
+// started with.
+type ModelLoader struct {
+	path     string
+	auditLog *audit.Logger
+
+	mu       sync.RWMutex
+	model    *ml.Model
+	loadedAt time.Time
+}
+
+// NewModelLoader loads the model file at path. A model that cannot be read
+// or needs an unknown feature is an error.
+func NewModelLoader(path string, auditLog *audit.Logger) (*ModelLoader, error) {
+	l := &ModelLoader{path: path, auditLog: auditLog}
+	if err := l.Reload(context.Background()); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Reload re-reads the model file. The file is only swapped in when its
+// version differs from the model in use; an invalid file keeps the current
+// model.
+func (l *ModelLoader) Reload(ctx context.Context) error {
+	m, err := ml.Load(l.path)
+	if err != nil {
+		return err
+	}
+	if current := l.Model(); current != nil && current.Version == m.Version {
+		return nil
+	}
+	return l.Swap(m)
+}
+
+// Swap validates m against the features the engine provides and makes it
+// the model in use.
+func (l *ModelLoader) Swap(m *ml.Model) error {
+	for _, name := range m.Inputs() {
+		if !knownFeature(name) {
+			return fmt.Errorf("model %s: unknown feature %q", m.Version, name)
+		}
+	}
+
+	l.mu.Lock()
+	previous := l.model
+	l.model = m
+	l.loadedAt = time.Now()
+	l.mu.Unlock()
+
+	if l.auditLog != nil {
+		entry := audit.AuditLog{
+			EventType:  audit.EventModelLoaded,
+			Action:     "LOAD",
+			EntityType: "models",
+			EntityID:   m.Version,
+			ActorType:  "SYSTEM",
+			NewValues:  map[string]interface{}{"version": m.Version, "type": m.Type, "path": l.path},
+			Status:     "SUCCESS",
+		}
+		if previous != nil {
+			entry.OldValues = map[string]interface{}{"version": previous.Version}
+		}
+		l.auditLog.Log(entry)
+	}
+	return nil
+}
+
+// Model returns the model in use.
+func (l *ModelLoader) Model() *ml.Model {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.model
+}
+
+// Info describes the model in use.
+func (l *ModelLoader) Info() ModelInfo {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.model == nil {
+		return ModelInfo{Path: l.path}
+	}
+	return ModelInfo{
+		Version:  l.model.Version,
+		Type:     l.model.Type,
+		Features: l.model.Inputs(),
+		Path:     l.path,
+		LoadedAt: l.loadedAt,
+	}
+}
+
+// modelRisk scores tx with m. The calibrated probability is the raw score,
+// so a rule weight of 100 maps a 0.8 probability to 80 points.
+func (s *service) modelRisk(ctx context.Context, m *ml.Model, tx TransactionDTO) (ScoreResult, error) {
+	if m == nil {
+		return ScoreResult{}, nil
+	}
+
+	behavior, err := s.repo.GetBehaviorByUserID(ctx, tx.UserID)
+	if err != nil {
+		log.Printf("unable to get behavior for model risk: %v", err)
+		behavior = nil
+	}
+
+	values := s.featureValues(ctx, tx, behavior)
+	for _, name := range m.Inputs() {
+		// Types other than the transaction's are known to be 0, not missing
+		if _, ok := values[name]; !ok && strings.HasPrefix(name, txTypeFeaturePrefix) {
+			values[name] = 0
+		}
+	}
+	p := m.Predict(m.Vector(values))
+	score := int(math.Round(p * 100))
+
+	inputs := map[string]interface{}{
+		"model_version": m.Version,
+		"probability":   p,
+	}
+	for _, name := range m.Inputs() {
+		// Missing features are recorded as null; NaN cannot be stored as JSON
+		if v, ok := values[name]; ok {
+			inputs[name] = v
+		} else {
+			inputs[name] = nil
+		}
+	}
+
+	return ScoreResult{
+		Score:        score,
+		Reasons:      []Reason{{Code: "MODEL_SCORE", Score: score, Inputs: inputs}},
+		ModelVersion: m.Version,
+	}, nil
+}
@@ -0,0 +1,121 @@
+package risk
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"risk-detection/internal/audit"
+	"risk-detection/internal/risk/ml"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testModel scores 0.5 at a z-score of zero and rises with it; withdrawals
+// add to the margin.
+func testModel(version string) string {
+	return `{
+  "version": "` + version + `",
+  "type": "logistic_regression",
+  "features": ["z", "is_withdrawal", "device_count_1h"],
+  "feature_map": {"z": "user.amount_zscore", "is_withdrawal": "tx.type.WITHDRAWAL", "device_count_1h": "velocity.device.count_1h"},
+  "intercept": 0,
+  "coefficients": [1, 1, 0]
+}`
+}
+
+func writeModel(t *testing.T, path string, model string) {
+	t.Helper()
+	assert.NoError(t, os.WriteFile(path, []byte(model), 0o600))
+}
+
+func TestModelLoader_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.json")
+	writeModel(t, path, testModel("v1"))
+
+	loader, err := NewModelLoader(path, &audit.Logger{})
+	assert.NoError(t, err)
+	first := loader.Model()
+	assert.Equal(t, "v1", first.Version)
+	assert.Equal(t, []string{"user.amount_zscore", "tx.type.WITHDRAWAL", "velocity.device.count_1h"}, loader.Info().Features)
+
+	// Same version is not swapped
+	assert.NoError(t, loader.Reload(context.Background()))
+	assert.Same(t, first, loader.Model())
+
+	writeModel(t, path, testModel("v2"))
+	assert.NoError(t, loader.Reload(context.Background()))
+	assert.Equal(t, "v2", loader.Model().Version)
+
+	// A broken or incompatible file keeps the model in use
+	writeModel(t, path, `{"version": "v3"`)
+	assert.Error(t, loader.Reload(context.Background()))
+	writeModel(t, path, strings.Replace(testModel("v4"), "user.amount_zscore", "user.shoe_size", 1))
+	assert.ErrorContains(t, loader.Reload(context.Background()), "user.shoe_size")
+	assert.Equal(t, "v2", loader.Model().Version)
+}
+
+func TestCalculateRisk_Model(t *testing.T) {
+	userID := uuid.New()
+
+	m, err := ml.Read(strings.NewReader(testModel("fraud-lr-3")))
+	assert.NoError(t, err)
+	loader := &ModelLoader{}
+	assert.NoError(t, loader.Swap(m))
+
+	tests := []struct {
+		name          string
+		txType        string
+		amount        float64
+		expectedScore int
+		description   string
+	}{
+		{
+			name:          "usual_deposit",
+			txType:        "DEPOSIT",
+			amount:        100,
+			expectedScore: 25,
+			description:   "A probability of 0.5 is a raw score of 50, weighted at 50%",
+		},
+		{
+			name:          "large_withdrawal",
+			txType:        "WITHDRAWAL",
+			amount:        200,
+			expectedScore: 47,
+			description:   "sigmoid(2+1) is a raw score of 95, weighted at 50%",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockTransactionRiskRepository)
+			mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{
+				{Name: RuleModel, Enabled: true, Weight: 50},
+			}, nil)
+			mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("GetBehaviorByUserID", mock.Anything, userID).Return(&UserBehavior{UserID: userID, TotalTransactions: 50, AvgTransactionAmount: 100, AmountStdDev: 50}, nil)
+			mockRepo.On("GetTypeBehavior", mock.Anything, userID, tt.txType).Return(&UserBehavior{UserID: userID, TransactionType: tt.txType, EMASmoothingFactor: 0.1}, nil)
+			mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
+
+			svc, err := NewService(mockRepo, nil, &audit.Logger{}, WithModel(loader))
+			assert.NoError(t, err)
+
+			result, err := svc.CalculateRisk(context.Background(), &TransactionDTO{TxID: uuid.New(), UserID: userID, TxType: tt.txType, Amount: tt.amount, TxTime: time.Now()})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedScore, result.RiskScore, tt.description)
+			if assert.NotNil(t, result.ModelVersion) {
+				assert.Equal(t, "fraud-lr-3", *result.ModelVersion)
+			}
+			if assert.Len(t, result.Reasons, 1) {
+				assert.Equal(t, "MODEL_SCORE", result.Reasons[0].ReasonCode)
+				assert.Equal(t, "fraud-lr-3", result.Reasons[0].Inputs["model_version"])
+				assert.Equal(t, 0.0, result.Reasons[0].Inputs["velocity.device.count_1h"], "Without a store velocity reads zero, as in rule expressions")
+			}
+		})
+	}
+}
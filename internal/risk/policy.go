@@ -0,0 +1,74 @@
+package risk
+
+import (
+	"context"
+	"strings"
+)
+
+// DefaultPolicyType is the policy applied when a transaction type has no
+// policy of its own.
+const DefaultPolicyType = "DEFAULT"
+
+// defaultPolicy is used until a DEFAULT row is loaded from the database.
+var defaultPolicy = DecisionPolicy{
+	TransactionType: DefaultPolicyType,
+	Version:         0,
+	LowMax:          30,
+	MediumMax:       70,
+}
+
+func (p DecisionPolicy) RiskLevel(riskScore int) string {
+	if riskScore <= p.LowMax {
+		return "LOW"
+	} else if riskScore <= p.MediumMax {
+		return "MEDIUM"
+	}
+	return "HIGH"
+}
+
+func (p DecisionPolicy) Decision(riskScore int) string {
+	if riskScore <= p.LowMax {
+		return "ALLOW"
+	} else if riskScore <= p.MediumMax {
+		return "FLAG"
+	}
+	return "BLOCK"
+}
+
+// ReloadPolicies replaces the in-memory decision policies with the stored ones.
+func (s *service) ReloadPolicies(ctx context.Context) error {
+	if s.policyRepo == nil {
+		return nil
+	}
+
+	policies, err := s.policyRepo.ListPolicies(ctx)
+	if err != nil {
+		return err
+	}
+
+	m := make(map[string]DecisionPolicy)
+	for _, p := range policies {
+		m[strings.ToUpper(p.TransactionType)] = p
+	}
+
+	s.mu.Lock()
+	s.policies = m
+	s.mu.Unlock()
+
+	return nil
+}
+
+// policyFor returns the policy for a transaction type, falling back to the
+// DEFAULT policy and then to the built-in thresholds.
+func (s *service) policyFor(transactionType string) DecisionPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if p, ok := s.policies[strings.ToUpper(transactionType)]; ok {
+		return p
+	}
+	if p, ok := s.policies[DefaultPolicyType]; ok {
+		return p
+	}
+	return defaultPolicy
+}
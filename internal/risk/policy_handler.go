@@ -0,0 +1,48 @@
+package risk
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PolicyHandler struct {
+	service PolicyService
+}
+
+func NewPolicyHandler(service PolicyService) *PolicyHandler {
+	return &PolicyHandler{service: service}
+}
+
+// ListPolicies handles GET /api/v1/admin/policies.
+func (h *PolicyHandler) ListPolicies(c *gin.Context) {
+	policies, err := h.service.ListPolicies(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": policies})
+}
+
+// UpsertPolicy handles PUT /api/v1/admin/policies/:type.
+func (h *PolicyHandler) UpsertPolicy(c *gin.Context) {
+	var req DecisionPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	policy, err := h.service.UpsertPolicy(c.Request.Context(), c.GetString("user_id"), c.Param("type"), req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidPolicy) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
@@ -0,0 +1,49 @@
+package risk
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type policyRepository struct {
+	db *gorm.DB
+}
+
+func NewPolicyRepository(db *gorm.DB) PolicyRepository {
+	return &policyRepository{db: db}
+}
+
+func (r *policyRepository) ListPolicies(ctx context.Context) ([]DecisionPolicy, error) {
+	var policies []DecisionPolicy
+	err := r.db.WithContext(ctx).
+		Order("transaction_type ASC").
+		Find(&policies).Error
+	return policies, err
+}
+
+// UpsertPolicy inserts the policy for its transaction type or replaces the
+// thresholds of the existing one, bumping its version either way.
+func (r *policyRepository) UpsertPolicy(ctx context.Context, policy *DecisionPolicy) error {
+	policy.UpdatedAt = time.Now()
+	if policy.Version == 0 {
+		policy.Version = 1
+	}
+
+	return r.db.WithContext(ctx).
+		Clauses(
+			clause.OnConflict{
+				Columns: []clause.Column{{Name: "transaction_type"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"low_max":    policy.LowMax,
+					"medium_max": policy.MediumMax,
+					"version":    gorm.Expr("decision_policies.version + 1"),
+					"updated_at": policy.UpdatedAt,
+				}),
+			},
+			clause.Returning{},
+		).
+		Create(policy).Error
+}
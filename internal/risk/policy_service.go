@@ -0,0 +1,74 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"risk-detection/internal/audit"
+)
+
+var ErrInvalidPolicy = errors.New("invalid decision policy")
+
+type policyService struct {
+	repo        PolicyRepository
+	riskService Service
+	auditLog    *audit.Logger
+}
+
+// NewPolicyService manages decision policies and reloads them into the risk
+// service after every change.
+func NewPolicyService(repo PolicyRepository, riskService Service, auditLog *audit.Logger) PolicyService {
+	return &policyService{
+		repo:        repo,
+		riskService: riskService,
+		auditLog:    auditLog,
+	}
+}
+
+func (s *policyService) ListPolicies(ctx context.Context) ([]DecisionPolicy, error) {
+	return s.repo.ListPolicies(ctx)
+}
+
+func (s *policyService) UpsertPolicy(ctx context.Context, actorID string, transactionType string, req DecisionPolicyRequest) (*DecisionPolicy, error) {
+	transactionType = strings.ToUpper(strings.TrimSpace(transactionType))
+	if transactionType == "" {
+		return nil, fmt.Errorf("%w: transaction type is required", ErrInvalidPolicy)
+	}
+	if req.LowMax < 0 || req.MediumMax <= req.LowMax || req.MediumMax > 100 {
+		return nil, fmt.Errorf("%w: thresholds must satisfy 0 <= low_max < medium_max <= 100", ErrInvalidPolicy)
+	}
+
+	policy := DecisionPolicy{
+		TransactionType: transactionType,
+		LowMax:          req.LowMax,
+		MediumMax:       req.MediumMax,
+	}
+	if err := s.repo.UpsertPolicy(ctx, &policy); err != nil {
+		return nil, fmt.Errorf("upsert policy: %w", err)
+	}
+
+	if s.auditLog != nil {
+		s.auditLog.Log(audit.AuditLog{
+			EventType:  audit.EventPolicyUpdated,
+			Action:     "UPDATE",
+			EntityType: "decision_policies",
+			EntityID:   policy.TransactionType,
+			ActorType:  "USER",
+			ActorID:    actorID,
+			ActorRole:  "ADMIN",
+			NewValues: map[string]interface{}{
+				"low_max":    policy.LowMax,
+				"medium_max": policy.MediumMax,
+				"version":    policy.Version,
+			},
+			Status: "SUCCESS",
+		})
+	}
+
+	if err := s.riskService.ReloadPolicies(ctx); err != nil {
+		return nil, fmt.Errorf("reload policies: %w", err)
+	}
+	return &policy, nil
+}
@@ -0,0 +1,150 @@
+package risk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"risk-detection/internal/audit"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPolicyRepository struct {
+	mock.Mock
+}
+
+func (m *MockPolicyRepository) ListPolicies(ctx context.Context) ([]DecisionPolicy, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]DecisionPolicy), args.Error(1)
+}
+
+func (m *MockPolicyRepository) UpsertPolicy(ctx context.Context, policy *DecisionPolicy) error {
+	args := m.Called(ctx, policy)
+	return args.Error(0)
+}
+
+// ============ DecisionPolicy Tests ============
+
+func TestDecisionPolicy_Bands(t *testing.T) {
+	policy := DecisionPolicy{LowMax: 20, MediumMax: 50}
+
+	tests := []struct {
+		score    int
+		level    string
+		decision string
+	}{
+		{score: 0, level: "LOW", decision: "ALLOW"},
+		{score: 20, level: "LOW", decision: "ALLOW"},
+		{score: 21, level: "MEDIUM", decision: "FLAG"},
+		{score: 50, level: "MEDIUM", decision: "FLAG"},
+		{score: 51, level: "HIGH", decision: "BLOCK"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.level, policy.RiskLevel(tt.score))
+		assert.Equal(t, tt.decision, policy.Decision(tt.score))
+	}
+}
+
+func TestCalculateRisk_PolicyPerTransactionType(t *testing.T) {
+	policies := []DecisionPolicy{
+		{TransactionType: "DEFAULT", Version: 1, LowMax: 30, MediumMax: 70},
+		{TransactionType: "WITHDRAWAL", Version: 4, LowMax: 10, MediumMax: 30},
+	}
+
+	tests := []struct {
+		name             string
+		txType           string
+		expectedDecision string
+		expectedType     string
+		expectedVersion  int
+	}{
+		{name: "withdrawal_uses_strict_policy", txType: "WITHDRAWAL", expectedDecision: "BLOCK", expectedType: "WITHDRAWAL", expectedVersion: 4},
+		{name: "lowercase_type_matches", txType: "withdrawal", expectedDecision: "BLOCK", expectedType: "WITHDRAWAL", expectedVersion: 4},
+		{name: "deposit_falls_back_to_default", txType: "DEPOSIT", expectedDecision: "FLAG", expectedType: "DEFAULT", expectedVersion: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockTransactionRiskRepository)
+			mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{{Name: "FIXED", Enabled: true, Weight: 100}}, nil)
+			mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("GetBehaviorByUserID", mock.Anything, mock.Anything).Return(&UserBehavior{UserID: uuid.New()}, nil)
+			mockRepo.On("GetTypeBehavior", mock.Anything, mock.Anything, mock.Anything).Return(&UserBehavior{UserID: uuid.New(), TransactionType: tt.txType}, nil)
+			mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
+
+			policyRepo := new(MockPolicyRepository)
+			policyRepo.On("ListPolicies", mock.Anything).Return(policies, nil)
+
+			fixed := ScorerFunc{
+				RuleName: "FIXED",
+				Fn: func(ctx context.Context, tx TransactionDTO) (int, error) {
+					return 40, nil
+				},
+			}
+
+			svc, err := NewService(mockRepo, nil, &audit.Logger{}, WithScorers(fixed), WithPolicyRepository(policyRepo))
+			assert.NoError(t, err)
+
+			result, err := svc.CalculateRisk(context.Background(), &TransactionDTO{TxID: uuid.New(), UserID: uuid.New(), TxType: tt.txType, TxTime: time.Now()})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedDecision, result.Decision)
+			assert.Equal(t, tt.expectedType, result.PolicyType)
+			assert.Equal(t, tt.expectedVersion, result.PolicyVersion)
+		})
+	}
+}
+
+func TestCalculateRisk_NoPolicyRepositoryUsesBuiltIn(t *testing.T) {
+	svc := &service{policies: make(map[string]DecisionPolicy)}
+
+	policy := svc.policyFor("TRANSFER")
+	assert.Equal(t, DefaultPolicyType, policy.TransactionType)
+	assert.Equal(t, 30, policy.LowMax)
+	assert.Equal(t, 70, policy.MediumMax)
+}
+
+// ============ PolicyService Tests ============
+
+func TestPolicyService_UpsertPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		txType      string
+		req         DecisionPolicyRequest
+		expectError bool
+	}{
+		{name: "valid_policy", txType: "withdrawal", req: DecisionPolicyRequest{LowMax: 10, MediumMax: 40}},
+		{name: "inverted_thresholds", txType: "WITHDRAWAL", req: DecisionPolicyRequest{LowMax: 50, MediumMax: 40}, expectError: true},
+		{name: "medium_above_100", txType: "WITHDRAWAL", req: DecisionPolicyRequest{LowMax: 10, MediumMax: 140}, expectError: true},
+		{name: "blank_type", txType: " ", req: DecisionPolicyRequest{LowMax: 10, MediumMax: 40}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockPolicyRepository)
+			riskSvc := new(MockRiskService)
+			repo.On("UpsertPolicy", mock.Anything, mock.MatchedBy(func(p *DecisionPolicy) bool {
+				return p.TransactionType == "WITHDRAWAL"
+			})).Return(nil)
+			riskSvc.On("ReloadPolicies", mock.Anything).Return(nil)
+
+			svc := NewPolicyService(repo, riskSvc, &audit.Logger{})
+			_, err := svc.UpsertPolicy(context.Background(), "admin-1", tt.txType, tt.req)
+
+			if tt.expectError {
+				assert.ErrorIs(t, err, ErrInvalidPolicy)
+				repo.AssertNotCalled(t, "UpsertPolicy", mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+				repo.AssertExpectations(t)
+				riskSvc.AssertExpectations(t)
+			}
+		})
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"errors"
 	"time"
 
+	"risk-detection/internal/db"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type repository struct {
@@ -17,13 +20,13 @@ func NewRepository(db *gorm.DB) TransactionRiskRepository {
 	return &repository{db: db}
 }
 
-func (r *repository) Create(risk *TransactionRisk) error {
-	return r.db.Create(risk).Error
+func (r *repository) Create(ctx context.Context, risk *TransactionRisk) error {
+	return db.Conn(ctx, r.db).Create(risk).Error
 }
 
-func (r *repository) GetRiskByTransactionID(id uuid.UUID) (*TransactionRisk, error) {
+func (r *repository) GetRiskByTransactionID(ctx context.Context, id uuid.UUID) (*TransactionRisk, error) {
 	var risk TransactionRisk
-	if err := r.db.First(&risk, "transaction_id = ?", id).Error; err != nil {
+	if err := db.Conn(ctx, r.db).Preload("Reasons").First(&risk, "transaction_id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &risk, nil
@@ -33,11 +36,19 @@ func (r *repository) GetBehaviorByUserID(
 	ctx context.Context,
 	userID uuid.UUID,
 ) (*UserBehavior, error) {
+	return r.GetTypeBehavior(ctx, userID, BehaviorAllTypes)
+}
+
+func (r *repository) GetTypeBehavior(
+	ctx context.Context,
+	userID uuid.UUID,
+	txType string,
+) (*UserBehavior, error) {
 
 	var behavior UserBehavior
 
-	err := r.db.WithContext(ctx).
-		Where("user_id = ?", userID).
+	err := db.Conn(ctx, r.db).
+		Where("user_id = ? AND transaction_type = ?", userID, txType).
 		First(&behavior).Error
 
 	if err != nil {
@@ -57,10 +68,11 @@ func (r *repository) GetDailyTransactionAggregate(
 
 	var result []DailyAggregate
 
-	err := r.db.WithContext(ctx).
+	err := db.Conn(ctx, r.db).
 		Raw(`
 			SELECT
 				user_id,
+				CASE WHEN GROUPING(transaction_type) = 1 THEN ? ELSE transaction_type END AS transaction_type,
 				COUNT(*) AS txn_count,
 				AVG(amount) AS avg_amount,
 				PERCENTILE_CONT(0.95)
@@ -68,8 +80,8 @@ func (r *repository) GetDailyTransactionAggregate(
 			FROM transactions
 			WHERE transaction_time >= ?
 			  AND transaction_time < ?
-			GROUP BY user_id
-		`, from, to).
+			GROUP BY GROUPING SETS ((user_id, transaction_type), (user_id))
+		`, BehaviorAllTypes, from, to).
 		Scan(&result).Error
 
 	return result, err
@@ -78,41 +90,86 @@ func (r *repository) GetDailyTransactionAggregate(
 func (r *repository) UpdateBehaviorParams(
 	ctx context.Context,
 	userID uuid.UUID,
+	txType string,
 	stdDev float64,
 	p95 float64,
 ) error {
 
-	return r.db.WithContext(ctx).
+	return db.Conn(ctx, r.db).
 		Table("user_behavior").
-		Where("user_id = ?", userID).
+		Where("user_id = ? AND transaction_type = ?", userID, txType).
 		Updates(map[string]interface{}{
 			"amount_std_dev":       stdDev,
 			"high_value_threshold": p95,
 			"updated_at":           time.Now(),
+			"version":              gorm.Expr("version + 1"),
 		}).Error
 }
 
 func (r *repository) UpdateBehaviorPerTransaction(ctx context.Context, behavior *UserBehavior) error {
-	return r.db.WithContext(ctx).
+	// Verified challenges are counted by RecordVerifiedChallenge and must not
+	// be overwritten by a stale copy
+	read := behavior.Version
+	behavior.Version++
+	res := db.Conn(ctx, r.db).
 		Table("user_behavior").
-		Where("user_id = ?", behavior.UserID).
-		Updates(behavior).Error
+		Where("user_id = ? AND transaction_type = ? AND version = ?", behavior.UserID, behavior.TransactionType, read).
+		Omit("verified_challenges", "last_verified_at").
+		Updates(behavior)
+	if res.Error != nil {
+		behavior.Version = read
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		behavior.Version = read
+		return ErrBehaviorConflict
+	}
+	return nil
+}
+
+func (r *repository) RecordVerifiedChallenge(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	return db.Conn(ctx, r.db).
+		Table("user_behavior").
+		Where("user_id = ? AND transaction_type = ?", userID, BehaviorAllTypes).
+		Updates(map[string]interface{}{
+			"verified_challenges": gorm.Expr("verified_challenges + 1"),
+			"last_verified_at":    at,
+			"updated_at":          time.Now(),
+		}).Error
+}
+
+func (r *repository) DecayHourOfWeek(ctx context.Context, factor float64) (int64, error) {
+	res := db.Conn(ctx, r.db).Exec(`
+		UPDATE user_behavior
+		SET hour_of_week = (
+			SELECT jsonb_agg(ROUND(h.value::numeric * ?, 4) ORDER BY h.ord)
+			FROM jsonb_array_elements_text(hour_of_week) WITH ORDINALITY AS h(value, ord)
+		),
+		version = version + 1
+		WHERE jsonb_typeof(hour_of_week) = 'array'
+		  AND jsonb_array_length(hour_of_week) > 0
+	`, factor)
+	return res.RowsAffected, res.Error
 }
 
+// CreateFirstBehavior leaves an existing baseline alone, so two first
+// transactions of a user racing to create it both succeed.
 func (r *repository) CreateFirstBehavior(ctx context.Context, behavior *UserBehavior) error {
-	return r.db.WithContext(ctx).Create(behavior).Error
+	return db.Conn(ctx, r.db).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(behavior).Error
 }
 
 func (r *repository) GetDeviceInfo(ctx context.Context, userID uuid.UUID) (*UserSecurity, error) {
 	var userSecurity UserSecurity
-	if err := r.db.First(&userSecurity, "user_id = ?", userID).Error; err != nil {
+	if err := db.Conn(ctx, r.db).First(&userSecurity, "user_id = ?", userID).Error; err != nil {
 		return nil, err
 	}
 	return &userSecurity, nil
 }
 func (r *repository) GetEnabledRules(ctx context.Context) ([]RiskRule, error) {
 	var rules []RiskRule
-	err := r.db.WithContext(ctx).
+	err := db.Conn(ctx, r.db).
 		Where("enabled = true").
 		Find(&rules).Error
 	return rules, err
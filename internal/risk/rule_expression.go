@@ -0,0 +1,119 @@
+package risk
+
+import (
+	"risk-detection/internal/risk/expr"
+	"risk-detection/internal/risk/velocity"
+)
+
+// ruleSchema lists the variables every rule expression can reference. The
+// velocity variables depend on the store a service is built with and are
+// added per service by newRuleSchema.
+var ruleSchema = expr.Schema{
+	"tx.amount":     expr.KindNumber,
+	"tx.type":       expr.KindString,
+	"tx.device_id":  expr.KindString,
+	"tx.ip_address": expr.KindString,
+	"tx.hour":       expr.KindNumber,
+
+	"user.total_transactions": expr.KindNumber,
+	"user.avg_amount":         expr.KindNumber,
+	"user.std_dev":            expr.KindNumber,
+	"user.variance":           expr.KindNumber,
+	"user.recent_avg_amount":  expr.KindNumber,
+	"user.last_amount":        expr.KindNumber,
+	"user.p95_amount":         expr.KindNumber,
+	"user.seconds_since_last": expr.KindNumber,
+	"user.is_new":             expr.KindBool,
+
+	"user.verified_challenges": expr.KindNumber,
+}
+
+// ruleWindows returns the velocity windows rule expressions can reference:
+// the default ones, so rules written against them stay valid, and those of
+// store.
+func ruleWindows(store *velocity.Store) []velocity.Window {
+	windows := append([]velocity.Window(nil), velocity.DefaultWindows...)
+	if store == nil {
+		return windows
+	}
+
+	for _, w := range store.Windows() {
+		known := false
+		for _, existing := range windows {
+			if existing.Name == w.Name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
+
+// newRuleSchema returns ruleSchema with the velocity variables of windows.
+func newRuleSchema(windows []velocity.Window) expr.Schema {
+	schema := make(expr.Schema, len(ruleSchema))
+	for name, kind := range ruleSchema {
+		schema[name] = kind
+	}
+	for _, name := range velocityVariables(windows) {
+		schema[name] = expr.KindNumber
+	}
+	return schema
+}
+
+func (s *service) ValidateRuleExpression(src string) error {
+	_, err := expr.Compile(src, s.schema)
+	return err
+}
+
+// expressionScore is the raw score of a matched expression rule. Threshold
+// holds the score when set, otherwise a match counts as full risk.
+func expressionScore(rule RiskRule) int {
+	if rule.Threshold > 0 && rule.Threshold <= 100 {
+		return rule.Threshold
+	}
+	return 100
+}
+
+func buildExpressionEnv(tx TransactionDTO, behavior *UserBehavior) expr.Env {
+	if behavior == nil {
+		behavior = &UserBehavior{}
+	}
+
+	secondsSinceLast := -1.0
+	if behavior.LastTransactionTime != nil {
+		secondsSinceLast = tx.TxTime.Sub(*behavior.LastTransactionTime).Seconds()
+	}
+
+	return expr.Env{
+		"tx.amount":     tx.Amount,
+		"tx.type":       tx.TxType,
+		"tx.device_id":  tx.DeviceID,
+		"tx.ip_address": tx.IPAddress,
+		"tx.hour":       float64(tx.TxTime.UTC().Hour()),
+
+		"user.total_transactions": float64(behavior.TotalTransactions),
+		"user.avg_amount":         behavior.AvgTransactionAmount,
+		"user.std_dev":            behavior.AmountStdDev,
+		"user.variance":           behavior.AmountVariance,
+		"user.recent_avg_amount":  behavior.RecentAvgAmount,
+		"user.last_amount":        behavior.LastTransactionAmount,
+		"user.p95_amount":         behavior.HighValueThreshold,
+		"user.seconds_since_last": secondsSinceLast,
+		"user.is_new":             behavior.TotalTransactions == 0,
+
+		"user.verified_challenges": float64(behavior.VerifiedChallenges),
+	}
+}
+
+// expressionInputs records the expression and the variables it referenced.
+func expressionInputs(prog *expr.Program, env expr.Env) map[string]interface{} {
+	inputs := map[string]interface{}{"expression": prog.Source()}
+	for _, name := range prog.Variables() {
+		inputs[name] = env[name]
+	}
+	return inputs
+}
@@ -0,0 +1,138 @@
+package risk
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RuleHandler struct {
+	service RuleService
+}
+
+func NewRuleHandler(service RuleService) *RuleHandler {
+	return &RuleHandler{service: service}
+}
+
+// ListRules handles GET /api/v1/admin/rules.
+func (h *RuleHandler) ListRules(c *gin.Context) {
+	rules, err := h.service.ListRules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rules})
+}
+
+// CreateRule handles POST /api/v1/admin/rules.
+func (h *RuleHandler) CreateRule(c *gin.Context) {
+	var req RuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	rule, err := h.service.CreateRule(c.Request.Context(), c.GetString("user_id"), req)
+	if err != nil {
+		writeRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule handles PUT /api/v1/admin/rules/:id.
+func (h *RuleHandler) UpdateRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule id"})
+		return
+	}
+
+	var req RuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	rule, err := h.service.UpdateRule(c.Request.Context(), c.GetString("user_id"), id, req)
+	if err != nil {
+		writeRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// EnableRule handles POST /api/v1/admin/rules/:id/enable.
+func (h *RuleHandler) EnableRule(c *gin.Context) {
+	h.setEnabled(c, true)
+}
+
+// DisableRule handles POST /api/v1/admin/rules/:id/disable.
+func (h *RuleHandler) DisableRule(c *gin.Context) {
+	h.setEnabled(c, false)
+}
+
+func (h *RuleHandler) setEnabled(c *gin.Context, enabled bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule id"})
+		return
+	}
+
+	rule, err := h.service.SetRuleEnabled(c.Request.Context(), c.GetString("user_id"), id, enabled)
+	if err != nil {
+		writeRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// ShadowReport handles GET /api/v1/admin/rules/shadow/report.
+// from and to are RFC3339 timestamps; the default window is the last 7 days.
+func (h *RuleHandler) ShadowReport(c *gin.Context) {
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -7)
+
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from timestamp"})
+			return
+		}
+		from = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to timestamp"})
+			return
+		}
+		to = t
+	}
+
+	report, err := h.service.ShadowReport(c.Request.Context(), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func writeRuleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "rule not found"})
+	case errors.Is(err, ErrInvalidRule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,95 @@
+package risk
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type rulePerformanceRepository struct {
+	db *gorm.DB
+}
+
+func NewRulePerformanceRepository(db *gorm.DB) RulePerformanceRepository {
+	return &rulePerformanceRepository{db: db}
+}
+
+func (r *rulePerformanceRepository) DailyTotals(ctx context.Context, from time.Time, to time.Time) ([]PerformanceTotals, error) {
+	var totals []PerformanceTotals
+	err := r.db.WithContext(ctx).
+		Table("transaction_risks AS tr").
+		Select(`
+			date_trunc('day', tr.evaluated_at AT TIME ZONE 'UTC') AS day,
+			COUNT(*) AS evaluated,
+			COUNT(l.transaction_id) AS labeled,
+			COUNT(*) FILTER (WHERE l.label = ?) AS frauds
+		`, LabelFraud).
+		Joins("LEFT JOIN fraud_labels l ON l.transaction_id = tr.transaction_id").
+		Where("tr.evaluated_at >= ? AND tr.evaluated_at < ?", from, to).
+		Group("1").
+		Order("1").
+		Scan(&totals).Error
+	return totals, err
+}
+
+func (r *rulePerformanceRepository) DailyRuleHits(ctx context.Context, from time.Time, to time.Time) ([]PerformanceHits, error) {
+	var hits []PerformanceHits
+	err := r.db.WithContext(ctx).
+		Table("transaction_risks AS tr").
+		Select(`
+			date_trunc('day', tr.evaluated_at AT TIME ZONE 'UTC') AS day,
+			rr.rule_name AS name,
+			COUNT(DISTINCT tr.transaction_id) AS hits,
+			COUNT(DISTINCT tr.transaction_id) FILTER (WHERE l.label = ?) AS true_positives,
+			COUNT(DISTINCT tr.transaction_id) FILTER (WHERE l.label = ?) AS false_positives
+		`, LabelFraud, LabelLegit).
+		Joins("JOIN transaction_risk_reasons rr ON rr.transaction_id = tr.transaction_id AND rr.raw_score > 0").
+		Joins("LEFT JOIN fraud_labels l ON l.transaction_id = tr.transaction_id").
+		Where("tr.evaluated_at >= ? AND tr.evaluated_at < ?", from, to).
+		Group("1, 2").
+		Order("1, 2").
+		Scan(&hits).Error
+	return hits, err
+}
+
+func (r *rulePerformanceRepository) DailyDecisionHits(ctx context.Context, from time.Time, to time.Time) ([]PerformanceHits, error) {
+	var hits []PerformanceHits
+	err := r.db.WithContext(ctx).
+		Table("transaction_risks AS tr").
+		Select(`
+			date_trunc('day', tr.evaluated_at AT TIME ZONE 'UTC') AS day,
+			tr.decision AS name,
+			COUNT(*) AS hits,
+			COUNT(*) FILTER (WHERE l.label = ?) AS true_positives,
+			COUNT(*) FILTER (WHERE l.label = ?) AS false_positives
+		`, LabelFraud, LabelLegit).
+		Joins("LEFT JOIN fraud_labels l ON l.transaction_id = tr.transaction_id").
+		Where("tr.evaluated_at >= ? AND tr.evaluated_at < ?", from, to).
+		Group("1, 2").
+		Order("1, 2").
+		Scan(&hits).Error
+	return hits, err
+}
+
+func (r *rulePerformanceRepository) UpsertPerformance(ctx context.Context, rows []RulePerformance) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "day"}, {Name: "dimension"}, {Name: "name"}},
+			UpdateAll: true,
+		}).
+		CreateInBatches(rows, 500).Error
+}
+
+func (r *rulePerformanceRepository) ListPerformance(ctx context.Context, from time.Time, to time.Time) ([]RulePerformance, error) {
+	var rows []RulePerformance
+	err := r.db.WithContext(ctx).
+		Where("day >= ? AND day < ?", from, to).
+		Order("day ASC, dimension ASC, name ASC").
+		Find(&rows).Error
+	return rows, err
+}
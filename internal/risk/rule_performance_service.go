@@ -0,0 +1,169 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrInvalidReportWindow = errors.New("invalid report window")
+
+type rulePerformanceService struct {
+	repo RulePerformanceRepository
+	now  func() time.Time
+}
+
+func NewRulePerformanceService(repo RulePerformanceRepository) RulePerformanceService {
+	return &rulePerformanceService{repo: repo, now: time.Now}
+}
+
+// ComputePerformance widens [from, to) to whole UTC days so every row it
+// writes covers a full day of evaluations.
+func (s *rulePerformanceService) ComputePerformance(ctx context.Context, from time.Time, to time.Time) (int, error) {
+	from = truncateDay(from)
+	if end := truncateDay(to); end.Before(to) {
+		to = end.AddDate(0, 0, 1)
+	}
+	if !from.Before(to) {
+		return 0, ErrInvalidReportWindow
+	}
+
+	totals, err := s.repo.DailyTotals(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+	ruleHits, err := s.repo.DailyRuleHits(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+	decisionHits, err := s.repo.DailyDecisionHits(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	byDay := make(map[time.Time]PerformanceTotals, len(totals))
+	for _, t := range totals {
+		byDay[truncateDay(t.Day)] = t
+	}
+
+	computedAt := s.now()
+	var rows []RulePerformance
+	addRow := func(dimension string, h PerformanceHits) {
+		day := truncateDay(h.Day)
+		t := byDay[day]
+		m := PerformanceMetrics{
+			Evaluated:      t.Evaluated,
+			Labeled:        t.Labeled,
+			Frauds:         t.Frauds,
+			Hits:           h.Hits,
+			TruePositives:  h.TruePositives,
+			FalsePositives: h.FalsePositives,
+		}
+		m.computeRates()
+		rows = append(rows, RulePerformance{
+			Day:                day,
+			Dimension:          dimension,
+			Name:               h.Name,
+			PerformanceMetrics: m,
+			ComputedAt:         computedAt,
+		})
+	}
+
+	for _, h := range ruleHits {
+		addRow(PerformanceRule, h)
+	}
+
+	// Every band gets a row on days with evaluations, so a band that missed
+	// all fraud shows zero recall instead of no data
+	seen := make(map[time.Time]map[string]bool)
+	for _, h := range decisionHits {
+		day := truncateDay(h.Day)
+		if seen[day] == nil {
+			seen[day] = make(map[string]bool)
+		}
+		seen[day][h.Name] = true
+		addRow(PerformanceDecision, h)
+	}
+	for _, t := range totals {
+		day := truncateDay(t.Day)
+		for _, decision := range []string{"ALLOW", "FLAG", "BLOCK"} {
+			if !seen[day][decision] {
+				addRow(PerformanceDecision, PerformanceHits{Day: day, Name: decision})
+			}
+		}
+	}
+
+	if err := s.repo.UpsertPerformance(ctx, rows); err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// Report sums the stored daily rows of [from, to). Rates are recomputed from
+// the summed counts rather than averaged.
+func (s *rulePerformanceService) Report(ctx context.Context, from time.Time, to time.Time) (*RulePerformanceReport, error) {
+	if !from.Before(to) {
+		return nil, ErrInvalidReportWindow
+	}
+
+	rows, err := s.repo.ListPerformance(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RulePerformanceReport{
+		From:      from,
+		To:        to,
+		Rules:     make(map[string]PerformanceMetrics),
+		Decisions: make(map[string]PerformanceMetrics),
+		Daily:     rows,
+	}
+	if report.Daily == nil {
+		report.Daily = []RulePerformance{}
+	}
+
+	for _, row := range rows {
+		target := report.Rules
+		if row.Dimension == PerformanceDecision {
+			target = report.Decisions
+		}
+		m := target[row.Name]
+		m.add(row.PerformanceMetrics)
+		target[row.Name] = m
+	}
+	for _, target := range []map[string]PerformanceMetrics{report.Rules, report.Decisions} {
+		for name, m := range target {
+			m.computeRates()
+			target[name] = m
+		}
+	}
+	return report, nil
+}
+
+// add sums the counts of o into m. Rates must be recomputed afterwards.
+func (m *PerformanceMetrics) add(o PerformanceMetrics) {
+	m.Evaluated += o.Evaluated
+	m.Labeled += o.Labeled
+	m.Frauds += o.Frauds
+	m.Hits += o.Hits
+	m.TruePositives += o.TruePositives
+	m.FalsePositives += o.FalsePositives
+}
+
+func (m *PerformanceMetrics) computeRates() {
+	m.Precision, m.Recall, m.HitRate = 0, 0, 0
+	if labeledHits := m.TruePositives + m.FalsePositives; labeledHits > 0 {
+		m.Precision = float64(m.TruePositives) / float64(labeledHits)
+	}
+	if m.Frauds > 0 {
+		m.Recall = float64(m.TruePositives) / float64(m.Frauds)
+	}
+	if m.Evaluated > 0 {
+		m.HitRate = float64(m.Hits) / float64(m.Evaluated)
+	}
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,81 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ruleRepository struct {
+	db *gorm.DB
+}
+
+func NewRuleRepository(db *gorm.DB) RuleRepository {
+	return &ruleRepository{db: db}
+}
+
+func (r *ruleRepository) ListRules(ctx context.Context) ([]RiskRule, error) {
+	var rules []RiskRule
+	err := r.db.WithContext(ctx).
+		Order("id ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+func (r *ruleRepository) GetRuleByID(ctx context.Context, id int64) (*RiskRule, error) {
+	var rule RiskRule
+	err := r.db.WithContext(ctx).First(&rule, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRuleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *ruleRepository) CreateRule(ctx context.Context, rule *RiskRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+func (r *ruleRepository) UpdateRule(ctx context.Context, rule *RiskRule) error {
+	return r.db.WithContext(ctx).
+		Table("rules").
+		Where("id = ?", rule.ID).
+		Updates(map[string]interface{}{
+			"name":       rule.Name,
+			"threshold":  rule.Threshold,
+			"weight":     rule.Weight,
+			"enabled":    rule.Enabled,
+			"expression": rule.Expression,
+			"rule_set":   rule.RuleSet,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+func (r *ruleRepository) GetShadowComparison(
+	ctx context.Context,
+	from time.Time,
+	to time.Time,
+) ([]ShadowComparison, error) {
+
+	var result []ShadowComparison
+
+	err := r.db.WithContext(ctx).
+		Raw(`
+			SELECT
+				decision,
+				shadow_decision,
+				COUNT(*) AS count
+			FROM transaction_risks
+			WHERE shadow_decision IS NOT NULL
+			  AND evaluated_at >= ?
+			  AND evaluated_at < ?
+			GROUP BY decision, shadow_decision
+		`, from, to).
+		Scan(&result).Error
+
+	return result, err
+}
@@ -0,0 +1,216 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"risk-detection/internal/audit"
+)
+
+var (
+	ErrRuleNotFound = errors.New("rule not found")
+	ErrInvalidRule  = errors.New("invalid rule")
+)
+
+type ruleService struct {
+	repo        RuleRepository
+	riskService Service
+	auditLog    *audit.Logger
+}
+
+// NewRuleService manages rows in the rules table. Every change is validated,
+// audited and pushed into the risk service by reloading its rules.
+func NewRuleService(repo RuleRepository, riskService Service, auditLog *audit.Logger) RuleService {
+	return &ruleService{
+		repo:        repo,
+		riskService: riskService,
+		auditLog:    auditLog,
+	}
+}
+
+func (s *ruleService) ListRules(ctx context.Context) ([]RiskRule, error) {
+	return s.repo.ListRules(ctx)
+}
+
+func (s *ruleService) CreateRule(ctx context.Context, actorID string, req RuleRequest) (*RiskRule, error) {
+	rule := RiskRule{
+		Name:       strings.TrimSpace(req.Name),
+		Threshold:  req.Threshold,
+		Weight:     req.Weight,
+		Enabled:    true,
+		Expression: strings.TrimSpace(req.Expression),
+		RuleSet:    RuleSetLive,
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if req.RuleSet != "" {
+		rule.RuleSet = req.RuleSet
+	}
+
+	if err := s.validateRule(rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateRule(ctx, &rule); err != nil {
+		return nil, fmt.Errorf("create rule: %w", err)
+	}
+
+	s.logChange(audit.EventRuleCreated, "CREATE", actorID, rule, nil)
+
+	if err := s.riskService.ReloadRules(ctx); err != nil {
+		return nil, fmt.Errorf("reload rules: %w", err)
+	}
+	return &rule, nil
+}
+
+func (s *ruleService) UpdateRule(ctx context.Context, actorID string, id int64, req RuleRequest) (*RiskRule, error) {
+	existing, err := s.repo.GetRuleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	old := *existing
+
+	rule := *existing
+	rule.Name = strings.TrimSpace(req.Name)
+	rule.Threshold = req.Threshold
+	rule.Weight = req.Weight
+	rule.Expression = strings.TrimSpace(req.Expression)
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if req.RuleSet != "" {
+		rule.RuleSet = req.RuleSet
+	}
+
+	return s.applyUpdate(ctx, actorID, old, rule)
+}
+
+func (s *ruleService) SetRuleEnabled(ctx context.Context, actorID string, id int64, enabled bool) (*RiskRule, error) {
+	existing, err := s.repo.GetRuleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	old := *existing
+
+	rule := *existing
+	rule.Enabled = enabled
+
+	return s.applyUpdate(ctx, actorID, old, rule)
+}
+
+func (s *ruleService) applyUpdate(ctx context.Context, actorID string, old RiskRule, rule RiskRule) (*RiskRule, error) {
+	if err := s.validateRule(rule); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateRule(ctx, &rule); err != nil {
+		return nil, fmt.Errorf("update rule: %w", err)
+	}
+
+	s.logChange(audit.EventRuleUpdated, "UPDATE", actorID, rule, &old)
+
+	if err := s.riskService.ReloadRules(ctx); err != nil {
+		return nil, fmt.Errorf("reload rules: %w", err)
+	}
+	return &rule, nil
+}
+
+// ShadowReport compares live and shadow decisions of evaluations in [from, to).
+func (s *ruleService) ShadowReport(ctx context.Context, from time.Time, to time.Time) (*ShadowReport, error) {
+	rows, err := s.repo.GetShadowComparison(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ShadowReport{
+		From:       from,
+		To:         to,
+		ByDecision: make(map[string]ShadowDecisionReport),
+	}
+	for _, decision := range []string{"ALLOW", "FLAG", "BLOCK"} {
+		report.ByDecision[decision] = ShadowDecisionReport{ShadowCounts: make(map[string]int64)}
+	}
+
+	for _, row := range rows {
+		bucket, ok := report.ByDecision[row.Decision]
+		if !ok {
+			bucket = ShadowDecisionReport{ShadowCounts: make(map[string]int64)}
+		}
+		bucket.Total += row.Count
+		bucket.ShadowCounts[row.ShadowDecision] += row.Count
+		if row.ShadowDecision != row.Decision {
+			bucket.Disagreements += row.Count
+			report.Disagreements += row.Count
+		}
+		report.Total += row.Count
+		report.ByDecision[row.Decision] = bucket
+	}
+
+	for decision, bucket := range report.ByDecision {
+		if bucket.Total > 0 {
+			bucket.Rate = float64(bucket.Disagreements) / float64(bucket.Total)
+			report.ByDecision[decision] = bucket
+		}
+	}
+
+	return report, nil
+}
+
+func (s *ruleService) validateRule(rule RiskRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+	if rule.Weight < 0 || rule.Weight > 100 {
+		return fmt.Errorf("%w: weight must be between 0 and 100", ErrInvalidRule)
+	}
+	if rule.Threshold < 0 || rule.Threshold > 100 {
+		return fmt.Errorf("%w: threshold must be between 0 and 100", ErrInvalidRule)
+	}
+	if rule.RuleSet != "" && rule.RuleSet != RuleSetLive && rule.RuleSet != RuleSetShadow {
+		return fmt.Errorf("%w: rule_set must be LIVE or SHADOW", ErrInvalidRule)
+	}
+	if rule.Expression != "" {
+		if err := s.riskService.ValidateRuleExpression(rule.Expression); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	}
+	return nil
+}
+
+func ruleValues(rule RiskRule) map[string]interface{} {
+	return map[string]interface{}{
+		"name":       rule.Name,
+		"threshold":  rule.Threshold,
+		"weight":     rule.Weight,
+		"enabled":    rule.Enabled,
+		"expression": rule.Expression,
+		"rule_set":   rule.RuleSet,
+	}
+}
+
+func (s *ruleService) logChange(event audit.EventType, action string, actorID string, rule RiskRule, old *RiskRule) {
+	if s.auditLog == nil {
+		return
+	}
+
+	entry := audit.AuditLog{
+		EventType:  event,
+		Action:     action,
+		EntityType: "rules",
+		EntityID:   strconv.FormatInt(rule.ID, 10),
+		ActorType:  "USER",
+		ActorID:    actorID,
+		ActorRole:  "ADMIN",
+		NewValues:  ruleValues(rule),
+		Status:     "SUCCESS",
+	}
+	if old != nil {
+		entry.OldValues = ruleValues(*old)
+	}
+	s.auditLog.Log(entry)
+}
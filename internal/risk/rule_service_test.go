@@ -0,0 +1,285 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"risk-detection/internal/audit"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// ============ Mock Definitions ============
+
+type MockRuleRepository struct {
+	mock.Mock
+}
+
+func (m *MockRuleRepository) ListRules(ctx context.Context) ([]RiskRule, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]RiskRule), args.Error(1)
+}
+
+func (m *MockRuleRepository) GetRuleByID(ctx context.Context, id int64) (*RiskRule, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*RiskRule), args.Error(1)
+}
+
+func (m *MockRuleRepository) CreateRule(ctx context.Context, rule *RiskRule) error {
+	args := m.Called(ctx, rule)
+	return args.Error(0)
+}
+
+func (m *MockRuleRepository) UpdateRule(ctx context.Context, rule *RiskRule) error {
+	args := m.Called(ctx, rule)
+	return args.Error(0)
+}
+
+func (m *MockRuleRepository) GetShadowComparison(ctx context.Context, from time.Time, to time.Time) ([]ShadowComparison, error) {
+	args := m.Called(ctx, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]ShadowComparison), args.Error(1)
+}
+
+type MockRiskService struct {
+	mock.Mock
+}
+
+func (m *MockRiskService) CalculateRisk(ctx context.Context, tx interface{}) (*TransactionRisk, error) {
+	args := m.Called(ctx, tx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TransactionRisk), args.Error(1)
+}
+
+func (m *MockRiskService) GetRisk(ctx context.Context, transactionID uuid.UUID) (*TransactionRisk, error) {
+	args := m.Called(ctx, transactionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TransactionRisk), args.Error(1)
+}
+
+func (m *MockRiskService) ReloadRules(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockRiskService) ReloadPolicies(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockRiskService) RecordVerifiedChallenge(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	args := m.Called(ctx, userID, at)
+	return args.Error(0)
+}
+
+func (m *MockRiskService) SkippableNames() []string {
+	args := m.Called()
+	return args.Get(0).([]string)
+}
+
+func (m *MockRiskService) ValidateRuleExpression(src string) error {
+	args := m.Called(src)
+	return args.Error(0)
+}
+
+// ============ CreateRule Tests ============
+
+func TestRuleService_CreateRule(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         RuleRequest
+		validateErr error
+		createErr   error
+		reloadErr   error
+		expectError error
+		expectWrite bool
+		description string
+	}{
+		{
+			name:        "valid_scorer_rule",
+			req:         RuleRequest{Name: "NEW_DEVICE_RISK", Weight: 25},
+			expectWrite: true,
+			description: "Valid rule should be stored and reloaded",
+		},
+		{
+			name:        "valid_expression_rule",
+			req:         RuleRequest{Name: "LARGE_TRANSFER", Weight: 40, Expression: `tx.amount > 5 * user.avg_amount`},
+			expectWrite: true,
+			description: "Valid expression rule should be stored and reloaded",
+		},
+		{
+			name:        "invalid_expression",
+			req:         RuleRequest{Name: "BROKEN", Weight: 40, Expression: `tx.amount >`},
+			validateErr: errors.New("unexpected end of expression"),
+			expectError: ErrInvalidRule,
+			expectWrite: false,
+			description: "Invalid expression should be rejected before writing",
+		},
+		{
+			name:        "blank_name",
+			req:         RuleRequest{Name: "   ", Weight: 10},
+			expectError: ErrInvalidRule,
+			expectWrite: false,
+			description: "Blank name should be rejected",
+		},
+		{
+			name:        "weight_out_of_range",
+			req:         RuleRequest{Name: "TOO_HEAVY", Weight: 150},
+			expectError: ErrInvalidRule,
+			expectWrite: false,
+			description: "Weight above 100 should be rejected",
+		},
+		{
+			name:        "reload_failure",
+			req:         RuleRequest{Name: "NEW_DEVICE_RISK", Weight: 25},
+			reloadErr:   errors.New("db down"),
+			expectError: errors.New("reload rules: db down"),
+			expectWrite: true,
+			description: "Reload errors should be surfaced",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockRuleRepository)
+			riskSvc := new(MockRiskService)
+			repo.On("CreateRule", mock.Anything, mock.Anything).Return(tt.createErr)
+			riskSvc.On("ValidateRuleExpression", tt.req.Expression).Return(tt.validateErr)
+			riskSvc.On("ReloadRules", mock.Anything).Return(tt.reloadErr)
+
+			svc := NewRuleService(repo, riskSvc, &audit.Logger{})
+			rule, err := svc.CreateRule(context.Background(), "admin-1", tt.req)
+
+			if tt.expectError != nil {
+				assert.Error(t, err, tt.description)
+				if errors.Is(tt.expectError, ErrInvalidRule) {
+					assert.ErrorIs(t, err, ErrInvalidRule)
+				}
+			} else {
+				assert.NoError(t, err, tt.description)
+				assert.True(t, rule.Enabled, "Rules should default to enabled")
+			}
+
+			if tt.expectWrite {
+				repo.AssertCalled(t, "CreateRule", mock.Anything, mock.Anything)
+				riskSvc.AssertCalled(t, "ReloadRules", mock.Anything)
+			} else {
+				repo.AssertNotCalled(t, "CreateRule", mock.Anything, mock.Anything)
+				riskSvc.AssertNotCalled(t, "ReloadRules", mock.Anything)
+			}
+		})
+	}
+}
+
+// ============ UpdateRule / SetRuleEnabled Tests ============
+
+func TestRuleService_UpdateRule(t *testing.T) {
+	existing := &RiskRule{ID: 7, Name: "NEW_DEVICE_RISK", Weight: 25, Enabled: true}
+
+	repo := new(MockRuleRepository)
+	riskSvc := new(MockRiskService)
+	repo.On("GetRuleByID", mock.Anything, int64(7)).Return(existing, nil)
+	repo.On("UpdateRule", mock.Anything, mock.MatchedBy(func(r *RiskRule) bool {
+		return r.ID == 7 && r.Weight == 60 && r.Enabled
+	})).Return(nil)
+	riskSvc.On("ReloadRules", mock.Anything).Return(nil)
+
+	svc := NewRuleService(repo, riskSvc, &audit.Logger{})
+	rule, err := svc.UpdateRule(context.Background(), "admin-1", 7, RuleRequest{Name: "NEW_DEVICE_RISK", Weight: 60})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 60, rule.Weight)
+	assert.Equal(t, 25, existing.Weight, "Existing rule should not be mutated")
+	repo.AssertExpectations(t)
+	riskSvc.AssertExpectations(t)
+}
+
+func TestRuleService_UpdateRule_NotFound(t *testing.T) {
+	repo := new(MockRuleRepository)
+	riskSvc := new(MockRiskService)
+	repo.On("GetRuleByID", mock.Anything, int64(99)).Return(nil, ErrRuleNotFound)
+
+	svc := NewRuleService(repo, riskSvc, &audit.Logger{})
+	_, err := svc.UpdateRule(context.Background(), "admin-1", 99, RuleRequest{Name: "X", Weight: 1})
+
+	assert.ErrorIs(t, err, ErrRuleNotFound)
+	riskSvc.AssertNotCalled(t, "ReloadRules", mock.Anything)
+}
+
+func TestRuleService_SetRuleEnabled(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+	}{
+		{name: "disable_rule", enabled: false},
+		{name: "enable_rule", enabled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockRuleRepository)
+			riskSvc := new(MockRiskService)
+			repo.On("GetRuleByID", mock.Anything, int64(3)).Return(&RiskRule{ID: 3, Name: "R", Weight: 10, Enabled: !tt.enabled}, nil)
+			repo.On("UpdateRule", mock.Anything, mock.MatchedBy(func(r *RiskRule) bool {
+				return r.Enabled == tt.enabled
+			})).Return(nil)
+			riskSvc.On("ReloadRules", mock.Anything).Return(nil)
+
+			svc := NewRuleService(repo, riskSvc, &audit.Logger{})
+			rule, err := svc.SetRuleEnabled(context.Background(), "admin-1", 3, tt.enabled)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.enabled, rule.Enabled)
+			riskSvc.AssertExpectations(t)
+		})
+	}
+}
+
+// ============ ShadowReport Tests ============
+
+func TestRuleService_ShadowReport(t *testing.T) {
+	repo := new(MockRuleRepository)
+	from := time.Now().Add(-24 * time.Hour)
+	to := time.Now()
+	repo.On("GetShadowComparison", mock.Anything, from, to).Return([]ShadowComparison{
+		{Decision: "ALLOW", ShadowDecision: "ALLOW", Count: 90},
+		{Decision: "ALLOW", ShadowDecision: "FLAG", Count: 10},
+		{Decision: "FLAG", ShadowDecision: "BLOCK", Count: 4},
+		{Decision: "FLAG", ShadowDecision: "FLAG", Count: 4},
+	}, nil)
+
+	svc := NewRuleService(repo, new(MockRiskService), &audit.Logger{})
+	report, err := svc.ShadowReport(context.Background(), from, to)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(108), report.Total)
+	assert.Equal(t, int64(14), report.Disagreements)
+
+	allow := report.ByDecision["ALLOW"]
+	assert.Equal(t, int64(100), allow.Total)
+	assert.Equal(t, int64(10), allow.Disagreements)
+	assert.InDelta(t, 0.1, allow.Rate, 1e-9)
+	assert.Equal(t, int64(10), allow.ShadowCounts["FLAG"])
+
+	flag := report.ByDecision["FLAG"]
+	assert.InDelta(t, 0.5, flag.Rate, 1e-9)
+
+	block := report.ByDecision["BLOCK"]
+	assert.Equal(t, int64(0), block.Total)
+}
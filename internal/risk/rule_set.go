@@ -0,0 +1,184 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"risk-detection/internal/risk/expr"
+
+	"github.com/google/uuid"
+)
+
+// Rule sets stored in rules.rule_set. LIVE rules drive the decision; SHADOW
+// rules are a candidate configuration scored alongside for comparison only.
+const (
+	RuleSetLive   = "LIVE"
+	RuleSetShadow = "SHADOW"
+)
+
+// ruleSet is an immutable snapshot of rules and their compiled expressions.
+type ruleSet struct {
+	rules    map[string]RiskRule
+	programs map[string]*expr.Program
+}
+
+// newRuleSet compiles every rule that carries an expression against schema.
+// One invalid rule rejects the whole set so a bad edit never goes live.
+func newRuleSet(rules []RiskRule, schema expr.Schema) (*ruleSet, error) {
+	rs := &ruleSet{
+		rules:    make(map[string]RiskRule),
+		programs: make(map[string]*expr.Program),
+	}
+
+	for _, r := range rules {
+		rs.rules[r.Name] = r
+		if r.Expression == "" {
+			continue
+		}
+		prog, err := expr.Compile(r.Expression, schema)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		rs.programs[r.Name] = prog
+	}
+	return rs, nil
+}
+
+// splitRuleSets separates loaded rules by rule set. Rows without a rule set
+// belong to LIVE.
+func splitRuleSets(rules []RiskRule) (live []RiskRule, shadow []RiskRule) {
+	for _, r := range rules {
+		if r.RuleSet == RuleSetShadow {
+			shadow = append(shadow, r)
+		} else {
+			live = append(live, r)
+		}
+	}
+	return live, shadow
+}
+
+// scorerRule returns the enabled, non-expression rule weighting a scorer.
+func (rs *ruleSet) scorerRule(name string) (RiskRule, bool) {
+	if rs == nil {
+		return RiskRule{}, false
+	}
+	rule, ok := rs.rules[name]
+	if !ok || !rule.Enabled || rule.Expression != "" {
+		return RiskRule{}, false
+	}
+	return rule, true
+}
+
+func (rs *ruleSet) hasExpressions() bool {
+	return rs != nil && len(rs.programs) > 0
+}
+
+// apply weights raw scorer results and expression matches by this rule set
+// and returns the total score with its reason breakdown.
+func (rs *ruleSet) apply(txID uuid.UUID, signals *signals) (int, []RiskReason) {
+	total := 0
+	var reasons []RiskReason
+
+	for _, name := range signals.order {
+		rule, ok := rs.scorerRule(name)
+		if !ok {
+			continue
+		}
+		res := signals.results[name]
+		total += applyRule(res.Score, rule)
+		reasons = append(reasons, res.riskReasons(txID, rule)...)
+	}
+
+	names := make([]string, 0, len(rs.programs))
+	for name := range rs.programs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		rule := rs.rules[name]
+		if !rule.Enabled || signals.lists.skips(name) {
+			continue
+		}
+
+		prog := rs.programs[name]
+		matched, err := prog.Eval(signals.env)
+		if err != nil {
+			log.Printf("rule %s evaluation failed: %v", name, err)
+			continue
+		}
+		if matched {
+			res := ScoreResult{
+				Score:   expressionScore(rule),
+				Reasons: []Reason{{Code: rule.Name, Score: expressionScore(rule), Inputs: expressionInputs(prog, signals.env)}},
+			}
+			total += applyRule(res.Score, rule)
+			reasons = append(reasons, res.riskReasons(txID, rule)...)
+		}
+	}
+
+	return total, reasons
+}
+
+// signals holds the raw inputs of one evaluation, shared by every rule set.
+type signals struct {
+	order   []string
+	results map[string]ScoreResult
+	env     expr.Env
+	lists   ListMatch
+}
+
+// collectSignals runs each registered scorer once if any of the given rule
+// sets weights it and no allowlist entry skips it, and builds the
+// expression environment if needed.
+func (s *service) collectSignals(ctx context.Context, txdto TransactionDTO, lists ListMatch, sets ...*ruleSet) (*signals, error) {
+	out := &signals{results: make(map[string]ScoreResult), lists: lists}
+
+	for _, sc := range s.getScorers() {
+		if lists.skips(sc.Name()) {
+			continue
+		}
+		needed := false
+		for _, rs := range sets {
+			if _, ok := rs.scorerRule(sc.Name()); ok {
+				needed = true
+				break
+			}
+		}
+		if !needed {
+			continue
+		}
+
+		res, err := sc.Score(ctx, txdto)
+		if err != nil {
+			return nil, err
+		}
+		out.order = append(out.order, sc.Name())
+		out.results[sc.Name()] = res
+	}
+
+	for _, rs := range sets {
+		if !rs.hasExpressions() {
+			continue
+		}
+		behavior, err := s.repo.GetBehaviorByUserID(ctx, txdto.UserID)
+		if err != nil {
+			return nil, err
+		}
+		out.env = buildExpressionEnv(txdto, behavior)
+		for name, v := range s.velocityEnv(txdto) {
+			out.env[name] = v
+		}
+		break
+	}
+
+	return out, nil
+}
+
+func (s *service) ruleSets() (live *ruleSet, shadow *ruleSet) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.live, s.shadow
+}
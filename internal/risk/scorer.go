@@ -0,0 +1,152 @@
+package risk
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Rule names of the built-in scorers. Each scorer is looked up in the rules
+// table by its name, so a row with the same name enables and weights it.
+const (
+	RuleTransactionAmount    = "TRANSACTION_AMOUNT_RISK"
+	RuleNewDevice            = "NEW_DEVICE_RISK"
+	RuleTransactionFrequency = "TRANSACTION_FREQUENCY_RISK"
+	RuleGeoLocation          = "GEO_LOCATION_RISK"
+	RuleCounterparty         = "COUNTERPARTY_RISK"
+	RuleTimeOfDay            = "TIME_OF_DAY_RISK"
+	RuleModel                = "MODEL_RISK"
+)
+
+// Reason is one sub-check of a scorer that contributed to its raw score,
+// together with the input values it was computed from.
+type Reason struct {
+	Code   string
+	Score  int
+	Inputs map[string]interface{}
+}
+
+// ScoreResult is the raw score (0-100) of a scorer and the reasons behind it.
+type ScoreResult struct {
+	Score   int
+	Reasons []Reason
+
+	// ModelVersion is set by scorers backed by a trained model
+	ModelVersion string
+}
+
+// Scorer computes a raw risk score for a single signal.
+// The final contribution is weighted by the rule with the same name.
+type Scorer interface {
+	Name() string
+	Score(ctx context.Context, tx TransactionDTO) (ScoreResult, error)
+}
+
+// ScorerFunc adapts a plain function to the Scorer interface. A non-zero
+// score is reported under a single reason named after the rule.
+type ScorerFunc struct {
+	RuleName string
+	Fn       func(ctx context.Context, tx TransactionDTO) (int, error)
+}
+
+func (f ScorerFunc) Name() string {
+	return f.RuleName
+}
+
+func (f ScorerFunc) Score(ctx context.Context, tx TransactionDTO) (ScoreResult, error) {
+	score, err := f.Fn(ctx, tx)
+	if err != nil {
+		return ScoreResult{}, err
+	}
+	return ScoreResult{Score: score}, nil
+}
+
+// detailedScorer adapts a function that reports its own reasons.
+type detailedScorer struct {
+	name string
+	fn   func(ctx context.Context, tx TransactionDTO) (ScoreResult, error)
+}
+
+func (d detailedScorer) Name() string {
+	return d.name
+}
+
+func (d detailedScorer) Score(ctx context.Context, tx TransactionDTO) (ScoreResult, error) {
+	return d.fn(ctx, tx)
+}
+
+// riskReasons converts the result into rows for transaction_risk_reasons.
+func (r ScoreResult) riskReasons(txID uuid.UUID, rule RiskRule) []RiskReason {
+	reasons := r.Reasons
+	if len(reasons) == 0 && r.Score != 0 {
+		reasons = []Reason{{Code: rule.Name, Score: r.Score}}
+	}
+
+	out := make([]RiskReason, 0, len(reasons))
+	for _, reason := range reasons {
+		out = append(out, RiskReason{
+			TransactionID: txID,
+			RuleName:      rule.Name,
+			ReasonCode:    reason.Code,
+			RawScore:      reason.Score,
+			Weight:        rule.Weight,
+			WeightedScore: applyRule(reason.Score, rule),
+			Inputs:        reason.Inputs,
+		})
+	}
+	return out
+}
+
+// RegisterScorer adds a scorer to the service. A scorer registered under an
+// existing name replaces the previous one.
+func (s *service) RegisterScorer(sc Scorer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.scorers {
+		if existing.Name() == sc.Name() {
+			s.scorers[i] = sc
+			return
+		}
+	}
+	s.scorers = append(s.scorers, sc)
+}
+
+func (s *service) getScorers() []Scorer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]Scorer, len(s.scorers))
+	copy(out, s.scorers)
+	return out
+}
+
+// registerDefaultScorers wires the built-in amount, device, frequency and
+// time-of-day signals.
+func (s *service) registerDefaultScorers() {
+	s.RegisterScorer(detailedScorer{
+		name: RuleTransactionAmount,
+		fn: func(ctx context.Context, tx TransactionDTO) (ScoreResult, error) {
+			score, reasons, err := s.transactionAmountRisk(ctx, tx.UserID, tx.TxType, tx.Amount, tx.TxTime)
+			return ScoreResult{Score: int(score), Reasons: reasons}, err
+		},
+	})
+	s.RegisterScorer(detailedScorer{
+		name: RuleNewDevice,
+		fn: func(ctx context.Context, tx TransactionDTO) (ScoreResult, error) {
+			score, reasons, err := s.transactionDeviceRisk(ctx, tx.UserID, tx.DeviceID, tx.IPAddress)
+			return ScoreResult{Score: int(score), Reasons: reasons}, err
+		},
+	})
+	s.RegisterScorer(detailedScorer{
+		name: RuleTransactionFrequency,
+		fn: func(ctx context.Context, tx TransactionDTO) (ScoreResult, error) {
+			score, reasons, err := s.transactionFrequencyRisk(ctx, tx)
+			return ScoreResult{Score: int(score), Reasons: reasons}, err
+		},
+	})
+	s.RegisterScorer(detailedScorer{
+		name: RuleTimeOfDay,
+		fn:   s.timeOfDayRisk,
+	})
+}
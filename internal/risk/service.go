@@ -3,42 +3,100 @@ package risk
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 	"math"
 	"reflect"
 	"risk-detection/internal/audit"
+	"risk-detection/internal/db"
+	"risk-detection/internal/risk/expr"
+	"risk-detection/internal/risk/velocity"
 	"sync"
 	"time"
 	"runtime/debug"
+	"sort"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrRiskNotFound = errors.New("risk evaluation not found")
+	// ErrBehaviorConflict means the baseline changed since it was read.
+	ErrBehaviorConflict = errors.New("user behavior was updated concurrently")
+)
+
 // TransactionRepository interface - abstraction to avoid circular dependency
 type TransactionRepository interface {
-	CountTransactionFrequency(tx context.Context, userID uuid.UUID, duration int32) (float64, error)
+	// CountTransactionFrequency counts the user's transactions of the last
+	// duration minutes other than excludeTxID.
+	CountTransactionFrequency(ctx context.Context, userID uuid.UUID, duration int32, excludeTxID uuid.UUID) (float64, error)
 }
 
 type service struct {
 	repo            TransactionRiskRepository
 	transactionRepo TransactionRepository
-	rules           map[string]RiskRule
+	live            *ruleSet
+	shadow          *ruleSet
+	scorers         []Scorer
+	policyRepo      PolicyRepository
+	policies        map[string]DecisionPolicy
+	deviceRepo      DeviceRepository
+	velocity        *velocity.Store
+	windows         []velocity.Window
+	schema          expr.Schema
+	lists           *ListIndex
+	snapshots       FeatureSnapshotRepository
+	evalTimeout     time.Duration
 	mu              sync.RWMutex
 	auditLog        *audit.Logger
 }
 
-func NewService(repo TransactionRiskRepository, transactionRepo TransactionRepository, auditLog *audit.Logger) (Service, error) {
+// Option configures optional dependencies of the risk service.
+type Option func(*service)
+
+// WithScorers registers additional scorers after the built-in ones. They are
+// enabled and weighted by rules with the same name.
+func WithScorers(scorers ...Scorer) Option {
+	return func(s *service) {
+		for _, sc := range scorers {
+			s.RegisterScorer(sc)
+		}
+	}
+}
+
+// WithPolicyRepository loads per-transaction-type decision policies.
+// Without it the built-in 30/70 thresholds apply.
+func WithPolicyRepository(repo PolicyRepository) Option {
+	return func(s *service) {
+		s.policyRepo = repo
+	}
+}
+
+func NewService(repo TransactionRiskRepository, transactionRepo TransactionRepository, auditLog *audit.Logger, opts ...Option) (Service, error) {
 
 	s := &service{
 		repo:            repo,
 		transactionRepo: transactionRepo,
 		auditLog:        auditLog,
-		rules:           make(map[string]RiskRule),
+		live:            &ruleSet{},
+		policies:        make(map[string]DecisionPolicy),
 	}
+	s.registerDefaultScorers()
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	// Rule expressions see the velocity windows of this service's store
+	s.windows = ruleWindows(s.velocity)
+	s.schema = newRuleSchema(s.windows)
+
 	if err := s.ReloadRules(context.Background()); err != nil {
 		return nil, err
 	}
+	if err := s.ReloadPolicies(context.Background()); err != nil {
+		return nil, err
+	}
 
 	return s, nil
 }
@@ -49,67 +107,108 @@ func (s *service) ReloadRules(ctx context.Context) error {
 		return err
 	}
 
-	m := make(map[string]RiskRule)
-	for _, r := range rules {
-		m[r.Name] = r
+	liveRules, shadowRules := splitRuleSets(rules)
+
+	live, err := newRuleSet(liveRules, s.schema)
+	if err != nil {
+		return err
+	}
+
+	// Shadow is optional; nil means no candidate configuration is running
+	var shadow *ruleSet
+	if len(shadowRules) > 0 {
+		shadow, err = newRuleSet(shadowRules, s.schema)
+		if err != nil {
+			return fmt.Errorf("shadow %w", err)
+		}
 	}
 
 	s.mu.Lock()
-	s.rules = m
+	s.live = live
+	s.shadow = shadow
 	s.mu.Unlock()
 
 	return nil
 }
 
-func (s *service) CalculateRisk(tx interface{}) (*TransactionRisk, error) {
-
-	txdto, err := ExtractTxContext(tx)
-	if err != nil {
-		log.Printf("unable to extract transaction context: %v", err)
-		return nil, err
-	}
-
-	var result TransactionRisk
-	result.TransactionID = txdto.TxID
-
-	// Calculate risk score
-	riskScore1, err := s.transactionAmountRisk(context.Background(), txdto.UserID, txdto.Amount, txdto.TxTime)
+// GetRisk returns the stored evaluation of a transaction with its reasons.
+func (s *service) GetRisk(ctx context.Context, transactionID uuid.UUID) (*TransactionRisk, error) {
+	risk, err := s.repo.GetRiskByTransactionID(ctx, transactionID)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRiskNotFound
+		}
 		return nil, err
 	}
+	return risk, nil
+}
 
-	riskScore2, err := s.transactionDeviceRisk(context.Background(), txdto.UserID, txdto.DeviceID, txdto.IPAddress)
-	if err != nil {
-		return nil, err
-	}
-	riskScore3, err := s.transactionFrequencyRisk(context.Background(), txdto.UserID)
+func (s *service) CalculateRisk(ctx context.Context, tx interface{}) (*TransactionRisk, error) {
 
+	txdto, err := ExtractTxContext(tx)
 	if err != nil {
+		log.Printf("unable to extract transaction context: %v", err)
 		return nil, err
 	}
-	totalRisk := 0
 
-	if rule, ok := s.getRule("TRANSACTION_AMOUNT_RISK"); ok {
-		totalRisk += applyRule(int(riskScore1), rule)
-	}
+	var result TransactionRisk
+	result.TransactionID = txdto.TxID
 
-	if rule, ok := s.getRule("NEW_DEVICE_RISK"); ok {
-		totalRisk += applyRule(int(riskScore2), rule)
-	}
+	live, shadow := s.ruleSets()
+	match := s.matchLists(txdto)
+
+	// Scoring runs under the evaluation deadline and outside the caller's
+	// unit of work, so a timed-out read cannot abort it; persisting the
+	// result below joins the unit of work
+	evalCtx, cancel := s.evaluationContext(db.Detach(ctx))
+	defer cancel()
+	snapshot := s.takeSnapshot(evalCtx, txdto)
+
+	policy := s.policyFor(txdto.TxType)
+	result.PolicyType = policy.TransactionType
+	result.PolicyVersion = policy.Version
+
+	if match.Block != nil {
+		// A blocklist hit decides on its own; no scorer can outweigh it
+		result.Reasons = match.reasons(txdto.TxID)
+		result.RiskScore = 100
+		result.RiskLevel = "HIGH"
+		result.Decision = "BLOCK"
+	} else {
+		// Scorers run once; live and shadow rules only differ in weighting
+		sig, err := s.collectSignals(evalCtx, txdto, match, live, shadow)
+		switch {
+		case err != nil && evaluationTimedOut(ctx, evalCtx, err):
+			log.Printf("risk evaluation of %s timed out, falling back to %s", txdto.TxID, FallbackDecision)
+			s.applyFallback(&result, policy, txdto.TxID)
+		case err != nil:
+			return nil, err
+		default:
+			totalRisk, reasons := live.apply(txdto.TxID, sig)
+			result.Reasons = append(match.reasons(txdto.TxID), reasons...)
+
+			result.RiskScore = int(totalRisk)
+			result.RiskLevel = policy.RiskLevel(result.RiskScore)
+			result.Decision = policy.Decision(result.RiskScore)
+
+			if res, ok := sig.results[RuleModel]; ok && res.ModelVersion != "" {
+				result.ModelVersion = &res.ModelVersion
+			}
 
-	if rule, ok := s.getRule("TRANSACTION_FREQUENCY_RISK"); ok {
-		totalRisk += applyRule(int(riskScore3), rule)
+			if shadow != nil {
+				shadowScore, _ := shadow.apply(txdto.TxID, sig)
+				shadowDecision := policy.Decision(shadowScore)
+				result.ShadowRiskScore = &shadowScore
+				result.ShadowDecision = &shadowDecision
+			}
+		}
 	}
-
-	result.RiskScore = int(totalRisk)
-	result.RiskLevel = calculateRiskLevel(result.RiskScore)
-	result.Decision = riskDesion(result.RiskScore)
 	result.EvaluatedAt = time.Now()
 
-	if s.repo.Create(&result) != nil {
-		log.Printf("unable to save risk matrix")
-
+	if err := s.repo.Create(ctx, &result); err != nil {
+		return nil, fmt.Errorf("unable to save risk matrix: %w", err)
 	}
+	s.saveSnapshot(ctx, snapshot, result.EvaluatedAt)
 	s.auditLog.Log(audit.AuditLog{
 		EventType:  audit.EventRiskEvaluated,
 		Action:     "EVALUATE",
@@ -122,35 +221,69 @@ func (s *service) CalculateRisk(tx interface{}) (*TransactionRisk, error) {
 		Status:     "SUCCESS",
 	})
 
-	// Fetch behavior to update it
-	behavior, err := s.repo.GetBehaviorByUserID(context.Background(), txdto.UserID)
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+	s.recordDevice(ctx, txdto)
+
+	if hasTypeBaseline(txdto.TxType) {
+		s.updateTypeBehavior(ctx, txdto)
+	}
+
+	// Behavior errors fail the evaluation so the caller's unit of work
+	// rolls back with them
+	if err := s.foldBehavior(ctx, txdto, BehaviorAllTypes); err != nil {
 		return nil, err
 	}
+	s.recordVelocity(ctx, txdto)
 
-	// If behavior doesn't exist, create it
-	if behavior == nil {
-		if err := s.CreateUserBehavior(context.Background(), txdto.UserID); err != nil {
-			log.Printf("failed to create user behavior: %v", err)
-			return &result, nil // Return result even if behavior creation fails
+	return &result, nil
+}
+
+// foldBehavior adds tx to the user's baseline for txType, creating the
+// baseline on the user's first transaction. When a concurrent transaction of
+// the same user updates the baseline first, it re-reads and tries again. Every
+// conflict means another update went through, so it keeps trying until it
+// wins or ctx ends rather than giving up after a fixed number of losses.
+func (s *service) foldBehavior(ctx context.Context, tx TransactionDTO, txType string) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("unable to update user behavior: %w", err)
 		}
-		// Fetch the newly created behavior
-		behavior, err = s.repo.GetBehaviorByUserID(context.Background(), txdto.UserID)
+
+		behavior, err := s.behaviorFor(ctx, tx.UserID, txType)
 		if err != nil {
-			log.Printf("unable to fetch behavior after creation: %v", err)
-			return &result, nil // Return result even if fetch fails
+			return err
 		}
-	}
 
-	// Update behavior after transaction only if behavior exists
-	if behavior != nil {
-		if err := s.UpdateUserBehaviorAfterTransaction(context.Background(), behavior, txdto.Amount, txdto.TxID, txdto.TxTime); err != nil {
-			log.Printf("unable to update user behavior: %v", err)
-			// Continue even if behavior update fails - risk already calculated
+		// If behavior doesn't exist, create it
+		if behavior == nil {
+			if err := s.createBehavior(ctx, tx.UserID, txType); err != nil {
+				return fmt.Errorf("failed to create user behavior: %w", err)
+			}
+			// Fetch the newly created behavior
+			behavior, err = s.behaviorFor(ctx, tx.UserID, txType)
+			if err != nil {
+				return fmt.Errorf("unable to fetch behavior after creation: %w", err)
+			}
+			if behavior == nil {
+				return fmt.Errorf("user behavior missing after creation")
+			}
+		}
+
+		err = s.UpdateUserBehaviorAfterTransaction(ctx, behavior, tx.Amount, tx.TxID, tx.TxTime)
+		if errors.Is(err, ErrBehaviorConflict) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("unable to update user behavior: %w", err)
 		}
+		return nil
 	}
+}
 
-	return &result, nil
+func (s *service) behaviorFor(ctx context.Context, userID uuid.UUID, txType string) (*UserBehavior, error) {
+	if txType == BehaviorAllTypes {
+		return s.repo.GetBehaviorByUserID(ctx, userID)
+	}
+	return s.repo.GetTypeBehavior(ctx, userID, txType)
 }
 
 func ExtractTxContext(tx any) (TransactionDTO, error) {
@@ -160,6 +293,17 @@ func ExtractTxContext(tx any) (TransactionDTO, error) {
 		return dto, errors.New("nil transaction input")
 	}
 
+	// Already normalized
+	switch v := tx.(type) {
+	case TransactionDTO:
+		return v, nil
+	case *TransactionDTO:
+		if v == nil {
+			return dto, errors.New("nil transaction pointer")
+		}
+		return *v, nil
+	}
+
 	val := reflect.ValueOf(tx)
 	if !val.IsValid() {
 		return dto, errors.New("nil transaction")
@@ -188,6 +332,10 @@ func ExtractTxContext(tx any) (TransactionDTO, error) {
 		dto.Amount, _ = f.Interface().(float64)
 	}
 
+	if f := val.FieldByName("TransactionType"); f.IsValid() && f.CanInterface() {
+		dto.TxType, _ = f.Interface().(string)
+	}
+
 	if f := val.FieldByName("TransactionTime"); f.IsValid() && f.CanInterface() {
 		dto.TxTime, _ = f.Interface().(time.Time)
 	}
@@ -196,10 +344,19 @@ func ExtractTxContext(tx any) (TransactionDTO, error) {
 		dto.DeviceID, _ = f.Interface().(string)
 	}
 
-	if f := val.FieldByName("IpAdress"); f.IsValid() && f.CanInterface() {
+	if f := val.FieldByName("IPAddress"); f.IsValid() && f.CanInterface() {
 		dto.IPAddress, _ = f.Interface().(string)
 	}
 
+	if f := val.FieldByName("ReceiverID"); f.IsValid() && f.CanInterface() {
+		switch v := f.Interface().(type) {
+		case *uuid.UUID:
+			dto.ReceiverID = v
+		case uuid.UUID:
+			dto.ReceiverID = &v
+		}
+	}
+
 	return dto, nil
 }
 
@@ -210,30 +367,6 @@ func applyRule(rawScore int, rule RiskRule) int {
 
 	return (rule.Weight * rawScore) / 100
 }
-func (s *service) getRule(name string) (RiskRule, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	r, ok := s.rules[name]
-	return r, ok
-}
-func calculateRiskLevel(riskScore int) string {
-	if riskScore <= 30 {
-		return "LOW"
-	} else if riskScore <= 70 {
-		return "MEDIUM"
-	}
-	return "HIGH"
-}
-func riskDesion(riskScore int) string {
-	if riskScore <= 30 {
-		return "ALLOW"
-	} else if riskScore <= 70 {
-		return "FLAG"
-	}
-	return "BLOCK"
-}
-
 func safeGo(wg *sync.WaitGroup, fn func()) {
 	wg.Add(1)
 	go func() {
@@ -251,9 +384,10 @@ func safeGo(wg *sync.WaitGroup, fn func()) {
 func (s *service) transactionAmountRisk(
 	ctx context.Context,
 	userID uuid.UUID,
+	txType string,
 	amount float64,
 	txTime time.Time,
-) (score int32, err error) {
+) (score int32, reasons []Reason, err error) {
 
 	// ---- Top-level panic protection ----
 	defer func() {
@@ -261,6 +395,7 @@ func (s *service) transactionAmountRisk(
 			log.Printf("[PANIC][transactionAmountRisk][TOP] %v\n%s",
 				r, debug.Stack())
 			score = 50 // safe fallback score
+			reasons = []Reason{{Code: "AMOUNT_EVALUATION_FAILED", Score: 50}}
 			err = nil
 		}
 	}()
@@ -269,16 +404,25 @@ func (s *service) transactionAmountRisk(
 
 	behavior, err := s.repo.GetBehaviorByUserID(ctx, userID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			_ = s.CreateUserBehavior(context.Background(), userID)
-			return 20, nil
-		}
-		return 0, err
+		return 0, nil, err
 	}
 
-	// New / empty user
+	// New / empty user; the baseline is created once the transaction is
+	// folded in
 	if behavior == nil || behavior.TotalTransactions == 0 {
-		return 20, nil
+		return 20, []Reason{{Code: "AMOUNT_NO_HISTORY", Score: 20}}, nil
+	}
+
+	// Compare against the transaction type's own baseline once it has
+	// enough history, otherwise against the user-wide one
+	baseline := BehaviorAllTypes
+	typed, err := s.typeBaseline(ctx, userID, txType)
+	if err != nil {
+		return 0, nil, err
+	}
+	if typed != nil {
+		behavior = typed
+		baseline = txType
 	}
 
 	var (
@@ -287,20 +431,25 @@ func (s *service) transactionAmountRisk(
 		mu        sync.Mutex
 	)
 
+	addReason := func(points int32, code string, inputs map[string]interface{}) {
+		inputs["baseline"] = baseline
+		mu.Lock()
+		riskScore += points
+		reasons = append(reasons, Reason{Code: code, Score: int(points), Inputs: inputs})
+		mu.Unlock()
+	}
+
 	// ---- Rule 1: Relative Amount (avg) ----
 	safeGo(&wg, func() {
 		if behavior.AvgTransactionAmount <= 0 {
 			return
 		}
 		ratio := amount / behavior.AvgTransactionAmount
+		inputs := map[string]interface{}{"amount": amount, "avg_amount": behavior.AvgTransactionAmount, "ratio": ratio}
 		if ratio > 10 {
-			mu.Lock()
-			riskScore += 35
-			mu.Unlock()
+			addReason(35, "AMOUNT_RATIO_GT_10", inputs)
 		} else if ratio > 5 {
-			mu.Lock()
-			riskScore += 20
-			mu.Unlock()
+			addReason(20, "AMOUNT_RATIO_GT_5", inputs)
 		}
 	})
 
@@ -310,14 +459,11 @@ func (s *service) transactionAmountRisk(
 			return
 		}
 		z := (amount - behavior.AvgTransactionAmount) / behavior.AmountStdDev
+		inputs := map[string]interface{}{"amount": amount, "avg_amount": behavior.AvgTransactionAmount, "std_dev": behavior.AmountStdDev, "z_score": z}
 		if z > 3 {
-			mu.Lock()
-			riskScore += 30
-			mu.Unlock()
+			addReason(30, "AMOUNT_ZSCORE_GT_3", inputs)
 		} else if z > 2 {
-			mu.Lock()
-			riskScore += 20
-			mu.Unlock()
+			addReason(20, "AMOUNT_ZSCORE_GT_2", inputs)
 		}
 	})
 
@@ -327,9 +473,7 @@ func (s *service) transactionAmountRisk(
 			return
 		}
 		if amount/behavior.RecentAvgAmount > 4 {
-			mu.Lock()
-			riskScore += 10
-			mu.Unlock()
+			addReason(10, "AMOUNT_EMA_DEVIATION", map[string]interface{}{"amount": amount, "recent_avg_amount": behavior.RecentAvgAmount})
 		}
 	})
 
@@ -342,9 +486,7 @@ func (s *service) transactionAmountRisk(
 			(amount - behavior.LastTransactionAmount) /
 				behavior.LastTransactionAmount
 		if velocity > 3 {
-			mu.Lock()
-			riskScore += 20
-			mu.Unlock()
+			addReason(20, "AMOUNT_SUDDEN_JUMP", map[string]interface{}{"amount": amount, "last_amount": behavior.LastTransactionAmount, "velocity": velocity})
 		}
 	})
 
@@ -353,14 +495,11 @@ func (s *service) transactionAmountRisk(
 		if behavior.HighValueThreshold <= 0 {
 			return
 		}
+		inputs := map[string]interface{}{"amount": amount, "p95_amount": behavior.HighValueThreshold}
 		if amount > 2*behavior.HighValueThreshold {
-			mu.Lock()
-			riskScore += 30
-			mu.Unlock()
+			addReason(30, "AMOUNT_ABOVE_2X_P95", inputs)
 		} else if amount > behavior.HighValueThreshold {
-			mu.Lock()
-			riskScore += 20
-			mu.Unlock()
+			addReason(20, "AMOUNT_ABOVE_P95", inputs)
 		}
 	})
 
@@ -369,76 +508,83 @@ func (s *service) transactionAmountRisk(
 		if behavior.LastTransactionTime == nil {
 			return
 		}
-		if txTime.Sub(*behavior.LastTransactionTime).Seconds() < 30 {
-			mu.Lock()
-			riskScore += 20
-			mu.Unlock()
+		gap := txTime.Sub(*behavior.LastTransactionTime).Seconds()
+		if gap < 30 {
+			addReason(20, "BACK_TO_BACK_TRANSACTION", map[string]interface{}{"seconds_since_last": gap})
 		}
 	})
 
 	wg.Wait()
 
+	// Goroutines finish in any order; keep the breakdown stable
+	sort.Slice(reasons, func(i, j int) bool { return reasons[i].Code < reasons[j].Code })
+
 	// ---- Clamp final score ----
 	if riskScore > 100 {
 		riskScore = 100
 	}
 
-	return riskScore, nil
+	return riskScore, reasons, nil
 }
 
-func (s *service) transactionDeviceRisk(ctx context.Context, userID uuid.UUID, txDeviceID string, txIpAddress string) (int32, error) {
+func (s *service) transactionDeviceRisk(ctx context.Context, userID uuid.UUID, txDeviceID string, txIpAddress string) (int32, []Reason, error) {
 	log.Println("device security risk triggered")
 
 	// Return 0 if no device ID provided
 	if txDeviceID == "" {
-		return 0, nil
+		return 0, nil, nil
 	}
 
-	deviceInfo, err := s.repo.GetDeviceInfo(context.Background(), userID)
+	deviceInfo, err := s.repo.GetDeviceInfo(ctx, userID)
 	if err != nil {
+		if ctx.Err() != nil {
+			return 0, nil, ctx.Err()
+		}
 		log.Printf("unable to get device information: %v", err)
 		// Return moderate risk if device info not found
-		return 20, nil
+		return 20, []Reason{{Code: "DEVICE_UNKNOWN_HISTORY", Score: 20, Inputs: map[string]interface{}{"device_id": txDeviceID}}}, nil
 	}
 
 	// Nil check for deviceInfo
 	if deviceInfo == nil {
-		return 20, nil
+		return 20, []Reason{{Code: "DEVICE_UNKNOWN_HISTORY", Score: 20, Inputs: map[string]interface{}{"device_id": txDeviceID}}}, nil
 	}
 
 	deviceID := deviceInfo.DeviceID
 
 	if deviceID == txDeviceID {
-		return 0, nil
+		return 0, nil, nil
 	}
-	return 100, nil
+	return 100, []Reason{{Code: "DEVICE_MISMATCH", Score: 100, Inputs: map[string]interface{}{"device_id": txDeviceID, "known_device_id": deviceID}}}, nil
 }
 
-func (s *service) transactionFrequencyRisk(ctx context.Context, userID uuid.UUID) (float64, error) {
+func (s *service) transactionFrequencyRisk(ctx context.Context, tx TransactionDTO) (float64, []Reason, error) {
 	log.Println("high frequency in short duration risk triggered")
 
-	// Check if transaction repo is nil
-	if s.transactionRepo == nil {
-		log.Printf("transaction repository is nil, skipping frequency risk check")
-		return 0, nil
+	count, ok, err := s.userTransactionCount(ctx, tx, frequencyWindow)
+	if !ok {
+		log.Printf("no velocity store or transaction repository, skipping frequency risk check")
+		return 0, nil, nil
 	}
-
-	count, err := s.transactionRepo.CountTransactionFrequency(ctx, userID, 5)
 	if err != nil {
+		if ctx.Err() != nil {
+			return 0, nil, ctx.Err()
+		}
 		log.Printf("unable to count frequency: %v", err)
-		return 0, nil
-	}
-	if count == 0 {
-		return 90, nil
+		return 0, nil, nil
 	}
+	inputs := map[string]interface{}{"count": count, "window_minutes": int(frequencyWindow / time.Minute)}
 
-	// Convert int64 to float64 and apply risk calculation
-	riskScore := float64(count-1) * 20
+	// Every earlier transaction in the window adds risk
+	riskScore := count * 20
 
 	if riskScore > 100 {
 		riskScore = 100
 	}
-	return riskScore, nil
+	if riskScore <= 0 {
+		return 0, nil, nil
+	}
+	return riskScore, []Reason{{Code: "HIGH_FREQUENCY", Score: int(riskScore), Inputs: inputs}}, nil
 }
 
 func (s *service) UpdateUserBehaviorAfterTransaction(ctx context.Context, behavior *UserBehavior, amount float64, txID uuid.UUID, txTime time.Time) error {
@@ -496,11 +642,16 @@ func (s *service) UpdateUserBehaviorAfterTransaction(ctx context.Context, behavi
 	// ---------- 6. Update last transaction ----------
 	behavior.LastTransactionAmount = amount
 	behavior.LastTransactionTime = &txTime
+
+	// ---------- 7. Update hour-of-week histogram ----------
+	behavior.HourOfWeek = behavior.HourOfWeek.Observe(txTime)
 	behavior.UpdatedAt = time.Now()
 
 	// ---------- Persist ----------
 	if err := s.repo.UpdateBehaviorPerTransaction(ctx, behavior); err != nil {
-		log.Printf("unable to update behavior parameter: %v", err)
+		if !errors.Is(err, ErrBehaviorConflict) {
+			log.Printf("unable to update behavior parameter: %v", err)
+		}
 		return err
 	}
 
@@ -533,8 +684,13 @@ func (s *service) UpdateUserBehaviorAfterTransaction(ctx context.Context, behavi
 }
 
 func (s *service) CreateUserBehavior(ctx context.Context, userID uuid.UUID) error {
+	return s.createBehavior(ctx, userID, BehaviorAllTypes)
+}
+
+func (s *service) createBehavior(ctx context.Context, userID uuid.UUID, txType string) error {
 	behavior := &UserBehavior{
 		UserID:                userID,
+		TransactionType:       txType,
 		TotalTransactions:     0,
 		AvgTransactionAmount:  0,
 		AmountVarianceAcc:     0,
@@ -560,6 +716,7 @@ func (s *service) CreateUserBehavior(ctx context.Context, userID uuid.UUID) erro
 		EntityID:   userID.String(),
 		ActorType:  "SYSTEM",
 		NewValues: map[string]interface{}{
+			"transaction_type":     txType,
 			"total_transactions":   1,
 			"ema_smoothing_factor": 0.1,
 		},
@@ -3,6 +3,8 @@ package risk
 import (
 	"context"
 	"errors"
+	"runtime"
+	"sync"
 	"testing"
 	"time"
 
@@ -19,13 +21,13 @@ type MockTransactionRiskRepository struct {
 	mock.Mock
 }
 
-func (m *MockTransactionRiskRepository) Create(risk *TransactionRisk) error {
-	args := m.Called(risk)
+func (m *MockTransactionRiskRepository) Create(ctx context.Context, risk *TransactionRisk) error {
+	args := m.Called(ctx, risk)
 	return args.Error(0)
 }
 
-func (m *MockTransactionRiskRepository) GetRiskByTransactionID(id uuid.UUID) (*TransactionRisk, error) {
-	args := m.Called(id)
+func (m *MockTransactionRiskRepository) GetRiskByTransactionID(ctx context.Context, id uuid.UUID) (*TransactionRisk, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -40,6 +42,14 @@ func (m *MockTransactionRiskRepository) GetBehaviorByUserID(ctx context.Context,
 	return args.Get(0).(*UserBehavior), args.Error(1)
 }
 
+func (m *MockTransactionRiskRepository) GetTypeBehavior(ctx context.Context, userID uuid.UUID, txType string) (*UserBehavior, error) {
+	args := m.Called(ctx, userID, txType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*UserBehavior), args.Error(1)
+}
+
 func (m *MockTransactionRiskRepository) GetDailyTransactionAggregate(ctx context.Context, from, to time.Time) ([]DailyAggregate, error) {
 	args := m.Called(ctx, from, to)
 	if args.Get(0) == nil {
@@ -48,8 +58,8 @@ func (m *MockTransactionRiskRepository) GetDailyTransactionAggregate(ctx context
 	return args.Get(0).([]DailyAggregate), args.Error(1)
 }
 
-func (m *MockTransactionRiskRepository) UpdateBehaviorParams(ctx context.Context, userID uuid.UUID, stdDev, p95 float64) error {
-	args := m.Called(ctx, userID, stdDev, p95)
+func (m *MockTransactionRiskRepository) UpdateBehaviorParams(ctx context.Context, userID uuid.UUID, txType string, stdDev, p95 float64) error {
+	args := m.Called(ctx, userID, txType, stdDev, p95)
 	return args.Error(0)
 }
 
@@ -79,12 +89,22 @@ func (m *MockTransactionRiskRepository) GetEnabledRules(ctx context.Context) ([]
 	return args.Get(0).([]RiskRule), args.Error(1)
 }
 
+func (m *MockTransactionRiskRepository) DecayHourOfWeek(ctx context.Context, factor float64) (int64, error) {
+	args := m.Called(ctx, factor)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTransactionRiskRepository) RecordVerifiedChallenge(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	args := m.Called(ctx, userID, at)
+	return args.Error(0)
+}
+
 type MockTransactionRepository struct {
 	mock.Mock
 }
 
-func (m *MockTransactionRepository) CountTransactionFrequency(ctx context.Context, userID uuid.UUID, duration int32) (float64, error) {
-	args := m.Called(ctx, userID, duration)
+func (m *MockTransactionRepository) CountTransactionFrequency(ctx context.Context, userID uuid.UUID, duration int32, excludeTxID uuid.UUID) (float64, error) {
+	args := m.Called(ctx, userID, duration, excludeTxID)
 	return args.Get(0).(float64), args.Error(1)
 }
 
@@ -129,9 +149,9 @@ func TestCalculateRisk_CompleteFlow(t *testing.T) {
 					UserID:   uuid.New(),
 					DeviceID: "device_123",
 				}, nil)
-				mockRepo.On("Create", mock.Anything).Return(nil)
+				mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
 				mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
-				mockTxRepo.On("CountTransactionFrequency", mock.Anything, mock.Anything, int32(5)).Return(1.0, nil)
+				mockTxRepo.On("CountTransactionFrequency", mock.Anything, mock.Anything, int32(5), mock.Anything).Return(0.0, nil)
 			},
 			expectedRiskLevel: "ALLOW",
 			expectError:       false,
@@ -161,9 +181,9 @@ func TestCalculateRisk_CompleteFlow(t *testing.T) {
 					AmountStdDev:         20.0,
 				}, nil)
 				mockRepo.On("GetDeviceInfo", mock.Anything, mock.Anything).Return(nil, errors.New("device not found"))
-				mockRepo.On("Create", mock.Anything).Return(nil)
+				mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
 				mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
-				mockTxRepo.On("CountTransactionFrequency", mock.Anything, mock.Anything, int32(5)).Return(15.0, nil)
+				mockTxRepo.On("CountTransactionFrequency", mock.Anything, mock.Anything, int32(5), mock.Anything).Return(14.0, nil)
 			},
 			expectedRiskLevel: "BLOCK",
 			expectError:       false,
@@ -196,9 +216,9 @@ func TestCalculateRisk_CompleteFlow(t *testing.T) {
 				}, nil)
 				mockRepo.On("CreateFirstBehavior", mock.Anything, mock.Anything).Return(nil)
 				mockRepo.On("GetDeviceInfo", mock.Anything, mock.Anything).Return(nil, errors.New("not found"))
-				mockRepo.On("Create", mock.Anything).Return(nil)
+				mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
 				mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
-				mockTxRepo.On("CountTransactionFrequency", mock.Anything, mock.Anything, int32(5)).Return(1.0, nil)
+				mockTxRepo.On("CountTransactionFrequency", mock.Anything, mock.Anything, int32(5), mock.Anything).Return(0.0, nil)
 			},
 			expectedRiskLevel: "FLAG",
 			expectError:       false,
@@ -278,9 +298,9 @@ func TestCalculateRisk_CompleteFlow(t *testing.T) {
 				mockRepo.On("GetDeviceInfo", mock.Anything, mock.Anything).Return(&UserSecurity{
 					UserID: uuid.New(),
 				}, nil)
-				mockRepo.On("Create", mock.Anything).Return(nil)
+				mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
 				mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
-				mockTxRepo.On("CountTransactionFrequency", mock.Anything, mock.Anything, int32(5)).Return(1.0, nil)
+				mockTxRepo.On("CountTransactionFrequency", mock.Anything, mock.Anything, int32(5), mock.Anything).Return(0.0, nil)
 			},
 			expectedRiskLevel: "FLAG",
 			expectError:       false,
@@ -304,7 +324,7 @@ func TestCalculateRisk_CompleteFlow(t *testing.T) {
 			svc, err := NewService(mockRiskRepo, mockTxRepo, auditLog)
 			assert.NoError(t, err)
 
-			result, err := svc.(*service).CalculateRisk(tt.input)
+			result, err := svc.(*service).CalculateRisk(context.Background(), tt.input)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -327,6 +347,349 @@ func TestCalculateRisk_CompleteFlow(t *testing.T) {
 	}
 }
 
+// ============ Scorer Registry Tests ============
+
+func TestCalculateRisk_RegisteredScorers(t *testing.T) {
+	tests := []struct {
+		name          string
+		rules         []RiskRule
+		expectedScore int
+		expectCalled  bool
+		description   string
+	}{
+		{
+			name: "custom_scorer_weighted_by_rule",
+			rules: []RiskRule{
+				{Name: "CUSTOM_SIGNAL", Enabled: true, Weight: 50},
+			},
+			expectedScore: 40,
+			expectCalled:  true,
+			description:   "Custom scorer should contribute weight * raw / 100",
+		},
+		{
+			name:          "custom_scorer_without_rule",
+			rules:         []RiskRule{},
+			expectedScore: 0,
+			expectCalled:  false,
+			description:   "Scorer without a rule should not run",
+		},
+		{
+			name: "custom_scorer_disabled_rule",
+			rules: []RiskRule{
+				{Name: "CUSTOM_SIGNAL", Enabled: false, Weight: 50},
+			},
+			expectedScore: 0,
+			expectCalled:  false,
+			description:   "Scorer with a disabled rule should not run",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockTransactionRiskRepository)
+			mockRepo.On("GetEnabledRules", mock.Anything).Return(tt.rules, nil)
+			mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("GetBehaviorByUserID", mock.Anything, mock.Anything).Return(&UserBehavior{UserID: uuid.New()}, nil)
+			mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
+
+			called := false
+			custom := ScorerFunc{
+				RuleName: "CUSTOM_SIGNAL",
+				Fn: func(ctx context.Context, tx TransactionDTO) (int, error) {
+					called = true
+					return 80, nil
+				},
+			}
+
+			svc, err := NewService(mockRepo, nil, &audit.Logger{}, WithScorers(custom))
+			assert.NoError(t, err)
+
+			result, err := svc.CalculateRisk(context.Background(), &TransactionDTO{TxID: uuid.New(), UserID: uuid.New(), Amount: 10, TxTime: time.Now()})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedScore, result.RiskScore, tt.description)
+			assert.Equal(t, tt.expectCalled, called, tt.description)
+		})
+	}
+}
+
+func TestCalculateRisk_EvaluationTimeout(t *testing.T) {
+	newService := func(t *testing.T) (Service, *MockTransactionRiskRepository) {
+		mockRepo := new(MockTransactionRiskRepository)
+		mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{{Name: "SLOW", Enabled: true, Weight: 100}}, nil)
+		mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+		mockRepo.On("GetBehaviorByUserID", mock.Anything, mock.Anything).Return(&UserBehavior{UserID: uuid.New()}, nil)
+		mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
+
+		// The scorer only returns once its context is done
+		slow := ScorerFunc{
+			RuleName: "SLOW",
+			Fn: func(ctx context.Context, tx TransactionDTO) (int, error) {
+				<-ctx.Done()
+				return 0, ctx.Err()
+			},
+		}
+
+		svc, err := NewService(mockRepo, nil, &audit.Logger{}, WithScorers(slow), WithEvaluationTimeout(20*time.Millisecond))
+		assert.NoError(t, err)
+		return svc, mockRepo
+	}
+
+	t.Run("deadline_falls_back_to_flag", func(t *testing.T) {
+		svc, mockRepo := newService(t)
+
+		result, err := svc.CalculateRisk(context.Background(), &TransactionDTO{TxID: uuid.New(), UserID: uuid.New(), Amount: 10, TxTime: time.Now()})
+		assert.NoError(t, err)
+		assert.Equal(t, FallbackDecision, result.Decision)
+		assert.Equal(t, "MEDIUM", result.RiskLevel)
+		assert.Equal(t, 31, result.RiskScore)
+		if assert.Len(t, result.Reasons, 1) {
+			assert.Equal(t, ReasonEvaluationTimeout, result.Reasons[0].ReasonCode)
+			assert.Equal(t, int64(20), result.Reasons[0].Inputs["timeout_ms"])
+		}
+		mockRepo.AssertCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("caller_cancel_returns_error", func(t *testing.T) {
+		svc, mockRepo := newService(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := svc.CalculateRisk(ctx, &TransactionDTO{TxID: uuid.New(), UserID: uuid.New(), Amount: 10, TxTime: time.Now()})
+		assert.ErrorIs(t, err, context.Canceled)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestCalculateRisk_PersistFailuresReturnError(t *testing.T) {
+	tests := []struct {
+		name      string
+		createErr error
+		updateErr error
+	}{
+		{name: "risk_save_fails", createErr: errors.New("db down")},
+		{name: "behavior_update_fails", updateErr: errors.New("db down")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockTransactionRiskRepository)
+			mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{}, nil)
+			mockRepo.On("Create", mock.Anything, mock.Anything).Return(tt.createErr)
+			mockRepo.On("GetBehaviorByUserID", mock.Anything, mock.Anything).Return(&UserBehavior{UserID: uuid.New()}, nil)
+			mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(tt.updateErr)
+
+			svc, err := NewService(mockRepo, nil, &audit.Logger{})
+			assert.NoError(t, err)
+
+			// The caller's unit of work rolls back on the error
+			result, err := svc.CalculateRisk(context.Background(), &TransactionDTO{TxID: uuid.New(), UserID: uuid.New(), Amount: 10, TxTime: time.Now()})
+			assert.Error(t, err)
+			assert.Nil(t, result)
+		})
+	}
+}
+
+func TestRegisterScorer_ReplacesByName(t *testing.T) {
+	svc := &service{live: &ruleSet{}}
+	svc.registerDefaultScorers()
+	assert.Len(t, svc.getScorers(), 4)
+
+	svc.RegisterScorer(ScorerFunc{
+		RuleName: RuleNewDevice,
+		Fn: func(ctx context.Context, tx TransactionDTO) (int, error) {
+			return 7, nil
+		},
+	})
+
+	scorers := svc.getScorers()
+	assert.Len(t, scorers, 4)
+	res, err := scorers[1].Score(context.Background(), TransactionDTO{})
+	assert.NoError(t, err)
+	assert.Equal(t, 7, res.Score)
+}
+
+// ============ Reason Breakdown Tests ============
+
+func TestCalculateRisk_PersistsReasons(t *testing.T) {
+	mockRepo := new(MockTransactionRiskRepository)
+	mockTxRepo := new(MockTransactionRepository)
+
+	mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{
+		{Name: RuleTransactionAmount, Enabled: true, Weight: 50},
+		{Name: RuleNewDevice, Enabled: true, Weight: 20},
+		{Name: RuleTransactionFrequency, Enabled: true, Weight: 30},
+	}, nil)
+	mockRepo.On("GetBehaviorByUserID", mock.Anything, mock.Anything).Return(&UserBehavior{
+		UserID:               uuid.New(),
+		TotalTransactions:    20,
+		AvgTransactionAmount: 100.0,
+		AmountStdDev:         10.0,
+	}, nil)
+	mockRepo.On("GetDeviceInfo", mock.Anything, mock.Anything).Return(&UserSecurity{DeviceID: "known"}, nil)
+	mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
+	mockTxRepo.On("CountTransactionFrequency", mock.Anything, mock.Anything, int32(5), mock.Anything).Return(0.0, nil)
+
+	var saved *TransactionRisk
+	mockRepo.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*TransactionRisk)
+	}).Return(nil)
+
+	svc, err := NewService(mockRepo, mockTxRepo, &audit.Logger{})
+	assert.NoError(t, err)
+
+	txID := uuid.New()
+	result, err := svc.CalculateRisk(context.Background(), &TransactionDTO{TxID: txID, UserID: uuid.New(), Amount: 1200, DeviceID: "new-phone", TxTime: time.Now()})
+	assert.NoError(t, err)
+	assert.Same(t, result, saved)
+
+	codes := map[string]RiskReason{}
+	for _, r := range result.Reasons {
+		assert.Equal(t, txID, r.TransactionID)
+		codes[r.ReasonCode] = r
+	}
+
+	// ratio 12 and z-score 110 fire; known device would not, a new device does
+	assert.Contains(t, codes, "AMOUNT_RATIO_GT_10")
+	assert.Contains(t, codes, "AMOUNT_ZSCORE_GT_3")
+	assert.Contains(t, codes, "DEVICE_MISMATCH")
+	assert.NotContains(t, codes, "HIGH_FREQUENCY")
+
+	ratio := codes["AMOUNT_RATIO_GT_10"]
+	assert.Equal(t, RuleTransactionAmount, ratio.RuleName)
+	assert.Equal(t, 35, ratio.RawScore)
+	assert.Equal(t, 50, ratio.Weight)
+	assert.Equal(t, 17, ratio.WeightedScore)
+	assert.Equal(t, 12.0, ratio.Inputs["ratio"])
+
+	device := codes["DEVICE_MISMATCH"]
+	assert.Equal(t, 20, device.WeightedScore)
+	assert.Equal(t, "known", device.Inputs["known_device_id"])
+}
+
+// ============ Expression Rule Tests ============
+
+func TestCalculateRisk_ExpressionRules(t *testing.T) {
+	tests := []struct {
+		name          string
+		rule          RiskRule
+		txType        string
+		amount        float64
+		expectedScore int
+		description   string
+	}{
+		{
+			name:          "matching_rule_uses_threshold",
+			rule:          RiskRule{Name: "LARGE_TRANSFER", Enabled: true, Weight: 50, Threshold: 80, Expression: `tx.amount > 5 * user.avg_amount && tx.type == "TRANSFER"`},
+			txType:        "TRANSFER",
+			amount:        1000,
+			expectedScore: 40,
+			description:   "Matched rule should contribute weight * threshold / 100",
+		},
+		{
+			name:          "matching_rule_without_threshold",
+			rule:          RiskRule{Name: "LARGE_TRANSFER", Enabled: true, Weight: 50, Expression: `tx.amount > 5 * user.avg_amount`},
+			txType:        "TRANSFER",
+			amount:        1000,
+			expectedScore: 50,
+			description:   "Matched rule without threshold should score 100 before weighting",
+		},
+		{
+			name:          "non_matching_rule",
+			rule:          RiskRule{Name: "LARGE_TRANSFER", Enabled: true, Weight: 50, Expression: `tx.amount > 5 * user.avg_amount && tx.type == "TRANSFER"`},
+			txType:        "DEPOSIT",
+			amount:        1000,
+			expectedScore: 0,
+			description:   "Unmatched rule should contribute nothing",
+		},
+		{
+			name:          "runtime_error_is_skipped",
+			rule:          RiskRule{Name: "RATIO", Enabled: true, Weight: 50, Expression: `tx.amount / user.std_dev > 3`},
+			txType:        "TRANSFER",
+			amount:        1000,
+			expectedScore: 0,
+			description:   "Division by zero should skip the rule instead of failing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockTransactionRiskRepository)
+			mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{tt.rule}, nil)
+			mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("GetBehaviorByUserID", mock.Anything, mock.Anything).Return(&UserBehavior{
+				UserID:               uuid.New(),
+				TotalTransactions:    10,
+				AvgTransactionAmount: 100,
+			}, nil)
+			mockRepo.On("GetTypeBehavior", mock.Anything, mock.Anything, mock.Anything).Return(&UserBehavior{UserID: uuid.New(), TransactionType: tt.txType}, nil)
+			mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
+
+			svc, err := NewService(mockRepo, nil, &audit.Logger{})
+			assert.NoError(t, err)
+
+			result, err := svc.CalculateRisk(context.Background(), &TransactionDTO{TxID: uuid.New(), UserID: uuid.New(), TxType: tt.txType, Amount: tt.amount, TxTime: time.Now()})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedScore, result.RiskScore, tt.description)
+		})
+	}
+}
+
+func TestCalculateRisk_ShadowRuleSet(t *testing.T) {
+	tests := []struct {
+		name           string
+		rules          []RiskRule
+		expectedScore  int
+		expectedShadow *int
+		shadowDecision *string
+		description    string
+	}{
+		{
+			name: "shadow_scored_alongside_live",
+			rules: []RiskRule{
+				{Name: "LARGE_TX", Enabled: true, Weight: 20, Expression: `tx.amount > 500`, RuleSet: RuleSetLive},
+				{Name: "LARGE_TX", Enabled: true, Weight: 80, Expression: `tx.amount > 500`, RuleSet: RuleSetShadow},
+			},
+			expectedScore:  20,
+			expectedShadow: intPtr(80),
+			shadowDecision: strPtr("BLOCK"),
+			description:    "Shadow weighting should be recorded without changing the live decision",
+		},
+		{
+			name: "no_shadow_rules",
+			rules: []RiskRule{
+				{Name: "LARGE_TX", Enabled: true, Weight: 20, Expression: `tx.amount > 500`},
+			},
+			expectedScore: 20,
+			description:   "Without shadow rules no shadow result should be stored",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockTransactionRiskRepository)
+			mockRepo.On("GetEnabledRules", mock.Anything).Return(tt.rules, nil)
+			mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("GetBehaviorByUserID", mock.Anything, mock.Anything).Return(&UserBehavior{UserID: uuid.New()}, nil)
+			mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
+
+			svc, err := NewService(mockRepo, nil, &audit.Logger{})
+			assert.NoError(t, err)
+
+			result, err := svc.CalculateRisk(context.Background(), &TransactionDTO{TxID: uuid.New(), UserID: uuid.New(), Amount: 1000, TxTime: time.Now()})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedScore, result.RiskScore, tt.description)
+			assert.Equal(t, "ALLOW", result.Decision, tt.description)
+			assert.Equal(t, tt.expectedShadow, result.ShadowRiskScore, tt.description)
+			assert.Equal(t, tt.shadowDecision, result.ShadowDecision, tt.description)
+		})
+	}
+}
+
+func intPtr(v int) *int { return &v }
+
+func strPtr(v string) *string { return &v }
+
 // ============ ReloadRules Tests ============
 
 func TestReloadRules(t *testing.T) {
@@ -373,6 +736,24 @@ func TestReloadRules(t *testing.T) {
 			expectError: false,
 			description: "Should only load enabled rules",
 		},
+		{
+			name: "valid_expression_rule",
+			mockRules: []RiskRule{
+				{Name: "LARGE_TRANSFER", Enabled: true, Weight: 40, Expression: `tx.amount > 5 * user.avg_amount && tx.type == "TRANSFER"`},
+			},
+			setupError:  nil,
+			expectError: false,
+			description: "Valid expression rules should compile on reload",
+		},
+		{
+			name: "invalid_expression_rule",
+			mockRules: []RiskRule{
+				{Name: "BROKEN", Enabled: true, Weight: 40, Expression: `tx.amount >`},
+			},
+			setupError:  nil,
+			expectError: true,
+			description: "Invalid expression should reject the reload",
+		},
 		{
 			name: "duplicate_rule_names",
 			mockRules: []RiskRule{
@@ -391,7 +772,7 @@ func TestReloadRules(t *testing.T) {
 			mockRepo.On("GetEnabledRules", mock.Anything).Return(tt.mockRules, tt.setupError)
 
 			svc, err := NewService(mockRepo, nil, &audit.Logger{})
-			if tt.setupError != nil {
+			if tt.setupError != nil || tt.expectError {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
@@ -551,6 +932,35 @@ func TestExtractTxContext_AllInputTypes(t *testing.T) {
 	}
 }
 
+func TestExtractTxContext_TransactionFields(t *testing.T) {
+	type transaction struct {
+		ID              uuid.UUID
+		UserID          uuid.UUID
+		TransactionType string
+		Amount          float64
+		DeviceID        string
+		IPAddress       string
+		TransactionTime time.Time
+	}
+
+	tx := transaction{
+		ID:              uuid.New(),
+		UserID:          uuid.New(),
+		TransactionType: "TRANSFER",
+		Amount:          250,
+		DeviceID:        "dev1",
+		IPAddress:       "203.0.113.9",
+		TransactionTime: time.Now(),
+	}
+
+	result, err := ExtractTxContext(&tx)
+	assert.NoError(t, err)
+	assert.Equal(t, tx.ID, result.TxID)
+	assert.Equal(t, "TRANSFER", result.TxType)
+	assert.Equal(t, "dev1", result.DeviceID)
+	assert.Equal(t, "203.0.113.9", result.IPAddress, "IP address should be read from the IPAddress field")
+}
+
 // ============ ApplyRule Tests ============
 
 func TestApplyRule_AllVariations(t *testing.T) {
@@ -799,6 +1209,133 @@ func TestUpdateUserBehaviorAfterTransaction_BehaviorUpdates(t *testing.T) {
 	}
 }
 
+// behaviorStore keeps baselines in memory and writes them with the same
+// version check as the database repository.
+type behaviorStore struct {
+	*MockTransactionRiskRepository
+	mu   sync.Mutex
+	rows map[string]UserBehavior
+}
+
+func newBehaviorStore() *behaviorStore {
+	mockRepo := new(MockTransactionRiskRepository)
+	mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{}, nil)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	return &behaviorStore{MockTransactionRiskRepository: mockRepo, rows: make(map[string]UserBehavior)}
+}
+
+func behaviorRowKey(userID uuid.UUID, txType string) string {
+	return userID.String() + "/" + txType
+}
+
+// copyBehavior detaches the hour-of-week slice, which Observe updates in place
+func copyBehavior(b UserBehavior) UserBehavior {
+	b.HourOfWeek = append(HourHistogram(nil), b.HourOfWeek...)
+	return b
+}
+
+func (s *behaviorStore) GetBehaviorByUserID(ctx context.Context, userID uuid.UUID) (*UserBehavior, error) {
+	return s.GetTypeBehavior(ctx, userID, BehaviorAllTypes)
+}
+
+func (s *behaviorStore) GetTypeBehavior(ctx context.Context, userID uuid.UUID, txType string) (*UserBehavior, error) {
+	s.mu.Lock()
+	row, ok := s.rows[behaviorRowKey(userID, txType)]
+	s.mu.Unlock()
+
+	// Let other transactions in between the read and the write
+	runtime.Gosched()
+	if !ok {
+		return nil, nil
+	}
+	b := copyBehavior(row)
+	return &b, nil
+}
+
+func (s *behaviorStore) CreateFirstBehavior(ctx context.Context, behavior *UserBehavior) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := behaviorRowKey(behavior.UserID, behavior.TransactionType)
+	if _, ok := s.rows[key]; !ok {
+		s.rows[key] = copyBehavior(*behavior)
+	}
+	return nil
+}
+
+func (s *behaviorStore) UpdateBehaviorPerTransaction(ctx context.Context, behavior *UserBehavior) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := behaviorRowKey(behavior.UserID, behavior.TransactionType)
+	if s.rows[key].Version != behavior.Version {
+		return ErrBehaviorConflict
+	}
+	behavior.Version++
+	s.rows[key] = copyBehavior(*behavior)
+	return nil
+}
+
+func TestCalculateRisk_ConcurrentBehaviorUpdates(t *testing.T) {
+	// Far more writers than any one of them could lose to in a bounded retry
+	const n = 50
+
+	store := newBehaviorStore()
+	svc, err := NewService(store, nil, &audit.Logger{})
+	assert.NoError(t, err)
+
+	userID := uuid.New()
+	start := make(chan struct{})
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := 1; i <= n; i++ {
+		wg.Add(1)
+		go func(amount float64) {
+			defer wg.Done()
+			<-start
+			_, err := svc.CalculateRisk(context.Background(), &TransactionDTO{TxID: uuid.New(), UserID: userID, Amount: amount, TxTime: time.Now()})
+			errs <- err
+		}(float64(i))
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	// Amounts 1..n have mean (n+1)/2 and sample variance n(n+1)/12
+	behavior, err := store.GetBehaviorByUserID(context.Background(), userID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(n), behavior.TotalTransactions)
+	assert.Equal(t, int64(n), behavior.Version)
+	assert.InDelta(t, float64(n+1)/2, behavior.AvgTransactionAmount, 1e-9)
+	assert.InDelta(t, float64(n*(n+1))/12, behavior.AmountVariance, 1e-9)
+	assert.InDelta(t, float64(n*(n+1))/12*float64(n-1), behavior.AmountVarianceAcc, 1e-9)
+	assert.InDelta(t, float64(n), behavior.HourOfWeek.Total(), 1e-9)
+}
+
+func TestFoldBehavior_StopsWhenContextEnds(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mockRepo := new(MockTransactionRiskRepository)
+	mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{}, nil)
+	mockRepo.On("GetBehaviorByUserID", mock.Anything, mock.Anything).Return(&UserBehavior{UserID: uuid.New()}, nil)
+	calls := 0
+	mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		if calls++; calls == 3 {
+			cancel()
+		}
+	}).Return(ErrBehaviorConflict)
+
+	svc, err := NewService(mockRepo, nil, &audit.Logger{})
+	assert.NoError(t, err)
+
+	err = svc.(*service).foldBehavior(ctx, TransactionDTO{TxID: uuid.New(), UserID: uuid.New(), Amount: 10, TxTime: time.Now()}, BehaviorAllTypes)
+	assert.ErrorIs(t, err, context.Canceled)
+	mockRepo.AssertNumberOfCalls(t, "UpdateBehaviorPerTransaction", 3)
+}
+
 // ============ CreateUserBehavior Tests ============
 
 func TestCreateUserBehavior_Scenarios(t *testing.T) {
@@ -0,0 +1,64 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type sightingRepository struct {
+	db *gorm.DB
+}
+
+// NewSightingRepository reads where users were last seen from the
+// transactions and user_security tables.
+func NewSightingRepository(db *gorm.DB) SightingRepository {
+	return &sightingRepository{db: db}
+}
+
+func (r *sightingRepository) GetLastSighting(
+	ctx context.Context,
+	userID uuid.UUID,
+	before time.Time,
+	excludeTxID uuid.UUID,
+) (*Sighting, error) {
+
+	var last *Sighting
+
+	var tx struct {
+		IPAddress       string
+		TransactionTime time.Time
+	}
+	err := r.db.WithContext(ctx).
+		Table("transactions").
+		Select("ip_address, transaction_time").
+		Where("user_id = ?", userID).
+		Where("id <> ?", excludeTxID).
+		Where("transaction_time <= ?", before).
+		Order("transaction_time DESC").
+		Limit(1).
+		Take(&tx).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		last = &Sighting{IPAddress: tx.IPAddress, SeenAt: tx.TransactionTime, Source: SightingTransaction}
+	}
+
+	var login UserSecurity
+	err = r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("updated_at <= ?", before).
+		Take(&login).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil && (last == nil || login.UpdatedAt.After(last.SeenAt)) {
+		last = &Sighting{IPAddress: login.IPAddress, SeenAt: login.UpdatedAt, Source: SightingLogin}
+	}
+
+	return last, nil
+}
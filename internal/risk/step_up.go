@@ -0,0 +1,34 @@
+package risk
+
+import (
+	"context"
+	"time"
+
+	"risk-detection/internal/audit"
+
+	"github.com/google/uuid"
+)
+
+// RecordVerifiedChallenge counts a step-up challenge the user passed. Rules
+// and models see the count as user.verified_challenges.
+func (s *service) RecordVerifiedChallenge(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	if err := s.repo.RecordVerifiedChallenge(ctx, userID, at); err != nil {
+		return err
+	}
+
+	if s.auditLog != nil {
+		s.auditLog.Log(audit.AuditLog{
+			EventType:  audit.EventUserBehaviorUpdated,
+			Action:     "UPDATE",
+			EntityType: "user_behavior",
+			EntityID:   userID.String(),
+			ActorType:  "SYSTEM",
+			NewValues: map[string]interface{}{
+				"verified_challenge": true,
+				"last_verified_at":   at,
+			},
+			Status: "SUCCESS",
+		})
+	}
+	return nil
+}
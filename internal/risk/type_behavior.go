@@ -0,0 +1,54 @@
+package risk
+
+import (
+	"context"
+	"log"
+
+	"risk-detection/internal/db"
+
+	"github.com/google/uuid"
+)
+
+// A transaction type's baseline replaces the user-wide one for amount risk
+// once it holds at least this many transactions.
+const minTypeBaselineTransactions = 10
+
+// hasTypeBaseline reports whether transactions of txType keep a baseline of
+// their own next to the user-wide one.
+func hasTypeBaseline(txType string) bool {
+	return txType != "" && txType != BehaviorAllTypes
+}
+
+// typeBaseline returns the user's baseline for txType if it has enough
+// history to compare against, or nil to fall back to the user-wide one. A
+// failed lookup falls back too, unless ctx is done, which ends the scorer.
+func (s *service) typeBaseline(ctx context.Context, userID uuid.UUID, txType string) (*UserBehavior, error) {
+	if !hasTypeBaseline(txType) {
+		return nil, nil
+	}
+
+	behavior, err := s.repo.GetTypeBehavior(ctx, userID, txType)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Printf("unable to get %s behavior: %v", txType, err)
+		return nil, nil
+	}
+	if behavior == nil || behavior.TotalTransactions < minTypeBaselineTransactions {
+		return nil, nil
+	}
+	return behavior, nil
+}
+
+// updateTypeBehavior folds tx into the baseline of its transaction type,
+// creating the baseline on the user's first transaction of that type. A
+// failure is logged and rolled back alone; the evaluation goes on.
+func (s *service) updateTypeBehavior(ctx context.Context, tx TransactionDTO) {
+	err := db.Savepoint(ctx, func(ctx context.Context) error {
+		return s.foldBehavior(ctx, tx, tx.TxType)
+	})
+	if err != nil {
+		log.Printf("unable to update %s behavior: %v", tx.TxType, err)
+	}
+}
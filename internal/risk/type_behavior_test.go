@@ -0,0 +1,139 @@
+package risk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"risk-detection/internal/audit"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTransactionAmountRisk_TypeBaseline(t *testing.T) {
+	userID := uuid.New()
+
+	// Small deposits and large withdrawals blended into one baseline
+	userWide := &UserBehavior{UserID: userID, TransactionType: BehaviorAllTypes, TotalTransactions: 40, AvgTransactionAmount: 550, AmountStdDev: 450}
+	withdrawals := &UserBehavior{UserID: userID, TransactionType: "WITHDRAWAL", TotalTransactions: 20, AvgTransactionAmount: 1000, AmountStdDev: 50}
+	newType := &UserBehavior{UserID: userID, TransactionType: "TRANSFER", TotalTransactions: 3, AvgTransactionAmount: 10, AmountStdDev: 1}
+
+	tests := []struct {
+		name             string
+		txType           string
+		amount           float64
+		typeBehavior     *UserBehavior
+		expectedCodes    []string
+		expectedBaseline string
+		description      string
+	}{
+		{
+			name:          "usual_withdrawal",
+			txType:        "WITHDRAWAL",
+			amount:        1050,
+			typeBehavior:  withdrawals,
+			expectedCodes: nil,
+			description:   "A usual withdrawal should not score against the withdrawal baseline",
+		},
+		{
+			name:             "unusual_withdrawal",
+			txType:           "WITHDRAWAL",
+			amount:           1400,
+			typeBehavior:     withdrawals,
+			expectedCodes:    []string{"AMOUNT_ZSCORE_GT_3"},
+			expectedBaseline: "WITHDRAWAL",
+			description:      "A withdrawal far from the withdrawal baseline should score even if the blend hides it",
+		},
+		{
+			name:             "thin_type_history_falls_back",
+			txType:           "TRANSFER",
+			amount:           1400,
+			typeBehavior:     newType,
+			expectedCodes:    nil,
+			expectedBaseline: BehaviorAllTypes,
+			description:      "A type with too little history should be compared with the user-wide baseline",
+		},
+		{
+			name:          "no_type_baseline_yet",
+			txType:        "DEPOSIT",
+			amount:        1400,
+			typeBehavior:  nil,
+			expectedCodes: nil,
+			description:   "A type never seen should be compared with the user-wide baseline",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockTransactionRiskRepository)
+			mockRepo.On("GetBehaviorByUserID", mock.Anything, userID).Return(userWide, nil)
+			if tt.typeBehavior != nil {
+				mockRepo.On("GetTypeBehavior", mock.Anything, userID, tt.txType).Return(tt.typeBehavior, nil)
+			} else {
+				mockRepo.On("GetTypeBehavior", mock.Anything, userID, tt.txType).Return(nil, nil)
+			}
+
+			svc := &service{repo: mockRepo}
+			_, reasons, err := svc.transactionAmountRisk(context.Background(), userID, tt.txType, tt.amount, time.Now())
+			assert.NoError(t, err)
+
+			var codes []string
+			for _, r := range reasons {
+				codes = append(codes, r.Code)
+				if tt.expectedBaseline != "" {
+					assert.Equal(t, tt.expectedBaseline, r.Inputs["baseline"], tt.description)
+				}
+			}
+			assert.Equal(t, tt.expectedCodes, codes, tt.description)
+		})
+	}
+}
+
+func TestTransactionAmountRisk_TypeBaselineCanceled(t *testing.T) {
+	userID := uuid.New()
+	userWide := &UserBehavior{UserID: userID, TransactionType: BehaviorAllTypes, TotalTransactions: 40, AvgTransactionAmount: 550, AmountStdDev: 450}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mockRepo := new(MockTransactionRiskRepository)
+	mockRepo.On("GetBehaviorByUserID", mock.Anything, userID).Return(userWide, nil)
+	mockRepo.On("GetTypeBehavior", mock.Anything, userID, "WITHDRAWAL").Run(func(args mock.Arguments) {
+		cancel()
+	}).Return(nil, context.Canceled)
+
+	// A canceled lookup ends the scorer instead of scoring the blend
+	svc := &service{repo: mockRepo}
+	_, reasons, err := svc.transactionAmountRisk(ctx, userID, "WITHDRAWAL", 1400, time.Now())
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, reasons)
+}
+
+func TestCalculateRisk_CreatesTypeBaseline(t *testing.T) {
+	userID := uuid.New()
+	created := &UserBehavior{UserID: userID, TransactionType: "DEPOSIT", EMASmoothingFactor: 0.1}
+
+	mockRepo := new(MockTransactionRiskRepository)
+	mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{}, nil)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("GetBehaviorByUserID", mock.Anything, userID).Return(&UserBehavior{UserID: userID, TransactionType: BehaviorAllTypes}, nil)
+	mockRepo.On("GetTypeBehavior", mock.Anything, userID, "DEPOSIT").Return(nil, nil).Once()
+	mockRepo.On("CreateFirstBehavior", mock.Anything, mock.MatchedBy(func(b *UserBehavior) bool {
+		return b.UserID == userID && b.TransactionType == "DEPOSIT"
+	})).Return(nil)
+	mockRepo.On("GetTypeBehavior", mock.Anything, userID, "DEPOSIT").Return(created, nil).Once()
+
+	var updated []string
+	mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		updated = append(updated, args.Get(1).(*UserBehavior).TransactionType)
+	}).Return(nil)
+
+	svc, err := NewService(mockRepo, nil, &audit.Logger{})
+	assert.NoError(t, err)
+
+	_, err = svc.CalculateRisk(context.Background(), &TransactionDTO{TxID: uuid.New(), UserID: userID, TxType: "DEPOSIT", Amount: 25, TxTime: time.Now()})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"DEPOSIT", BehaviorAllTypes}, updated, "Both the type and the user-wide baseline should be updated")
+	assert.Equal(t, int64(1), created.TotalTransactions)
+	mockRepo.AssertExpectations(t)
+}
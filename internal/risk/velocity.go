@@ -0,0 +1,125 @@
+package risk
+
+import (
+	"context"
+	"time"
+
+	"risk-detection/internal/db"
+	"risk-detection/internal/risk/velocity"
+)
+
+// frequencyWindow is the look-back of TRANSACTION_FREQUENCY_RISK.
+const frequencyWindow = 5 * time.Minute
+
+// WithVelocity records every evaluated transaction in store and exposes its
+// windows to rule expressions as velocity.<user|device|ip>.<count|sum>_<window>.
+// TRANSACTION_FREQUENCY_RISK reads the store instead of the database.
+func WithVelocity(store *velocity.Store) Option {
+	return func(s *service) {
+		s.velocity = store
+	}
+}
+
+// WarmVelocity loads the transactions inside the store's horizon so features
+// are complete straight after a restart.
+func WarmVelocity(ctx context.Context, repo VelocityRepository, store *velocity.Store, now time.Time) (int, error) {
+	txs, err := repo.ListTransactionsSince(ctx, now.Add(-store.Horizon()))
+	if err != nil {
+		return 0, err
+	}
+	for _, tx := range txs {
+		addVelocity(store, tx)
+	}
+	return len(txs), nil
+}
+
+func velocityKeys(tx TransactionDTO) map[velocity.Dimension]velocity.Key {
+	return map[velocity.Dimension]velocity.Key{
+		velocity.User:   {Dimension: velocity.User, Value: tx.UserID.String()},
+		velocity.Device: {Dimension: velocity.Device, Value: tx.DeviceID},
+		velocity.IP:     {Dimension: velocity.IP, Value: tx.IPAddress},
+	}
+}
+
+// velocityTime is the end of every window for tx. Transactions read without
+// a time are counted at the moment they are evaluated.
+func velocityTime(tx TransactionDTO) time.Time {
+	if tx.TxTime.IsZero() {
+		return time.Now()
+	}
+	return tx.TxTime
+}
+
+func addVelocity(store *velocity.Store, tx TransactionDTO) {
+	at := velocityTime(tx)
+	for _, key := range velocityKeys(tx) {
+		store.Add(key, at, tx.Amount)
+	}
+}
+
+// recordVelocity adds tx to the store once the caller's unit of work commits,
+// so an evaluation that rolls back leaves no trace in the windows.
+func (s *service) recordVelocity(ctx context.Context, tx TransactionDTO) {
+	if s.velocity == nil {
+		return
+	}
+	db.AfterCommit(ctx, func() {
+		addVelocity(s.velocity, tx)
+	})
+}
+
+// velocityEnv returns the velocity variables of tx for rule expressions.
+// Every window includes tx itself, which the store only holds once its
+// evaluation commits. Windows the store does not track, or all of them
+// without a store, read zero.
+func (s *service) velocityEnv(tx TransactionDTO) map[string]interface{} {
+	env := make(map[string]interface{})
+	for _, name := range velocityVariables(s.windows) {
+		env[name] = 0.0
+	}
+	if s.velocity == nil {
+		return env
+	}
+
+	at := velocityTime(tx)
+	for dim, key := range velocityKeys(tx) {
+		for window, stat := range s.velocity.Stats(key, at) {
+			env[velocityVariable(dim, "count", window)] = float64(stat.Count + 1)
+			env[velocityVariable(dim, "sum", window)] = stat.Sum + tx.Amount
+		}
+	}
+	return env
+}
+
+func velocityVariable(dim velocity.Dimension, metric string, window string) string {
+	return "velocity." + string(dim) + "." + metric + "_" + window
+}
+
+func velocityVariables(windows []velocity.Window) []string {
+	var names []string
+	for _, dim := range velocity.Dimensions {
+		for _, w := range windows {
+			names = append(names,
+				velocityVariable(dim, "count", w.Name),
+				velocityVariable(dim, "sum", w.Name))
+		}
+	}
+	return names
+}
+
+// userTransactionCount counts the user's transactions before tx in the
+// window ending at it, preferring the velocity store over the database.
+func (s *service) userTransactionCount(ctx context.Context, tx TransactionDTO, window time.Duration) (float64, bool, error) {
+	if s.velocity != nil {
+		key := velocityKeys(tx)[velocity.User]
+		if stat, ok := s.velocity.Stat(key, velocityTime(tx), window); ok {
+			return float64(stat.Count), true, nil
+		}
+	}
+
+	if s.transactionRepo == nil {
+		return 0, false, nil
+	}
+	count, err := s.transactionRepo.CountTransactionFrequency(ctx, tx.UserID, int32(window/time.Minute), tx.TxID)
+	return count, true, err
+}
@@ -0,0 +1,289 @@
+// Package velocity keeps sliding-window transaction counts and amount sums
+// in process, keyed by user, device or IP, so velocity features can be read
+// on every evaluation without querying Postgres.
+package velocity
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dimension is what a series of transactions is keyed by.
+type Dimension string
+
+const (
+	User   Dimension = "user"
+	Device Dimension = "device"
+	IP     Dimension = "ip"
+)
+
+// Dimensions lists every dimension in the order features are reported.
+var Dimensions = []Dimension{User, Device, IP}
+
+// Window is a named look-back period, e.g. "5m" for the last five minutes.
+// The name is used in rule variables such as velocity.user.count_5m.
+type Window struct {
+	Name     string
+	Duration time.Duration
+}
+
+// DefaultWindows are used when no windows are configured.
+var DefaultWindows = []Window{
+	{Name: "1m", Duration: time.Minute},
+	{Name: "5m", Duration: 5 * time.Minute},
+	{Name: "1h", Duration: time.Hour},
+	{Name: "24h", Duration: 24 * time.Hour},
+}
+
+// ParseWindows parses a comma-separated list of Go durations such as
+// "1m,5m,1h,24h". Each window is named after its entry.
+func ParseWindows(s string) ([]Window, error) {
+	var windows []Window
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(s, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		if name == "" {
+			continue
+		}
+		for _, c := range name {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+				return nil, fmt.Errorf("window %q: only letters and digits are allowed", name)
+			}
+		}
+		d, err := time.ParseDuration(name)
+		if err != nil {
+			return nil, fmt.Errorf("window %q: %w", name, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("window %q: must be positive", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("window %q: duplicate", name)
+		}
+		seen[name] = true
+		windows = append(windows, Window{Name: name, Duration: d})
+	}
+
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no windows given")
+	}
+	return windows, nil
+}
+
+// Key identifies one series, e.g. {User, "<user id>"}.
+type Key struct {
+	Dimension Dimension
+	Value     string
+}
+
+// Stat is the number and total amount of transactions in a window.
+type Stat struct {
+	Count int64
+	Sum   float64
+}
+
+type event struct {
+	at     time.Time
+	amount float64
+}
+
+// series holds the events of one key in time order.
+type series struct {
+	events []event
+}
+
+const shardCount = 32
+
+type shard struct {
+	mu     sync.Mutex
+	series map[Key]*series
+}
+
+// Store is a concurrency-safe set of sliding windows. Events older than the
+// longest window are dropped as new ones arrive; Sweep removes idle keys.
+type Store struct {
+	windows []Window
+	horizon time.Duration
+	shards  [shardCount]shard
+}
+
+// NewStore returns a store tracking the given windows, or DefaultWindows if
+// none are given.
+func NewStore(windows []Window) *Store {
+	if len(windows) == 0 {
+		windows = DefaultWindows
+	}
+
+	st := &Store{windows: make([]Window, len(windows))}
+	copy(st.windows, windows)
+	sort.SliceStable(st.windows, func(i, j int) bool {
+		return st.windows[i].Duration < st.windows[j].Duration
+	})
+	st.horizon = st.windows[len(st.windows)-1].Duration
+
+	for i := range st.shards {
+		st.shards[i].series = make(map[Key]*series)
+	}
+	return st
+}
+
+// Windows returns the tracked windows, shortest first.
+func (st *Store) Windows() []Window {
+	out := make([]Window, len(st.windows))
+	copy(out, st.windows)
+	return out
+}
+
+// Horizon is the longest tracked window; nothing older is kept.
+func (st *Store) Horizon() time.Duration {
+	return st.horizon
+}
+
+func (st *Store) shardFor(key Key) *shard {
+	h := fnv.New32a()
+	h.Write([]byte(key.Dimension))
+	h.Write([]byte(key.Value))
+	return &st.shards[h.Sum32()%shardCount]
+}
+
+// Add records a transaction of amount at the given time. Keys with an empty
+// value are ignored.
+func (st *Store) Add(key Key, at time.Time, amount float64) {
+	if key.Value == "" {
+		return
+	}
+
+	sh := st.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	s, ok := sh.series[key]
+	if !ok {
+		s = &series{}
+		sh.series[key] = s
+	}
+
+	// Transactions mostly arrive in order; insert late ones in place
+	i := len(s.events)
+	for i > 0 && s.events[i-1].at.After(at) {
+		i--
+	}
+	s.events = append(s.events, event{})
+	copy(s.events[i+1:], s.events[i:])
+	s.events[i] = event{at: at, amount: amount}
+
+	s.prune(s.events[len(s.events)-1].at.Add(-st.horizon))
+}
+
+// prune drops events at or before cutoff.
+func (s *series) prune(cutoff time.Time) {
+	n := 0
+	for n < len(s.events) && !s.events[n].at.After(cutoff) {
+		n++
+	}
+	if n > 0 {
+		s.events = append(s.events[:0], s.events[n:]...)
+	}
+}
+
+// stat sums the events in (at-d, at].
+func (s *series) stat(at time.Time, d time.Duration) Stat {
+	var out Stat
+	from := at.Add(-d)
+	for i := len(s.events) - 1; i >= 0; i-- {
+		e := s.events[i]
+		if e.at.After(at) {
+			continue
+		}
+		if !e.at.After(from) {
+			break
+		}
+		out.Count++
+		out.Sum += e.amount
+	}
+	return out
+}
+
+// Stats returns the stat of every tracked window ending at the given time,
+// keyed by window name.
+func (st *Store) Stats(key Key, at time.Time) map[string]Stat {
+	out := make(map[string]Stat, len(st.windows))
+	for _, w := range st.windows {
+		out[w.Name] = Stat{}
+	}
+	if key.Value == "" {
+		return out
+	}
+
+	sh := st.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	s, ok := sh.series[key]
+	if !ok {
+		return out
+	}
+	for _, w := range st.windows {
+		out[w.Name] = s.stat(at, w.Duration)
+	}
+	return out
+}
+
+// Stat returns the stat of an arbitrary window ending at the given time.
+// ok is false if d is longer than the horizon, as older events are gone.
+func (st *Store) Stat(key Key, at time.Time, d time.Duration) (Stat, bool) {
+	if d > st.horizon {
+		return Stat{}, false
+	}
+	if key.Value == "" {
+		return Stat{}, true
+	}
+
+	sh := st.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	s, ok := sh.series[key]
+	if !ok {
+		return Stat{}, true
+	}
+	return s.stat(at, d), true
+}
+
+// Sweep removes keys with no events inside the horizon ending at now and
+// returns how many were removed.
+func (st *Store) Sweep(now time.Time) int {
+	cutoff := now.Add(-st.horizon)
+	removed := 0
+
+	for i := range st.shards {
+		sh := &st.shards[i]
+		sh.mu.Lock()
+		for key, s := range sh.series {
+			s.prune(cutoff)
+			if len(s.events) == 0 {
+				delete(sh.series, key)
+				removed++
+			}
+		}
+		sh.mu.Unlock()
+	}
+	return removed
+}
+
+// Len returns the number of keys currently tracked.
+func (st *Store) Len() int {
+	n := 0
+	for i := range st.shards {
+		sh := &st.shards[i]
+		sh.mu.Lock()
+		n += len(sh.series)
+		sh.mu.Unlock()
+	}
+	return n
+}
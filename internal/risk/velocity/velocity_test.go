@@ -0,0 +1,60 @@
+package velocity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWindows(t *testing.T) {
+	windows, err := ParseWindows(" 1m, 5m,1h ,24h")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultWindows, windows)
+
+	for _, spec := range []string{"", "5m,5m", "-1m", "1m,soon", "1.5h"} {
+		_, err := ParseWindows(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestStore_Stats(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	user := Key{Dimension: User, Value: "u1"}
+
+	st := NewStore(nil)
+	st.Add(user, now.Add(-30*time.Hour), 1000) // beyond every window
+	st.Add(user, now.Add(-2*time.Hour), 500)
+	st.Add(user, now.Add(-10*time.Minute), 50)
+	st.Add(user, now, 20)
+	st.Add(user, now.Add(-30*time.Second), 10) // arrives late
+
+	stats := st.Stats(user, now)
+	assert.Equal(t, Stat{Count: 2, Sum: 30}, stats["1m"])
+	assert.Equal(t, Stat{Count: 2, Sum: 30}, stats["5m"])
+	assert.Equal(t, Stat{Count: 3, Sum: 80}, stats["1h"])
+	assert.Equal(t, Stat{Count: 4, Sum: 580}, stats["24h"])
+
+	// Other keys and dimensions are separate series
+	assert.Equal(t, Stat{}, st.Stats(Key{Dimension: Device, Value: "u1"}, now)["24h"])
+
+	stat, ok := st.Stat(user, now, 15*time.Minute)
+	assert.True(t, ok)
+	assert.Equal(t, Stat{Count: 3, Sum: 80}, stat)
+
+	_, ok = st.Stat(user, now, 48*time.Hour)
+	assert.False(t, ok, "Windows beyond the horizon cannot be answered")
+}
+
+func TestStore_Sweep(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	st := NewStore([]Window{{Name: "1h", Duration: time.Hour}})
+	st.Add(Key{Dimension: IP, Value: "203.0.113.9"}, now.Add(-2*time.Hour), 10)
+	st.Add(Key{Dimension: IP, Value: "198.51.100.7"}, now.Add(-time.Minute), 10)
+	st.Add(Key{Dimension: Device, Value: ""}, now, 10)
+
+	assert.Equal(t, 2, st.Len(), "Keys without a value should not be tracked")
+	assert.Equal(t, 1, st.Sweep(now))
+	assert.Equal(t, 1, st.Len())
+}
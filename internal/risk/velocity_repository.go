@@ -0,0 +1,51 @@
+package risk
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type velocityRepository struct {
+	db *gorm.DB
+}
+
+func NewVelocityRepository(db *gorm.DB) VelocityRepository {
+	return &velocityRepository{db: db}
+}
+
+func (r *velocityRepository) ListTransactionsSince(ctx context.Context, since time.Time) ([]TransactionDTO, error) {
+	var rows []struct {
+		ID              uuid.UUID
+		UserID          uuid.UUID
+		Amount          float64
+		DeviceID        string
+		IPAddress       string
+		TransactionTime time.Time
+	}
+
+	err := r.db.WithContext(ctx).
+		Table("transactions").
+		Select("id, user_id, amount, device_id, ip_address, transaction_time").
+		Where("transaction_time >= ?", since).
+		Order("transaction_time ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	txs := make([]TransactionDTO, 0, len(rows))
+	for _, row := range rows {
+		txs = append(txs, TransactionDTO{
+			TxID:      row.ID,
+			UserID:    row.UserID,
+			Amount:    row.Amount,
+			DeviceID:  row.DeviceID,
+			IPAddress: row.IPAddress,
+			TxTime:    row.TransactionTime,
+		})
+	}
+	return txs, nil
+}
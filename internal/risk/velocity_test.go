@@ -0,0 +1,121 @@
+package risk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"risk-detection/internal/audit"
+	"risk-detection/internal/risk/velocity"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockVelocityRepository struct {
+	mock.Mock
+}
+
+func (m *MockVelocityRepository) ListTransactionsSince(ctx context.Context, since time.Time) ([]TransactionDTO, error) {
+	args := m.Called(ctx, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]TransactionDTO), args.Error(1)
+}
+
+func TestTransactionFrequencyRisk_ZeroCount(t *testing.T) {
+	mockTxRepo := new(MockTransactionRepository)
+	mockTxRepo.On("CountTransactionFrequency", mock.Anything, mock.Anything, int32(5), mock.Anything).Return(0.0, nil)
+
+	svc := &service{transactionRepo: mockTxRepo}
+	score, reasons, err := svc.transactionFrequencyRisk(context.Background(), TransactionDTO{UserID: uuid.New()})
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, score, "A user without earlier transactions should not score")
+	assert.Empty(t, reasons)
+}
+
+func TestCalculateRisk_VelocityStore(t *testing.T) {
+	now := time.Now()
+	userID := uuid.New()
+
+	mockRepo := new(MockTransactionRiskRepository)
+	mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{
+		{Name: RuleTransactionFrequency, Enabled: true, Weight: 50},
+		{Name: "DEVICE_BURST", Enabled: true, Weight: 50, Threshold: 60, Expression: `velocity.device.count_1m >= 3 && velocity.user.sum_24h > 1000`},
+	}, nil)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	mockRepo.On("GetBehaviorByUserID", mock.Anything, mock.Anything).Return(&UserBehavior{UserID: userID}, nil)
+	mockRepo.On("UpdateBehaviorPerTransaction", mock.Anything, mock.Anything).Return(nil)
+
+	// Two earlier transactions today are loaded from the database at startup
+	velocityRepo := new(MockVelocityRepository)
+	velocityRepo.On("ListTransactionsSince", mock.Anything, now.Add(-24*time.Hour)).Return([]TransactionDTO{
+		{TxID: uuid.New(), UserID: userID, Amount: 900, DeviceID: "phone", IPAddress: "203.0.113.9", TxTime: now.Add(-3 * time.Hour)},
+		{TxID: uuid.New(), UserID: userID, Amount: 100, DeviceID: "phone", IPAddress: "203.0.113.9", TxTime: now.Add(-40 * time.Second)},
+	}, nil)
+
+	store := velocity.NewStore(nil)
+	warmed, err := WarmVelocity(context.Background(), velocityRepo, store, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, warmed)
+
+	// No transaction repository: the frequency scorer reads the store only
+	svc, err := NewService(mockRepo, nil, &audit.Logger{}, WithVelocity(store))
+	assert.NoError(t, err)
+
+	first, err := svc.CalculateRisk(context.Background(), &TransactionDTO{TxID: uuid.New(), UserID: userID, Amount: 50, DeviceID: "phone", TxTime: now.Add(-20 * time.Second)})
+	assert.NoError(t, err)
+	assert.Equal(t, 10, first.RiskScore, "One earlier transaction in 5 minutes scores 20 at weight 50")
+
+	second, err := svc.CalculateRisk(context.Background(), &TransactionDTO{TxID: uuid.New(), UserID: userID, Amount: 50, DeviceID: "phone", TxTime: now})
+	assert.NoError(t, err)
+
+	codes := map[string]RiskReason{}
+	for _, r := range second.Reasons {
+		codes[r.ReasonCode] = r
+	}
+	assert.Equal(t, 20, codes["HIGH_FREQUENCY"].WeightedScore)
+	assert.Equal(t, 3.0, codes["DEVICE_BURST"].Inputs["velocity.device.count_1m"])
+	assert.Equal(t, 1100.0, codes["DEVICE_BURST"].Inputs["velocity.user.sum_24h"])
+	assert.Equal(t, 50, second.RiskScore)
+}
+
+func TestCalculateRisk_VelocityNotRecordedOnFailure(t *testing.T) {
+	mockRepo := new(MockTransactionRiskRepository)
+	mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{{Name: RuleTransactionFrequency, Enabled: true, Weight: 50}}, nil)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(errors.New("db down"))
+
+	store := velocity.NewStore(nil)
+	svc, err := NewService(mockRepo, nil, &audit.Logger{}, WithVelocity(store))
+	assert.NoError(t, err)
+
+	tx := TransactionDTO{TxID: uuid.New(), UserID: uuid.New(), Amount: 50, TxTime: time.Now()}
+	_, err = svc.CalculateRisk(context.Background(), &tx)
+	assert.Error(t, err)
+
+	stat, ok := store.Stat(velocityKeys(tx)[velocity.User], tx.TxTime, frequencyWindow)
+	assert.True(t, ok)
+	assert.Equal(t, 0, int(stat.Count), "A failed evaluation must not count towards later ones")
+}
+
+func TestValidateRuleExpression_VelocityWindows(t *testing.T) {
+	configured, err := NewService(newEmptyRuleRepository(), nil, &audit.Logger{},
+		WithVelocity(velocity.NewStore([]velocity.Window{{Name: "2d", Duration: 48 * time.Hour}})))
+	assert.NoError(t, err)
+	assert.NoError(t, configured.ValidateRuleExpression(`velocity.ip.count_2d > 10`), "Configured windows should become rule variables")
+	assert.NoError(t, configured.ValidateRuleExpression(`velocity.ip.count_5m > 10`), "Default windows should stay valid")
+
+	plain, err := NewService(newEmptyRuleRepository(), nil, &audit.Logger{})
+	assert.NoError(t, err)
+	assert.NoError(t, plain.ValidateRuleExpression(`velocity.ip.count_5m > 10`))
+	assert.Error(t, plain.ValidateRuleExpression(`velocity.ip.count_2d > 10`), "Another service's windows should not leak into this one")
+}
+
+func newEmptyRuleRepository() *MockTransactionRiskRepository {
+	mockRepo := new(MockTransactionRiskRepository)
+	mockRepo.On("GetEnabledRules", mock.Anything).Return([]RiskRule{}, nil)
+	return mockRepo
+}
@@ -2,8 +2,13 @@ package customrouter
 
 import (
 	"risk-detection/internal/auth"
+	"risk-detection/internal/callback"
+	"risk-detection/internal/challenge"
 	"risk-detection/internal/middleware"
+	"risk-detection/internal/review"
+	"risk-detection/internal/risk"
 	"risk-detection/internal/transaction"
+	"risk-detection/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,6 +16,18 @@ import (
 func RegisterRoutes(router *gin.Engine,
 	authHandler *auth.Handler,
 	transactionHandler *transaction.TransactionHandler,
+	ruleHandler *risk.RuleHandler,
+	policyHandler *risk.PolicyHandler,
+	deviceHandler *risk.DeviceHandler,
+	counterpartyHandler *risk.CounterpartyHandler,
+	listHandler *risk.ListHandler,
+	modelHandler *risk.ModelHandler,
+	featureSnapshotHandler *risk.FeatureSnapshotHandler,
+	labelHandler *risk.LabelHandler,
+	reviewHandler *review.Handler,
+	challengeHandler *challenge.Handler,
+	callbackHandler *callback.Handler,
+	webhookHandler *webhook.Handler,
 	jwtSecret string,
 ) {
 
@@ -25,4 +42,57 @@ func RegisterRoutes(router *gin.Engine,
 
 	api.POST("/transaction", transactionHandler.HandleTransaction)
 	api.GET("/transactions", transactionHandler.GetTransactions)
+	api.GET("/transactions/:id", transactionHandler.GetTransaction)
+	api.GET("/transactions/:id/risk", transactionHandler.GetTransactionRisk)
+	api.POST("/transactions/:id/challenge", challengeHandler.Verify)
+
+	api.PUT("/callback", callbackHandler.RegisterEndpoint)
+	api.GET("/callback", callbackHandler.GetEndpoint)
+	api.DELETE("/callback", callbackHandler.DeleteEndpoint)
+
+	//admin routes
+	admin := api.Group("/admin")
+	admin.Use(middleware.RequireRole("ADMIN"))
+
+	admin.GET("/rules", ruleHandler.ListRules)
+	admin.GET("/rules/shadow/report", ruleHandler.ShadowReport)
+	admin.GET("/rules/performance", labelHandler.PerformanceReport)
+	admin.POST("/rules", ruleHandler.CreateRule)
+	admin.PUT("/rules/:id", ruleHandler.UpdateRule)
+	admin.POST("/rules/:id/enable", ruleHandler.EnableRule)
+	admin.POST("/rules/:id/disable", ruleHandler.DisableRule)
+
+	admin.GET("/policies", policyHandler.ListPolicies)
+	admin.PUT("/policies/:type", policyHandler.UpsertPolicy)
+
+	admin.GET("/users/:id/devices", deviceHandler.ListDevices)
+	admin.PUT("/users/:id/devices/:device_id/trust", deviceHandler.SetTrustState)
+
+	admin.GET("/receivers/:id/profile", counterpartyHandler.GetReceiverProfile)
+
+	admin.GET("/lists", listHandler.ListEntries)
+	admin.POST("/lists", listHandler.CreateEntry)
+	admin.DELETE("/lists/:id", listHandler.DeleteEntry)
+
+	admin.GET("/model", modelHandler.GetModel)
+	admin.POST("/model/reload", modelHandler.ReloadModel)
+
+	admin.GET("/feature-snapshots/export", featureSnapshotHandler.Export)
+
+	admin.GET("/transactions/:id/label", labelHandler.GetLabel)
+	admin.PUT("/transactions/:id/label", labelHandler.SetLabel)
+
+	admin.GET("/cases", reviewHandler.ListCases)
+	admin.GET("/cases/:id", reviewHandler.GetCase)
+	admin.POST("/cases/:id/claim", reviewHandler.ClaimCase)
+	admin.POST("/cases/:id/comments", reviewHandler.AddComment)
+	admin.POST("/cases/:id/approve", reviewHandler.ApproveCase)
+	admin.POST("/cases/:id/reject", reviewHandler.RejectCase)
+
+	admin.GET("/webhooks", webhookHandler.ListSubscriptions)
+	admin.POST("/webhooks", webhookHandler.CreateSubscription)
+	admin.PUT("/webhooks/:id", webhookHandler.UpdateSubscription)
+	admin.DELETE("/webhooks/:id", webhookHandler.DeleteSubscription)
+	admin.GET("/webhook-deliveries", webhookHandler.ListDeliveries)
+	admin.POST("/webhook-deliveries/:id/redeliver", webhookHandler.Redeliver)
 }
@@ -1,9 +1,13 @@
 package transaction
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"risk-detection/internal/idempotency"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -56,10 +60,23 @@ func (h *TransactionHandler) HandleTransaction(c *gin.Context) {
         TransactionTime: req.TransactionTime,
     }
 
+    // mode=async only records the transaction; a worker scores it and the
+    // client polls GET /api/v1/transactions/:id or receives a callback
+    if c.Query("mode") == modeAsync {
+        status, err := h.service.SubmitAsync(c.Request.Context(), c.GetHeader("Idempotency-Key"), &transaction)
+        if err != nil {
+            c.JSON(evaluationErrorStatus(err), gin.H{"error": err.Error()})
+            return
+        }
+        c.JSON(http.StatusAccepted, status)
+        return
+    }
+
     // Call service to calculate risk
-    riskResult, err := h.service.CalculateRiskMatrix(&transaction)
+    // Retries carrying the same Idempotency-Key get the first response back
+    riskResult, err := h.service.CalculateRiskMatrixOnce(c.Request.Context(), c.GetHeader("Idempotency-Key"), &transaction)
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        c.JSON(evaluationErrorStatus(err), gin.H{"error": err.Error()})
         return
     }
 
@@ -108,3 +125,95 @@ func (h *TransactionHandler) GetTransactions(c *gin.Context) {
 		},
 	})
 }
+
+// GetTransaction handles the /api/v1/transactions/:id endpoint.
+func (h *TransactionHandler) GetTransaction(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDValue.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id format"})
+		return
+	}
+
+	transactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transaction id format"})
+		return
+	}
+
+	status, err := h.service.GetTransaction(c.Request.Context(), userID, c.GetString("role"), transactionID)
+	if err != nil {
+		if errors.Is(err, ErrTransactionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "transaction not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// GetTransactionRisk handles the /api/v1/transactions/:id/risk endpoint.
+func (h *TransactionHandler) GetTransactionRisk(c *gin.Context) {
+	userIDValue, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	userID, err := uuid.Parse(userIDValue.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id format"})
+		return
+	}
+
+	transactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transaction id format"})
+		return
+	}
+
+	riskResult, err := h.service.GetTransactionRisk(c.Request.Context(), userID, c.GetString("role"), transactionID)
+	if err != nil {
+		if errors.Is(err, ErrTransactionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "transaction not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"risk_result": riskResult,
+	})
+}
+
+// statusClientClosedRequest is the non-standard status logged when the
+// client went away before the evaluation finished.
+const statusClientClosedRequest = 499
+
+// evaluationErrorStatus maps a failed evaluation to its response status. The
+// risk service answers its own evaluation deadline with a fallback decision,
+// so a deadline error here means the request's own deadline passed.
+func evaluationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, idempotency.ErrInvalidKey):
+		return http.StatusBadRequest
+	case errors.Is(err, idempotency.ErrKeyReused), errors.Is(err, idempotency.ErrInProgress):
+		return http.StatusConflict
+	case errors.Is(err, ErrAsyncDisabled), errors.Is(err, ErrQueueFull), errors.Is(err, ErrQueueClosed):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return statusClientClosedRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
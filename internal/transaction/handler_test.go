@@ -9,6 +9,9 @@ import (
 	"testing"
 	"time"
 
+	"risk-detection/internal/idempotency"
+	"risk-detection/internal/risk"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -20,8 +23,16 @@ type MockService struct {
 	mock.Mock
 }
 
-func (m *MockService) CalculateRiskMatrix(tx *Transaction) (*TransactionRiskResponse, error) {
-	args := m.Called(tx)
+func (m *MockService) CalculateRiskMatrix(ctx context.Context, tx *Transaction) (*TransactionRiskResponse, error) {
+	args := m.Called(ctx, tx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TransactionRiskResponse), args.Error(1)
+}
+
+func (m *MockService) CalculateRiskMatrixOnce(ctx context.Context, idempotencyKey string, tx *Transaction) (*TransactionRiskResponse, error) {
+	args := m.Called(ctx, idempotencyKey, tx)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -36,6 +47,40 @@ func (m *MockService) GetTransactions(ctx context.Context, userID uuid.UUID, off
 	return args.Get(0).([]*Transaction), args.Get(1).(int64), args.Error(2)
 }
 
+func (m *MockService) GetTransactionRisk(ctx context.Context, userID uuid.UUID, role string, transactionID uuid.UUID) (*risk.TransactionRisk, error) {
+	args := m.Called(ctx, userID, role, transactionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*risk.TransactionRisk), args.Error(1)
+}
+
+func (m *MockService) SubmitAsync(ctx context.Context, idempotencyKey string, tx *Transaction) (*TransactionStatusResponse, error) {
+	args := m.Called(ctx, idempotencyKey, tx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TransactionStatusResponse), args.Error(1)
+}
+
+func (m *MockService) EvaluateQueued(ctx context.Context, tx *Transaction) error {
+	args := m.Called(ctx, tx)
+	return args.Error(0)
+}
+
+func (m *MockService) ResumePending(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockService) GetTransaction(ctx context.Context, userID uuid.UUID, role string, transactionID uuid.UUID) (*TransactionStatusResponse, error) {
+	args := m.Called(ctx, userID, role, transactionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TransactionStatusResponse), args.Error(1)
+}
+
 // Helper function to create a test request
 func createTestContext(userID string, body []byte) (*gin.Context, *httptest.ResponseRecorder) {
 	gin.SetMode(gin.TestMode)
@@ -71,7 +116,7 @@ func TestHandleTransaction_Success(t *testing.T) {
 	reqBody, _ := json.Marshal(req)
 	c, w := createTestContext(userID.String(), reqBody)
 
-	mockService.On("CalculateRiskMatrix", mock.MatchedBy(func(tx *Transaction) bool {
+	mockService.On("CalculateRiskMatrixOnce", mock.Anything, "", mock.MatchedBy(func(tx *Transaction) bool {
 		return tx.UserID == userID && tx.Amount == 100.50
 	})).Return(&TransactionRiskResponse{
 		TransactionID: uuid.New(),
@@ -226,7 +271,7 @@ func TestHandleTransaction_ServiceError(t *testing.T) {
 	reqBody, _ := json.Marshal(req)
 	c, w := createTestContext(userID.String(), reqBody)
 
-	mockService.On("CalculateRiskMatrix", mock.Anything).Return(nil, assert.AnError)
+	mockService.On("CalculateRiskMatrixOnce", mock.Anything, "", mock.Anything).Return(nil, assert.AnError)
 
 	handler.HandleTransaction(c)
 
@@ -237,6 +282,106 @@ func TestHandleTransaction_ServiceError(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestHandleTransaction_ErrorStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedCode int
+	}{
+		{name: "deadline_exceeded", err: context.DeadlineExceeded, expectedCode: http.StatusGatewayTimeout},
+		{name: "client_canceled", err: context.Canceled, expectedCode: 499},
+		{name: "idempotency_key_invalid", err: idempotency.ErrInvalidKey, expectedCode: http.StatusBadRequest},
+		{name: "idempotency_key_reused", err: idempotency.ErrKeyReused, expectedCode: http.StatusConflict},
+		{name: "idempotency_key_in_progress", err: idempotency.ErrInProgress, expectedCode: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockService)
+			handler := NewHandler(mockService)
+
+			reqBody, _ := json.Marshal(TransactionRequest{
+				TransactionType: "TRANSFER",
+				Amount:          100.50,
+				DeviceID:        "device123",
+				TransactionTime: time.Now(),
+			})
+			c, w := createTestContext(uuid.New().String(), reqBody)
+
+			mockService.On("CalculateRiskMatrixOnce", mock.Anything, "", mock.Anything).Return(nil, tt.err)
+
+			handler.HandleTransaction(c)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestHandleTransaction_IdempotencyKeyHeader(t *testing.T) {
+	mockService := new(MockService)
+	handler := NewHandler(mockService)
+
+	reqBody, _ := json.Marshal(TransactionRequest{
+		TransactionType: "TRANSFER",
+		Amount:          100.50,
+		DeviceID:        "device123",
+		TransactionTime: time.Now(),
+	})
+	c, w := createTestContext(uuid.New().String(), reqBody)
+	c.Request.Header.Set("Idempotency-Key", "retry-123")
+
+	mockService.On("CalculateRiskMatrixOnce", mock.Anything, "retry-123", mock.Anything).Return(&TransactionRiskResponse{Decision: "ALLOW"}, nil)
+
+	handler.HandleTransaction(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandleTransaction_AsyncMode(t *testing.T) {
+	reqBody, _ := json.Marshal(TransactionRequest{
+		TransactionType: "TRANSFER",
+		Amount:          100.50,
+		DeviceID:        "device123",
+		TransactionTime: time.Now(),
+	})
+	txID := uuid.New()
+
+	tests := []struct {
+		name           string
+		result         *TransactionStatusResponse
+		err            error
+		expectedStatus int
+	}{
+		{name: "accepted", result: &TransactionStatusResponse{TransactionID: txID, Status: "PENDING"}, expectedStatus: http.StatusAccepted},
+		{name: "queue_full", err: ErrQueueFull, expectedStatus: http.StatusServiceUnavailable},
+		{name: "async_disabled", err: ErrAsyncDisabled, expectedStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockService)
+			handler := NewHandler(mockService)
+
+			c, w := createTestContext(uuid.New().String(), reqBody)
+			c.Request.URL.RawQuery = "mode=async"
+
+			mockService.On("SubmitAsync", mock.Anything, "", mock.Anything).Return(tt.result, tt.err)
+
+			handler.HandleTransaction(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertNotCalled(t, "CalculateRiskMatrixOnce", mock.Anything, mock.Anything, mock.Anything)
+			if tt.result != nil {
+				var response TransactionStatusResponse
+				json.Unmarshal(w.Body.Bytes(), &response)
+				assert.Equal(t, txID, response.TransactionID)
+				assert.Equal(t, "PENDING", response.Status)
+			}
+		})
+	}
+}
+
 // ============ GetTransactions Tests ============
 
 func TestGetTransactions_Success(t *testing.T) {
@@ -311,3 +456,107 @@ func TestNewHandler_NotNil(t *testing.T) {
 
 	assert.NotNil(t, handler)
 }
+
+// ============ GetTransactionRisk Tests ============
+
+func createRiskTestContext(userID string, transactionID string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v1/transactions/"+transactionID+"/risk", nil)
+
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: transactionID}}
+	if userID != "" {
+		c.Set("user_id", userID)
+		c.Set("role", "USER")
+	}
+	return c, w
+}
+
+func TestGetTransactionRisk_Success(t *testing.T) {
+	mockService := new(MockService)
+	handler := NewHandler(mockService)
+
+	userID := uuid.New()
+	txID := uuid.New()
+	c, w := createRiskTestContext(userID.String(), txID.String())
+
+	mockService.On("GetTransactionRisk", mock.Anything, userID, "USER", txID).Return(&risk.TransactionRisk{
+		TransactionID: txID,
+		RiskScore:     45,
+		RiskLevel:     "MEDIUM",
+		Decision:      "FLAG",
+		Reasons: []risk.RiskReason{
+			{RuleName: "NEW_DEVICE_RISK", ReasonCode: "DEVICE_MISMATCH", RawScore: 100, Weight: 25, WeightedScore: 25},
+		},
+	}, nil)
+
+	handler.GetTransactionRisk(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	reasons := response["risk_result"]["reasons"].([]interface{})
+	assert.Len(t, reasons, 1)
+	assert.Equal(t, "DEVICE_MISMATCH", reasons[0].(map[string]interface{})["reason_code"])
+	mockService.AssertExpectations(t)
+}
+
+func TestGetTransactionRisk_NotFound(t *testing.T) {
+	mockService := new(MockService)
+	handler := NewHandler(mockService)
+
+	userID := uuid.New()
+	txID := uuid.New()
+	c, w := createRiskTestContext(userID.String(), txID.String())
+
+	mockService.On("GetTransactionRisk", mock.Anything, userID, "USER", txID).Return(nil, ErrTransactionNotFound)
+
+	handler.GetTransactionRisk(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetTransactionRisk_InvalidTransactionID(t *testing.T) {
+	mockService := new(MockService)
+	handler := NewHandler(mockService)
+
+	c, w := createRiskTestContext(uuid.New().String(), "not-a-uuid")
+
+	handler.GetTransactionRisk(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// ============ GetTransaction Tests ============
+
+func TestGetTransaction(t *testing.T) {
+	userID := uuid.New()
+	txID := uuid.New()
+
+	tests := []struct {
+		name           string
+		result         *TransactionStatusResponse
+		err            error
+		expectedStatus int
+	}{
+		{name: "pending", result: &TransactionStatusResponse{TransactionID: txID, Status: "PENDING"}, expectedStatus: http.StatusOK},
+		{name: "not_found", err: ErrTransactionNotFound, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockService)
+			handler := NewHandler(mockService)
+			c, w := createRiskTestContext(userID.String(), txID.String())
+
+			mockService.On("GetTransaction", mock.Anything, userID, "USER", txID).Return(tt.result, tt.err)
+
+			handler.GetTransaction(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -3,6 +3,12 @@ package transaction
 import (
 	"time"
 	"context"
+	"encoding/json"
+
+	"risk-detection/internal/challenge"
+	"risk-detection/internal/review"
+	"risk-detection/internal/webhook"
+	"risk-detection/internal/risk"
 
 	"github.com/google/uuid"
 )
@@ -29,6 +35,8 @@ type TransactionRequest struct {
 	ReceiverID      *uuid.UUID `json:"receiver_id"`
 	Amount          float64    `json:"amount" binding:"required,gt=0"`
 	DeviceID        string     `json:"device_id" binding:"required"`
+	// TransactionTime is read as an instant. Its UTC offset is not kept, so
+	// time-of-day risk compares users' habits by UTC hour, not local hour.
 	TransactionTime time.Time  `json:"transaction_time" binding:"required"`
 }
 
@@ -41,20 +49,87 @@ type TransactionRiskResponse struct {
 	Decision  string `json:"decision"`   
 
 	EvaluatedAt time.Time `json:"evaluated_at"`
+
+	// Challenge is set when the transaction waits for a step-up code
+	Challenge *challenge.Challenge `json:"challenge,omitempty"`
+}
+
+// TransactionStatusResponse is where a transaction stands. RiskResult is
+// set once it has been evaluated.
+type TransactionStatusResponse struct {
+	TransactionID uuid.UUID                `json:"transaction_id"`
+	Status        string                   `json:"status"`
+	RiskResult    *TransactionRiskResponse `json:"risk_result,omitempty"`
 }
 
 type Repository interface {
-	GetByID(id uuid.UUID) (*Transaction, error)
-	Create(tx *Transaction) error
-	UpdateStatusByID(id uuid.UUID, status string) error
-	CountTransactionFrequency(ctx context.Context, userID uuid.UUID, duration int32,) (float64, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*Transaction, error)
+	Create(ctx context.Context, tx *Transaction) error
+	UpdateStatusByID(ctx context.Context, id uuid.UUID, status string) error
+	CountTransactionFrequency(ctx context.Context, userID uuid.UUID, duration int32, excludeTxID uuid.UUID) (float64, error)
 	GetTransactions(ctx context.Context, userID uuid.UUID, offset int, limit int,) ([]*Transaction, error)
 	CountTotalTransaction(ctx context.Context, userID uuid.UUID,) (int64, error)
+	// ListUnevaluated returns PENDING transactions created before
+	// createdBefore that have no risk evaluation yet, oldest first.
+	ListUnevaluated(ctx context.Context, createdBefore time.Time, limit int) ([]*Transaction, error)
+	// ClaimUnevaluated locks the PENDING transaction id for evaluation until
+	// the unit of work in ctx ends. It returns nil if the transaction has
+	// been evaluated already or another evaluation holds it.
+	ClaimUnevaluated(ctx context.Context, id uuid.UUID) (*Transaction, error)
+}
+
+// ReviewQueue opens manual review cases for FLAGGED transactions.
+type ReviewQueue interface {
+	OpenCase(ctx context.Context, req review.OpenCaseRequest) (*review.Case, error)
+}
+
+// ChallengeIssuer sends step-up challenges for FLAGGED transactions.
+type ChallengeIssuer interface {
+	Issue(ctx context.Context, req challenge.IssueRequest) (*challenge.Challenge, error)
+}
+
+// IdempotencyStore remembers the response to each Idempotency-Key. It is
+// satisfied by idempotency.Service. Complete joins the caller's unit of work.
+type IdempotencyStore interface {
+	Begin(ctx context.Context, userID uuid.UUID, key string, fingerprint string) (json.RawMessage, error)
+	Complete(ctx context.Context, userID uuid.UUID, key string, response interface{}) error
+	Release(ctx context.Context, userID uuid.UUID, key string) error
+}
+
+// AsyncQueue hands transactions to background evaluation. It is satisfied
+// by *WorkerPool.
+type AsyncQueue interface {
+	Full() bool
+	Enqueue(tx *Transaction) error
+}
+
+// DecisionNotifier tells the client an asynchronous evaluation finished. It
+// is satisfied by callback.Service.
+type DecisionNotifier interface {
+	Notify(ctx context.Context, userID uuid.UUID, eventType string, data interface{}) error
+}
+
+// DecisionPublisher queues decisions for webhook subscribers. It is
+// satisfied by webhook.Service.
+type DecisionPublisher interface {
+	Publish(ctx context.Context, eventType string, d webhook.Decision) error
 }
 
 type Service interface {
-	CalculateRiskMatrix(tx *Transaction) (*TransactionRiskResponse, error)
+	CalculateRiskMatrix(ctx context.Context, tx *Transaction) (*TransactionRiskResponse, error)
+	// CalculateRiskMatrixOnce evaluates tx once per Idempotency-Key; a repeat
+	// of the same request gets the original response back.
+	CalculateRiskMatrixOnce(ctx context.Context, idempotencyKey string, tx *Transaction) (*TransactionRiskResponse, error)
+	// SubmitAsync saves tx as PENDING and queues it for evaluation.
+	SubmitAsync(ctx context.Context, idempotencyKey string, tx *Transaction) (*TransactionStatusResponse, error)
+	// EvaluateQueued evaluates a transaction saved by SubmitAsync.
+	EvaluateQueued(ctx context.Context, tx *Transaction) error
+	// ResumePending queues saved transactions that were never evaluated,
+	// e.g. after a restart, and returns how many were queued.
+	ResumePending(ctx context.Context) (int, error)
+	GetTransaction(ctx context.Context, userID uuid.UUID, role string, transactionID uuid.UUID) (*TransactionStatusResponse, error)
 	GetTransactions(ctx context.Context, userID uuid.UUID, offset int, limit int,)([]*Transaction, int64, error)
+	GetTransactionRisk(ctx context.Context, userID uuid.UUID, role string, transactionID uuid.UUID) (*risk.TransactionRisk, error)
 	
 
 }
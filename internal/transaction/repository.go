@@ -3,8 +3,11 @@ package transaction
 import (
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"context"
 	"time"
+
+	"risk-detection/internal/db"
 	
 	
 )
@@ -17,20 +20,20 @@ type repository struct {
 func NewRepository(db *gorm.DB) Repository {
 	return &repository{db: db}
 }
-func (r *repository) GetByID(id uuid.UUID) (*Transaction, error) {
+func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*Transaction, error) {
 	var tx Transaction
-	if err := r.db.First(&tx, "id = ?", id).Error; err != nil {
+	if err := db.Conn(ctx, r.db).First(&tx, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &tx, nil
 }
 
-func (r *repository) Create(tx *Transaction) error {
-	return r.db.Create(tx).Error
+func (r *repository) Create(ctx context.Context, tx *Transaction) error {
+	return db.Conn(ctx, r.db).Create(tx).Error
 }
 
-func (r *repository) UpdateStatusByID(id uuid.UUID, status string) error {
-	return r.db.
+func (r *repository) UpdateStatusByID(ctx context.Context, id uuid.UUID, status string) error {
+	return db.Conn(ctx, r.db).
 		Model(&Transaction{}).
 		Where("id = ?", id).
 		Update("transaction_status", status).Error
@@ -40,15 +43,17 @@ func (r *repository) CountTransactionFrequency(
 	ctx context.Context,
 	userID uuid.UUID,
 	duration int32,
+	excludeTxID uuid.UUID,
 ) (float64, error) {
 
 	var count int64
 
 	fromTime := time.Now().Add(-time.Duration(duration) * time.Minute)
 
-	err := r.db.WithContext(ctx).
+	err := db.Conn(ctx, r.db).
 		Model(&Transaction{}).
 		Where("user_id = ?", userID).
+		Where("id <> ?", excludeTxID).
 		Where("created_at >= ?", fromTime).
 		Count(&count).Error
 
@@ -68,7 +73,7 @@ func (r *repository) GetTransactions(
 
 	var transactions []*Transaction
 
-	err := r.db.WithContext(ctx).
+	err := db.Conn(ctx, r.db).
 		Where("user_id = ?", userID).
 		Order("created_at DESC").
 		Offset(offset1).
@@ -90,7 +95,7 @@ func (r *repository) CountTotalTransaction(
 
 	var count int64
 
-	err := r.db.WithContext(ctx).
+	err := db.Conn(ctx, r.db).
 		Model(&Transaction{}).
 		Where("user_id = ?", userID).
 		Count(&count).
@@ -103,3 +108,37 @@ func (r *repository) CountTotalTransaction(
 	return count, nil
 }
 
+
+// ListUnevaluated returns PENDING transactions created before createdBefore
+// that have no risk evaluation yet, oldest first.
+func (r *repository) ListUnevaluated(ctx context.Context, createdBefore time.Time, limit int) ([]*Transaction, error) {
+	var transactions []*Transaction
+
+	err := db.Conn(ctx, r.db).
+		Where("transaction_status = ?", "PENDING").
+		Where("created_at < ?", createdBefore).
+		Where("NOT EXISTS (SELECT 1 FROM transaction_risks tr WHERE tr.transaction_id = transactions.id)").
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&transactions).Error
+
+	return transactions, err
+}
+
+// ClaimUnevaluated locks the row with SKIP LOCKED, so instances racing for the
+// same transaction do not wait for each other: the loser gets nothing.
+func (r *repository) ClaimUnevaluated(ctx context.Context, id uuid.UUID) (*Transaction, error) {
+	var transactions []*Transaction
+
+	err := db.Conn(ctx, r.db).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("id = ?", id).
+		Where("transaction_status = ?", "PENDING").
+		Where("NOT EXISTS (SELECT 1 FROM transaction_risks tr WHERE tr.transaction_id = transactions.id)").
+		Limit(1).
+		Find(&transactions).Error
+	if err != nil || len(transactions) == 0 {
+		return nil, err
+	}
+	return transactions[0], nil
+}
@@ -2,32 +2,113 @@ package transaction
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	"risk-detection/internal/audit"
+	"risk-detection/internal/challenge"
+	"risk-detection/internal/db"
+	"risk-detection/internal/review"
 	"risk-detection/internal/risk"
+	"risk-detection/internal/webhook"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrAsyncDisabled       = errors.New("asynchronous evaluation is not enabled")
+)
+
+// EventDecisionReady is the callback event sent when an asynchronous
+// evaluation finished.
+const EventDecisionReady = "transaction.decision"
+
+// Evaluation modes of POST /api/v1/transaction.
+const (
+	modeSync  = "sync"
+	modeAsync = "async"
+)
+
+const (
+	// resumeAfter keeps ResumePending away from submissions that are still
+	// on their way into the queue.
+	resumeAfter     = time.Minute
+	resumeBatchSize = 100
 )
 
 type service struct {
 	repo        Repository
+	uow         db.UnitOfWork
 	riskService risk.Service
+	reviewQueue ReviewQueue
+	challenges  ChallengeIssuer
+	idempotency IdempotencyStore
+	queue       AsyncQueue
+	notifier    DecisionNotifier
+	webhooks    DecisionPublisher
 	auditLog    *audit.Logger
 }
 
-func NewService(repo Repository, riskService risk.Service, auditLog *audit.Logger) Service {
+// NewService wires the transaction flow. FLAGGED transactions get a step-up
+// challenge when challenges is set, otherwise a review case when
+// reviewQueue is set. Without idempotency, Idempotency-Key is ignored.
+// Without uow each step of an evaluation commits on its own. Without queue,
+// asynchronous submissions are refused; notifier and webhooks are optional.
+func NewService(repo Repository, uow db.UnitOfWork, riskService risk.Service, reviewQueue ReviewQueue, challenges ChallengeIssuer, idempotency IdempotencyStore, queue AsyncQueue, notifier DecisionNotifier, webhooks DecisionPublisher, auditLog *audit.Logger) Service {
 	return &service{
 		repo:        repo,
+		uow:         uow,
 		riskService: riskService,
+		reviewQueue: reviewQueue,
+		challenges:  challenges,
+		idempotency: idempotency,
+		queue:       queue,
+		notifier:    notifier,
+		webhooks:    webhooks,
 		auditLog:    auditLog,
 	}
 }
 
-func (s *service) CalculateRiskMatrix(tx *Transaction) (*TransactionRiskResponse, error) {
+// CalculateRiskMatrix saves, evaluates and settles tx in one unit of work,
+// so a failed step leaves neither the transaction nor its evaluation behind.
+func (s *service) CalculateRiskMatrix(ctx context.Context, tx *Transaction) (*TransactionRiskResponse, error) {
+	var response *TransactionRiskResponse
+	err := s.atomic(ctx, func(ctx context.Context) error {
+		var err error
+		response, err = s.evaluate(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (s *service) atomic(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.uow == nil {
+		return fn(ctx)
+	}
+	return s.uow.Do(ctx, fn)
+}
+
+func (s *service) evaluate(ctx context.Context, tx *Transaction) (*TransactionRiskResponse, error) {
+	if err := s.create(ctx, tx); err != nil {
+		return nil, err
+	}
+	return s.settle(ctx, tx)
+}
+
+func (s *service) create(ctx context.Context, tx *Transaction) error {
 	// Step 1: Save transaction to database
-	if err := s.repo.Create(tx); err != nil {
-		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	if err := s.repo.Create(ctx, tx); err != nil {
+		return fmt.Errorf("failed to create transaction: %w", err)
 	}
 
 	if s.auditLog != nil {
@@ -43,9 +124,13 @@ func (s *service) CalculateRiskMatrix(tx *Transaction) (*TransactionRiskResponse
 			DeviceID:   tx.DeviceID,
 		})
 	}
+	return nil
+}
 
+// settle evaluates a saved transaction and moves it to the decided status.
+func (s *service) settle(ctx context.Context, tx *Transaction) (*TransactionRiskResponse, error) {
 	// Step 2: Calculate risk score from risk service
-	riskResult, err := s.riskService.CalculateRisk(tx)
+	riskResult, err := s.riskService.CalculateRisk(ctx, tx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate risk: %w", err)
 	}
@@ -55,7 +140,13 @@ func (s *service) CalculateRiskMatrix(tx *Transaction) (*TransactionRiskResponse
 
 	// Step 3: Update transaction status based on risk decision
 	newStatus := s.mapDecisionToStatus(riskResult.Decision)
-	if err := s.repo.UpdateStatusByID(tx.ID, newStatus); err != nil {
+
+	// A step-up challenge keeps the transaction PENDING until it is answered
+	stepUp := newStatus == "FLAGGED" && s.challenges != nil
+	if stepUp {
+		newStatus = "PENDING"
+	}
+	if err := s.repo.UpdateStatusByID(ctx, tx.ID, newStatus); err != nil {
 		return nil, fmt.Errorf("failed to update transaction status: %w", err)
 	}
 
@@ -75,6 +166,49 @@ func (s *service) CalculateRiskMatrix(tx *Transaction) (*TransactionRiskResponse
 		Status: "SUCCESS",
 	})}
 
+	var ch *challenge.Challenge
+	if stepUp {
+		ch, err = s.challenges.Issue(ctx, challenge.IssueRequest{
+			TransactionID: tx.ID,
+			UserID:        tx.UserID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to issue challenge: %w", err)
+		}
+	}
+
+	// Flagged transactions wait for an analyst
+	if newStatus == "FLAGGED" && s.reviewQueue != nil {
+		_, err := s.reviewQueue.OpenCase(ctx, review.OpenCaseRequest{
+			TransactionID: tx.ID,
+			UserID:        tx.UserID,
+			Amount:        tx.Amount,
+			RiskScore:     riskResult.RiskScore,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to open review case: %w", err)
+		}
+	}
+
+	// Subscribers hear about the decision in the same unit of work, so a
+	// rolled back evaluation never reaches them
+	if s.webhooks != nil {
+		err := s.webhooks.Publish(ctx, webhook.EventTransactionEvaluated, webhook.Decision{
+			TransactionID:     tx.ID,
+			UserID:            tx.UserID,
+			TransactionType:   tx.TransactionType,
+			Amount:            tx.Amount,
+			RiskScore:         riskResult.RiskScore,
+			RiskLevel:         riskResult.RiskLevel,
+			Decision:          riskResult.Decision,
+			TransactionStatus: newStatus,
+			EvaluatedAt:       riskResult.EvaluatedAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to publish decision: %w", err)
+		}
+	}
+
 	// Step 4: Return formatted risk response to handler
 	response := &TransactionRiskResponse{
 		TransactionID: tx.ID,
@@ -82,10 +216,185 @@ func (s *service) CalculateRiskMatrix(tx *Transaction) (*TransactionRiskResponse
 		RiskLevel:     riskResult.RiskLevel,
 		Decision:      riskResult.Decision,
 		EvaluatedAt:   riskResult.EvaluatedAt,
+		Challenge:     ch,
 	}
 
 	return response, nil
 }
+// CalculateRiskMatrixOnce reserves the key before evaluating, so a retry
+// racing the original request is rejected instead of evaluated twice.
+func (s *service) CalculateRiskMatrixOnce(ctx context.Context, idempotencyKey string, tx *Transaction) (*TransactionRiskResponse, error) {
+	if idempotencyKey == "" || s.idempotency == nil {
+		return s.CalculateRiskMatrix(ctx, tx)
+	}
+
+	var response TransactionRiskResponse
+	err := s.once(ctx, idempotencyKey, requestFingerprint(modeSync, tx), tx.UserID, &response, func(ctx context.Context) (interface{}, error) {
+		return s.CalculateRiskMatrix(ctx, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// SubmitAsync saves tx as PENDING and leaves the evaluation to the queue.
+// The saved row is what makes the submission durable: if the queue cannot
+// take tx, ResumePending queues it later.
+func (s *service) SubmitAsync(ctx context.Context, idempotencyKey string, tx *Transaction) (*TransactionStatusResponse, error) {
+	if s.queue == nil {
+		return nil, ErrAsyncDisabled
+	}
+
+	submit := func(ctx context.Context) (interface{}, error) {
+		if s.queue.Full() {
+			return nil, ErrQueueFull
+		}
+		tx.TransactionStatus = "PENDING"
+		if err := s.create(ctx, tx); err != nil {
+			return nil, err
+		}
+		// A worker can only claim the row once it is committed
+		db.AfterCommit(ctx, func() {
+			if err := s.queue.Enqueue(tx); err != nil {
+				log.Printf("transaction %s left for the resume sweep: %v", tx.ID, err)
+			}
+		})
+		return &TransactionStatusResponse{TransactionID: tx.ID, Status: tx.TransactionStatus}, nil
+	}
+
+	if idempotencyKey == "" || s.idempotency == nil {
+		response, err := submit(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return response.(*TransactionStatusResponse), nil
+	}
+
+	var response TransactionStatusResponse
+	if err := s.once(ctx, idempotencyKey, requestFingerprint(modeAsync, tx), tx.UserID, &response, submit); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// EvaluateQueued settles a transaction saved by SubmitAsync in one unit of
+// work and calls the user back with the decision. The transaction is claimed
+// first, so when several instances pick it up, only one evaluates it.
+func (s *service) EvaluateQueued(ctx context.Context, tx *Transaction) error {
+	var response *TransactionRiskResponse
+	err := s.atomic(ctx, func(ctx context.Context) error {
+		claimed, err := s.repo.ClaimUnevaluated(ctx, tx.ID)
+		if err != nil {
+			return fmt.Errorf("failed to claim transaction: %w", err)
+		}
+		if claimed == nil {
+			return nil
+		}
+		response, err = s.settle(ctx, claimed)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if response == nil {
+		log.Printf("transaction %s was evaluated or claimed elsewhere, skipping", tx.ID)
+		return nil
+	}
+
+	if s.notifier != nil {
+		if err := s.notifier.Notify(ctx, tx.UserID, EventDecisionReady, response); err != nil {
+			log.Printf("unable to deliver decision of transaction %s: %v", tx.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *service) ResumePending(ctx context.Context) (int, error) {
+	if s.queue == nil {
+		return 0, nil
+	}
+
+	pending, err := s.repo.ListUnevaluated(ctx, time.Now().Add(-resumeAfter), resumeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for _, tx := range pending {
+		if err := s.queue.Enqueue(tx); err != nil {
+			// The rest waits for the next sweep
+			break
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+// once runs fn once per idempotency key and decodes its response, or the
+// response stored for the key, into out. Both go through JSON so a replay
+// reads exactly like the first response. fn and the stored response commit
+// in one unit of work, so a key is never left open for work that is done.
+func (s *service) once(ctx context.Context, idempotencyKey string, fingerprint string, userID uuid.UUID, out interface{}, fn func(ctx context.Context) (interface{}, error)) error {
+	stored, err := s.idempotency.Begin(ctx, userID, idempotencyKey, fingerprint)
+	if err != nil {
+		return err
+	}
+	if stored != nil {
+		if err := json.Unmarshal(stored, out); err != nil {
+			return fmt.Errorf("failed to decode stored response: %w", err)
+		}
+		return nil
+	}
+
+	// The key is released even if the client is gone, so its retry can run
+	settleCtx := context.WithoutCancel(ctx)
+
+	var response interface{}
+	err = s.atomic(ctx, func(ctx context.Context) error {
+		var err error
+		response, err = fn(ctx)
+		if err != nil {
+			return err
+		}
+		if err := s.idempotency.Complete(ctx, userID, idempotencyKey, response); err != nil {
+			return fmt.Errorf("failed to store idempotent response: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if relErr := s.idempotency.Release(settleCtx, userID, idempotencyKey); relErr != nil {
+			log.Printf("unable to release idempotency key: %v", relErr)
+		}
+		return err
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, out)
+}
+
+// requestFingerprint hashes the client-supplied fields of a transaction
+// request. The IP address is left out since retries may come from another
+// network.
+func requestFingerprint(mode string, tx *Transaction) string {
+	receiver := ""
+	if tx.ReceiverID != nil {
+		receiver = tx.ReceiverID.String()
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%.2f|%s|%s",
+		mode,
+		tx.TransactionType,
+		receiver,
+		tx.Amount,
+		tx.DeviceID,
+		tx.TransactionTime.UTC().Format(time.RFC3339Nano),
+	)))
+	return hex.EncodeToString(sum[:])
+}
+
 func (s *service) GetTransactions(
 	ctx context.Context,
 	userID uuid.UUID,
@@ -113,6 +422,82 @@ func (s *service) GetTransactions(
 	return transactions, total, nil
 }
 
+// GetTransactionRisk returns the risk evaluation of a transaction with its
+// reason breakdown. Users can only read their own transactions; ADMIN can
+// read any.
+func (s *service) GetTransactionRisk(
+	ctx context.Context,
+	userID uuid.UUID,
+	role string,
+	transactionID uuid.UUID,
+) (*risk.TransactionRisk, error) {
+
+	tx, err := s.repo.GetByID(ctx, transactionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, err
+	}
+
+	// Hide other users' transactions behind not found
+	if tx.UserID != userID && role != "ADMIN" {
+		return nil, ErrTransactionNotFound
+	}
+
+	riskResult, err := s.riskService.GetRisk(ctx, transactionID)
+	if err != nil {
+		if errors.Is(err, risk.ErrRiskNotFound) {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, err
+	}
+	return riskResult, nil
+}
+
+// GetTransaction returns the status of a transaction and, once evaluated,
+// its decision. Users can only read their own transactions; ADMIN can read
+// any.
+func (s *service) GetTransaction(
+	ctx context.Context,
+	userID uuid.UUID,
+	role string,
+	transactionID uuid.UUID,
+) (*TransactionStatusResponse, error) {
+
+	tx, err := s.repo.GetByID(ctx, transactionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, err
+	}
+
+	// Hide other users' transactions behind not found
+	if tx.UserID != userID && role != "ADMIN" {
+		return nil, ErrTransactionNotFound
+	}
+
+	response := &TransactionStatusResponse{TransactionID: tx.ID, Status: tx.TransactionStatus}
+
+	riskResult, err := s.riskService.GetRisk(ctx, transactionID)
+	if err != nil {
+		if errors.Is(err, risk.ErrRiskNotFound) {
+			// Still waiting for a worker
+			return response, nil
+		}
+		return nil, err
+	}
+	response.RiskResult = &TransactionRiskResponse{
+		TransactionID: tx.ID,
+		RiskScore:     riskResult.RiskScore,
+		RiskLevel:     riskResult.RiskLevel,
+		Decision:      riskResult.Decision,
+		EvaluatedAt:   riskResult.EvaluatedAt,
+	}
+	return response, nil
+}
+
 // mapDecisionToStatus converts risk decision to transaction status
 func (s *service) mapDecisionToStatus(decision string) string {
 	switch decision {
@@ -2,10 +2,16 @@ package transaction
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
+	"risk-detection/internal/challenge"
+	"risk-detection/internal/idempotency"
+	"risk-detection/internal/review"
 	"risk-detection/internal/risk"
+	"risk-detection/internal/webhook"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -17,26 +23,26 @@ type MockRepository struct {
 	mock.Mock
 }
 
-func (m *MockRepository) GetByID(id uuid.UUID) (*Transaction, error) {
-	args := m.Called(id)
+func (m *MockRepository) GetByID(ctx context.Context, id uuid.UUID) (*Transaction, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*Transaction), args.Error(1)
 }
 
-func (m *MockRepository) Create(tx *Transaction) error {
-	args := m.Called(tx)
+func (m *MockRepository) Create(ctx context.Context, tx *Transaction) error {
+	args := m.Called(ctx, tx)
 	return args.Error(0)
 }
 
-func (m *MockRepository) UpdateStatusByID(id uuid.UUID, status string) error {
-	args := m.Called(id, status)
+func (m *MockRepository) UpdateStatusByID(ctx context.Context, id uuid.UUID, status string) error {
+	args := m.Called(ctx, id, status)
 	return args.Error(0)
 }
 
-func (m *MockRepository) CountTransactionFrequency(ctx context.Context, userID uuid.UUID, duration int32) (float64, error) {
-	args := m.Called(ctx, userID, duration)
+func (m *MockRepository) CountTransactionFrequency(ctx context.Context, userID uuid.UUID, duration int32, excludeTxID uuid.UUID) (float64, error) {
+	args := m.Called(ctx, userID, duration, excludeTxID)
 	return args.Get(0).(float64), args.Error(1)
 }
 
@@ -53,25 +59,74 @@ func (m *MockRepository) CountTotalTransaction(ctx context.Context, userID uuid.
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockRepository) ListUnevaluated(ctx context.Context, createdBefore time.Time, limit int) ([]*Transaction, error) {
+	args := m.Called(ctx, createdBefore, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*Transaction), args.Error(1)
+}
+
+func (m *MockRepository) ClaimUnevaluated(ctx context.Context, id uuid.UUID) (*Transaction, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Transaction), args.Error(1)
+}
+
 // MockRiskService is a mock implementation of the risk.Service interface
 type MockRiskService struct {
 	mock.Mock
 }
 
-func (m *MockRiskService) CalculateRisk(tx interface{}) (*risk.TransactionRisk, error) {
-	args := m.Called(tx)
+func (m *MockRiskService) CalculateRisk(ctx context.Context, tx interface{}) (*risk.TransactionRisk, error) {
+	args := m.Called(ctx, tx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*risk.TransactionRisk), args.Error(1)
+}
+
+func (m *MockRiskService) GetRisk(ctx context.Context, transactionID uuid.UUID) (*risk.TransactionRisk, error) {
+	args := m.Called(ctx, transactionID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*risk.TransactionRisk), args.Error(1)
 }
+
+func (m *MockRiskService) ReloadRules(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockRiskService) ReloadPolicies(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockRiskService) RecordVerifiedChallenge(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	args := m.Called(ctx, userID, at)
+	return args.Error(0)
+}
+
+func (m *MockRiskService) SkippableNames() []string {
+	args := m.Called()
+	return args.Get(0).([]string)
+}
+
+func (m *MockRiskService) ValidateRuleExpression(src string) error {
+	args := m.Called(src)
+	return args.Error(0)
+}
 //========== GetTransactions Tests ============
 
 func TestService_GetTransactions_Success(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockRiskService := new(MockRiskService)
 
-	service := NewService(mockRepo, mockRiskService, nil)
+	service := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, nil, nil, nil)
 
 	userID := uuid.New()
 	ctx := context.Background()
@@ -97,7 +152,7 @@ func TestGetTransactions_EmptyResult(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockRiskService := new(MockRiskService)
 
-	service := NewService(mockRepo, mockRiskService, nil)
+	service := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, nil, nil, nil)
 
 	userID := uuid.New()
 	ctx := context.Background()
@@ -117,7 +172,7 @@ func TestGetTransactions_QueryError(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockRiskService := new(MockRiskService)
 
-	service := NewService(mockRepo, mockRiskService, nil)
+	service := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, nil, nil, nil)
 
 	userID := uuid.New()
 	ctx := context.Background()
@@ -135,7 +190,7 @@ func TestGetTransactions_CountError(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockRiskService := new(MockRiskService)
 
-	service := NewService(mockRepo, mockRiskService, nil)
+	service := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, nil, nil, nil)
 
 	userID := uuid.New()
 	ctx := context.Background()
@@ -156,7 +211,7 @@ func TestGetTransactions_NegativeOffsetAdjustment(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockRiskService := new(MockRiskService)
 
-	service := NewService(mockRepo, mockRiskService, nil)
+	service := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, nil, nil, nil)
 
 	userID := uuid.New()
 	ctx := context.Background()
@@ -175,7 +230,7 @@ func TestGetTransactions_NegativeLimitAdjustment(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockRiskService := new(MockRiskService)
 
-	service := NewService(mockRepo, mockRiskService, nil)
+	service := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, nil, nil, nil)
 
 	userID := uuid.New()
 	ctx := context.Background()
@@ -194,7 +249,7 @@ func TestGetTransactions_ZeroLimitAdjustment(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockRiskService := new(MockRiskService)
 
-	service := NewService(mockRepo, mockRiskService, nil)
+	service := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, nil, nil, nil)
 
 	userID := uuid.New()
 	ctx := context.Background()
@@ -215,7 +270,7 @@ func TestMapDecisionToStatus_Allow(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockRiskService := new(MockRiskService)
 
-	service := NewService(mockRepo, mockRiskService, nil).(*service)
+	service := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, nil, nil, nil).(*service)
 
 	status := service.mapDecisionToStatus("ALLOW")
 	assert.Equal(t, "COMPLETED", status)
@@ -225,7 +280,7 @@ func TestMapDecisionToStatus_Flag(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockRiskService := new(MockRiskService)
 
-	service := NewService(mockRepo, mockRiskService, nil).(*service)
+	service := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, nil, nil, nil).(*service)
 
 	status := service.mapDecisionToStatus("FLAG")
 	assert.Equal(t, "FLAGGED", status)
@@ -235,7 +290,7 @@ func TestMapDecisionToStatus_Block(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockRiskService := new(MockRiskService)
 
-	service := NewService(mockRepo, mockRiskService, nil).(*service)
+	service := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, nil, nil, nil).(*service)
 
 	status := service.mapDecisionToStatus("BLOCK")
 	assert.Equal(t, "BLOCKED", status)
@@ -245,7 +300,7 @@ func TestMapDecisionToStatus_Unknown(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockRiskService := new(MockRiskService)
 
-	service := NewService(mockRepo, mockRiskService, nil).(*service)
+	service := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, nil, nil, nil).(*service)
 
 	status := service.mapDecisionToStatus("UNKNOWN_DECISION")
 	assert.Equal(t, "PENDING", status)
@@ -255,7 +310,7 @@ func TestMapDecisionToStatus_Empty(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockRiskService := new(MockRiskService)
 
-	service := NewService(mockRepo, mockRiskService, nil).(*service)
+	service := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, nil, nil, nil).(*service)
 
 	status := service.mapDecisionToStatus("")
 	assert.Equal(t, "PENDING", status)
@@ -265,20 +320,600 @@ func TestMapDecisionToStatus_CaseSensitive(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockRiskService := new(MockRiskService)
 
-	service := NewService(mockRepo, mockRiskService, nil).(*service)
+	service := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, nil, nil, nil).(*service)
 
 	// Lowercase should not match - should return PENDING
 	status := service.mapDecisionToStatus("allow")
 	assert.Equal(t, "PENDING", status)
 }
 
+// ============ GetTransactionRisk Tests ============
+
+func TestService_GetTransactionRisk(t *testing.T) {
+	ownerID := uuid.New()
+	otherID := uuid.New()
+	txID := uuid.New()
+
+	tests := []struct {
+		name        string
+		userID      uuid.UUID
+		role        string
+		expectError error
+	}{
+		{name: "owner_can_read", userID: ownerID, role: "USER"},
+		{name: "admin_can_read_any", userID: otherID, role: "ADMIN"},
+		{name: "other_user_gets_not_found", userID: otherID, role: "USER", expectError: ErrTransactionNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockRiskService := new(MockRiskService)
+			mockRepo.On("GetByID", mock.Anything, txID).Return(&Transaction{ID: txID, UserID: ownerID}, nil)
+			mockRiskService.On("GetRisk", mock.Anything, txID).Return(&risk.TransactionRisk{TransactionID: txID}, nil)
+
+			svc := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, nil, nil, nil)
+			result, err := svc.GetTransactionRisk(context.Background(), tt.userID, tt.role, txID)
+
+			if tt.expectError != nil {
+				assert.ErrorIs(t, err, tt.expectError)
+				mockRiskService.AssertNotCalled(t, "GetRisk", mock.Anything, mock.Anything)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, txID, result.TransactionID)
+			}
+		})
+	}
+}
+
+// ============ Review Queue Tests ============
+
+// MockReviewQueue is a mock implementation of the ReviewQueue interface
+type MockReviewQueue struct {
+	mock.Mock
+}
+
+func (m *MockReviewQueue) OpenCase(ctx context.Context, req review.OpenCaseRequest) (*review.Case, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*review.Case), args.Error(1)
+}
+
+func TestCalculateRiskMatrix_ReviewQueue(t *testing.T) {
+	tests := []struct {
+		name       string
+		decision   string
+		expectCase bool
+	}{
+		{name: "flag_opens_case", decision: "FLAG", expectCase: true},
+		{name: "allow_skips_queue", decision: "ALLOW"},
+		{name: "block_skips_queue", decision: "BLOCK"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &Transaction{ID: uuid.New(), UserID: uuid.New(), Amount: 1500}
+
+			mockRepo := new(MockRepository)
+			mockRiskService := new(MockRiskService)
+			mockQueue := new(MockReviewQueue)
+			mockRepo.On("Create", mock.Anything, tx).Return(nil)
+			mockRepo.On("UpdateStatusByID", mock.Anything, tx.ID, mock.Anything).Return(nil)
+			mockRiskService.On("CalculateRisk", mock.Anything, tx).Return(&risk.TransactionRisk{TransactionID: tx.ID, RiskScore: 45, Decision: tt.decision}, nil)
+			mockQueue.On("OpenCase", mock.Anything, review.OpenCaseRequest{
+				TransactionID: tx.ID,
+				UserID:        tx.UserID,
+				Amount:        tx.Amount,
+				RiskScore:     45,
+			}).Return(&review.Case{ID: 1}, nil)
+
+			svc := NewService(mockRepo, nil, mockRiskService, mockQueue, nil, nil, nil, nil, nil, nil)
+			_, err := svc.CalculateRiskMatrix(context.Background(), tx)
+			assert.NoError(t, err)
+
+			if tt.expectCase {
+				mockQueue.AssertExpectations(t)
+			} else {
+				mockQueue.AssertNotCalled(t, "OpenCase", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+// MockChallengeIssuer is a mock implementation of the ChallengeIssuer interface
+type MockChallengeIssuer struct {
+	mock.Mock
+}
+
+func (m *MockChallengeIssuer) Issue(ctx context.Context, req challenge.IssueRequest) (*challenge.Challenge, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*challenge.Challenge), args.Error(1)
+}
+
+func TestCalculateRiskMatrix_StepUpChallenge(t *testing.T) {
+	tx := &Transaction{ID: uuid.New(), UserID: uuid.New(), Amount: 1500}
+	issued := &challenge.Challenge{ID: uuid.New(), TransactionID: tx.ID, Status: challenge.StatusPending}
+
+	mockRepo := new(MockRepository)
+	mockRiskService := new(MockRiskService)
+	mockQueue := new(MockReviewQueue)
+	mockChallenges := new(MockChallengeIssuer)
+	mockRepo.On("Create", mock.Anything, tx).Return(nil)
+	mockRepo.On("UpdateStatusByID", mock.Anything, tx.ID, "PENDING").Return(nil)
+	mockRiskService.On("CalculateRisk", mock.Anything, tx).Return(&risk.TransactionRisk{TransactionID: tx.ID, RiskScore: 45, Decision: "FLAG"}, nil)
+	mockChallenges.On("Issue", mock.Anything, challenge.IssueRequest{TransactionID: tx.ID, UserID: tx.UserID}).Return(issued, nil)
+
+	svc := NewService(mockRepo, nil, mockRiskService, mockQueue, mockChallenges, nil, nil, nil, nil, nil)
+	response, err := svc.CalculateRiskMatrix(context.Background(), tx)
+	assert.NoError(t, err)
+	assert.Equal(t, issued, response.Challenge)
+
+	// The transaction waits for the code instead of an analyst
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "UpdateStatusByID", mock.Anything, tx.ID, "FLAGGED")
+	mockQueue.AssertNotCalled(t, "OpenCase", mock.Anything, mock.Anything)
+}
+
+// MockIdempotencyStore is a mock implementation of the IdempotencyStore interface
+type MockIdempotencyStore struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyStore) Begin(ctx context.Context, userID uuid.UUID, key string, fingerprint string) (json.RawMessage, error) {
+	args := m.Called(ctx, userID, key, fingerprint)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(json.RawMessage), args.Error(1)
+}
+
+func (m *MockIdempotencyStore) Complete(ctx context.Context, userID uuid.UUID, key string, response interface{}) error {
+	args := m.Called(ctx, userID, key, response)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyStore) Release(ctx context.Context, userID uuid.UUID, key string) error {
+	args := m.Called(ctx, userID, key)
+	return args.Error(0)
+}
+
+func TestCalculateRiskMatrixOnce(t *testing.T) {
+	newTx := func() *Transaction {
+		return &Transaction{ID: uuid.New(), UserID: uuid.New(), TransactionType: "TRANSFER", Amount: 100, DeviceID: "device123", TransactionTime: time.Now()}
+	}
+
+	t.Run("first_request_is_evaluated_and_stored", func(t *testing.T) {
+		tx := newTx()
+		mockRepo := new(MockRepository)
+		mockRiskService := new(MockRiskService)
+		store := new(MockIdempotencyStore)
+		mockRepo.On("Create", mock.Anything, tx).Return(nil)
+		mockRepo.On("UpdateStatusByID", mock.Anything, tx.ID, "COMPLETED").Return(nil)
+		mockRiskService.On("CalculateRisk", mock.Anything, tx).Return(&risk.TransactionRisk{TransactionID: tx.ID, RiskScore: 10, Decision: "ALLOW"}, nil)
+		store.On("Begin", mock.Anything, tx.UserID, "key-1", requestFingerprint(modeSync, tx)).Return(nil, nil)
+		store.On("Complete", mock.Anything, tx.UserID, "key-1", mock.AnythingOfType("*transaction.TransactionRiskResponse")).Return(nil)
+
+		svc := NewService(mockRepo, nil, mockRiskService, nil, nil, store, nil, nil, nil, nil)
+		response, err := svc.CalculateRiskMatrixOnce(context.Background(), "key-1", tx)
+		assert.NoError(t, err)
+		assert.Equal(t, "ALLOW", response.Decision)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("repeat_replays_stored_response", func(t *testing.T) {
+		tx := newTx()
+		original := TransactionRiskResponse{TransactionID: uuid.New(), RiskScore: 10, RiskLevel: "LOW", Decision: "ALLOW"}
+		stored, _ := json.Marshal(original)
+		mockRepo := new(MockRepository)
+		mockRiskService := new(MockRiskService)
+		store := new(MockIdempotencyStore)
+		store.On("Begin", mock.Anything, tx.UserID, "key-1", mock.Anything).Return(json.RawMessage(stored), nil)
+
+		svc := NewService(mockRepo, nil, mockRiskService, nil, nil, store, nil, nil, nil, nil)
+		response, err := svc.CalculateRiskMatrixOnce(context.Background(), "key-1", tx)
+		assert.NoError(t, err)
+		assert.Equal(t, original.TransactionID, response.TransactionID)
+		assert.Equal(t, "ALLOW", response.Decision)
+
+		// Nothing is inserted or evaluated a second time
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		mockRiskService.AssertNotCalled(t, "CalculateRisk", mock.Anything, mock.Anything)
+	})
+
+	t.Run("conflict_is_returned", func(t *testing.T) {
+		tx := newTx()
+		mockRepo := new(MockRepository)
+		store := new(MockIdempotencyStore)
+		store.On("Begin", mock.Anything, tx.UserID, "key-1", mock.Anything).Return(nil, idempotency.ErrKeyReused)
+
+		svc := NewService(mockRepo, nil, new(MockRiskService), nil, nil, store, nil, nil, nil, nil)
+		_, err := svc.CalculateRiskMatrixOnce(context.Background(), "key-1", tx)
+		assert.ErrorIs(t, err, idempotency.ErrKeyReused)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("failure_releases_key", func(t *testing.T) {
+		tx := newTx()
+		mockRepo := new(MockRepository)
+		store := new(MockIdempotencyStore)
+		mockRepo.On("Create", mock.Anything, tx).Return(errors.New("db down"))
+		store.On("Begin", mock.Anything, tx.UserID, "key-1", mock.Anything).Return(nil, nil)
+		store.On("Release", mock.Anything, tx.UserID, "key-1").Return(nil)
+
+		svc := NewService(mockRepo, nil, new(MockRiskService), nil, nil, store, nil, nil, nil, nil)
+		_, err := svc.CalculateRiskMatrixOnce(context.Background(), "key-1", tx)
+		assert.Error(t, err)
+		store.AssertExpectations(t)
+		store.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("complete_failure_rolls_back", func(t *testing.T) {
+		tx := newTx()
+		mockRepo := new(MockRepository)
+		mockRiskService := new(MockRiskService)
+		store := new(MockIdempotencyStore)
+		mockRepo.On("Create", mock.Anything, tx).Return(nil)
+		mockRepo.On("UpdateStatusByID", mock.Anything, tx.ID, "COMPLETED").Return(nil)
+		mockRiskService.On("CalculateRisk", mock.Anything, tx).Return(&risk.TransactionRisk{TransactionID: tx.ID, Decision: "ALLOW"}, nil)
+		store.On("Begin", mock.Anything, tx.UserID, "key-1", mock.Anything).Return(nil, nil)
+		store.On("Complete", mock.Anything, tx.UserID, "key-1", mock.Anything).Return(errors.New("db down"))
+		store.On("Release", mock.Anything, tx.UserID, "key-1").Return(nil)
+
+		// A transaction whose key cannot be completed must not stay behind,
+		// or a retry would create it a second time
+		uow := &fakeUnitOfWork{}
+		svc := NewService(mockRepo, uow, mockRiskService, nil, nil, store, nil, nil, nil, nil)
+		_, err := svc.CalculateRiskMatrixOnce(context.Background(), "key-1", tx)
+		assert.Error(t, err)
+		assert.True(t, uow.rolledBack)
+		assert.False(t, uow.committed)
+		store.AssertExpectations(t)
+	})
+
+	t.Run("no_key_skips_store", func(t *testing.T) {
+		tx := newTx()
+		mockRepo := new(MockRepository)
+		mockRiskService := new(MockRiskService)
+		store := new(MockIdempotencyStore)
+		mockRepo.On("Create", mock.Anything, tx).Return(nil)
+		mockRepo.On("UpdateStatusByID", mock.Anything, tx.ID, "COMPLETED").Return(nil)
+		mockRiskService.On("CalculateRisk", mock.Anything, tx).Return(&risk.TransactionRisk{TransactionID: tx.ID, Decision: "ALLOW"}, nil)
+
+		svc := NewService(mockRepo, nil, mockRiskService, nil, nil, store, nil, nil, nil, nil)
+		_, err := svc.CalculateRiskMatrixOnce(context.Background(), "", tx)
+		assert.NoError(t, err)
+		store.AssertNotCalled(t, "Begin", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestRequestFingerprint(t *testing.T) {
+	receiver := uuid.New()
+	base := Transaction{UserID: uuid.New(), TransactionType: "TRANSFER", ReceiverID: &receiver, Amount: 100, DeviceID: "device123", IPAddress: "10.0.0.1", TransactionTime: time.Now()}
+
+	retry := base
+	retry.ID = uuid.New()
+	retry.IPAddress = "10.0.0.2"
+	assert.Equal(t, requestFingerprint(modeSync, &base), requestFingerprint(modeSync, &retry))
+
+	changed := base
+	changed.Amount = 101
+	assert.NotEqual(t, requestFingerprint(modeSync, &base), requestFingerprint(modeSync, &changed))
+}
+
+// fakeUnitOfWork records whether the work it ran committed or rolled back.
+// A Do inside another joins it, as with db.UnitOfWork.
+type fakeUnitOfWork struct {
+	committed  bool
+	rolledBack bool
+	active     bool
+}
+
+func (u *fakeUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if u.active {
+		return fn(ctx)
+	}
+	u.active = true
+	defer func() { u.active = false }()
+	if err := fn(ctx); err != nil {
+		u.rolledBack = true
+		return err
+	}
+	u.committed = true
+	return nil
+}
+
+func TestCalculateRiskMatrix_UnitOfWork(t *testing.T) {
+	t.Run("success_commits", func(t *testing.T) {
+		tx := &Transaction{ID: uuid.New(), UserID: uuid.New()}
+		mockRepo := new(MockRepository)
+		mockRiskService := new(MockRiskService)
+		mockRepo.On("Create", mock.Anything, tx).Return(nil)
+		mockRepo.On("UpdateStatusByID", mock.Anything, tx.ID, "COMPLETED").Return(nil)
+		mockRiskService.On("CalculateRisk", mock.Anything, tx).Return(&risk.TransactionRisk{TransactionID: tx.ID, Decision: "ALLOW"}, nil)
+
+		uow := &fakeUnitOfWork{}
+		_, err := NewService(mockRepo, uow, mockRiskService, nil, nil, nil, nil, nil, nil, nil).CalculateRiskMatrix(context.Background(), tx)
+		assert.NoError(t, err)
+		assert.True(t, uow.committed)
+	})
+
+	t.Run("risk_failure_rolls_back", func(t *testing.T) {
+		tx := &Transaction{ID: uuid.New(), UserID: uuid.New()}
+		mockRepo := new(MockRepository)
+		mockRiskService := new(MockRiskService)
+		mockRepo.On("Create", mock.Anything, tx).Return(nil)
+		mockRiskService.On("CalculateRisk", mock.Anything, tx).Return(nil, errors.New("unable to save risk matrix"))
+
+		uow := &fakeUnitOfWork{}
+		_, err := NewService(mockRepo, uow, mockRiskService, nil, nil, nil, nil, nil, nil, nil).CalculateRiskMatrix(context.Background(), tx)
+		assert.Error(t, err)
+		assert.True(t, uow.rolledBack)
+		assert.False(t, uow.committed)
+		mockRepo.AssertNotCalled(t, "UpdateStatusByID", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("status_update_failure_rolls_back", func(t *testing.T) {
+		tx := &Transaction{ID: uuid.New(), UserID: uuid.New()}
+		mockRepo := new(MockRepository)
+		mockRiskService := new(MockRiskService)
+		mockRepo.On("Create", mock.Anything, tx).Return(nil)
+		mockRepo.On("UpdateStatusByID", mock.Anything, tx.ID, "BLOCKED").Return(errors.New("db down"))
+		mockRiskService.On("CalculateRisk", mock.Anything, tx).Return(&risk.TransactionRisk{TransactionID: tx.ID, Decision: "BLOCK"}, nil)
+
+		uow := &fakeUnitOfWork{}
+		_, err := NewService(mockRepo, uow, mockRiskService, nil, nil, nil, nil, nil, nil, nil).CalculateRiskMatrix(context.Background(), tx)
+		assert.Error(t, err)
+		assert.True(t, uow.rolledBack)
+	})
+}
+
 // ============ Service Initialization Tests ============
 
 func TestNewService_NotNil(t *testing.T) {
 	mockRepo := new(MockRepository)
 	mockRiskService := new(MockRiskService)
 
-	service := NewService(mockRepo, mockRiskService, nil)
+	service := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, nil, nil, nil)
 
 	assert.NotNil(t, service)
 }
+
+// MockAsyncQueue is a mock implementation of the AsyncQueue interface
+type MockAsyncQueue struct {
+	mock.Mock
+}
+
+func (m *MockAsyncQueue) Full() bool {
+	args := m.Called()
+	return args.Bool(0)
+}
+
+func (m *MockAsyncQueue) Enqueue(tx *Transaction) error {
+	args := m.Called(tx)
+	return args.Error(0)
+}
+
+// MockDecisionNotifier is a mock implementation of the DecisionNotifier interface
+type MockDecisionNotifier struct {
+	mock.Mock
+}
+
+func (m *MockDecisionNotifier) Notify(ctx context.Context, userID uuid.UUID, eventType string, data interface{}) error {
+	args := m.Called(ctx, userID, eventType, data)
+	return args.Error(0)
+}
+
+func TestSubmitAsync(t *testing.T) {
+	t.Run("disabled_without_queue", func(t *testing.T) {
+		svc := NewService(new(MockRepository), nil, new(MockRiskService), nil, nil, nil, nil, nil, nil, nil)
+		_, err := svc.SubmitAsync(context.Background(), "", &Transaction{ID: uuid.New()})
+		assert.ErrorIs(t, err, ErrAsyncDisabled)
+	})
+
+	t.Run("full_queue_saves_nothing", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		mockQueue := new(MockAsyncQueue)
+		mockQueue.On("Full").Return(true)
+
+		svc := NewService(mockRepo, nil, new(MockRiskService), nil, nil, nil, mockQueue, nil, nil, nil)
+		_, err := svc.SubmitAsync(context.Background(), "", &Transaction{ID: uuid.New()})
+		assert.ErrorIs(t, err, ErrQueueFull)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("saves_pending_and_queues", func(t *testing.T) {
+		tx := &Transaction{ID: uuid.New(), UserID: uuid.New(), Amount: 100}
+		mockRepo := new(MockRepository)
+		mockRiskService := new(MockRiskService)
+		mockQueue := new(MockAsyncQueue)
+		mockQueue.On("Full").Return(false)
+		mockQueue.On("Enqueue", tx).Return(nil)
+		mockRepo.On("Create", mock.Anything, tx).Return(nil)
+
+		svc := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, mockQueue, nil, nil, nil)
+		response, err := svc.SubmitAsync(context.Background(), "", tx)
+		assert.NoError(t, err)
+		assert.Equal(t, &TransactionStatusResponse{TransactionID: tx.ID, Status: "PENDING"}, response)
+		mockRiskService.AssertNotCalled(t, "CalculateRisk", mock.Anything, mock.Anything)
+		mockQueue.AssertExpectations(t)
+	})
+
+	t.Run("enqueue_failure_leaves_it_to_resume", func(t *testing.T) {
+		tx := &Transaction{ID: uuid.New(), UserID: uuid.New(), Amount: 100}
+		mockRepo := new(MockRepository)
+		mockQueue := new(MockAsyncQueue)
+		mockQueue.On("Full").Return(false)
+		mockQueue.On("Enqueue", tx).Return(ErrQueueFull)
+		mockRepo.On("Create", mock.Anything, tx).Return(nil)
+
+		svc := NewService(mockRepo, nil, new(MockRiskService), nil, nil, nil, mockQueue, nil, nil, nil)
+		response, err := svc.SubmitAsync(context.Background(), "", tx)
+		assert.NoError(t, err)
+		assert.Equal(t, "PENDING", response.Status)
+	})
+}
+
+func TestEvaluateQueued_NotifiesDecision(t *testing.T) {
+	tx := &Transaction{ID: uuid.New(), UserID: uuid.New(), Amount: 100}
+
+	mockRepo := new(MockRepository)
+	mockRiskService := new(MockRiskService)
+	mockNotifier := new(MockDecisionNotifier)
+	mockRepo.On("ClaimUnevaluated", mock.Anything, tx.ID).Return(tx, nil)
+	mockRepo.On("UpdateStatusByID", mock.Anything, tx.ID, "COMPLETED").Return(nil)
+	mockRiskService.On("CalculateRisk", mock.Anything, tx).Return(&risk.TransactionRisk{TransactionID: tx.ID, RiskScore: 10, Decision: "ALLOW"}, nil)
+	mockNotifier.On("Notify", mock.Anything, tx.UserID, EventDecisionReady, mock.MatchedBy(func(r *TransactionRiskResponse) bool {
+		return r.TransactionID == tx.ID && r.Decision == "ALLOW"
+	})).Return(errors.New("endpoint down"))
+
+	svc := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, mockNotifier, nil, nil)
+
+	// A failed callback does not fail the evaluation; the client can poll
+	assert.NoError(t, svc.EvaluateQueued(context.Background(), tx))
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	mockNotifier.AssertExpectations(t)
+}
+
+func TestEvaluateQueued_SkipsClaimedTransaction(t *testing.T) {
+	tx := &Transaction{ID: uuid.New(), UserID: uuid.New(), Amount: 100}
+
+	// Another instance holds the transaction or has evaluated it already
+	mockRepo := new(MockRepository)
+	mockRiskService := new(MockRiskService)
+	mockNotifier := new(MockDecisionNotifier)
+	mockRepo.On("ClaimUnevaluated", mock.Anything, tx.ID).Return(nil, nil)
+
+	svc := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, mockNotifier, nil, nil)
+
+	assert.NoError(t, svc.EvaluateQueued(context.Background(), tx))
+	mockRiskService.AssertNotCalled(t, "CalculateRisk", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "UpdateStatusByID", mock.Anything, mock.Anything, mock.Anything)
+	mockNotifier.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestResumePending(t *testing.T) {
+	first := &Transaction{ID: uuid.New()}
+	second := &Transaction{ID: uuid.New()}
+	third := &Transaction{ID: uuid.New()}
+
+	mockRepo := new(MockRepository)
+	mockQueue := new(MockAsyncQueue)
+	mockRepo.On("ListUnevaluated", mock.Anything, mock.Anything, resumeBatchSize).Return([]*Transaction{first, second, third}, nil)
+	mockQueue.On("Enqueue", first).Return(nil)
+	mockQueue.On("Enqueue", second).Return(ErrQueueFull)
+
+	svc := NewService(mockRepo, nil, new(MockRiskService), nil, nil, nil, mockQueue, nil, nil, nil)
+	n, err := svc.ResumePending(context.Background())
+
+	// The rest waits for the next sweep
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	mockQueue.AssertNotCalled(t, "Enqueue", third)
+}
+
+func TestService_GetTransaction(t *testing.T) {
+	ownerID := uuid.New()
+	txID := uuid.New()
+	evaluatedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		userID        uuid.UUID
+		role          string
+		risk          *risk.TransactionRisk
+		riskErr       error
+		expected      *TransactionStatusResponse
+		expectedError error
+	}{
+		{
+			name:     "pending_has_no_result",
+			userID:   ownerID,
+			role:     "USER",
+			riskErr:  risk.ErrRiskNotFound,
+			expected: &TransactionStatusResponse{TransactionID: txID, Status: "PENDING"},
+		},
+		{
+			name:   "evaluated_includes_result",
+			userID: uuid.New(),
+			role:   "ADMIN",
+			risk:   &risk.TransactionRisk{TransactionID: txID, RiskScore: 10, RiskLevel: "LOW", Decision: "ALLOW", EvaluatedAt: evaluatedAt},
+			expected: &TransactionStatusResponse{TransactionID: txID, Status: "PENDING", RiskResult: &TransactionRiskResponse{
+				TransactionID: txID, RiskScore: 10, RiskLevel: "LOW", Decision: "ALLOW", EvaluatedAt: evaluatedAt,
+			}},
+		},
+		{
+			name:          "other_user_not_found",
+			userID:        uuid.New(),
+			role:          "USER",
+			expectedError: ErrTransactionNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockRiskService := new(MockRiskService)
+			mockRepo.On("GetByID", mock.Anything, txID).Return(&Transaction{ID: txID, UserID: ownerID, TransactionStatus: "PENDING"}, nil)
+			mockRiskService.On("GetRisk", mock.Anything, txID).Return(tt.risk, tt.riskErr)
+
+			svc := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, nil, nil, nil)
+			response, err := svc.GetTransaction(context.Background(), tt.userID, tt.role, txID)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, response)
+		})
+	}
+}
+
+// MockDecisionPublisher is a mock implementation of the DecisionPublisher interface
+type MockDecisionPublisher struct {
+	mock.Mock
+}
+
+func (m *MockDecisionPublisher) Publish(ctx context.Context, eventType string, d webhook.Decision) error {
+	args := m.Called(ctx, eventType, d)
+	return args.Error(0)
+}
+
+func TestCalculateRiskMatrix_PublishesDecision(t *testing.T) {
+	tx := &Transaction{ID: uuid.New(), UserID: uuid.New(), TransactionType: "TRANSFER", Amount: 9000}
+	evaluatedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	mockRepo := new(MockRepository)
+	mockRiskService := new(MockRiskService)
+	mockPublisher := new(MockDecisionPublisher)
+	mockRepo.On("Create", mock.Anything, tx).Return(nil)
+	mockRepo.On("UpdateStatusByID", mock.Anything, tx.ID, "BLOCKED").Return(nil)
+	mockRiskService.On("CalculateRisk", mock.Anything, tx).Return(&risk.TransactionRisk{
+		TransactionID: tx.ID, RiskScore: 90, RiskLevel: "HIGH", Decision: "BLOCK", EvaluatedAt: evaluatedAt,
+	}, nil)
+	mockPublisher.On("Publish", mock.Anything, webhook.EventTransactionEvaluated, webhook.Decision{
+		TransactionID:     tx.ID,
+		UserID:            tx.UserID,
+		TransactionType:   "TRANSFER",
+		Amount:            9000,
+		RiskScore:         90,
+		RiskLevel:         "HIGH",
+		Decision:          "BLOCK",
+		TransactionStatus: "BLOCKED",
+		EvaluatedAt:       evaluatedAt,
+	}).Return(nil).Once()
+
+	svc := NewService(mockRepo, nil, mockRiskService, nil, nil, nil, nil, nil, mockPublisher, nil)
+	_, err := svc.CalculateRiskMatrix(context.Background(), tx)
+	assert.NoError(t, err)
+	mockPublisher.AssertExpectations(t)
+
+	// A decision that cannot be queued fails the evaluation, rolling it back
+	mockPublisher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("db down"))
+	_, err = svc.CalculateRiskMatrix(context.Background(), tx)
+	assert.Error(t, err)
+}
@@ -0,0 +1,118 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrQueueFull   = errors.New("evaluation queue is full")
+	ErrQueueClosed = errors.New("evaluation queue is shutting down")
+)
+
+// WorkerPool evaluates queued transactions in the background. A transaction
+// is queued at most once at a time.
+type WorkerPool struct {
+	workers int
+	jobs    chan *Transaction
+
+	mu     sync.Mutex
+	queued map[uuid.UUID]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewWorkerPool queues up to depth transactions for workers goroutines.
+func NewWorkerPool(workers int, depth int) *WorkerPool {
+	if workers < 1 {
+		workers = 1
+	}
+	if depth < 0 {
+		depth = 0
+	}
+	return &WorkerPool{
+		workers: workers,
+		jobs:    make(chan *Transaction, depth),
+		queued:  make(map[uuid.UUID]struct{}),
+	}
+}
+
+// Start runs the workers. Each job is handled with its own background
+// context, so shutting down lets in-flight evaluations finish.
+func (p *WorkerPool) Start(handle func(ctx context.Context, tx *Transaction) error) {
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for tx := range p.jobs {
+				if err := handle(context.Background(), tx); err != nil {
+					log.Printf("async evaluation of transaction %s failed: %v", tx.ID, err)
+				}
+				p.done(tx.ID)
+			}
+		}()
+	}
+}
+
+// Full reports whether Enqueue would be rejected right now.
+func (p *WorkerPool) Full() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed || len(p.jobs) == cap(p.jobs)
+}
+
+// Enqueue queues tx unless it is already queued or running.
+func (p *WorkerPool) Enqueue(tx *Transaction) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return ErrQueueClosed
+	}
+	if _, ok := p.queued[tx.ID]; ok {
+		return nil
+	}
+
+	select {
+	case p.jobs <- tx:
+		p.queued[tx.ID] = struct{}{}
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (p *WorkerPool) done(id uuid.UUID) {
+	p.mu.Lock()
+	delete(p.queued, id)
+	p.mu.Unlock()
+}
+
+// Shutdown stops accepting work and waits for the queue to drain or ctx to
+// end. Transactions still queued then stay PENDING in the database and are
+// picked up again by ResumePending on the next start.
+func (p *WorkerPool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+	p.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
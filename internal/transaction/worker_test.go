@@ -0,0 +1,75 @@
+package transaction
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPool_EnqueueDedupesAndFills(t *testing.T) {
+	pool := NewWorkerPool(1, 2)
+	tx := &Transaction{ID: uuid.New()}
+
+	assert.NoError(t, pool.Enqueue(tx))
+	assert.NoError(t, pool.Enqueue(tx))
+	assert.False(t, pool.Full())
+
+	assert.NoError(t, pool.Enqueue(&Transaction{ID: uuid.New()}))
+	assert.True(t, pool.Full())
+	assert.ErrorIs(t, pool.Enqueue(&Transaction{ID: uuid.New()}), ErrQueueFull)
+}
+
+func TestWorkerPool_ShutdownDrainsQueue(t *testing.T) {
+	pool := NewWorkerPool(2, 10)
+
+	var mu sync.Mutex
+	handled := map[uuid.UUID]bool{}
+	release := make(chan struct{})
+	pool.Start(func(ctx context.Context, tx *Transaction) error {
+		<-release
+		mu.Lock()
+		handled[tx.ID] = true
+		mu.Unlock()
+		return nil
+	})
+
+	var ids []uuid.UUID
+	for i := 0; i < 5; i++ {
+		tx := &Transaction{ID: uuid.New()}
+		ids = append(ids, tx.ID)
+		assert.NoError(t, pool.Enqueue(tx))
+	}
+
+	done := make(chan error)
+	go func() { done <- pool.Shutdown(context.Background()) }()
+
+	// Shutting down refuses new work but finishes what was queued
+	assert.Eventually(t, func() bool {
+		return pool.Enqueue(&Transaction{ID: uuid.New()}) == ErrQueueClosed
+	}, time.Second, time.Millisecond)
+	close(release)
+
+	assert.NoError(t, <-done)
+	for _, id := range ids {
+		assert.True(t, handled[id])
+	}
+}
+
+func TestWorkerPool_ShutdownTimeout(t *testing.T) {
+	pool := NewWorkerPool(1, 1)
+	release := make(chan struct{})
+	defer close(release)
+	pool.Start(func(ctx context.Context, tx *Transaction) error {
+		<-release
+		return nil
+	})
+	assert.NoError(t, pool.Enqueue(&Transaction{ID: uuid.New()}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pool.Shutdown(ctx), context.DeadlineExceeded)
+}
@@ -0,0 +1,156 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventTransactionEvaluated is published once a transaction has been
+// scored, for synchronous and asynchronous submissions alike.
+const EventTransactionEvaluated = "transaction.evaluated"
+
+// eventTypes are the event types a subscription can filter on.
+var eventTypes = []string{EventTransactionEvaluated}
+
+// Delivery states. A delivery is retried while PENDING and moves to DEAD
+// once it runs out of attempts.
+const (
+	DeliveryPending   = "PENDING"
+	DeliveryDelivered = "DELIVERED"
+	DeliveryDead      = "DEAD"
+)
+
+// Headers sent with every delivery. Receivers check HeaderSignature with
+// Verify before trusting the body.
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+)
+
+// Subscription sends matching events to URL. Empty EventTypes or Decisions
+// match everything; MinScore 0 matches every score.
+type Subscription struct {
+	ID         int64     `gorm:"primaryKey" json:"id"`
+	URL        string    `gorm:"type:text;not null" json:"url"`
+	Secret     string    `gorm:"type:varchar(64);not null" json:"-"`
+	EventTypes []string  `gorm:"type:jsonb;serializer:json;not null" json:"event_types"`
+	Decisions  []string  `gorm:"type:jsonb;serializer:json;not null" json:"decisions"`
+	MinScore   int       `gorm:"not null" json:"min_score"`
+	Enabled    bool      `gorm:"not null" json:"enabled"`
+	CreatedBy  string    `gorm:"type:varchar(255);not null" json:"created_by"`
+	CreatedAt  time.Time `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"type:timestamptz;not null;default:now()" json:"updated_at"`
+}
+
+// CreatedSubscription is returned when a subscription is created. It is the
+// only response that carries the signing secret.
+type CreatedSubscription struct {
+	Subscription
+	Secret string `json:"secret"`
+}
+
+// SubscriptionRequest creates or replaces a subscription. Decisions
+// defaults to FLAG and BLOCK and Enabled to true.
+type SubscriptionRequest struct {
+	URL        string   `json:"url" binding:"required"`
+	EventTypes []string `json:"event_types"`
+	Decisions  []string `json:"decisions"`
+	MinScore   int      `json:"min_score"`
+	Enabled    *bool    `json:"enabled"`
+}
+
+// Decision is the data of an EventTransactionEvaluated event.
+type Decision struct {
+	TransactionID     uuid.UUID `json:"transaction_id"`
+	UserID            uuid.UUID `json:"user_id"`
+	TransactionType   string    `json:"transaction_type"`
+	Amount            float64   `json:"amount"`
+	RiskScore         int       `json:"risk_score"`
+	RiskLevel         string    `json:"risk_level"`
+	Decision          string    `json:"decision"`
+	TransactionStatus string    `json:"transaction_status"`
+	EvaluatedAt       time.Time `json:"evaluated_at"`
+}
+
+// Event is the signed body POSTed to a subscription. ID is the delivery ID,
+// so receivers can drop retries they already processed.
+type Event struct {
+	ID        uuid.UUID   `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Delivery is one event queued for one subscription. The body is fixed when
+// it is queued; every attempt signs it again with a fresh timestamp.
+type Delivery struct {
+	ID             uuid.UUID       `gorm:"type:uuid;primaryKey" json:"id"`
+	SubscriptionID int64           `gorm:"not null" json:"subscription_id"`
+	EventType      string          `gorm:"type:varchar(100);not null" json:"event_type"`
+	Payload        json.RawMessage `gorm:"type:jsonb;serializer:json;not null" json:"payload"`
+	Status         string          `gorm:"type:varchar(20);not null" json:"status"`
+	Attempts       int             `gorm:"not null" json:"attempts"`
+	NextAttemptAt  time.Time       `gorm:"type:timestamptz;not null" json:"next_attempt_at"`
+	LastStatusCode int             `gorm:"not null" json:"last_status_code,omitempty"`
+	LastError      string          `gorm:"type:text;not null" json:"last_error,omitempty"`
+	CreatedAt      time.Time       `gorm:"type:timestamptz;not null;default:now()" json:"created_at"`
+	DeliveredAt    *time.Time      `gorm:"type:timestamptz" json:"delivered_at,omitempty"`
+}
+
+type Repository interface {
+	CreateSubscription(ctx context.Context, s *Subscription) error
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+	// GetSubscription returns gorm.ErrRecordNotFound if there is none.
+	GetSubscription(ctx context.Context, id int64) (*Subscription, error)
+	UpdateSubscription(ctx context.Context, s *Subscription) error
+	// DeleteSubscription deletes the subscription with its deliveries. It
+	// returns gorm.ErrRecordNotFound if there is none.
+	DeleteSubscription(ctx context.Context, id int64) error
+	ListEnabledSubscriptions(ctx context.Context) ([]Subscription, error)
+
+	CreateDeliveries(ctx context.Context, deliveries []Delivery) error
+	// ClaimDue leases up to limit PENDING deliveries due at now by moving
+	// their next attempt to leaseUntil. A delivery whose attempt never
+	// reports back is claimed again once the lease ends.
+	ClaimDue(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]Delivery, error)
+	// SaveAttempt stores the outcome of an attempt on d.
+	SaveAttempt(ctx context.Context, d *Delivery) error
+	// ListDeliveries returns deliveries newest first, optionally filtered by
+	// status and subscription.
+	ListDeliveries(ctx context.Context, status string, subscriptionID int64, offset int, limit int) ([]Delivery, error)
+	// GetDelivery returns gorm.ErrRecordNotFound if there is none.
+	GetDelivery(ctx context.Context, id uuid.UUID) (*Delivery, error)
+	// Requeue makes a DELIVERED or DEAD delivery PENDING again with a fresh
+	// set of attempts. It returns nil if the delivery is still PENDING.
+	Requeue(ctx context.Context, id uuid.UUID, now time.Time) (*Delivery, error)
+}
+
+type Service interface {
+	CreateSubscription(ctx context.Context, actorID string, req SubscriptionRequest) (*CreatedSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+	UpdateSubscription(ctx context.Context, actorID string, id int64, req SubscriptionRequest) (*Subscription, error)
+	DeleteSubscription(ctx context.Context, actorID string, id int64) error
+
+	// Publish queues d for every enabled subscription it matches. It joins
+	// the caller's unit of work, so the deliveries commit with the decision.
+	Publish(ctx context.Context, eventType string, d Decision) error
+	// DeliverDue attempts the deliveries that are due and returns how many
+	// were delivered.
+	DeliverDue(ctx context.Context) (int, error)
+
+	ListDeliveries(ctx context.Context, status string, subscriptionID int64, offset int, limit int) ([]Delivery, error)
+	Redeliver(ctx context.Context, actorID string, id uuid.UUID) (*Delivery, error)
+}
+
+func (Subscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+func (Delivery) TableName() string {
+	return "webhook_deliveries"
+}
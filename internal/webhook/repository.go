@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"risk-detection/internal/db"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CreateSubscription(ctx context.Context, s *Subscription) error {
+	return db.Conn(ctx, r.db).Create(s).Error
+}
+
+func (r *repository) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	var subs []Subscription
+	err := db.Conn(ctx, r.db).Order("id ASC").Find(&subs).Error
+	return subs, err
+}
+
+func (r *repository) GetSubscription(ctx context.Context, id int64) (*Subscription, error) {
+	var s Subscription
+	if err := db.Conn(ctx, r.db).First(&s, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *repository) UpdateSubscription(ctx context.Context, s *Subscription) error {
+	return db.Conn(ctx, r.db).
+		Model(s).
+		Select("url", "event_types", "decisions", "min_score", "enabled", "updated_at").
+		Updates(s).Error
+}
+
+func (r *repository) DeleteSubscription(ctx context.Context, id int64) error {
+	res := db.Conn(ctx, r.db).Delete(&Subscription{}, "id = ?", id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *repository) ListEnabledSubscriptions(ctx context.Context) ([]Subscription, error) {
+	var subs []Subscription
+	err := db.Conn(ctx, r.db).Where("enabled").Order("id ASC").Find(&subs).Error
+	return subs, err
+}
+
+func (r *repository) CreateDeliveries(ctx context.Context, deliveries []Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return db.Conn(ctx, r.db).Create(&deliveries).Error
+}
+
+func (r *repository) ClaimDue(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]Delivery, error) {
+	var deliveries []Delivery
+
+	// SKIP LOCKED lets overlapping dispatch runs claim disjoint batches
+	err := db.Conn(ctx, r.db).Raw(`
+		UPDATE webhook_deliveries
+		SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at ASC, created_at ASC
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		leaseUntil, DeliveryPending, now, limit,
+	).Scan(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *repository) SaveAttempt(ctx context.Context, d *Delivery) error {
+	return db.Conn(ctx, r.db).
+		Model(d).
+		Select("status", "attempts", "next_attempt_at", "last_status_code", "last_error", "delivered_at").
+		Updates(d).Error
+}
+
+func (r *repository) ListDeliveries(ctx context.Context, status string, subscriptionID int64, offset int, limit int) ([]Delivery, error) {
+	var deliveries []Delivery
+
+	q := db.Conn(ctx, r.db)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	if subscriptionID != 0 {
+		q = q.Where("subscription_id = ?", subscriptionID)
+	}
+
+	err := q.Order("created_at DESC, id ASC").
+		Offset(offset).
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *repository) GetDelivery(ctx context.Context, id uuid.UUID) (*Delivery, error) {
+	var d Delivery
+	if err := db.Conn(ctx, r.db).First(&d, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *repository) Requeue(ctx context.Context, id uuid.UUID, now time.Time) (*Delivery, error) {
+	var d Delivery
+	res := db.Conn(ctx, r.db).
+		Model(&d).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND status <> ?", id, DeliveryPending).
+		Updates(map[string]interface{}{
+			"status":           DeliveryPending,
+			"attempts":         0,
+			"next_attempt_at":  now,
+			"last_status_code": 0,
+			"last_error":       "",
+			"delivered_at":     nil,
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &d, nil
+}
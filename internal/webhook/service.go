@@ -0,0 +1,466 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"risk-detection/internal/audit"
+	"risk-detection/internal/callback"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrInvalidSubscription  = errors.New("invalid webhook subscription")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrDeliveryPending      = errors.New("webhook delivery is still pending")
+)
+
+const (
+	// maxAttempts is how often a delivery is tried before it is dead-lettered.
+	maxAttempts = 8
+
+	// Retries wait baseBackoff, doubling after every failed attempt up to
+	// maxBackoff, so a delivery gives up after a bit over an hour.
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 30 * time.Minute
+
+	// deliveryLease must outlast a batch of attempts that all time out, or
+	// a slow batch is claimed a second time.
+	deliveryBatchSize = 20
+	deliveryLease     = 5 * time.Minute
+
+	// maxErrorLength caps the part of a failed response kept on a delivery.
+	maxErrorLength = 512
+)
+
+// decisions are the values a subscription's Decisions filter accepts.
+var decisions = []string{"ALLOW", "FLAG", "BLOCK"}
+
+// defaultDecisions are the decisions a subscription gets when it names none.
+var defaultDecisions = []string{"FLAG", "BLOCK"}
+
+type service struct {
+	repo     Repository
+	client   *http.Client
+	auditLog *audit.Logger
+	lookup   func(ctx context.Context, host string) ([]net.IPAddr, error)
+	now      func() time.Time
+}
+
+// NewService delivers events with client, which should carry a timeout well
+// below deliveryLease / deliveryBatchSize and refuse internal addresses like
+// callback.NewClient does.
+func NewService(repo Repository, client *http.Client, auditLog *audit.Logger) Service {
+	return &service{
+		repo:     repo,
+		client:   client,
+		auditLog: auditLog,
+		lookup:   net.DefaultResolver.LookupIPAddr,
+		now:      time.Now,
+	}
+}
+
+func (s *service) CreateSubscription(ctx context.Context, actorID string, req SubscriptionRequest) (*CreatedSubscription, error) {
+	sub, err := s.subscriptionFromRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	sub.Secret = secret
+	sub.CreatedBy = actorID
+	sub.CreatedAt = now
+	sub.UpdatedAt = now
+
+	if err := s.repo.CreateSubscription(ctx, sub); err != nil {
+		return nil, fmt.Errorf("create webhook subscription: %w", err)
+	}
+
+	s.logSubscription(audit.EventWebhookSubscriptionCreated, "CREATE", actorID, sub)
+	return &CreatedSubscription{Subscription: *sub, Secret: secret}, nil
+}
+
+func (s *service) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	return s.repo.ListSubscriptions(ctx)
+}
+
+func (s *service) UpdateSubscription(ctx context.Context, actorID string, id int64, req SubscriptionRequest) (*Subscription, error) {
+	update, err := s.subscriptionFromRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := s.repo.GetSubscription(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSubscriptionNotFound
+		}
+		return nil, err
+	}
+
+	sub.URL = update.URL
+	sub.EventTypes = update.EventTypes
+	sub.Decisions = update.Decisions
+	sub.MinScore = update.MinScore
+	sub.Enabled = update.Enabled
+	sub.UpdatedAt = s.now()
+
+	if err := s.repo.UpdateSubscription(ctx, sub); err != nil {
+		return nil, fmt.Errorf("update webhook subscription: %w", err)
+	}
+
+	s.logSubscription(audit.EventWebhookSubscriptionUpdated, "UPDATE", actorID, sub)
+	return sub, nil
+}
+
+func (s *service) DeleteSubscription(ctx context.Context, actorID string, id int64) error {
+	if err := s.repo.DeleteSubscription(ctx, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSubscriptionNotFound
+		}
+		return err
+	}
+
+	s.logSubscription(audit.EventWebhookSubscriptionDeleted, "DELETE", actorID, &Subscription{ID: id})
+	return nil
+}
+
+func (s *service) Publish(ctx context.Context, eventType string, d Decision) error {
+	subs, err := s.repo.ListEnabledSubscriptions(ctx)
+	if err != nil {
+		return fmt.Errorf("load webhook subscriptions: %w", err)
+	}
+
+	now := s.now()
+	var deliveries []Delivery
+	for _, sub := range subs {
+		if !sub.matches(eventType, d) {
+			continue
+		}
+
+		id := uuid.New()
+		payload, err := json.Marshal(Event{ID: id, Type: eventType, CreatedAt: now, Data: d})
+		if err != nil {
+			return err
+		}
+		deliveries = append(deliveries, Delivery{
+			ID:             id,
+			SubscriptionID: sub.ID,
+			EventType:      eventType,
+			Payload:        payload,
+			Status:         DeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		})
+	}
+
+	if err := s.repo.CreateDeliveries(ctx, deliveries); err != nil {
+		return fmt.Errorf("queue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+func (s *service) DeliverDue(ctx context.Context) (int, error) {
+	now := s.now()
+	due, err := s.repo.ClaimDue(ctx, now, now.Add(deliveryLease), deliveryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	subs := make(map[int64]*Subscription)
+	delivered := 0
+	for i := range due {
+		d := &due[i]
+
+		sub, ok := subs[d.SubscriptionID]
+		if !ok {
+			sub, err = s.repo.GetSubscription(ctx, d.SubscriptionID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return delivered, err
+			}
+			subs[d.SubscriptionID] = sub
+		}
+
+		if sub == nil || !sub.Enabled {
+			// Kept as dead letters so they can be redelivered once the
+			// subscription is enabled again
+			d.Status = DeliveryDead
+			d.LastError = "subscription is disabled"
+		} else {
+			s.attempt(ctx, sub, d)
+		}
+
+		if err := s.repo.SaveAttempt(ctx, d); err != nil {
+			return delivered, fmt.Errorf("save webhook delivery %s: %w", d.ID, err)
+		}
+
+		switch d.Status {
+		case DeliveryDelivered:
+			delivered++
+		case DeliveryDead:
+			log.Printf("webhook delivery %s to subscription %d dead-lettered: %s", d.ID, d.SubscriptionID, d.LastError)
+			s.logDelivery(audit.EventWebhookDeliveryDead, "DELIVER", "SYSTEM", "", "FAILURE", d)
+		}
+	}
+	return delivered, nil
+}
+
+// attempt POSTs d to sub and records the outcome on d.
+func (s *service) attempt(ctx context.Context, sub *Subscription, d *Delivery) {
+	now := s.now()
+	d.Attempts++
+
+	statusCode, err := s.post(ctx, sub, d, now)
+	d.LastStatusCode = statusCode
+	if err == nil {
+		d.Status = DeliveryDelivered
+		d.LastError = ""
+		d.DeliveredAt = &now
+		return
+	}
+
+	d.LastError = truncate(err.Error(), maxErrorLength)
+	if d.Attempts >= maxAttempts {
+		d.Status = DeliveryDead
+		return
+	}
+	d.NextAttemptAt = now.Add(backoff(d.Attempts))
+}
+
+func (s *service) post(ctx context.Context, sub *Subscription, d *Delivery, now time.Time) (int, error) {
+	timestamp := now.Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderDelivery, d.ID.String())
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff is the wait after the given number of failed attempts.
+func backoff(attempts int) time.Duration {
+	wait := baseBackoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return wait
+}
+
+func (s *service) ListDeliveries(ctx context.Context, status string, subscriptionID int64, offset int, limit int) ([]Delivery, error) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.repo.ListDeliveries(ctx, strings.ToUpper(status), subscriptionID, offset, limit)
+}
+
+// Redeliver queues a delivered or dead-lettered delivery again with a fresh
+// set of attempts. The body, and so its ID, stays the same.
+func (s *service) Redeliver(ctx context.Context, actorID string, id uuid.UUID) (*Delivery, error) {
+	d, err := s.repo.Requeue(ctx, id, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		if _, err := s.repo.GetDelivery(ctx, id); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrDeliveryNotFound
+			}
+			return nil, err
+		}
+		return nil, ErrDeliveryPending
+	}
+
+	s.logDelivery(audit.EventWebhookRedelivered, "REDELIVER", "USER", actorID, "SUCCESS", d)
+	return d, nil
+}
+
+// matches reports whether an event with data d goes to s.
+func (s Subscription) matches(eventType string, d Decision) bool {
+	if len(s.EventTypes) > 0 && !contains(s.EventTypes, eventType) {
+		return false
+	}
+	if len(s.Decisions) > 0 && !contains(s.Decisions, d.Decision) {
+		return false
+	}
+	return d.RiskScore >= s.MinScore
+}
+
+func (s *service) subscriptionFromRequest(ctx context.Context, req SubscriptionRequest) (*Subscription, error) {
+	u, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https url", ErrInvalidSubscription)
+	}
+	if err := callback.CheckHost(ctx, s.lookup, u.Hostname()); err != nil {
+		if errors.Is(err, callback.ErrForbiddenDestination) {
+			return nil, fmt.Errorf("%w: url must not point to a loopback, link-local or private address", ErrInvalidSubscription)
+		}
+		return nil, fmt.Errorf("%w: url host does not resolve", ErrInvalidSubscription)
+	}
+
+	eventTypes, err := normalizeFilter(req.EventTypes, eventTypes, strings.ToLower)
+	if err != nil {
+		return nil, fmt.Errorf("%w: event_types: %v", ErrInvalidSubscription, err)
+	}
+
+	filter := req.Decisions
+	if filter == nil {
+		filter = defaultDecisions
+	}
+	decisionFilter, err := normalizeFilter(filter, decisions, strings.ToUpper)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decisions: %v", ErrInvalidSubscription, err)
+	}
+
+	if req.MinScore < 0 || req.MinScore > 100 {
+		return nil, fmt.Errorf("%w: min_score must be between 0 and 100", ErrInvalidSubscription)
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	return &Subscription{
+		URL:        u.String(),
+		EventTypes: eventTypes,
+		Decisions:  decisionFilter,
+		MinScore:   req.MinScore,
+		Enabled:    enabled,
+	}, nil
+}
+
+// normalizeFilter checks values against allowed and drops duplicates. It
+// never returns nil, so an empty filter is stored as [] rather than null.
+func normalizeFilter(values []string, allowed []string, normalize func(string) string) ([]string, error) {
+	out := []string{}
+	for _, v := range values {
+		v = normalize(strings.TrimSpace(v))
+		if !contains(allowed, v) {
+			return nil, fmt.Errorf("%q is not one of %s", v, strings.Join(allowed, ", "))
+		}
+		if !contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// newSecret draws a 256-bit signing secret.
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *service) logSubscription(event audit.EventType, action string, actorID string, sub *Subscription) {
+	if s.auditLog == nil {
+		return
+	}
+
+	entry := audit.AuditLog{
+		EventType:  event,
+		Action:     action,
+		EntityType: "webhook_subscriptions",
+		EntityID:   strconv.FormatInt(sub.ID, 10),
+		ActorType:  "USER",
+		ActorID:    actorID,
+		ActorRole:  "ADMIN",
+		Status:     "SUCCESS",
+	}
+	if action != "DELETE" {
+		entry.NewValues = map[string]interface{}{
+			"url":         sub.URL,
+			"event_types": sub.EventTypes,
+			"decisions":   sub.Decisions,
+			"min_score":   sub.MinScore,
+			"enabled":     sub.Enabled,
+		}
+	}
+	s.auditLog.Log(entry)
+}
+
+func (s *service) logDelivery(event audit.EventType, action string, actorType string, actorID string, status string, d *Delivery) {
+	if s.auditLog == nil {
+		return
+	}
+
+	entry := audit.AuditLog{
+		EventType:  event,
+		Action:     action,
+		EntityType: "webhook_deliveries",
+		EntityID:   d.ID.String(),
+		ActorType:  actorType,
+		ActorID:    actorID,
+		NewValues: map[string]interface{}{
+			"subscription_id": d.SubscriptionID,
+			"event_type":      d.EventType,
+			"attempts":        d.Attempts,
+			"last_error":      d.LastError,
+		},
+		Status: status,
+	}
+	if actorType == "USER" {
+		entry.ActorRole = "ADMIN"
+	}
+	s.auditLog.Log(entry)
+}
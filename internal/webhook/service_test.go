@@ -0,0 +1,421 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// MockRepository is a mock implementation of the Repository interface
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) CreateSubscription(ctx context.Context, s *Subscription) error {
+	args := m.Called(ctx, s)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]Subscription), args.Error(1)
+}
+
+func (m *MockRepository) GetSubscription(ctx context.Context, id int64) (*Subscription, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Subscription), args.Error(1)
+}
+
+func (m *MockRepository) UpdateSubscription(ctx context.Context, s *Subscription) error {
+	args := m.Called(ctx, s)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteSubscription(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListEnabledSubscriptions(ctx context.Context) ([]Subscription, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]Subscription), args.Error(1)
+}
+
+func (m *MockRepository) CreateDeliveries(ctx context.Context, deliveries []Delivery) error {
+	args := m.Called(ctx, deliveries)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ClaimDue(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]Delivery, error) {
+	args := m.Called(ctx, now, leaseUntil, limit)
+	return args.Get(0).([]Delivery), args.Error(1)
+}
+
+func (m *MockRepository) SaveAttempt(ctx context.Context, d *Delivery) error {
+	args := m.Called(ctx, d)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListDeliveries(ctx context.Context, status string, subscriptionID int64, offset int, limit int) ([]Delivery, error) {
+	args := m.Called(ctx, status, subscriptionID, offset, limit)
+	return args.Get(0).([]Delivery), args.Error(1)
+}
+
+func (m *MockRepository) GetDelivery(ctx context.Context, id uuid.UUID) (*Delivery, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Delivery), args.Error(1)
+}
+
+func (m *MockRepository) Requeue(ctx context.Context, id uuid.UUID, now time.Time) (*Delivery, error) {
+	args := m.Called(ctx, id, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Delivery), args.Error(1)
+}
+
+func newTestService(repo Repository, now time.Time) *service {
+	svc := NewService(repo, &http.Client{Timeout: time.Second}, nil).(*service)
+	svc.lookup = staticLookup("203.0.113.10")
+	svc.now = func() time.Time { return now }
+	return svc
+}
+
+// staticLookup resolves every host to addrs.
+func staticLookup(addrs ...string) func(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		var out []net.IPAddr
+		for _, a := range addrs {
+			out = append(out, net.IPAddr{IP: net.ParseIP(a)})
+		}
+		return out, nil
+	}
+}
+
+func TestCreateSubscription(t *testing.T) {
+	enabled := false
+
+	tests := []struct {
+		name          string
+		req           SubscriptionRequest
+		expected      *Subscription
+		expectedError error
+	}{
+		{
+			name:     "defaults_to_flag_and_block",
+			req:      SubscriptionRequest{URL: "https://processor.example.com/hooks"},
+			expected: &Subscription{URL: "https://processor.example.com/hooks", EventTypes: []string{}, Decisions: []string{"FLAG", "BLOCK"}, Enabled: true},
+		},
+		{
+			name: "normalizes_filters",
+			req: SubscriptionRequest{
+				URL:        "https://processor.example.com/hooks",
+				EventTypes: []string{" Transaction.Evaluated "},
+				Decisions:  []string{"block", "BLOCK"},
+				MinScore:   70,
+				Enabled:    &enabled,
+			},
+			expected: &Subscription{URL: "https://processor.example.com/hooks", EventTypes: []string{EventTransactionEvaluated}, Decisions: []string{"BLOCK"}, MinScore: 70},
+		},
+		{
+			name:          "relative_url",
+			req:           SubscriptionRequest{URL: "/hooks"},
+			expectedError: ErrInvalidSubscription,
+		},
+		{
+			name:          "loopback_url",
+			req:           SubscriptionRequest{URL: "http://127.0.0.1:8080/hooks"},
+			expectedError: ErrInvalidSubscription,
+		},
+		{
+			name:          "metadata_url",
+			req:           SubscriptionRequest{URL: "http://169.254.169.254/latest/meta-data"},
+			expectedError: ErrInvalidSubscription,
+		},
+		{
+			name:          "unknown_decision",
+			req:           SubscriptionRequest{URL: "https://processor.example.com/hooks", Decisions: []string{"REVIEW"}},
+			expectedError: ErrInvalidSubscription,
+		},
+		{
+			name:          "unknown_event_type",
+			req:           SubscriptionRequest{URL: "https://processor.example.com/hooks", EventTypes: []string{"user.created"}},
+			expectedError: ErrInvalidSubscription,
+		},
+		{
+			name:          "min_score_out_of_range",
+			req:           SubscriptionRequest{URL: "https://processor.example.com/hooks", MinScore: 101},
+			expectedError: ErrInvalidSubscription,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockRepo.On("CreateSubscription", mock.Anything, mock.Anything).Return(nil)
+
+			created, err := newTestService(mockRepo, time.Now()).CreateSubscription(context.Background(), "admin-1", tt.req)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				mockRepo.AssertNotCalled(t, "CreateSubscription", mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected.URL, created.URL)
+			assert.Equal(t, tt.expected.EventTypes, created.EventTypes)
+			assert.Equal(t, tt.expected.Decisions, created.Decisions)
+			assert.Equal(t, tt.expected.MinScore, created.MinScore)
+			assert.Equal(t, tt.expected.Enabled, created.Enabled)
+			assert.Equal(t, "admin-1", created.CreatedBy)
+			assert.Len(t, created.Secret, 64)
+			assert.Equal(t, created.Secret, created.Subscription.Secret)
+		})
+	}
+}
+
+func TestUpdateSubscription_ForbiddenDestination(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := newTestService(mockRepo, time.Now())
+	svc.lookup = staticLookup("203.0.113.10", "10.1.2.3")
+
+	// A public name that also resolves to an internal address is refused
+	_, err := svc.UpdateSubscription(context.Background(), "admin-1", 1, SubscriptionRequest{URL: "https://processor.example.com/hooks"})
+	assert.ErrorIs(t, err, ErrInvalidSubscription)
+	mockRepo.AssertNotCalled(t, "GetSubscription", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "UpdateSubscription", mock.Anything, mock.Anything)
+}
+
+func TestCreatedSubscription_ShowsSecretOnce(t *testing.T) {
+	sub := Subscription{ID: 1, Secret: "s3cret"}
+
+	listed, _ := json.Marshal(sub)
+	assert.NotContains(t, string(listed), "s3cret")
+
+	created, _ := json.Marshal(CreatedSubscription{Subscription: sub, Secret: sub.Secret})
+	assert.Contains(t, string(created), `"secret":"s3cret"`)
+}
+
+func TestPublish_FiltersSubscriptions(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	d := Decision{TransactionID: uuid.New(), RiskScore: 65, Decision: "FLAG"}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("ListEnabledSubscriptions", mock.Anything).Return([]Subscription{
+		{ID: 1},
+		{ID: 2, Decisions: []string{"FLAG", "BLOCK"}, MinScore: 60},
+		{ID: 3, Decisions: []string{"BLOCK"}},
+		{ID: 4, MinScore: 70},
+		{ID: 5, EventTypes: []string{"transaction.other"}},
+	}, nil)
+
+	var queued []Delivery
+	mockRepo.On("CreateDeliveries", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { queued = args.Get(1).([]Delivery) }).
+		Return(nil)
+
+	err := newTestService(mockRepo, now).Publish(context.Background(), EventTransactionEvaluated, d)
+	assert.NoError(t, err)
+
+	if assert.Len(t, queued, 2) {
+		assert.Equal(t, int64(1), queued[0].SubscriptionID)
+		assert.Equal(t, int64(2), queued[1].SubscriptionID)
+	}
+	for _, delivery := range queued {
+		assert.Equal(t, DeliveryPending, delivery.Status)
+		assert.Equal(t, now, delivery.NextAttemptAt)
+
+		var event struct {
+			ID   uuid.UUID `json:"id"`
+			Type string    `json:"type"`
+			Data Decision  `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(delivery.Payload, &event))
+		assert.Equal(t, delivery.ID, event.ID)
+		assert.Equal(t, EventTransactionEvaluated, event.Type)
+		assert.Equal(t, d.TransactionID, event.Data.TransactionID)
+	}
+}
+
+func TestDeliverDue_SignsRequest(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	sub := &Subscription{ID: 1, Secret: "shared-secret", Enabled: true}
+
+	var verifyErr error
+	var headers http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		verifyErr = Verify(sub.Secret, r.Header.Get(HeaderSignature), r.Header.Get(HeaderTimestamp), body, time.Now(), 5*time.Minute)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	sub.URL = server.URL
+
+	payload := json.RawMessage(`{"id":"x","type":"transaction.evaluated","data":{"decision":"BLOCK"}}`)
+	delivery := Delivery{ID: uuid.New(), SubscriptionID: 1, EventType: EventTransactionEvaluated, Payload: payload, Status: DeliveryPending}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("ClaimDue", mock.Anything, now, now.Add(deliveryLease), deliveryBatchSize).Return([]Delivery{delivery}, nil)
+	mockRepo.On("GetSubscription", mock.Anything, int64(1)).Return(sub, nil)
+	mockRepo.On("SaveAttempt", mock.Anything, mock.Anything).Return(nil)
+
+	n, err := newTestService(mockRepo, now).DeliverDue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	assert.NoError(t, verifyErr)
+	assert.Equal(t, string(payload), string(body))
+	assert.Equal(t, delivery.ID.String(), headers.Get(HeaderDelivery))
+	assert.Equal(t, EventTransactionEvaluated, headers.Get(HeaderEvent))
+	assert.Equal(t, "application/json", headers.Get("Content-Type"))
+
+	saved := mockRepo.Calls[len(mockRepo.Calls)-1].Arguments.Get(1).(*Delivery)
+	assert.Equal(t, DeliveryDelivered, saved.Status)
+	assert.Equal(t, 1, saved.Attempts)
+	assert.Equal(t, http.StatusOK, saved.LastStatusCode)
+	assert.Equal(t, &now, saved.DeliveredAt)
+
+	// A receiver holding another secret rejects the same request
+	assert.ErrorIs(t, Verify("other-secret", headers.Get(HeaderSignature), headers.Get(HeaderTimestamp), body, time.Now(), 5*time.Minute), ErrInvalidSignature)
+}
+
+func TestDeliverDue_RetriesThenDeadLetters(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "processor unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	sub := &Subscription{ID: 1, URL: server.URL, Secret: "shared-secret", Enabled: true}
+
+	tests := []struct {
+		name           string
+		attempts       int
+		expectedStatus string
+		expectedNext   time.Time
+	}{
+		{name: "first_failure_waits_base_backoff", attempts: 0, expectedStatus: DeliveryPending, expectedNext: now.Add(baseBackoff)},
+		{name: "third_failure_doubles_twice", attempts: 2, expectedStatus: DeliveryPending, expectedNext: now.Add(4 * baseBackoff)},
+		{name: "last_attempt_dead_letters", attempts: maxAttempts - 1, expectedStatus: DeliveryDead, expectedNext: now},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delivery := Delivery{ID: uuid.New(), SubscriptionID: 1, Payload: json.RawMessage(`{}`), Status: DeliveryPending, Attempts: tt.attempts, NextAttemptAt: now}
+
+			mockRepo := new(MockRepository)
+			mockRepo.On("ClaimDue", mock.Anything, now, now.Add(deliveryLease), deliveryBatchSize).Return([]Delivery{delivery}, nil)
+			mockRepo.On("GetSubscription", mock.Anything, int64(1)).Return(sub, nil)
+			mockRepo.On("SaveAttempt", mock.Anything, mock.Anything).Return(nil)
+
+			n, err := newTestService(mockRepo, now).DeliverDue(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, 0, n)
+
+			saved := mockRepo.Calls[len(mockRepo.Calls)-1].Arguments.Get(1).(*Delivery)
+			assert.Equal(t, tt.expectedStatus, saved.Status)
+			assert.Equal(t, tt.attempts+1, saved.Attempts)
+			assert.Equal(t, tt.expectedNext, saved.NextAttemptAt)
+			assert.Equal(t, http.StatusServiceUnavailable, saved.LastStatusCode)
+			assert.Contains(t, saved.LastError, "processor unavailable")
+		})
+	}
+}
+
+func TestDeliverDue_DisabledSubscription(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	delivery := Delivery{ID: uuid.New(), SubscriptionID: 1, Status: DeliveryPending}
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("ClaimDue", mock.Anything, now, now.Add(deliveryLease), deliveryBatchSize).Return([]Delivery{delivery}, nil)
+	mockRepo.On("GetSubscription", mock.Anything, int64(1)).Return(&Subscription{ID: 1, URL: "http://127.0.0.1:1", Enabled: false}, nil)
+	mockRepo.On("SaveAttempt", mock.Anything, mock.MatchedBy(func(d *Delivery) bool {
+		return d.Status == DeliveryDead && d.Attempts == 0
+	})).Return(nil)
+
+	_, err := newTestService(mockRepo, now).DeliverDue(context.Background())
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, backoff(1))
+	assert.Equal(t, time.Minute, backoff(2))
+	assert.Equal(t, 16*time.Minute, backoff(6))
+	assert.Equal(t, maxBackoff, backoff(7))
+	assert.Equal(t, maxBackoff, backoff(40))
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"type":"transaction.evaluated"}`)
+	ts := now.Unix()
+	sig := Sign("secret", ts, body)
+
+	assert.NoError(t, Verify("secret", sig, "1772366400", body, now, time.Minute))
+	assert.ErrorIs(t, Verify("secret", sig, "1772366400", []byte(`{}`), now, time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", sig, "1772366400", body, now.Add(2*time.Minute), time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", sig, "1772366401", body, now, time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", "not-a-signature", "1772366400", body, now, time.Minute), ErrInvalidSignature)
+}
+
+func TestRedeliver(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	id := uuid.New()
+
+	tests := []struct {
+		name          string
+		requeued      *Delivery
+		existing      *Delivery
+		existingErr   error
+		expectedError error
+	}{
+		{name: "dead_letter_is_queued", requeued: &Delivery{ID: id, Status: DeliveryPending}},
+		{name: "pending_conflicts", existing: &Delivery{ID: id, Status: DeliveryPending}, expectedError: ErrDeliveryPending},
+		{name: "unknown_delivery", existingErr: gorm.ErrRecordNotFound, expectedError: ErrDeliveryNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockRepo.On("Requeue", mock.Anything, id, now).Return(tt.requeued, nil)
+			mockRepo.On("GetDelivery", mock.Anything, id).Return(tt.existing, tt.existingErr)
+
+			d, err := newTestService(mockRepo, now).Redeliver(context.Background(), "admin-1", id)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, DeliveryPending, d.Status)
+		})
+	}
+}
+
+func TestPublish_RepositoryError(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockRepo.On("ListEnabledSubscriptions", mock.Anything).Return([]Subscription{{ID: 1}}, nil)
+	mockRepo.On("CreateDeliveries", mock.Anything, mock.Anything).Return(errors.New("db down"))
+
+	err := newTestService(mockRepo, time.Now()).Publish(context.Background(), EventTransactionEvaluated, Decision{Decision: "BLOCK"})
+	assert.Error(t, err)
+}